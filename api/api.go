@@ -2,15 +2,22 @@ package api
 
 import (
 	"context"
-	"encoding/csv"
+	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/render"
+	"github.com/mrnbort/summer_break/audit"
+	"github.com/mrnbort/summer_break/ingest"
+	"github.com/mrnbort/summer_break/jobs"
 	"github.com/mrnbort/summer_break/model"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -23,13 +30,78 @@ type Service struct {
 	httpServer   *http.Server
 	ReadTimeOut  time.Duration
 	WriteTimeOut time.Duration
+
+	IngestChunkSize int // transactions of uploaded file staged at once, ingest.DefaultChunkSize if not set
+	IngestWorkers   int // parallel parsers of uploaded file, ingest.DefaultWorkers if not set
+
+	Jobs *jobs.Manager // processes async uploads, they are disabled if not set
+
+	IdempotencyTTL time.Duration // how long responses are kept for retries with the same key, DefaultIdempotencyTTL if not set
+
+	Auth          bool          // require API key or session token with scope of the endpoint, the API is open if not set
+	AdminKey      string        // bootstrap API key with admin scope, to make the other keys and users with
+	SessionSecret []byte        // signs session tokens of logged in users, random if not set, so they end on restart
+	SessionTTL    time.Duration // how long session tokens are valid, DefaultSessionTTL if not set
+
+	Audit *audit.Log // records changes made through the API, disabled if not set
+
+	MaxBodySize   int64   // bytes of request body, unlimited if not set
+	MaxUploadSize int64   // bytes of uploaded CSV or OFX file, instead of MaxBodySize, unlimited if not set
+	MaxImportSize int64   // bytes of the archive of POST /import, instead of MaxBodySize, unlimited if not set
+	MaxRows       int     // rows of uploaded file or JSON array, unlimited if not set
+	MaxParts      int     // multipart parts read looking for the uploaded file, unlimited if not set
+	RateLimit     float64 // requests a second per client, unlimited if not set
+	RateBurst     int     // requests a client can make at once, RateLimit rounded up if not set
+	AddrRateLimit float64 // requests a second per address, checked before authentication, unlimited if not set
+	AddrRateBurst int     // requests an address can make at once, AddrRateLimit rounded up if not set
 }
 
 // Processor interface provides access to the functions that work with transaction data
 type Processor interface {
 	ParseTransaction(rec []string) (model.Transaction, error)
-	ProcessTransactions(ctx context.Context, transactions []model.Transaction) error
-	GenerateReport(ctx context.Context) (model.Report, error)
+	ProcessTransactions(ctx context.Context, transactions []model.Transaction) ([]model.Transaction, error)
+	StageTransactions(ctx context.Context, batchID string, transactions []model.Transaction) (string, error)
+	CommitBatch(ctx context.Context, batchID string) ([]model.Transaction, error)
+	DiscardBatch(ctx context.Context, batchID string) error
+	GenerateReport(ctx context.Context, filter model.Filter) (model.Report, error)
+	UpdateTransaction(ctx context.Context, id string, upd model.TransactionUpdate) (model.Transaction, error)
+	Accounts(ctx context.Context) ([]model.Account, error)
+	SetOpeningBalance(ctx context.Context, account string, amount float64, date time.Time) (model.Account, error)
+	Balance(ctx context.Context, accounts []string, asOf time.Time) (model.Balance, error)
+	Transactions(ctx context.Context, filter model.Filter) ([]model.LedgerLine, error)
+	Reconcile(ctx context.Context, account string, st model.Statement, tol model.Tolerance) (model.Reconciliation, error)
+	ChartOfAccounts(ctx context.Context) ([]model.ChartAccount, error)
+	SetChartAccount(ctx context.Context, acc model.ChartAccount) (model.ChartAccount, error)
+	PostEntry(ctx context.Context, entry model.JournalEntry) (model.JournalEntry, error)
+	JournalEntries(ctx context.Context, filter model.Filter) ([]model.JournalEntry, error)
+	TrialBalance(ctx context.Context, asOf time.Time) ([]model.ChartBalance, error)
+	ProfitAndLoss(ctx context.Context, filter model.Filter, compare string) (model.PnL, error)
+	TaxConfig(ctx context.Context) (model.TaxConfig, error)
+	SetTaxConfig(ctx context.Context, cfg model.TaxConfig) error
+	TaxSummary(ctx context.Context, year int, filter model.Filter) (model.TaxSummary, error)
+	Budgets(ctx context.Context) ([]model.Budget, error)
+	Budget(ctx context.Context, id string) (model.Budget, error)
+	AddBudget(ctx context.Context, b model.Budget) (model.Budget, error)
+	UpdateBudget(ctx context.Context, b model.Budget) (model.Budget, error)
+	DeleteBudget(ctx context.Context, id string) error
+	BudgetReport(ctx context.Context, filter model.Filter) (model.BudgetReport, error)
+	Schedules(ctx context.Context) ([]model.Schedule, error)
+	AddSchedule(ctx context.Context, sch model.Schedule) (model.Schedule, error)
+	DeleteSchedule(ctx context.Context, id string) error
+	Forecast(ctx context.Context, req model.ForecastRequest) (model.Forecast, error)
+	Compare(ctx context.Context, filter model.Filter, previous model.Period, byCategory bool) (model.Comparison, error)
+	Snapshot(ctx context.Context) (model.Snapshot, error)
+	Import(ctx context.Context, snap model.Snapshot, policy string) (model.ImportResult, error)
+	APIKeys(ctx context.Context) ([]model.APIKey, error)
+	AddAPIKey(ctx context.Context, key model.APIKey) (model.APIKey, error)
+	APIKeyByHash(ctx context.Context, hash string) (model.APIKey, error)
+	DeleteAPIKey(ctx context.Context, id string) error
+	Users(ctx context.Context) ([]model.User, error)
+	User(ctx context.Context, name string) (model.User, error)
+	AddUser(ctx context.Context, u model.User) (model.User, error)
+	UpdateUser(ctx context.Context, u model.User) (model.User, error)
+	DeleteUser(ctx context.Context, name string) error
+	Transaction(ctx context.Context, id string) (model.Transaction, error)
 }
 
 // JSON is a map alias, just for convenience
@@ -61,86 +133,577 @@ func (s Service) Run(ctx context.Context) error {
 	return nil
 }
 
+// Handler returns http handler of the service, to serve it without Run, i.e. in tests of API clients
+func (s Service) Handler() http.Handler {
+	return s.routes()
+}
+
 func (s Service) routes() chi.Router {
-	mux := chi.NewRouter()
-	mux.Post("/transactions", s.handleTransactions)
-	mux.Get("/report", s.handleReport)
-	return mux
+	if len(s.SessionSecret) == 0 {
+		s.SessionSecret = make([]byte, 32)
+		if _, err := rand.Read(s.SessionSecret); err != nil {
+			log.Printf("[WARN] can't make session secret: %v", err)
+		}
+	}
+
+	limiter := newRateLimiter(s.RateLimit, s.RateBurst)
+	root := chi.NewRouter()
+	root.Use(requestID, newRateLimiter(s.AddrRateLimit, s.AddrRateBurst).middleware(clientIP), s.limitBody)
+	root.With(limiter.middleware(clientName)).Post("/auth/login", s.handleLogin)
+	mux := root.With(s.authenticate, limiter.middleware(clientName))
+	idem := newIdempotency(s.IdempotencyTTL)
+	mux.Get("/auth/me", s.handleMe)
+
+	mux.Group(func(r chi.Router) {
+		r.Use(s.authorize(model.ScopeReadReport))
+		r.Get("/transactions", s.handleListTransactions)
+		r.Get("/report", s.handleReport)
+		r.Get("/balance", s.handleBalance)
+		r.Get("/accounts", s.handleAccounts)
+		r.Get("/accounts/{account}/transactions", s.handleListTransactions)
+		r.Get("/accounts/{account}/report", s.handleReport)
+		r.Get("/reports/pnl", s.handleProfitAndLoss)
+		r.Get("/reports/tax/config", s.handleTaxConfig)
+		r.Get("/reports/tax/{year}", s.handleTaxSummary)
+		r.Get("/reports/budget", s.handleBudgetReport)
+		r.Get("/reports/forecast", s.handleForecast)
+		r.Get("/reports/compare", s.handleCompare)
+		r.Get("/budgets", s.handleBudgets)
+		r.Get("/budgets/{id}", s.handleBudget)
+		r.Get("/schedules", s.handleSchedules)
+		r.Get("/jobs", s.handleJobs)
+		r.Get("/jobs/{id}", s.handleJob)
+		r.Get("/journal/accounts", s.handleChartOfAccounts)
+		r.Get("/journal/entries", s.handleJournalEntries)
+		r.Get("/journal/trial-balance", s.handleTrialBalance)
+	})
+
+	mux.Group(func(r chi.Router) {
+		r.Use(s.authorize(model.ScopeWriteTransactions), idem.middleware, s.audit(false))
+		r.Post("/transactions", s.handleTransactions)
+		r.Patch("/transactions/{id}", s.handlePatchTransaction)
+		r.Put("/accounts/{account}", s.handlePutAccount)
+		r.Post("/accounts/{account}/transactions", s.handleTransactions)
+		r.Post("/accounts/{account}/reconcile", s.handleReconcile)
+		r.Post("/budgets", s.handleSetBudget)
+		r.Put("/budgets/{id}", s.handleSetBudget)
+		r.Delete("/budgets/{id}", s.handleDeleteBudget)
+		r.Post("/schedules", s.handleAddSchedule)
+		r.Delete("/schedules/{id}", s.handleDeleteSchedule)
+		r.Delete("/jobs/{id}", s.handleCancelJob)
+		r.Post("/journal/entries", s.handlePostEntry)
+	})
+
+	mux.Group(func(r chi.Router) {
+		r.Use(s.authorize(model.ScopeAdmin), idem.middleware, s.audit(true))
+		r.Put("/reports/tax/config", s.handleSetTaxConfig)
+		r.Post("/journal/accounts", s.handleSetChartAccount)
+		r.Get("/export", s.handleExport)
+		r.Post("/import", s.handleImport)
+		r.Get("/keys", s.handleAPIKeys)
+		r.Post("/keys", s.handleAddAPIKey)
+		r.Delete("/keys/{id}", s.handleDeleteAPIKey)
+		r.Get("/users", s.handleUsers)
+		r.Post("/users", s.handleSetUser)
+		r.Put("/users/{name}", s.handleSetUser)
+		r.Delete("/users/{name}", s.handleDeleteUser)
+		r.Get("/audit", s.handleAudit)
+		r.Get("/audit/verify", s.handleVerifyAudit)
+	})
+	return root
 }
 
-// POST /transactions
+// POST /transactions and POST /accounts/{account}/transactions, accepts multipart CSV file
+// or JSON array of transactions. Transactions without account go to the default one.
+// With async=true CSV file is ingested in the background, see handleAsyncUpload.
 func (s Service) handleTransactions(w http.ResponseWriter, r *http.Request) {
-	file, _, err := r.FormFile("file")
+	account := model.DefaultAccount
+	if a := chi.URLParam(r, "account"); a != "" {
+		account = a
+	}
+	if err := model.ValidateAccount(account); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+
+	async := false
+	if v := r.URL.Query().Get("async"); v != "" {
+		var err error
+		if async, err = strconv.ParseBool(v); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, JSON{"error": fmt.Sprintf("invalid async value %q", v)})
+			return
+		}
+	}
+	isJSON := strings.HasPrefix(r.Header.Get("Content-Type"), "application/json")
+	switch {
+	case async && isJSON:
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": "async mode supports CSV files only"})
+		return
+	case async:
+		s.handleAsyncUpload(w, r, account)
+		return
+	case !isJSON:
+		s.handleCSV(w, r, account)
+		return
+	}
+
+	transactions, err := s.readJSON(r.Body)
 	if err != nil {
-		log.Printf("[WARN] can't get file: %v", err)
+		log.Printf("[WARN] can't read transactions: %v", err)
+		render.Status(r, readStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+
+	if len(transactions) <= 0 {
+		log.Printf("[WARN] input file has no valid transations")
 		render.Status(r, http.StatusBadRequest)
+		return
+	}
+	for i := range transactions {
+		transactions[i].Account = account
+	}
+
+	stored, err := s.Processor.ProcessTransactions(r.Context(), transactions)
+	if err != nil {
+		log.Printf("[WARN] can't process transactions: %v", err)
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+
+	resp := JSON{"status": "ok"}
+	if flagged, count := flaggedTransactions(stored); count > 0 {
+		resp["flagged"], resp["flaggedCount"] = flagged, count
+	}
+	render.JSON(w, r, resp)
+}
+
+// handleCSV streams multipart CSV file through the ingest pipeline, the file is stored completely or not
+// at all. Lines which can't be parsed are skipped and listed in the response with their numbers.
+func (s Service) handleCSV(w http.ResponseWriter, r *http.Request, account string) {
+	file, err := formFile(r, "file", s.MaxParts)
+	if err != nil {
+		log.Printf("[WARN] can't read transactions: %v", err)
+		render.Status(r, readStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+
+	pipeline := ingest.Pipeline{Store: s.Processor, ChunkSize: s.IngestChunkSize, Workers: s.IngestWorkers,
+		MaxRows: s.MaxRows}
+	res, err := pipeline.Run(r.Context(), file, account)
+	if err != nil {
+		log.Printf("[WARN] can't process transactions: %v", err)
+		render.Status(r, errStatus(err))
 		render.JSON(w, r, JSON{"error": err.Error()})
 		return
 	}
-	defer file.Close()
+	for _, e := range res.Errors {
+		log.Printf("[WARN] skipped line %d: %s", e.Line, e.Error)
+	}
+
+	if res.Accepted == 0 {
+		log.Printf("[WARN] input file has no valid transations")
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": "no valid transactions", "errors": res.Errors})
+		return
+	}
+	resp := JSON{"status": "ok", "accepted": res.Accepted, "rejected": res.Rejected}
+	if len(res.Errors) > 0 {
+		resp["errors"] = res.Errors
+	}
+	if len(res.Flagged) > 0 {
+		resp["flagged"], resp["flaggedCount"] = res.Flagged, res.FlaggedCount
+	}
+	render.JSON(w, r, resp)
+}
+
+// flaggedTransactions returns the first ingest.MaxFlagged transactions with anomaly flags and the number of all of them
+func flaggedTransactions(transactions []model.Transaction) ([]model.Transaction, int) {
+	var res []model.Transaction
+	count := 0
+	for _, tr := range transactions {
+		if len(tr.Flags) == 0 {
+			continue
+		}
+		if count++; len(res) < ingest.MaxFlagged {
+			res = append(res, tr)
+		}
+	}
+	return res, count
+}
 
-	reader := csv.NewReader(file)
-	transactions := []model.Transaction{}
-	for {
-		record, err := reader.Read()
+// formFile returns reader of the named file field of multipart request. Unlike r.FormFile it doesn't
+// buffer the request, parts before the file are skipped and the file is read as it arrives.
+// The file has to be in the first maxParts parts, if it's positive.
+func formFile(r *http.Request, name string, maxParts int) (io.Reader, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, fmt.Errorf("can't get file: %w", err)
+	}
+	for parts := 1; ; parts++ {
+		if maxParts > 0 && parts > maxParts {
+			return nil, fmt.Errorf("can't get file: %w, the limit is %d", errTooManyParts, maxParts)
+		}
+		part, err := mr.NextPart()
 		if err == io.EOF {
-			break
+			return nil, fmt.Errorf("can't get file: %w", http.ErrMissingFile)
 		}
 		if err != nil {
-			log.Printf("[WARN] failed to read line: %v", err)
-			continue
+			return nil, fmt.Errorf("can't get file: %w", err)
 		}
-
-		// Skip lines with a different number of fields
-		if len(record) < 4 {
-			log.Printf("[WARN] skipping line with wrong number of fields: %v", record)
-			continue
+		if part.FormName() == name {
+			return part, nil
 		}
+	}
+}
 
-		transaction, err := s.Processor.ParseTransaction(record)
+// transactionReq is a transaction in JSON ingest request
+type transactionReq struct {
+	Date     string   `json:"date"`
+	Type     string   `json:"type"`
+	Amount   float64  `json:"amount"`
+	Memo     string   `json:"memo"`
+	Tags     []string `json:"tags"`
+	Category string   `json:"category"`
+}
+
+// readJSON parses JSON array of transactions, all of them have to be valid
+func (s Service) readJSON(body io.Reader) ([]model.Transaction, error) {
+	var reqs []transactionReq
+	if err := json.NewDecoder(body).Decode(&reqs); err != nil {
+		return nil, fmt.Errorf("can't decode transactions: %w", err)
+	}
+	if s.MaxRows > 0 && len(reqs) > s.MaxRows {
+		return nil, fmt.Errorf("%w, the limit is %d", ingest.ErrTooManyRows, s.MaxRows)
+	}
+
+	transactions := make([]model.Transaction, 0, len(reqs))
+	for i, req := range reqs {
+		// reuse csv parsing to get the same validation for both formats
+		rec := []string{req.Date, req.Type, strconv.FormatFloat(req.Amount, 'f', -1, 64), req.Memo}
+		transaction, err := s.Processor.ParseTransaction(rec)
 		if err != nil {
-			log.Printf("[WARN] failed to parse %v: %v", record, err)
-			continue
+			return nil, fmt.Errorf("transaction #%d: %w", i, err)
 		}
+		transaction.Tags, transaction.Category = req.Tags, req.Category
 		transactions = append(transactions, transaction)
 	}
+	return transactions, nil
+}
 
-	if len(transactions) <= 0 {
-		log.Printf("[WARN] input file has no valid transations")
+// PATCH /transactions/{id}, sets transaction tags and/or category
+func (s Service) handlePatchTransaction(w http.ResponseWriter, r *http.Request) {
+	req := model.TransactionUpdate{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil || (req.Tags == nil && req.Category == nil) {
+		if err == nil {
+			err = errors.New("nothing to update")
+		}
 		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
 		return
 	}
 
-	err = s.Processor.ProcessTransactions(r.Context(), transactions)
+	transaction, err := s.Processor.UpdateTransaction(r.Context(), chi.URLParam(r, "id"), req)
 	if err != nil {
-		log.Printf("[WARN] can't process transactions: %v", err)
+		log.Printf("[WARN] can't update transaction: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	render.JSON(w, r, transaction)
+}
+
+// GET /accounts, optional "account" parameters leave the listed accounts only
+func (s Service) handleAccounts(w http.ResponseWriter, r *http.Request) {
+	accounts, err := s.Processor.Accounts(r.Context())
+	if err != nil {
+		log.Printf("[WARN] can't get accounts: %v", err)
 		render.Status(r, http.StatusInternalServerError)
 		render.JSON(w, r, JSON{"error": err.Error()})
 		return
 	}
+	if names := queryAccounts(r); len(names) > 0 {
+		res := []model.Account{}
+		for _, acc := range accounts {
+			if model.ContainsString(names, acc.Name) {
+				res = append(res, acc)
+			}
+		}
+		accounts = res
+	}
+	render.JSON(w, r, accounts)
+}
+
+// PUT /accounts/{account}, sets opening balance and date of the account
+func (s Service) handlePutAccount(w http.ResponseWriter, r *http.Request) {
+	req := struct {
+		OpeningBalance float64 `json:"openingBalance"`
+		OpeningDate    string  `json:"openingDate"`
+	}{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	date, err := parseDate(req.OpeningDate)
+	if err == nil {
+		err = model.ValidateAccount(chi.URLParam(r, "account"))
+	}
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+
+	account, err := s.Processor.SetOpeningBalance(r.Context(), chi.URLParam(r, "account"), req.OpeningBalance, date)
+	if err != nil {
+		log.Printf("[WARN] can't set opening balance: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	render.JSON(w, r, account)
+}
+
+// GET /balance?asOf=DATE, balance of the selected accounts at the end of the day
+func (s Service) handleBalance(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r)
+	var asOf time.Time
+	if err == nil {
+		asOf, err = parseDate(r.URL.Query().Get("asOf"))
+	}
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+
+	balance, err := s.Processor.Balance(r.Context(), filter.Accounts, asOf)
+	if err != nil {
+		log.Printf("[WARN] can't get balance: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	render.JSON(w, r, balance)
+}
+
+// GET /transactions and GET /accounts/{account}/transactions, lists transactions with running balance
+func (s Service) handleListTransactions(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+
+	lines, err := s.Processor.Transactions(r.Context(), filter)
+	if err != nil {
+		log.Printf("[WARN] can't list transactions: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	render.JSON(w, r, lines)
+}
+
+// statementReq is a bank statement in reconciliation request
+type statementReq struct {
+	From           string  `json:"from"`
+	To             string  `json:"to"`
+	ClosingBalance float64 `json:"closingBalance"`
+	Lines          []struct {
+		Date   string  `json:"date"`
+		Amount float64 `json:"amount"`
+		Memo   string  `json:"memo"`
+	} `json:"lines"`
+}
+
+// POST /accounts/{account}/reconcile?amountTolerance=0.01&dateTolerance=3, matches statement
+// with account transactions and marks matched ones as cleared
+func (s Service) handleReconcile(w http.ResponseWriter, r *http.Request) {
+	req := statementReq{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	st, tol, err := parseStatement(req, r.URL.Query())
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+
+	res, err := s.Processor.Reconcile(r.Context(), chi.URLParam(r, "account"), st, tol)
+	if err != nil {
+		log.Printf("[WARN] can't reconcile: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	render.JSON(w, r, res)
+}
 
-	render.JSON(w, r, JSON{"status": "ok"})
+// parseStatement converts statement request to model, tolerance defaults to a cent and 3 days
+func parseStatement(req statementReq, q url.Values) (model.Statement, model.Tolerance, error) {
+	tol := model.Tolerance{Amount: 0.01, Days: 3}
+	var err error
+	if v := q.Get("amountTolerance"); v != "" {
+		if tol.Amount, err = strconv.ParseFloat(v, 64); err != nil {
+			return model.Statement{}, model.Tolerance{}, fmt.Errorf("invalid amount tolerance %q", v)
+		}
+	}
+	if v := q.Get("dateTolerance"); v != "" {
+		if tol.Days, err = strconv.Atoi(v); err != nil {
+			return model.Statement{}, model.Tolerance{}, fmt.Errorf("invalid date tolerance %q", v)
+		}
+	}
+	if err = tol.Validate(); err != nil {
+		return model.Statement{}, model.Tolerance{}, err
+	}
+
+	st := model.Statement{ClosingBalance: req.ClosingBalance}
+	if st.From, err = parseDate(req.From); err != nil {
+		return model.Statement{}, model.Tolerance{}, err
+	}
+	if st.To, err = parseDate(req.To); err != nil {
+		return model.Statement{}, model.Tolerance{}, err
+	}
+	for i, l := range req.Lines {
+		date, err := parseDate(l.Date)
+		if err != nil || date.IsZero() {
+			return model.Statement{}, model.Tolerance{}, fmt.Errorf("line #%d: invalid date %q", i, l.Date)
+		}
+		st.Lines = append(st.Lines, model.StatementLine{Date: date, Amount: l.Amount, Memo: l.Memo})
+	}
+	return st, tol, nil
 }
 
-// GET /report
+// GET /report and GET /accounts/{account}/report, report as JSON, CSV, HTML or PDF
+// depending on "format" parameter or Accept header
 func (s Service) handleReport(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	report, err := s.Processor.GenerateReport(ctx)
+	format := negotiateFormat(r, formatJSON, formatCSV, formatHTML, formatPDF)
+	if format == "" {
+		render.Status(r, http.StatusNotAcceptable)
+		render.JSON(w, r, JSON{"error": "supported formats are json, csv, html and pdf"})
+		return
+	}
+
+	filter, err := parseFilter(r)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+
+	report, err := s.Processor.GenerateReport(ctx, filter)
 	if err != nil {
 		log.Printf("[WARN] can't generate report: %v", err)
-		render.Status(r, http.StatusInternalServerError)
+		render.Status(r, errStatus(err))
 		render.JSON(w, r, JSON{"error": err.Error()})
 		return
 	}
 
+	if format != formatJSON {
+		t := table{Title: "Report", Subtitle: filterDescription(filter), Header: []string{"", "Amount"},
+			Rows: []reportRow{
+				{Label: "Gross revenue", Amounts: []float64{report.GrossRevenue}},
+				{Label: "Expenses", Amounts: []float64{report.Expenses}},
+				{Label: "Net revenue", Amounts: []float64{report.NetRevenue}, Total: true},
+			}}
+		if err = writeTable(w, format, "report", t); err != nil {
+			log.Printf("[WARN] can't write report: %v", err)
+		}
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	if err = json.NewEncoder(w).Encode(report); err != nil {
 		log.Printf("[WARN] can't encode report: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, JSON{"error": err.Error()})
-		return
 	}
+}
+
+// parseFilter makes report filter from query parameters, multiple tag expressions are combined with AND.
+// Accounts are taken from the url or from "account" parameters, each one can have comma-separated list,
+// not both.
+// Optional "from" and "to" dates are inclusive, "flagged=true" leaves transactions with anomaly flags only.
+func parseFilter(r *http.Request) (model.Filter, error) {
+	filter := model.Filter{}
+	var err error
+	if filter.From, err = parseDate(r.URL.Query().Get("from")); err != nil {
+		return model.Filter{}, err
+	}
+	if filter.To, err = parseDate(r.URL.Query().Get("to")); err != nil {
+		return model.Filter{}, err
+	}
+	if account := chi.URLParam(r, "account"); account != "" {
+		if r.URL.Query().Has("account") {
+			return model.Filter{}, errors.New("account query can't be used with account path")
+		}
+		filter.Accounts = []string{account}
+	}
+	filter.Accounts = append(filter.Accounts, queryAccounts(r)...)
+	for _, q := range r.URL.Query()["tag"] {
+		expr, err := model.ParseTagExpr(q)
+		if err != nil {
+			return model.Filter{}, err
+		}
+		filter.Tags = model.And(filter.Tags, expr)
+	}
+	if q := r.URL.Query().Get("flagged"); q != "" {
+		if filter.Flagged, err = strconv.ParseBool(q); err != nil {
+			return model.Filter{}, fmt.Errorf("invalid flagged value %q", q)
+		}
+	}
+	return filter, nil
+}
+
+// queryAccounts returns accounts of "account" parameters, each one can have comma-separated list
+func queryAccounts(r *http.Request) []string {
+	res := []string{}
+	for _, q := range r.URL.Query()["account"] {
+		for _, account := range strings.Split(q, ",") {
+			if account = strings.TrimSpace(account); account != "" {
+				res = append(res, account)
+			}
+		}
+	}
+	return res
+}
 
-	w.WriteHeader(http.StatusOK)
+// parseDate parses date in model.DateLayout, empty string gives zero time
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	date, err := time.ParseInLocation(model.DateLayout, s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", s)
+	}
+	return date, nil
+}
+
+// errStatus maps processor errors to http status codes
+func errStatus(err error) int {
+	switch {
+	case errors.Is(err, model.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, model.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, model.ErrDoubleEntryDisabled):
+		return http.StatusNotImplemented
+	case errors.Is(err, jobs.ErrQueueFull):
+		return http.StatusServiceUnavailable
+	case tooLarge(err):
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusInternalServerError
 }
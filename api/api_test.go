@@ -13,6 +13,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"syscall"
 	"testing"
 	"time"
@@ -29,13 +30,24 @@ func TestService_Run(t *testing.T) {
 
 func TestService_handleTransactions(t *testing.T) {
 
+	var staged []model.Transaction
 	proc := &ProcessorMock{
-		ProcessTransactionsFunc: func(ctx context.Context, trs []model.Transaction) error {
-			return nil
+		ProcessTransactionsFunc: func(ctx context.Context, trs []model.Transaction) ([]model.Transaction, error) {
+			return trs, nil
 		},
 		ParseTransactionFunc: func(rec []string) (model.Transaction, error) {
 			return model.Transaction{Amount: 123, Type: model.Income, Memo: "aaaa", Date: time.Now()}, nil
 		},
+		StageTransactionsFunc: func(ctx context.Context, batchID string, trs []model.Transaction) (string, error) {
+			staged = append(staged, trs...)
+			return "1", nil
+		},
+		CommitBatchFunc: func(ctx context.Context, batchID string) ([]model.Transaction, error) {
+			return staged, nil
+		},
+		DiscardBatchFunc: func(ctx context.Context, batchID string) error {
+			return nil
+		},
 	}
 
 	svc := &Service{
@@ -71,13 +83,19 @@ func TestService_handleTransactions(t *testing.T) {
 		assert.Equal(t, http.StatusOK, resp.StatusCode)
 		data, err := io.ReadAll(resp.Body)
 		require.NoError(t, err)
-		assert.Equal(t, `{"status":"ok"}`+"\n", string(data))
-		require.Equal(t, 1, len(proc.ProcessTransactionsCalls()))
+		assert.Equal(t, `{"accepted":10,"errors":[{"line":4,"error":"wrong number of fields, expected at least 4, got 1"}],`+
+			`"rejected":1,"status":"ok"}`+"\n", string(data))
+		require.Equal(t, 1, len(proc.StageTransactionsCalls()))
+		assert.Equal(t, "", proc.StageTransactionsCalls()[0].BatchID)
+		assert.Equal(t, "default", staged[0].Account)
+		require.Equal(t, 1, len(proc.CommitBatchCalls()))
+		assert.Equal(t, "1", proc.CommitBatchCalls()[0].BatchID)
+		assert.Equal(t, 10, len(proc.ParseTransactionCalls()))
 	})
 
 	t.Run("failed post", func(t *testing.T) {
-		proc.ProcessTransactionsFunc = func(ctx context.Context, trs []model.Transaction) error {
-			return errors.New("oh oh")
+		proc.CommitBatchFunc = func(ctx context.Context, batchID string) ([]model.Transaction, error) {
+			return nil, errors.New("oh oh")
 		}
 
 		_, err := file.Seek(0, io.SeekStart) // Reset the file cursor to the beginning
@@ -104,13 +122,370 @@ func TestService_handleTransactions(t *testing.T) {
 		data, err := io.ReadAll(resp.Body)
 		require.NoError(t, err)
 		assert.Equal(t, `{"error":"oh oh"}`+"\n", string(data))
+		require.Equal(t, 2, len(proc.CommitBatchCalls()))
+		require.Equal(t, 1, len(proc.DiscardBatchCalls()))
+	})
+
+	t.Run("post without valid lines", func(t *testing.T) {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		require.NoError(t, writer.WriteField("comment", "skipped"))
+		fileField, err := writer.CreateFormFile("file", "test.csv")
+		require.NoError(t, err)
+		_, err = fileField.Write([]byte("2020-07-01,Expense\n\"bad\"quote\",Income,1,x\n"))
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+
+		resp, err := client.Post(ts.URL+"/transactions", writer.FormDataContentType(), body)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `{"error":"no valid transactions","errors":[{"line":1,"error":"wrong number of fields, expected at least 4, got 2"},`+
+			`{"line":2,"error":"extraneous or missing \" in quoted-field"}]}`+"\n", string(data))
+		require.Equal(t, 2, len(proc.StageTransactionsCalls()))
+	})
+
+	t.Run("post without file", func(t *testing.T) {
+		resp, err := client.Post(ts.URL+"/transactions", "text/csv", strings.NewReader("2020-07-01,Expense,1,x"))
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("successful json post", func(t *testing.T) {
+		proc.ProcessTransactionsFunc = func(ctx context.Context, trs []model.Transaction) ([]model.Transaction, error) {
+			return trs, nil
+		}
+		proc.ParseTransactionFunc = func(rec []string) (model.Transaction, error) {
+			assert.Equal(t, []string{"2020-07-04", "Income", "40", "347 Woodrow"}, rec)
+			return model.Transaction{Amount: 40, Type: model.Income, Memo: "347 Woodrow"}, nil
+		}
+		body := `[{"date":"2020-07-04","type":"Income","amount":40,"memo":"347 Woodrow","tags":["woodrow","lawn"]}]`
+		resp, err := client.Post(ts.URL+"/transactions", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, 1, len(proc.ProcessTransactionsCalls()))
+		trs := proc.ProcessTransactionsCalls()[0].Transactions
+		require.Len(t, trs, 1)
+		assert.Equal(t, []string{"woodrow", "lawn"}, trs[0].Tags)
+	})
+
+	t.Run("post with flagged transactions", func(t *testing.T) {
+		proc.ProcessTransactionsFunc = func(ctx context.Context, trs []model.Transaction) ([]model.Transaction, error) {
+			return []model.Transaction{{ID: "7", Account: "default", Type: model.Income, Amount: 40, Memo: "347 Woodrow",
+				Date: time.Date(2020, 7, 4, 0, 0, 0, 0, time.UTC), Flags: []string{model.FlagDuplicate}}, {ID: "8"}}, nil
+		}
+		body := `[{"date":"2020-07-04","type":"Income","amount":40,"memo":"347 Woodrow"}]`
+		resp, err := client.Post(ts.URL+"/transactions", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `{"flagged":[{"id":"7","account":"default","date":"2020-07-04T00:00:00Z","type":"Income",`+
+			`"amount":40,"memo":"347 Woodrow","cleared":false,"flags":["duplicate"]}],"flaggedCount":1,"status":"ok"}`+"\n", string(data))
+		require.Equal(t, 2, len(proc.ProcessTransactionsCalls()))
+	})
+
+	t.Run("invalid json post", func(t *testing.T) {
+		proc.ParseTransactionFunc = func(rec []string) (model.Transaction, error) {
+			return model.Transaction{}, errors.New("bad date")
+		}
+		body := `[{"date":"bad","type":"Income","amount":40,"memo":"347 Woodrow"}]`
+		resp, err := client.Post(ts.URL+"/transactions", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `{"error":"transaction #0: bad date"}`+"\n", string(data))
 		require.Equal(t, 2, len(proc.ProcessTransactionsCalls()))
 	})
 }
 
+func TestService_accounts(t *testing.T) {
+	proc := &ProcessorMock{
+		ProcessTransactionsFunc: func(ctx context.Context, trs []model.Transaction) ([]model.Transaction, error) {
+			return trs, nil
+		},
+		ParseTransactionFunc: func(rec []string) (model.Transaction, error) {
+			return model.Transaction{Amount: 40, Type: model.Income, Memo: rec[3]}, nil
+		},
+		AccountsFunc: func(ctx context.Context) ([]model.Account, error) {
+			return []model.Account{{Name: "card", Transactions: 1}, {Name: "default", Transactions: 2}}, nil
+		},
+		GenerateReportFunc: func(ctx context.Context, filter model.Filter) (model.Report, error) {
+			if len(filter.Accounts) == 1 && filter.Accounts[0] == "unknown" {
+				return model.Report{}, fmt.Errorf("account %q: %w", "unknown", model.ErrNotFound)
+			}
+			return model.Report{GrossRevenue: 40, NetRevenue: 40}, nil
+		},
+	}
+
+	svc := &Service{
+		Processor: proc,
+	}
+
+	ts := httptest.NewServer(svc.routes())
+	defer ts.Close()
+
+	client := http.Client{Timeout: time.Second}
+	body := `[{"date":"2020-07-04","type":"Income","amount":40,"memo":"347 Woodrow"}]`
+
+	t.Run("post to account", func(t *testing.T) {
+		resp, err := client.Post(ts.URL+"/accounts/card/transactions", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, 1, len(proc.ProcessTransactionsCalls()))
+		assert.Equal(t, "card", proc.ProcessTransactionsCalls()[0].Transactions[0].Account)
+	})
+
+	t.Run("post to default account", func(t *testing.T) {
+		resp, err := client.Post(ts.URL+"/transactions", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, 2, len(proc.ProcessTransactionsCalls()))
+		assert.Equal(t, model.DefaultAccount, proc.ProcessTransactionsCalls()[1].Transactions[0].Account)
+	})
+
+	t.Run("post to invalid account", func(t *testing.T) {
+		resp, err := client.Post(ts.URL+"/accounts/Bad%20Name/transactions", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		require.Equal(t, 2, len(proc.ProcessTransactionsCalls()))
+	})
+
+	t.Run("list accounts", func(t *testing.T) {
+		resp, err := client.Get(ts.URL + "/accounts")
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `[{"name":"card","transactions":1,"openingBalance":0,"openingDate":"0001-01-01T00:00:00Z"},`+
+			`{"name":"default","transactions":2,"openingBalance":0,"openingDate":"0001-01-01T00:00:00Z"}]`+"\n", string(data))
+	})
+
+	t.Run("scoped reports", func(t *testing.T) {
+		resp, err := client.Get(ts.URL + "/accounts/card/report")
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []string{"card"}, proc.GenerateReportCalls()[0].Filter.Accounts)
+
+		resp, err = client.Get(ts.URL + "/report?account=card,cash&account=default")
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []string{"card", "cash", "default"}, proc.GenerateReportCalls()[1].Filter.Accounts)
+
+		resp, err = client.Get(ts.URL + "/accounts/unknown/report")
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		resp, err = client.Get(ts.URL + "/accounts/card/report?account=default")
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "account query on account path")
+		assert.Len(t, proc.GenerateReportCalls(), 3)
+	})
+}
+
+func TestService_balance(t *testing.T) {
+	proc := &ProcessorMock{
+		SetOpeningBalanceFunc: func(ctx context.Context, account string, amount float64, date time.Time) (model.Account, error) {
+			return model.Account{Name: account, OpeningBalance: amount, OpeningDate: date}, nil
+		},
+		BalanceFunc: func(ctx context.Context, accounts []string, asOf time.Time) (model.Balance, error) {
+			return model.Balance{AsOf: asOf, Balance: 150, Accounts: map[string]float64{"card": 150}}, nil
+		},
+		TransactionsFunc: func(ctx context.Context, filter model.Filter) ([]model.LedgerLine, error) {
+			return []model.LedgerLine{{Transaction: model.Transaction{ID: "1", Account: "card", Type: model.Income,
+				Amount: 50, Date: time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC)}, RunningBalance: 150}}, nil
+		},
+	}
+
+	svc := &Service{
+		Processor: proc,
+	}
+
+	ts := httptest.NewServer(svc.routes())
+	defer ts.Close()
+
+	client := http.Client{Timeout: time.Second}
+
+	t.Run("set opening balance", func(t *testing.T) {
+		req, err := http.NewRequest("PUT", ts.URL+"/accounts/card", strings.NewReader(`{"openingBalance":100,"openingDate":"2020-06-30"}`))
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, 1, len(proc.SetOpeningBalanceCalls()))
+		call := proc.SetOpeningBalanceCalls()[0]
+		assert.Equal(t, "card", call.Account)
+		assert.Equal(t, 100.0, call.Amount)
+		assert.Equal(t, time.Date(2020, 6, 30, 0, 0, 0, 0, time.Local), call.Date)
+
+		req, err = http.NewRequest("PUT", ts.URL+"/accounts/card", strings.NewReader(`{"openingBalance":100,"openingDate":"30/06/2020"}`))
+		require.NoError(t, err)
+		resp, err = client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		require.Equal(t, 1, len(proc.SetOpeningBalanceCalls()))
+	})
+
+	t.Run("balance as of", func(t *testing.T) {
+		resp, err := client.Get(ts.URL + "/balance?asOf=2020-07-31&account=card")
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, 1, len(proc.BalanceCalls()))
+		assert.Equal(t, []string{"card"}, proc.BalanceCalls()[0].Accounts)
+		assert.Equal(t, time.Date(2020, 7, 31, 0, 0, 0, 0, time.Local), proc.BalanceCalls()[0].AsOf)
+
+		resp, err = client.Get(ts.URL + "/balance?asOf=yesterday")
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("list transactions", func(t *testing.T) {
+		resp, err := client.Get(ts.URL + "/accounts/card/transactions?from=2020-07-01&to=2020-07-31")
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `[{"id":"1","account":"card","date":"2020-07-01T00:00:00Z","type":"Income","amount":50,"memo":"","cleared":false,"runningBalance":150}]`+"\n", string(data))
+		filter := proc.TransactionsCalls()[0].Filter
+		assert.Equal(t, []string{"card"}, filter.Accounts)
+		assert.Equal(t, time.Date(2020, 7, 1, 0, 0, 0, 0, time.Local), filter.From)
+		assert.Equal(t, time.Date(2020, 7, 31, 0, 0, 0, 0, time.Local), filter.To)
+		assert.False(t, filter.Flagged)
+	})
+
+	t.Run("list flagged transactions", func(t *testing.T) {
+		resp, err := client.Get(ts.URL + "/transactions?flagged=true")
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.True(t, proc.TransactionsCalls()[1].Filter.Flagged)
+
+		resp, err = client.Get(ts.URL + "/transactions?flagged=maybe")
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestService_handleReconcile(t *testing.T) {
+	proc := &ProcessorMock{
+		ReconcileFunc: func(ctx context.Context, account string, st model.Statement, tol model.Tolerance) (model.Reconciliation, error) {
+			if account != "checking" {
+				return model.Reconciliation{}, fmt.Errorf("account %q: %w", account, model.ErrNotFound)
+			}
+			return model.Reconciliation{Account: account, ClosingBalance: st.ClosingBalance, Difference: 2.5}, nil
+		},
+	}
+
+	svc := &Service{
+		Processor: proc,
+	}
+
+	ts := httptest.NewServer(svc.routes())
+	defer ts.Close()
+
+	client := http.Client{Timeout: time.Second}
+	body := `{"from":"2020-07-01","to":"2020-07-31","closingBalance":150,` +
+		`"lines":[{"date":"2020-07-03","amount":-18.77,"memo":"SHELL"}]}`
+
+	resp, err := client.Post(ts.URL+"/accounts/checking/reconcile?dateTolerance=5", "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, 1, len(proc.ReconcileCalls()))
+	call := proc.ReconcileCalls()[0]
+	assert.Equal(t, model.Tolerance{Amount: 0.01, Days: 5}, call.Tol)
+	assert.Equal(t, time.Date(2020, 7, 31, 0, 0, 0, 0, time.Local), call.St.To)
+	assert.Equal(t, []model.StatementLine{{Date: time.Date(2020, 7, 3, 0, 0, 0, 0, time.Local), Amount: -18.77, Memo: "SHELL"}},
+		call.St.Lines)
+
+	for _, q := range []string{"amountTolerance=-1", "amountTolerance=NaN", "dateTolerance=-1", "dateTolerance=367",
+		"dateTolerance=200000"} {
+		resp, err = client.Post(ts.URL+"/accounts/checking/reconcile?"+q, "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, q)
+	}
+
+	resp, err = client.Post(ts.URL+"/accounts/checking/reconcile", "application/json",
+		strings.NewReader(`{"lines":[{"date":"","amount":1}]}`))
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = client.Post(ts.URL+"/accounts/unknown/reconcile", "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, 2, len(proc.ReconcileCalls()))
+}
+
+func TestService_handlePatchTransaction(t *testing.T) {
+	proc := &ProcessorMock{
+		UpdateTransactionFunc: func(ctx context.Context, id string, upd model.TransactionUpdate) (model.Transaction, error) {
+			if id != "1" {
+				return model.Transaction{}, fmt.Errorf("transaction %q: %w", id, model.ErrNotFound)
+			}
+			return model.Transaction{ID: id, Account: "default", Type: model.Income, Amount: 40, Memo: "347 Woodrow",
+				Date: time.Date(2020, 7, 4, 0, 0, 0, 0, time.UTC), Tags: *upd.Tags}, nil
+		},
+	}
+
+	svc := &Service{
+		Processor: proc,
+	}
+
+	ts := httptest.NewServer(svc.routes())
+	defer ts.Close()
+
+	client := http.Client{Timeout: time.Second}
+
+	patch := func(id, body string) (int, string) {
+		req, err := http.NewRequest("PATCH", ts.URL+"/transactions/"+id, strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(data)
+	}
+
+	code, body := patch("1", `{"tags":["woodrow"]}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `{"id":"1","account":"default","date":"2020-07-04T00:00:00Z","type":"Income","amount":40,"memo":"347 Woodrow","tags":["woodrow"],"cleared":false}`+"\n", body)
+
+	code, _ = patch("2", `{"tags":["woodrow"]}`)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _ = patch("1", `{}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	require.Equal(t, 2, len(proc.UpdateTransactionCalls()))
+}
+
 func TestService_handleReport(t *testing.T) {
 	proc := &ProcessorMock{
-		GenerateReportFunc: func(ctx context.Context) (model.Report, error) {
+		GenerateReportFunc: func(ctx context.Context, filter model.Filter) (model.Report, error) {
 			return model.Report{
 				GrossRevenue: 20,
 				Expenses:     30,
@@ -141,8 +516,79 @@ func TestService_handleReport(t *testing.T) {
 		require.Equal(t, 1, len(proc.GenerateReportCalls()))
 	})
 
+	t.Run("get with tag filter", func(t *testing.T) {
+		url := fmt.Sprintf("%s/report?tag=woodrow+OR+pleasant&tag=lawn", ts.URL)
+		resp, err := client.Get(url)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, 2, len(proc.GenerateReportCalls()))
+		assert.Equal(t, "(woodrow OR pleasant) AND lawn", proc.GenerateReportCalls()[1].Filter.Tags.String())
+	})
+
+	t.Run("get with bad tag filter", func(t *testing.T) {
+		resp, err := client.Get(fmt.Sprintf("%s/report?tag=woodrow+OR", ts.URL))
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		require.Equal(t, 2, len(proc.GenerateReportCalls()))
+	})
+
+	t.Run("get as csv", func(t *testing.T) {
+		req, err := http.NewRequest("GET", ts.URL+"/report?from=2020-07-01", nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept", "text/csv")
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/csv", resp.Header.Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="report.csv"`, resp.Header.Get("Content-Disposition"))
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, ",Amount\nGross revenue,20.00\nExpenses,30.00\nNet revenue,40.00\n", string(data))
+	})
+
+	t.Run("get as html", func(t *testing.T) {
+		resp, err := client.Get(ts.URL + "/report?format=html&account=card")
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "<p>accounts: card</p>")
+		assert.Contains(t, string(data), `<tr class="total"><td>Net revenue</td><td>40.00</td></tr>`)
+	})
+
+	t.Run("get as pdf", func(t *testing.T) {
+		req, err := http.NewRequest("GET", ts.URL+"/report", nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept", "application/pdf")
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/pdf", resp.Header.Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="report.pdf"`, resp.Header.Get("Content-Disposition"))
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(data), "%PDF-1.4\n"))
+		assert.Contains(t, string(data), "(Net revenue) Tj")
+	})
+
+	t.Run("get unsupported format", func(t *testing.T) {
+		req, err := http.NewRequest("GET", ts.URL+"/report", nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept", "image/png")
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusNotAcceptable, resp.StatusCode)
+	})
+
 	t.Run("failed get", func(t *testing.T) {
-		proc.GenerateReportFunc = func(ctx context.Context) (model.Report, error) {
+		proc.GenerateReportFunc = func(ctx context.Context, filter model.Filter) (model.Report, error) {
 			return model.Report{}, errors.New("oh oh")
 		}
 		url := fmt.Sprintf("%s/report", ts.URL)
@@ -154,6 +600,6 @@ func TestService_handleReport(t *testing.T) {
 		data, err := io.ReadAll(resp.Body)
 		require.NoError(t, err)
 		assert.Equal(t, `{"error":"oh oh"}`+"\n", string(data))
-		require.Equal(t, 2, len(proc.GenerateReportCalls()))
+		require.Equal(t, 6, len(proc.GenerateReportCalls()))
 	})
 }
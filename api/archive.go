@@ -0,0 +1,65 @@
+package api
+
+import (
+	"errors"
+	"github.com/go-chi/render"
+	"github.com/mrnbort/summer_break/archive"
+	"github.com/mrnbort/summer_break/model"
+	"log"
+	"net/http"
+	"time"
+)
+
+// GET /export, streams the stored state as NDJSON archive, see archive package for its format
+func (s Service) handleExport(w http.ResponseWriter, r *http.Request) {
+	snap, err := s.Processor.Snapshot(r.Context())
+	if err != nil {
+		log.Printf("[WARN] can't make snapshot: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	w.Header().Set("Content-Type", archive.ContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="summer_break-`+now.Format("20060102")+`.ndjson"`)
+	if err = archive.Write(w, snap, now); err != nil {
+		log.Printf("[WARN] can't write archive: %v", err)
+	}
+}
+
+// POST /import?policy=fail|skip|replace, merges archive made by GET /export into the stored state.
+// Records already stored with different content are conflicts, resolved by policy, fail by default.
+// Conflicts with fail policy are listed in 409 response and nothing is imported.
+func (s Service) handleImport(w http.ResponseWriter, r *http.Request) {
+	policy := r.URL.Query().Get("policy")
+	if policy == "" {
+		policy = model.ImportFail
+	}
+	if err := model.ValidateImportPolicy(policy); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	snap, err := archive.Read(r.Body)
+	if err != nil {
+		render.Status(r, readStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+
+	res, err := s.Processor.Import(r.Context(), snap, policy)
+	if errors.Is(err, model.ErrConflict) {
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, JSON{"error": err.Error(), "conflicts": res.Conflicts})
+		return
+	}
+	if err != nil {
+		log.Printf("[WARN] can't import archive: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	log.Printf("[INFO] imported archive with %s policy: %+v", policy, res)
+	render.JSON(w, r, res)
+}
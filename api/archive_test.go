@@ -0,0 +1,82 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/mrnbort/summer_break/archive"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestService_archive(t *testing.T) {
+	snap := model.Snapshot{
+		Version:      model.SnapshotVersion,
+		Accounts:     []model.Account{{Name: "card"}},
+		Transactions: []model.Transaction{{ID: "1", Account: "card", Date: time.Date(2020, 7, 1, 0, 0, 0, 0, time.Local), Type: model.Expense, Amount: 18.77, Memo: "Fuel"}},
+		Budgets:      []model.Budget{},
+		Schedules:    []model.Schedule{},
+		LastID:       1,
+	}
+	var imported []string
+	proc := &ProcessorMock{
+		SnapshotFunc: func(ctx context.Context) (model.Snapshot, error) {
+			return snap, nil
+		},
+		ImportFunc: func(ctx context.Context, s model.Snapshot, policy string) (model.ImportResult, error) {
+			imported = append(imported, policy)
+			if policy == model.ImportFail {
+				return model.ImportResult{Conflicts: []string{"transaction 1"}}, fmt.Errorf("1 records are already stored: %w", model.ErrConflict)
+			}
+			return model.ImportResult{Replaced: len(s.Transactions), Unchanged: len(s.Accounts)}, nil
+		},
+	}
+
+	ts := httptest.NewServer(Service{Processor: proc}.routes())
+	defer ts.Close()
+
+	client := http.Client{Timeout: time.Second}
+	do := func(method, url string, body io.Reader) (int, http.Header, string) {
+		req, err := http.NewRequest(method, ts.URL+url, body)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, resp.Header, string(data)
+	}
+
+	code, header, body := do("GET", "/export", nil)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, archive.ContentType, header.Get("Content-Type"))
+	assert.Contains(t, header.Get("Content-Disposition"), `filename="summer_break-`)
+	assert.Equal(t, 4, strings.Count(body, "\n"), "header, account, transaction and settings")
+	got, err := archive.Read(strings.NewReader(body))
+	require.NoError(t, err)
+	assert.Equal(t, snap.Transactions[0].Amount, got.Transactions[0].Amount)
+
+	code, _, body = do("POST", "/import", strings.NewReader(body))
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Contains(t, body, `"conflicts":["transaction 1"]`)
+
+	buf := bytes.Buffer{}
+	require.NoError(t, archive.Write(&buf, snap, time.Now()))
+	code, _, body = do("POST", "/import?policy=replace", &buf)
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"added":0,"replaced":1,"skipped":0,"unchanged":1}`, body)
+
+	code, _, _ = do("POST", "/import?policy=merge", strings.NewReader(""))
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _, body = do("POST", "/import", strings.NewReader(`{"kind":"account","data":{}}`+"\n"))
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, body, "line 1")
+	assert.Equal(t, []string{model.ImportFail, model.ImportReplace}, imported)
+}
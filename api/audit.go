@@ -0,0 +1,286 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/mrnbort/summer_break/model"
+	"hash"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// RequestIDHeader has id of the request, taken from the request or generated, and returned in the response
+const RequestIDHeader = "X-Request-Id"
+
+// requestID puts id of the request to its context and the response header
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > 64 {
+			b := make([]byte, 8)
+			_, _ = rand.Read(b)
+			id = hex.EncodeToString(b)
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, id)))
+	})
+}
+
+// auditBefore loads the resource a request is going to change, keyed by method and route pattern
+var auditBefore = map[string]func(s Service, r *http.Request) (interface{}, error){
+	"PATCH /transactions/{id}": func(s Service, r *http.Request) (interface{}, error) {
+		return s.Processor.Transaction(r.Context(), chi.URLParam(r, "id"))
+	},
+	"PUT /accounts/{account}": func(s Service, r *http.Request) (interface{}, error) {
+		accounts, err := s.Processor.Accounts(r.Context())
+		for _, acc := range accounts {
+			if acc.Name == chi.URLParam(r, "account") {
+				return acc, err
+			}
+		}
+		return nil, err
+	},
+	"PUT /budgets/{id}":    budgetBefore,
+	"DELETE /budgets/{id}": budgetBefore,
+	"DELETE /schedules/{id}": func(s Service, r *http.Request) (interface{}, error) {
+		schedules, err := s.Processor.Schedules(r.Context())
+		for _, sch := range schedules {
+			if sch.ID == chi.URLParam(r, "id") {
+				return sch, err
+			}
+		}
+		return nil, err
+	},
+	"DELETE /jobs/{id}": func(s Service, r *http.Request) (interface{}, error) {
+		if s.Jobs == nil {
+			return nil, nil
+		}
+		return s.Jobs.Job(chi.URLParam(r, "id"))
+	},
+	"PUT /reports/tax/config": func(s Service, r *http.Request) (interface{}, error) {
+		return s.Processor.TaxConfig(r.Context())
+	},
+	"DELETE /keys/{id}": func(s Service, r *http.Request) (interface{}, error) {
+		keys, err := s.Processor.APIKeys(r.Context())
+		for _, k := range keys {
+			if k.ID == chi.URLParam(r, "id") {
+				return k, err
+			}
+		}
+		return nil, err
+	},
+	"PUT /users/{name}":    userBefore,
+	"DELETE /users/{name}": userBefore,
+}
+
+func budgetBefore(s Service, r *http.Request) (interface{}, error) {
+	return s.Processor.Budget(r.Context(), chi.URLParam(r, "id"))
+}
+
+func userBefore(s Service, r *http.Request) (interface{}, error) {
+	return s.Processor.User(r.Context(), chi.URLParam(r, "name"))
+}
+
+// redacted are fields of audited resources and responses which are never logged
+var redacted = map[string]bool{"key": true, "hash": true, "passwordHash": true, "token": true}
+
+// audit records change requests in the audit log. A pending entry with the resource before the change,
+// if it's known, is appended before the request is handled, and the request fails with 503 if it can't be
+// written. The outcome with response status and body and SHA-256 and size of the request body is appended
+// after. Action of admin requests is model.AuditConfig, it's set by method and path otherwise.
+// Does nothing if the log is not set.
+func (s Service) audit(admin bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if s.Audit == nil || r.Method == http.MethodGet || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+			route := r.Method + " " + chi.RouteContext(r.Context()).RoutePattern()
+			e := model.AuditEntry{Actor: actor(r), Action: auditAction(admin, r), Method: r.Method, Resource: r.URL.Path}
+			e.RequestID, _ = r.Context().Value(requestIDKey).(string)
+			if load, ok := auditBefore[route]; ok {
+				if before, err := load(s, r); err == nil && before != nil { // the request fails on its own otherwise
+					e.Before = redact(before)
+				}
+			}
+			pending, err := s.Audit.Begin(e)
+			if err != nil {
+				log.Printf("[WARN] can't record %s of %s: %v", route, e.Actor, err)
+				render.Status(r, http.StatusServiceUnavailable)
+				render.JSON(w, r, JSON{"error": "can't record the change in audit log"})
+				return
+			}
+
+			digest := &bodyDigest{hash: sha256.New()}
+			body := r.Body
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.TeeReader(body, digest), body}
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			_, _ = io.Copy(io.Discard, r.Body) // the rest of the body the handler didn't read, to hash it all
+
+			done := model.AuditEntry{Status: rec.status, BodySHA256: hex.EncodeToString(digest.hash.Sum(nil)),
+				BodyBytes: digest.n}
+			if rec.status >= http.StatusBadRequest {
+				done.Outcome = model.AuditFailed
+			}
+			if rec.body.Len() > 0 {
+				done.After = redact(json.RawMessage(rec.body.Bytes()))
+			}
+			if _, err = s.Audit.Finish(pending, done); err != nil {
+				log.Printf("[WARN] can't record outcome of %s of %s: %v", route, e.Actor, err)
+			}
+		})
+	}
+}
+
+// bodyDigest hashes and counts the request body as it's read
+type bodyDigest struct {
+	hash hash.Hash
+	n    int64
+}
+
+func (d *bodyDigest) Write(p []byte) (int, error) {
+	d.n += int64(len(p))
+	return d.hash.Write(p)
+}
+
+// auditAction returns action of the change request
+func auditAction(admin bool, r *http.Request) string {
+	switch {
+	case r.URL.Path == "/import":
+		return model.AuditImport
+	case admin:
+		return model.AuditConfig
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/transactions"):
+		return model.AuditUpload
+	case r.Method == http.MethodPost:
+		return model.AuditCreate
+	case r.Method == http.MethodDelete:
+		return model.AuditDelete
+	}
+	return model.AuditUpdate
+}
+
+// actor returns name of the caller, "anonymous" if auth is disabled
+func actor(r *http.Request) string {
+	if p, ok := r.Context().Value(principalKey).(principal); ok {
+		return p.Name
+	}
+	return "anonymous"
+}
+
+// redact returns JSON of v without secret fields of the top-level object or objects of the top-level array
+func redact(v interface{}) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var obj map[string]json.RawMessage
+	var arr []map[string]json.RawMessage
+	switch {
+	case json.Unmarshal(data, &obj) == nil:
+		for k := range obj {
+			if redacted[k] {
+				delete(obj, k)
+			}
+		}
+		data, err = json.Marshal(obj)
+	case json.Unmarshal(data, &arr) == nil:
+		for _, o := range arr {
+			for k := range o {
+				if redacted[k] {
+					delete(o, k)
+				}
+			}
+		}
+		data, err = json.Marshal(arr)
+	}
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// limits of entries returned by GET /audit, the log is read from the file for every request
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// GET /audit?from=DATE&to=DATE&actor=NAME&action=ACTION&resource=PREFIX&requestId=ID&outcome=OUTCOME&before=SEQ&limit=N,
+// audit log entries, newest first, defaultAuditLimit of them if limit is not set. The next page is selected
+// with before set to the sequence number of the last returned entry.
+func (s Service) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if s.Audit == nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, JSON{"error": "audit log is disabled"})
+		return
+	}
+	q := r.URL.Query()
+	filter := model.AuditFilter{Actor: q.Get("actor"), Action: q.Get("action"), Resource: q.Get("resource"),
+		RequestID: q.Get("requestId"), Outcome: q.Get("outcome")}
+	from, err := parseDate(q.Get("from"))
+	if err == nil {
+		filter.From = from
+		filter.To, err = parseDate(q.Get("to"))
+	}
+	if !filter.To.IsZero() {
+		filter.To = filter.To.AddDate(0, 0, 1) // the whole last day
+	}
+	limit := defaultAuditLimit
+	if err == nil && q.Get("limit") != "" {
+		if limit, err = strconv.Atoi(q.Get("limit")); err == nil && (limit <= 0 || limit > maxAuditLimit) {
+			err = fmt.Errorf("invalid limit %d, expected 1-%d", limit, maxAuditLimit)
+		}
+	}
+	if err == nil && q.Get("before") != "" {
+		if filter.Before, err = strconv.ParseInt(q.Get("before"), 10, 64); err == nil && filter.Before <= 0 {
+			err = fmt.Errorf("invalid before %d", filter.Before)
+		}
+	}
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	entries, err := s.Audit.Entries(filter, limit)
+	if err != nil {
+		log.Printf("[WARN] can't read audit log: %v", err)
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	render.JSON(w, r, entries)
+}
+
+// GET /audit/verify, checks the hash chain of the audit log file against its head, responds with sequence
+// number and hash of the last entry to keep outside and compare with later
+func (s Service) handleVerifyAudit(w http.ResponseWriter, r *http.Request) {
+	if s.Audit == nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, JSON{"error": "audit log is disabled"})
+		return
+	}
+	n, err := s.Audit.Verify()
+	if err != nil {
+		log.Printf("[WARN] audit log verification failed: %v", err)
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, JSON{"valid": false, "error": err.Error()})
+		return
+	}
+	seq, last := s.Audit.Head()
+	render.JSON(w, r, JSON{"valid": true, "entries": n, "head": JSON{"seq": seq, "hash": last}})
+}
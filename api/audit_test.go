@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/mrnbort/summer_break/audit"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestService_audit(t *testing.T) {
+	budget := model.Budget{ID: "b-1", Category: "Fuel", Period: model.BudgetMonthly, Amount: 50}
+	proc := &ProcessorMock{
+		BudgetFunc: func(ctx context.Context, id string) (model.Budget, error) {
+			if id != budget.ID {
+				return model.Budget{}, model.ErrNotFound
+			}
+			return budget, nil
+		},
+		UpdateBudgetFunc: func(ctx context.Context, b model.Budget) (model.Budget, error) {
+			budget = b
+			return b, nil
+		},
+		DeleteBudgetFunc: func(ctx context.Context, id string) error {
+			return model.ErrNotFound
+		},
+		AddAPIKeyFunc: func(ctx context.Context, key model.APIKey) (model.APIKey, error) {
+			key.ID = "k-1"
+			return key, nil
+		},
+		BudgetsFunc: func(ctx context.Context) ([]model.Budget, error) {
+			return []model.Budget{budget}, nil
+		},
+	}
+	log, err := audit.Open(audit.Config{Key: []byte("secret")})
+	require.NoError(t, err)
+	ts := httptest.NewServer(Service{Processor: proc, Auth: true, AdminKey: "bootstrap", Audit: log}.routes())
+	defer ts.Close()
+
+	client := http.Client{Timeout: time.Second}
+	do := func(method, url, body string) (int, http.Header, string) {
+		req, err := http.NewRequest(method, ts.URL+url, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set(APIKeyHeader, "bootstrap")
+		req.Header.Set("Content-Type", "application/json")
+		if method == "PUT" {
+			req.Header.Set(RequestIDHeader, "req-1")
+		}
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, resp.Header, string(data)
+	}
+
+	code, header, _ := do("PUT", "/budgets/b-1", `{"category":"Fuel","period":"monthly","amount":60}`)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "req-1", header.Get(RequestIDHeader))
+	code, _, _ = do("DELETE", "/budgets/b-9", "")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, header, _ = do("GET", "/budgets", "")
+	assert.Equal(t, http.StatusOK, code, "reads are not recorded")
+	assert.Len(t, header.Get(RequestIDHeader), 16, "generated")
+	code, _, body := do("POST", "/keys", `{"name":"bank sync","scopes":["write-transactions"]}`)
+	require.Equal(t, http.StatusCreated, code)
+	created := model.APIKey{}
+	require.NoError(t, json.Unmarshal([]byte(body), &created))
+	require.NotEmpty(t, created.Key)
+
+	code, _, body = do("GET", "/audit", "")
+	require.Equal(t, http.StatusOK, code)
+	entries := []model.AuditEntry{}
+	require.NoError(t, json.Unmarshal([]byte(body), &entries))
+	require.Len(t, entries, 6, "pending and outcome of each change")
+	key, del, upd, pending := entries[0], entries[2], entries[4], entries[5]
+	assert.Equal(t, "admin key", pending.Actor)
+	assert.Equal(t, model.AuditUpdate, pending.Action)
+	assert.Equal(t, model.AuditPending, pending.Outcome)
+	assert.Equal(t, "/budgets/b-1", pending.Resource)
+	assert.Equal(t, "req-1", pending.RequestID)
+	assert.JSONEq(t, `{"id":"b-1","category":"Fuel","period":"monthly","amount":50}`, string(pending.Before))
+	assert.Equal(t, model.AuditDone, upd.Outcome)
+	assert.Equal(t, pending.Seq, upd.Ref)
+	assert.Equal(t, "req-1", upd.RequestID)
+	assert.Equal(t, http.StatusOK, upd.Status)
+	assert.JSONEq(t, `{"id":"b-1","category":"Fuel","period":"monthly","amount":60}`, string(upd.After))
+	assert.Equal(t, int64(len(`{"category":"Fuel","period":"monthly","amount":60}`)), upd.BodyBytes)
+	assert.Equal(t, "d12adca0424ff7f135baf1335bbb3894f4393c23712715aee885ddf270384550", upd.BodySHA256)
+	assert.Equal(t, model.AuditFailed, del.Outcome, "failed requests are recorded")
+	assert.Equal(t, http.StatusNotFound, del.Status)
+	assert.Equal(t, model.AuditConfig, key.Action)
+	assert.Equal(t, entries[1].Hash, key.PrevHash)
+	assert.NotContains(t, string(key.After), created.Key, "the key is not logged")
+	assert.NotContains(t, string(key.After), `"hash"`)
+	assert.Contains(t, string(key.After), created.Prefix)
+
+	code, _, body = do("GET", "/audit?action=update&outcome=done&from=2020-01-01&limit=5", "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 1, strings.Count(body, `"seq"`))
+	code, _, _ = do("GET", "/audit?limit=-1", "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _, _ = do("GET", "/audit?limit=1001", "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _, body = do("GET", "/audit?before=3&limit=1", "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `"seq":2,`)
+	assert.Equal(t, 1, strings.Count(body, `"seq"`))
+	code, _, body = do("GET", "/audit/verify", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"valid":true,"entries":6,"head":{"seq":6,"hash":"`+key.Hash+`"}}`, body)
+
+	// changes are not applied if they can't be recorded
+	fileLog, err := audit.Open(audit.Config{Path: filepath.Join(t.TempDir(), "audit.log"), Key: []byte("secret")})
+	require.NoError(t, err)
+	require.NoError(t, fileLog.Close())
+	ts.Config.Handler = Service{Processor: proc, Auth: true, AdminKey: "bootstrap", Audit: fileLog}.routes()
+	code, _, _ = do("PUT", "/budgets/b-1", `{"category":"Fuel","period":"monthly","amount":70}`)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, 60.0, budget.Amount, "not changed")
+}
@@ -0,0 +1,278 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/mrnbort/summer_break/model"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// APIKeyHeader is the request header with API key, "Authorization: Bearer <key>" works as well
+const APIKeyHeader = "X-API-Key"
+
+// keyPrefix starts every generated API key, so it's easy to recognize in configs and logs
+const keyPrefix = "sb_"
+
+// principal is the caller of the request with its allowed scopes
+type principal struct {
+	Name    string   `json:"name"` // i.e. "key k-1 (reports)", "admin key" or "user alice"
+	Scopes  []string `json:"scopes"`
+	Ledgers []string `json:"ledgers,omitempty"` // accounts the caller can access, all of them if empty
+}
+
+type ctxKey int
+
+const (
+	principalKey ctxKey = iota
+	requestIDKey
+)
+
+// authenticate finds the caller by API key or session token of the request and puts it to the request
+// context. Requests without valid credentials get 401 if auth is enabled, otherwise the API is open.
+func (s Service) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.Auth {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key := requestKey(r)
+		if key == "" {
+			unauthorized(w, r, errors.New("api key or session token is required"))
+			return
+		}
+		var p principal
+		var err error
+		if isToken(key) {
+			p, err = s.sessionPrincipal(r.Context(), key)
+		} else {
+			p, err = s.keyPrincipal(r.Context(), key)
+		}
+		if err != nil {
+			unauthorized(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey, p)))
+	})
+}
+
+// keyPrincipal returns the principal of API key, the bootstrap admin key is checked first
+func (s Service) keyPrincipal(ctx context.Context, key string) (principal, error) {
+	hash := hashKey(key)
+	if s.AdminKey != "" && subtle.ConstantTimeCompare([]byte(hash), []byte(hashKey(s.AdminKey))) == 1 {
+		return principal{Name: "admin key", Scopes: []string{model.ScopeAdmin}}, nil
+	}
+	k, err := s.Processor.APIKeyByHash(ctx, hash)
+	if errors.Is(err, model.ErrNotFound) {
+		return principal{}, errors.New("invalid api key")
+	}
+	if err != nil {
+		return principal{}, err
+	}
+	return principal{Name: "key " + k.ID + " (" + k.Name + ")", Scopes: k.Scopes}, nil
+}
+
+// authorize allows the request only if its caller has the scope and access to the ledgers of the request,
+// it's always allowed if auth is disabled
+func (s Service) authorize(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !s.Auth {
+				next.ServeHTTP(w, r)
+				return
+			}
+			p, ok := r.Context().Value(principalKey).(principal)
+			if !ok || !model.HasScope(p.Scopes, scope) {
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, JSON{"error": p.Name + " doesn't have " + scope + " scope"})
+				return
+			}
+			if len(p.Ledgers) > 0 {
+				var err error
+				if r, err = limitLedgers(r, p.Ledgers); err != nil {
+					render.Status(r, http.StatusForbidden)
+					render.JSON(w, r, JSON{"error": p.Name + " " + err.Error()})
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ledgerRoutes select ledgers by account path or query parameter, they are allowed to callers
+// with access to some ledgers only
+var ledgerRoutes = map[string]bool{
+	"/transactions":                    true,
+	"/report":                          true,
+	"/balance":                         true,
+	"/reports/pnl":                     true,
+	"/reports/tax/{year}":              true,
+	"/reports/budget":                  true,
+	"/reports/forecast":                true,
+	"/reports/compare":                 true,
+	"/journal/entries":                 true,
+	"/accounts":                        true,
+	"/schedules":                       true,
+	"/accounts/{account}":              true,
+	"/accounts/{account}/transactions": true,
+	"/accounts/{account}/report":       true,
+	"/accounts/{account}/reconcile":    true,
+}
+
+// sharedRoutes read settings common to all ledgers, they are allowed to callers with access to some
+// ledgers only. Other routes, like trial balance or jobs, span all ledgers and are refused to them.
+var sharedRoutes = map[string]bool{
+	"/budgets":            true,
+	"/budgets/{id}":       true,
+	"/reports/tax/config": true,
+	"/journal/accounts":   true,
+}
+
+// limitLedgers checks the request works with the ledgers only. Requests without account are limited
+// to the ledgers by account query parameter, uploads without account go to the default ledger.
+func limitLedgers(r *http.Request, ledgers []string) (*http.Request, error) {
+	granted := map[string]bool{}
+	for _, l := range ledgers {
+		granted[l] = true
+	}
+	pattern := chi.RouteContext(r.Context()).RoutePattern()
+	if sharedRoutes[pattern] && r.Method == http.MethodGet {
+		return r, nil
+	}
+	if !ledgerRoutes[pattern] || (pattern == "/transactions" && r.Method != http.MethodGet) {
+		if pattern == "/transactions" && r.Method == http.MethodPost && granted[model.DefaultAccount] {
+			return r, nil
+		}
+		return r, errors.New("has access to some ledgers only, " + r.Method + " " + pattern + " works with all of them")
+	}
+
+	accounts := []string{}
+	if a := chi.URLParam(r, "account"); a != "" {
+		accounts = append(accounts, a)
+	}
+	accounts = append(accounts, queryAccounts(r)...)
+	for _, a := range accounts {
+		if !granted[a] {
+			return r, errors.New("has no access to " + a + " ledger")
+		}
+	}
+	if len(accounts) > 0 {
+		return r, nil
+	}
+	u := *r.URL
+	q := u.Query()
+	q.Set("account", strings.Join(ledgers, ","))
+	u.RawQuery = q.Encode()
+	r = r.WithContext(r.Context())
+	r.URL = &u
+	return r, nil
+}
+
+// requestKey returns API key from X-API-Key or Authorization header, empty string if there is none
+func requestKey(r *http.Request) string {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return key
+	}
+	auth := r.Header.Get("Authorization")
+	if len(auth) > len("Bearer ") && strings.EqualFold(auth[:len("Bearer ")], "Bearer ") {
+		return strings.TrimSpace(auth[len("Bearer "):])
+	}
+	return ""
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="summer_break"`)
+	render.Status(r, http.StatusUnauthorized)
+	render.JSON(w, r, JSON{"error": err.Error()})
+}
+
+// hashKey returns hex SHA-256 of the key, keys are random so they don't need a slow hash
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// GET /keys, lists API keys without their hashes
+func (s Service) handleAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := s.Processor.APIKeys(r.Context())
+	if err != nil {
+		log.Printf("[WARN] can't get api keys: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	for i := range keys {
+		keys[i].Hash = ""
+	}
+	render.JSON(w, r, keys)
+}
+
+// POST /keys, makes API key with given name and scopes, i.e. {"name":"bank sync","scopes":["write-transactions"]}.
+// The key is in the response only, it can't be read again.
+func (s Service) handleAddAPIKey(w http.ResponseWriter, r *http.Request) {
+	req := struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+	}{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	key := model.APIKey{Name: strings.TrimSpace(req.Name), Scopes: req.Scopes, Created: time.Now()}
+	if err := key.Validate(); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	secret, err := newAPIKey()
+	if err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	key.Prefix, key.Hash = secret[:len(keyPrefix)+6], hashKey(secret)
+
+	key, err = s.Processor.AddAPIKey(r.Context(), key)
+	if err != nil {
+		log.Printf("[WARN] can't add api key: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	log.Printf("[INFO] api key %s (%s) added with scopes %v", key.ID, key.Name, key.Scopes)
+	key.Hash, key.Key = "", secret
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, key)
+}
+
+// DELETE /keys/{id}, revokes API key
+func (s Service) handleDeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := s.Processor.DeleteAPIKey(r.Context(), id); err != nil {
+		log.Printf("[WARN] can't delete api key: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	log.Printf("[INFO] api key %s deleted", id)
+	render.JSON(w, r, JSON{"status": "ok"})
+}
+
+// newAPIKey makes random API key
+func newAPIKey() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return keyPrefix + hex.EncodeToString(b), nil
+}
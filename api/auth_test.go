@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestService_auth(t *testing.T) {
+	keys := []model.APIKey{{ID: "k-1", Name: "reports", Hash: hashKey("sb_reader"), Scopes: []string{model.ScopeReadReport}}}
+	proc := &ProcessorMock{
+		APIKeyByHashFunc: func(ctx context.Context, hash string) (model.APIKey, error) {
+			for _, k := range keys {
+				if k.Hash == hash {
+					return k, nil
+				}
+			}
+			return model.APIKey{}, model.ErrNotFound
+		},
+		APIKeysFunc: func(ctx context.Context) ([]model.APIKey, error) {
+			return append([]model.APIKey{}, keys...), nil
+		},
+		AddAPIKeyFunc: func(ctx context.Context, key model.APIKey) (model.APIKey, error) {
+			key.ID = "k-2"
+			keys = append(keys, key)
+			return key, nil
+		},
+		DeleteAPIKeyFunc: func(ctx context.Context, id string) error {
+			keys = keys[:1]
+			return nil
+		},
+		GenerateReportFunc: func(ctx context.Context, filter model.Filter) (model.Report, error) {
+			return model.Report{}, nil
+		},
+		DeleteScheduleFunc: func(ctx context.Context, id string) error {
+			return nil
+		},
+	}
+
+	ts := httptest.NewServer(Service{Processor: proc, Auth: true, AdminKey: "bootstrap"}.routes())
+	defer ts.Close()
+
+	client := http.Client{Timeout: time.Second}
+	idemKey := ""
+	do := func(method, url, key, body string) (int, http.Header, string) {
+		req, err := http.NewRequest(method, ts.URL+url, strings.NewReader(body))
+		require.NoError(t, err)
+		if key != "" {
+			req.Header.Set(APIKeyHeader, key)
+		}
+		if idemKey != "" {
+			req.Header.Set(IdempotencyHeader, idemKey)
+		}
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, resp.Header, string(data)
+	}
+
+	code, header, _ := do("GET", "/report", "", "")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.NotEmpty(t, header.Get("WWW-Authenticate"))
+	code, _, _ = do("GET", "/report", "sb_wrong", "")
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, _, _ = do("GET", "/report", "sb_reader", "")
+	assert.Equal(t, http.StatusOK, code)
+	code, _, body := do("DELETE", "/schedules/s-1", "sb_reader", "")
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.Contains(t, body, "write-transactions")
+	code, _, _ = do("GET", "/keys", "sb_reader", "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, _, body = do("POST", "/keys", "bootstrap", `{"name":"bank sync","scopes":["write-transactions"]}`)
+	require.Equal(t, http.StatusCreated, code, body)
+	created := model.APIKey{}
+	require.NoError(t, json.Unmarshal([]byte(body), &created))
+	assert.Equal(t, "k-2", created.ID)
+	assert.True(t, strings.HasPrefix(created.Key, created.Prefix), created)
+	assert.Empty(t, created.Hash)
+	assert.Equal(t, hashKey(created.Key), keys[1].Hash, "only hash is stored")
+	code, _, _ = do("POST", "/keys", "bootstrap", `{"name":"root","scopes":["root"]}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	req, err := http.NewRequest("DELETE", ts.URL+"/schedules/s-1", http.NoBody)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+created.Key)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	code, _, _ = do("GET", "/report", created.Key, "")
+	assert.Equal(t, http.StatusForbidden, code, "write scope doesn't allow reading")
+
+	code, _, body = do("GET", "/keys", "bootstrap", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotContains(t, body, "hash")
+	code, _, _ = do("DELETE", "/keys/k-2", "bootstrap", "")
+	assert.Equal(t, http.StatusOK, code)
+	code, _, _ = do("DELETE", "/schedules/s-1", created.Key, "")
+	assert.Equal(t, http.StatusUnauthorized, code, "revoked")
+
+	idemKey = "retry-1"
+	code, _, body = do("POST", "/keys", "bootstrap", `{"name":"bank sync","scopes":["write-transactions"]}`)
+	require.Equal(t, http.StatusCreated, code)
+	code, _, other := do("POST", "/keys", "sb_reader", `{"name":"bank sync","scopes":["write-transactions"]}`)
+	assert.Equal(t, http.StatusForbidden, code, "not replayed to another caller")
+	assert.NotContains(t, other, `"key"`)
+	code, header, replayed := do("POST", "/keys", "bootstrap", `{"name":"bank sync","scopes":["write-transactions"]}`)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, "true", header.Get("Idempotent-Replayed"))
+	assert.Equal(t, body, replayed)
+	idemKey = ""
+
+	open := httptest.NewServer(Service{Processor: proc}.routes())
+	defer open.Close()
+	resp, err = client.Get(open.URL + "/keys")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "auth is disabled")
+}
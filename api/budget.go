@@ -0,0 +1,125 @@
+package api
+
+import (
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/mrnbort/summer_break/model"
+	"log"
+	"net/http"
+	"time"
+)
+
+// GET /budgets
+func (s Service) handleBudgets(w http.ResponseWriter, r *http.Request) {
+	budgets, err := s.Processor.Budgets(r.Context())
+	if err != nil {
+		log.Printf("[WARN] can't get budgets: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	render.JSON(w, r, budgets)
+}
+
+// GET /budgets/{id}
+func (s Service) handleBudget(w http.ResponseWriter, r *http.Request) {
+	b, err := s.Processor.Budget(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		log.Printf("[WARN] can't get budget: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	render.JSON(w, r, b)
+}
+
+// POST /budgets and PUT /budgets/{id}, i.e. {"category":"Fuel","period":"monthly","amount":50}
+func (s Service) handleSetBudget(w http.ResponseWriter, r *http.Request) {
+	b := model.Budget{}
+	if err := render.DecodeJSON(r.Body, &b); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	if err := b.Validate(); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+
+	var err error
+	if b.ID = chi.URLParam(r, "id"); b.ID == "" {
+		b, err = s.Processor.AddBudget(r.Context(), b)
+	} else {
+		b, err = s.Processor.UpdateBudget(r.Context(), b)
+	}
+	if err != nil {
+		log.Printf("[WARN] can't set budget: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	if chi.URLParam(r, "id") == "" {
+		render.Status(r, http.StatusCreated)
+	}
+	render.JSON(w, r, b)
+}
+
+// DELETE /budgets/{id}
+func (s Service) handleDeleteBudget(w http.ResponseWriter, r *http.Request) {
+	if err := s.Processor.DeleteBudget(r.Context(), chi.URLParam(r, "id")); err != nil {
+		log.Printf("[WARN] can't delete budget: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	render.JSON(w, r, JSON{"status": "ok"})
+}
+
+// GET /reports/budget?from=DATE&to=DATE, budget vs actual expenses, the current month by default.
+// Accepts the same account and tag filters as GET /report, renders as JSON, CSV, HTML or PDF.
+func (s Service) handleBudgetReport(w http.ResponseWriter, r *http.Request) {
+	format := negotiateFormat(r, formatJSON, formatCSV, formatHTML, formatPDF)
+	if format == "" {
+		render.Status(r, http.StatusNotAcceptable)
+		render.JSON(w, r, JSON{"error": "supported formats are json, csv, html and pdf"})
+		return
+	}
+	filter, err := parseFilter(r)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	if filter.From.IsZero() && filter.To.IsZero() {
+		now := time.Now()
+		filter.From = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+		filter.To = filter.From.AddDate(0, 1, -1)
+	}
+
+	res, err := s.Processor.BudgetReport(r.Context(), filter)
+	if err != nil {
+		log.Printf("[WARN] can't make budget report: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+
+	if format == formatJSON {
+		render.JSON(w, r, res)
+		return
+	}
+	t := table{Title: "Budget vs Actual", Subtitle: filterDescription(filter),
+		Header: []string{"Category", "Budgeted", "Actual", "Variance", "% used"}}
+	for _, l := range append(res.Lines, res.Total) {
+		label := l.Category
+		if l.OverBudget {
+			label += " (over budget)"
+		}
+		t.Rows = append(t.Rows, reportRow{Label: label, Amounts: []float64{l.Budgeted, l.Actual, l.Variance, l.PercentUsed}})
+	}
+	t.Rows[len(t.Rows)-1].Total = true
+	if err = writeTable(w, format, "budget", t); err != nil {
+		log.Printf("[WARN] can't write budget report: %v", err)
+	}
+}
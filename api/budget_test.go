@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestService_budgets(t *testing.T) {
+	proc := &ProcessorMock{
+		BudgetsFunc: func(ctx context.Context) ([]model.Budget, error) {
+			return []model.Budget{{ID: "b-1", Category: "Fuel", Period: model.BudgetMonthly, Amount: 50}}, nil
+		},
+		BudgetFunc: func(ctx context.Context, id string) (model.Budget, error) {
+			return model.Budget{}, fmt.Errorf("budget %q: %w", id, model.ErrNotFound)
+		},
+		AddBudgetFunc: func(ctx context.Context, b model.Budget) (model.Budget, error) {
+			b.ID = "b-2"
+			return b, nil
+		},
+		UpdateBudgetFunc: func(ctx context.Context, b model.Budget) (model.Budget, error) {
+			return model.Budget{}, fmt.Errorf("category %q already has budget: %w", b.Category, model.ErrConflict)
+		},
+		DeleteBudgetFunc: func(ctx context.Context, id string) error {
+			return nil
+		},
+		BudgetReportFunc: func(ctx context.Context, filter model.Filter) (model.BudgetReport, error) {
+			return model.BudgetReport{
+				Period: model.Period{From: filter.From, To: filter.To},
+				Lines:  []model.BudgetLine{{Category: "Fuel", Budgeted: 50, Actual: 58.77, Variance: -8.77, PercentUsed: 117.54, OverBudget: true}},
+				Total:  model.BudgetLine{Category: "Total", Budgeted: 50, Actual: 58.77, Variance: -8.77, PercentUsed: 117.54, OverBudget: true},
+			}, nil
+		},
+	}
+
+	ts := httptest.NewServer((&Service{Processor: proc}).routes())
+	defer ts.Close()
+
+	client := http.Client{Timeout: time.Second}
+	do := func(method, url, body string) (int, string) {
+		req, err := http.NewRequest(method, ts.URL+url, strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(data)
+	}
+
+	code, body := do("GET", "/budgets", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `[{"id":"b-1","category":"Fuel","period":"monthly","amount":50}]`+"\n", body)
+	code, _ = do("GET", "/budgets/b-9", "")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, body = do("POST", "/budgets", `{"category":"Repairs","period":"quarterly","amount":90}`)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, `{"id":"b-2","category":"Repairs","period":"quarterly","amount":90}`+"\n", body)
+	code, _ = do("POST", "/budgets", `{"category":"Repairs","period":"daily","amount":90}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Len(t, proc.AddBudgetCalls(), 1)
+
+	code, _ = do("PUT", "/budgets/b-2", `{"category":"Fuel","period":"quarterly","amount":90}`)
+	assert.Equal(t, http.StatusConflict, code)
+	require.Len(t, proc.UpdateBudgetCalls(), 1)
+	assert.Equal(t, "b-2", proc.UpdateBudgetCalls()[0].B.ID)
+
+	code, _ = do("DELETE", "/budgets/b-2", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "b-2", proc.DeleteBudgetCalls()[0].Id)
+
+	code, body = do("GET", "/reports/budget?from=2020-07-01&to=2020-07-31", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `"lines":[{"category":"Fuel","budgeted":50,"actual":58.77,"variance":-8.77,"percentUsed":117.54,"overBudget":true}]`)
+
+	code, body = do("GET", "/reports/budget?format=csv", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Category,Budgeted,Actual,Variance,% used\n"+
+		"Fuel (over budget),50.00,58.77,-8.77,117.54\n"+
+		"Total (over budget),50.00,58.77,-8.77,117.54\n", body)
+	filter := proc.BudgetReportCalls()[1].Filter
+	assert.Equal(t, 1, filter.From.Day(), "current month by default")
+	assert.Equal(t, filter.From.Month(), filter.To.Month())
+	assert.Equal(t, 1, filter.To.AddDate(0, 0, 1).Day())
+}
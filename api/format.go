@@ -0,0 +1,264 @@
+package api
+
+import (
+	"encoding/csv"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/mrnbort/summer_break/pdf"
+	"html/template"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// output formats of reports
+const (
+	formatJSON = "json"
+	formatCSV  = "csv"
+	formatHTML = "html"
+	formatPDF  = "pdf"
+)
+
+var formatTypes = map[string]string{
+	formatJSON: "application/json",
+	formatCSV:  "text/csv",
+	formatHTML: "text/html",
+	formatPDF:  "application/pdf",
+}
+
+// negotiateFormat picks output format from "format" query parameter or Accept header,
+// json is the default. Media ranges of the header are tried by q-value, the most preferred first,
+// and the ones with q=0 are excluded. Returns empty string if the requested format is not supported.
+func negotiateFormat(r *http.Request, supported ...string) string {
+	if f := strings.ToLower(r.URL.Query().Get("format")); f != "" {
+		for _, s := range supported {
+			if s == f {
+				return f
+			}
+		}
+		return ""
+	}
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return formatJSON
+	}
+	type mediaRange struct {
+		typ string
+		q   float64
+	}
+	var ranges []mediaRange
+	excluded := map[string]bool{}
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		if q == 0 {
+			excluded[mt] = true
+			continue
+		}
+		ranges = append(ranges, mediaRange{typ: mt, q: q})
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	for _, mr := range ranges {
+		for _, s := range supported { // json goes first, so it's picked for wildcards
+			t := formatTypes[s]
+			if excluded[t] {
+				continue
+			}
+			if mr.typ == t || mr.typ == "*/*" || (strings.HasSuffix(mr.typ, "/*") && strings.HasPrefix(t, strings.TrimSuffix(mr.typ, "*"))) {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// table is a tabular report which can be rendered as csv, html or pdf
+type table struct {
+	Title    string
+	Subtitle string
+	Header   []string // label column title followed by amount column titles
+	Rows     []reportRow
+}
+
+// reportRow is a row of tabular report, with amount per column
+type reportRow struct {
+	Section string
+	Label   string
+	Amounts []float64
+	Total   bool
+}
+
+// writeTable writes table in the given format, csv and pdf are sent as attachments named after the report
+func writeTable(w http.ResponseWriter, format, name string, t table) error {
+	switch format {
+	case formatCSV:
+		w.Header().Set("Content-Type", formatTypes[formatCSV])
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.csv"`)
+		return writeCSVTable(w, t)
+	case formatPDF:
+		w.Header().Set("Content-Type", formatTypes[formatPDF])
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.pdf"`)
+		return writePDFTable(w, t)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	return reportTmpl.Execute(w, t)
+}
+
+// writeCSVTable writes table as csv, with section column if rows have sections
+func writeCSVTable(w io.Writer, t table) error {
+	sections := false
+	for _, row := range t.Rows {
+		sections = sections || row.Section != ""
+	}
+	header := t.Header
+	if sections {
+		header = append([]string{"Section"}, header...)
+	}
+	rows := [][]string{header}
+	for _, row := range t.Rows {
+		var rec []string
+		if sections {
+			rec = append(rec, row.Section)
+		}
+		rec = append(rec, row.Label)
+		for _, a := range row.Amounts {
+			rec = append(rec, money(a))
+		}
+		rows = append(rows, rec)
+	}
+	return csv.NewWriter(w).WriteAll(rows)
+}
+
+// writePDFTable writes table as a printable pdf document, adding pages as needed
+func writePDFTable(w io.Writer, t table) error {
+	const (
+		margin   = 54.0
+		colWidth = 90.0
+		rowStep  = 16.0
+		size     = 10.0
+	)
+	doc := pdf.New()
+	right := pdf.PageWidth - margin
+	y := pdf.PageHeight - margin
+	doc.Text(margin, y, 16, true, t.Title)
+	y -= 20
+	if t.Subtitle != "" {
+		doc.Text(margin, y, size, false, t.Subtitle)
+		y -= 20
+	}
+
+	amounts := func(y float64, bold bool, cells []string) {
+		for i := len(cells) - 1; i >= 0; i-- {
+			doc.TextRight(right-float64(len(cells)-1-i)*colWidth, y, size, bold, cells[i])
+		}
+	}
+	header := func() {
+		doc.Text(margin, y, size, true, t.Header[0])
+		amounts(y, true, t.Header[1:])
+		doc.Line(margin, y-4, right, y-4)
+		y -= rowStep + 4
+	}
+	header()
+	section := ""
+	for _, row := range t.Rows {
+		if y < margin+2*rowStep {
+			doc.AddPage()
+			y = pdf.PageHeight - margin
+			header()
+		}
+		if row.Section != "" && !row.Total && row.Section != section {
+			y -= 4
+			doc.Text(margin, y, size, true, row.Section)
+			y -= rowStep
+		}
+		section = row.Section
+		if row.Total {
+			doc.Line(margin, y+rowStep-4, right, y+rowStep-4)
+		}
+		cells := make([]string, 0, len(row.Amounts))
+		for _, a := range row.Amounts {
+			cells = append(cells, money(a))
+		}
+		label := row.Label
+		if row.Section != "" && !row.Total {
+			label = "    " + label
+		}
+		doc.Text(margin, y, size, row.Total, label)
+		amounts(y, row.Total, cells)
+		y -= rowStep
+	}
+	_, err := doc.WriteTo(w)
+	return err
+}
+
+// filterDescription describes report filter in human terms, i.e. "2020-07-01 - 2020-07-31, accounts: card"
+func filterDescription(filter model.Filter) string {
+	var parts []string
+	switch {
+	case !filter.From.IsZero() && !filter.To.IsZero():
+		parts = append(parts, filter.From.Format(model.DateLayout)+" - "+filter.To.Format(model.DateLayout))
+	case !filter.From.IsZero():
+		parts = append(parts, "since "+filter.From.Format(model.DateLayout))
+	case !filter.To.IsZero():
+		parts = append(parts, "until "+filter.To.Format(model.DateLayout))
+	}
+	if len(filter.Accounts) > 0 {
+		parts = append(parts, "accounts: "+strings.Join(filter.Accounts, ", "))
+	}
+	if !filter.Tags.IsEmpty() {
+		parts = append(parts, "tags: "+filter.Tags.String())
+	}
+	return strings.Join(parts, "; ")
+}
+
+// money formats amount with two decimals
+func money(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+var reportTmpl = template.Must(template.New("report").Funcs(template.FuncMap{"money": money}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { padding: 4px 12px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+tr.section td { font-weight: bold; padding-top: 1em; text-align: left; }
+tr.total td { font-weight: bold; border-top: 1px solid #000; }
+@media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{- if .Subtitle}}
+<p>{{.Subtitle}}</p>
+{{- end}}
+<table>
+<tr>{{range .Header}}<th>{{.}}</th>{{end}}</tr>
+{{- $section := ""}}
+{{- range .Rows}}
+{{- if and .Section (not .Total) (ne .Section $section)}}
+<tr class="section"><td>{{.Section}}</td></tr>
+{{- end}}
+{{- $section = .Section}}
+<tr{{if .Total}} class="total"{{end}}><td>{{.Label}}</td>{{range .Amounts}}<td>{{money .}}</td>{{end}}</tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"github.com/go-chi/render"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IdempotencyHeader is the request header with a client-generated key of the change request
+const IdempotencyHeader = "Idempotency-Key"
+
+// DefaultIdempotencyTTL is how long responses are kept for replay if Service.IdempotencyTTL is not set
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// limits of the kept responses, the oldest ones are dropped to stay within them
+const (
+	maxIdemEntries = 10000
+	maxIdemBytes   = 64 << 20
+)
+
+// idempotency replays responses of POST, PUT, PATCH and DELETE requests with Idempotency-Key header,
+// so a client can retry the request without applying it twice. Requests are told apart by caller, key,
+// method and path, and the same key with a different body gets 422. Server errors are not kept, such
+// requests can be retried for real. It should run after authorize, so replays need the same scopes.
+type idempotency struct {
+	ttl        time.Duration
+	maxEntries int
+	maxBytes   int
+
+	mu      sync.Mutex
+	entries map[string]*idemEntry
+	order   []*idemEntry // by creation time, with removed entries until they are pruned
+	bytes   int          // bodies of kept responses
+}
+
+// idemEntry is a response of the request, done is closed when the response is complete
+type idemEntry struct {
+	key      string
+	done     chan struct{}
+	created  time.Time
+	bodyHash [sha256.Size]byte // of the request
+	status   int
+	header   http.Header
+	body     []byte
+}
+
+func newIdempotency(ttl time.Duration) *idempotency {
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+	return &idempotency{ttl: ttl, maxEntries: maxIdemEntries, maxBytes: maxIdemBytes, entries: map[string]*idemEntry{}}
+}
+
+func (i *idempotency) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyHeader)
+		if key == "" || r.Method == http.MethodGet || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key = strings.Join([]string{actor(r), r.Method, r.URL.Path, key}, "\n")
+
+		i.mu.Lock()
+		i.prune(time.Now())
+		e, ok := i.entries[key]
+		if !ok {
+			e = &idemEntry{key: key, done: make(chan struct{}), created: time.Now()}
+			i.entries[key] = e
+			i.order = append(i.order, e)
+		}
+		i.mu.Unlock()
+
+		if ok {
+			i.replay(w, r, e)
+			return
+		}
+
+		hash := sha256.New()
+		body := r.Body
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.TeeReader(body, hash), body}
+		rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			_, _ = io.Copy(io.Discard, r.Body) // the rest of the body the handler didn't read, to hash it all
+			i.mu.Lock()
+			defer i.mu.Unlock()
+			copy(e.bodyHash[:], hash.Sum(nil))
+			e.status, e.header, e.body = rec.status, w.Header().Clone(), rec.body.Bytes()
+			close(e.done)
+			if i.entries[key] != e {
+				return // dropped to stay within the limits while in progress
+			}
+			if rec.status >= http.StatusInternalServerError || len(e.body) > i.maxBytes {
+				delete(i.entries, key)
+				return
+			}
+			i.bytes += len(e.body)
+			i.prune(time.Now())
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// replay writes the kept response of the request if it's complete and the request has the same body
+func (i *idempotency) replay(w http.ResponseWriter, r *http.Request, e *idemEntry) {
+	select {
+	case <-e.done:
+	default:
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, JSON{"error": "request with the same idempotency key is in progress"})
+		return
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r.Body); err != nil {
+		render.Status(r, readStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	if !bytes.Equal(hash.Sum(nil), e.bodyHash[:]) {
+		render.Status(r, http.StatusUnprocessableEntity)
+		render.JSON(w, r, JSON{"error": "idempotency key was used with a different request body"})
+		return
+	}
+	e.replay(w)
+}
+
+// prune drops entries created more than ttl ago and the oldest ones over the limits of count and size,
+// caller should hold the lock
+func (i *idempotency) prune(now time.Time) {
+	for len(i.order) > 0 {
+		e := i.order[0]
+		if i.entries[e.key] == e && now.Sub(e.created) <= i.ttl && len(i.entries) <= i.maxEntries && i.bytes <= i.maxBytes {
+			return
+		}
+		i.order[0] = nil
+		i.order = i.order[1:]
+		if i.entries[e.key] == e {
+			delete(i.entries, e.key)
+			i.bytes -= len(e.body) // zero for entries in progress
+		}
+	}
+}
+
+// replay writes the kept response
+func (e *idemEntry) replay(w http.ResponseWriter) {
+	for k, v := range e.header {
+		w.Header()[k] = v
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(e.status)
+	_, _ = w.Write(e.body)
+}
+
+// recorder passes the response through and keeps its status and body
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	r.body.Write(p)
+	return r.ResponseWriter.Write(p)
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIdempotency(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Location", "/items/1")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, strings.Repeat("x", int(n)))
+	})
+	idem := newIdempotency(time.Hour)
+	withUser := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if user := r.Header.Get("X-User"); user != "" {
+				r = r.WithContext(context.WithValue(r.Context(), principalKey, principal{Name: user}))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	ts := httptest.NewServer(withUser(idem.middleware(handler)))
+	defer ts.Close()
+
+	user, reqBody := "", ""
+	do := func(method, path, key string) (*http.Response, string) {
+		req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(reqBody))
+		require.NoError(t, err)
+		if key != "" {
+			req.Header.Set(IdempotencyHeader, key)
+		}
+		if user != "" {
+			req.Header.Set("X-User", user)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(body)
+	}
+
+	resp, body := do("POST", "/items", "k1")
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "x", body)
+	resp, body = do("POST", "/items", "k1")
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "x", body, "replayed")
+	assert.Equal(t, "/items/1", resp.Header.Get("Location"))
+	assert.Equal(t, "true", resp.Header.Get("Idempotent-Replayed"))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	_, body = do("POST", "/items", "k2")
+	assert.Equal(t, "xx", body, "other key")
+	_, body = do("PUT", "/items", "k1")
+	assert.Equal(t, "xxx", body, "other method")
+	_, body = do("POST", "/items", "")
+	assert.Equal(t, "xxxx", body, "no key")
+	_, _ = do("GET", "/items", "k1")
+	assert.Equal(t, int32(5), atomic.LoadInt32(&calls), "get is not kept")
+
+	resp, _ = do("POST", "/fail", "k3")
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	_, _ = do("POST", "/fail", "k3")
+	assert.Equal(t, int32(7), atomic.LoadInt32(&calls), "server errors are retried")
+
+	go func() { _, _ = do("POST", "/slow", "k4") }()
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 8 }, time.Second, time.Millisecond)
+	resp, _ = do("POST", "/slow", "k4")
+	assert.Equal(t, http.StatusConflict, resp.StatusCode, "in progress")
+	close(release)
+
+	reqBody = "other body"
+	resp, _ = do("POST", "/items", "k1")
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode, "same key, different body")
+	reqBody, user = "", "user bob"
+	resp, body = do("POST", "/items", "k1")
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Idempotent-Replayed"), "keys are per caller")
+	assert.Equal(t, int32(9), atomic.LoadInt32(&calls))
+	user = ""
+
+	idem.mu.Lock()
+	idem.prune(time.Now().Add(2 * time.Hour))
+	assert.Empty(t, idem.entries)
+	assert.Zero(t, idem.bytes)
+	idem.maxEntries, idem.maxBytes = 2, 25
+	idem.mu.Unlock()
+
+	for _, key := range []string{"k5", "k6", "k7"} { // responses of 10, 11 and 12 bytes
+		_, _ = do("POST", "/items", key)
+	}
+	idem.mu.Lock()
+	assert.Len(t, idem.entries, 2, "the oldest dropped to keep bytes within the limit")
+	assert.Equal(t, 23, idem.bytes)
+	idem.mu.Unlock()
+	_, body = do("POST", "/items", "k7")
+	assert.Equal(t, strings.Repeat("x", 12), body, "replayed")
+	_, body = do("POST", "/items", "k5")
+	assert.Equal(t, strings.Repeat("x", 13), body, "done again")
+	_, _ = do("POST", "/items", "k9") // 14 bytes
+	idem.mu.Lock()
+	assert.Len(t, idem.entries, 1, "k7 dropped by count, k5 by bytes")
+	assert.Equal(t, 14, idem.bytes)
+	idem.mu.Unlock()
+}
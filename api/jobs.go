@@ -0,0 +1,77 @@
+package api
+
+import (
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"log"
+	"net/http"
+)
+
+// handleAsyncUpload stores multipart CSV file and queues it for ingestion, responds with
+// 202 Accepted and the job, its progress is available at /jobs/{id}
+func (s Service) handleAsyncUpload(w http.ResponseWriter, r *http.Request, account string) {
+	if s.Jobs == nil {
+		render.Status(r, http.StatusNotImplemented)
+		render.JSON(w, r, JSON{"error": "async uploads are disabled"})
+		return
+	}
+	file, err := formFile(r, "file", s.MaxParts)
+	if err != nil {
+		log.Printf("[WARN] can't read transactions: %v", err)
+		render.Status(r, readStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	job, err := s.Jobs.Submit(account, actor(r), file)
+	if err != nil {
+		log.Printf("[WARN] can't submit upload: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	render.Status(r, http.StatusAccepted)
+	render.JSON(w, r, job)
+}
+
+// GET /jobs, lists async uploads in the order they were submitted
+func (s Service) handleJobs(w http.ResponseWriter, r *http.Request) {
+	if s.Jobs == nil {
+		render.JSON(w, r, []struct{}{})
+		return
+	}
+	render.JSON(w, r, s.Jobs.Jobs())
+}
+
+// GET /jobs/{id}, returns status and progress of async upload
+func (s Service) handleJob(w http.ResponseWriter, r *http.Request) {
+	if s.Jobs == nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, JSON{"error": "async uploads are disabled"})
+		return
+	}
+	job, err := s.Jobs.Job(chi.URLParam(r, "id"))
+	if err != nil {
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	render.JSON(w, r, job)
+}
+
+// DELETE /jobs/{id}, cancels queued or running async upload, nothing of it is stored
+func (s Service) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	if s.Jobs == nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, JSON{"error": "async uploads are disabled"})
+		return
+	}
+	job, err := s.Jobs.Cancel(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Printf("[WARN] can't cancel job: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	render.JSON(w, r, job)
+}
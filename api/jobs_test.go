@@ -0,0 +1,121 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/mrnbort/summer_break/ingest"
+	"github.com/mrnbort/summer_break/jobs"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestService_jobs(t *testing.T) {
+	proc := &ProcessorMock{
+		ParseTransactionFunc: func(rec []string) (model.Transaction, error) {
+			return model.Transaction{Amount: 40, Type: model.Income, Memo: rec[3]}, nil
+		},
+		StageTransactionsFunc: func(ctx context.Context, batchID string, trs []model.Transaction) (string, error) {
+			return "1", nil
+		},
+		CommitBatchFunc: func(ctx context.Context, batchID string) ([]model.Transaction, error) {
+			return []model.Transaction{{ID: "1"}}, nil
+		},
+	}
+	manager := &jobs.Manager{Pipeline: ingest.Pipeline{Store: proc}, Dir: t.TempDir()}
+	svc := &Service{Processor: proc, Jobs: manager}
+	ts := httptest.NewServer(svc.routes())
+	defer ts.Close()
+
+	client := http.Client{Timeout: time.Second}
+	do := func(method, url, body string) (int, string) {
+		req, err := http.NewRequest(method, ts.URL+url, strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(data)
+	}
+	upload := func(url string) *http.Response { // url with host
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		fileField, err := writer.CreateFormFile("file", "test.csv")
+		require.NoError(t, err)
+		_, err = fileField.Write([]byte("2020-07-04,Income,40.00,347 Woodrow\n"))
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+		resp, err := client.Post(url, writer.FormDataContentType(), body)
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("async upload", func(t *testing.T) {
+		resp := upload(ts.URL + "/accounts/card/transactions?async=true")
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+		assert.Equal(t, "/jobs/1", resp.Header.Get("Location"))
+		job := model.Job{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&job))
+		assert.Equal(t, model.Job{ID: "1", Account: "card", Actor: "anonymous", Status: model.JobQueued, Created: job.Created}, job)
+		assert.Empty(t, proc.StageTransactionsCalls(), "not processed yet")
+
+		code, body := do("GET", "/jobs/1", "")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, `"status":"queued"`)
+		code, body = do("GET", "/jobs", "")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, `[{"id":"1",`)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() { _ = manager.Run(ctx) }()
+		assert.Eventually(t, func() bool {
+			_, body := do("GET", "/jobs/1", "")
+			return strings.Contains(body, `"status":"done"`)
+		}, time.Second, 10*time.Millisecond)
+		_, body = do("GET", "/jobs/1", "")
+		assert.Contains(t, body, `"result":{"lines":1,"accepted":1,"rejected":0}`)
+		require.Len(t, proc.StageTransactionsCalls(), 1)
+		assert.Equal(t, "card", proc.StageTransactionsCalls()[0].Transactions[0].Account)
+
+		code, body = do("DELETE", "/jobs/1", "")
+		assert.Equal(t, http.StatusConflict, code, body)
+	})
+
+	t.Run("unknown job", func(t *testing.T) {
+		code, _ := do("GET", "/jobs/42", "")
+		assert.Equal(t, http.StatusNotFound, code)
+		code, _ = do("DELETE", "/jobs/42", "")
+		assert.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("bad requests", func(t *testing.T) {
+		code, _ := do("POST", "/transactions?async=maybe", "")
+		assert.Equal(t, http.StatusBadRequest, code)
+		req, err := http.NewRequest("POST", ts.URL+"/transactions?async=true", strings.NewReader("[]"))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		ts := httptest.NewServer((&Service{Processor: proc}).routes())
+		defer ts.Close()
+		resp := upload(ts.URL + "/transactions?async=true")
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusNotImplemented, resp.StatusCode)
+	})
+}
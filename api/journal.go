@@ -0,0 +1,117 @@
+package api
+
+import (
+	"github.com/go-chi/render"
+	"github.com/mrnbort/summer_break/model"
+	"log"
+	"net/http"
+)
+
+// GET /journal/accounts
+func (s Service) handleChartOfAccounts(w http.ResponseWriter, r *http.Request) {
+	accounts, err := s.Processor.ChartOfAccounts(r.Context())
+	if err != nil {
+		log.Printf("[WARN] can't get chart of accounts: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	render.JSON(w, r, accounts)
+}
+
+// POST /journal/accounts, adds or updates account in the chart of accounts
+func (s Service) handleSetChartAccount(w http.ResponseWriter, r *http.Request) {
+	acc := model.ChartAccount{}
+	if err := render.DecodeJSON(r.Body, &acc); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	if err := acc.Validate(); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+
+	acc, err := s.Processor.SetChartAccount(r.Context(), acc)
+	if err != nil {
+		log.Printf("[WARN] can't set chart account: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	render.JSON(w, r, acc)
+}
+
+// GET /journal/entries, accepts the same filters as GET /transactions
+func (s Service) handleJournalEntries(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+
+	entries, err := s.Processor.JournalEntries(r.Context(), filter)
+	if err != nil {
+		log.Printf("[WARN] can't get journal entries: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	render.JSON(w, r, entries)
+}
+
+// POST /journal/entries, posts balanced journal entry
+func (s Service) handlePostEntry(w http.ResponseWriter, r *http.Request) {
+	req := struct {
+		Date     string          `json:"date"`
+		Memo     string          `json:"memo"`
+		Postings []model.Posting `json:"postings"`
+		Tags     []string        `json:"tags"`
+	}{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	entry := model.JournalEntry{Memo: req.Memo, Postings: req.Postings, Tags: req.Tags}
+	date, err := parseDate(req.Date)
+	if err == nil {
+		entry.Date = date
+		err = entry.Validate()
+	}
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+
+	entry, err = s.Processor.PostEntry(r.Context(), entry)
+	if err != nil {
+		log.Printf("[WARN] can't post journal entry: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	render.JSON(w, r, entry)
+}
+
+// GET /journal/trial-balance?asOf=DATE
+func (s Service) handleTrialBalance(w http.ResponseWriter, r *http.Request) {
+	asOf, err := parseDate(r.URL.Query().Get("asOf"))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+
+	balances, err := s.Processor.TrialBalance(r.Context(), asOf)
+	if err != nil {
+		log.Printf("[WARN] can't get trial balance: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	render.JSON(w, r, balances)
+}
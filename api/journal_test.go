@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestService_journal(t *testing.T) {
+	proc := &ProcessorMock{
+		ChartOfAccountsFunc: func(ctx context.Context) ([]model.ChartAccount, error) {
+			return nil, model.ErrDoubleEntryDisabled
+		},
+		SetChartAccountFunc: func(ctx context.Context, acc model.ChartAccount) (model.ChartAccount, error) {
+			if acc.Name == "Assets:Checking" {
+				return model.ChartAccount{}, fmt.Errorf("can't change type: %w", model.ErrConflict)
+			}
+			return acc, nil
+		},
+		PostEntryFunc: func(ctx context.Context, entry model.JournalEntry) (model.JournalEntry, error) {
+			entry.ID = "je-1"
+			return entry, nil
+		},
+		JournalEntriesFunc: func(ctx context.Context, filter model.Filter) ([]model.JournalEntry, error) {
+			return []model.JournalEntry{}, nil
+		},
+		TrialBalanceFunc: func(ctx context.Context, asOf time.Time) ([]model.ChartBalance, error) {
+			return []model.ChartBalance{{Account: "Assets:Checking", Type: model.AssetAccount, Balance: 10}}, nil
+		},
+	}
+
+	svc := &Service{
+		Processor: proc,
+	}
+
+	ts := httptest.NewServer(svc.routes())
+	defer ts.Close()
+
+	client := http.Client{Timeout: time.Second}
+	do := func(method, url, body string) (int, string) {
+		req, err := http.NewRequest(method, ts.URL+url, strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(data)
+	}
+
+	code, _ := do("GET", "/journal/accounts", "")
+	assert.Equal(t, http.StatusNotImplemented, code)
+
+	code, _ = do("POST", "/journal/accounts", `{"name":"Liabilities:Card","type":"Liability","ledger":"card"}`)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = do("POST", "/journal/accounts", `{"name":"Assets:Checking","type":"Equity"}`)
+	assert.Equal(t, http.StatusConflict, code)
+	code, _ = do("POST", "/journal/accounts", `{"name":"Assets:Checking","type":"Cash"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, 2, len(proc.SetChartAccountCalls()))
+
+	code, body := do("POST", "/journal/entries", `{"date":"2020-07-14","memo":"refund",`+
+		`"postings":[{"account":"Liabilities:Card","amount":7.5},{"account":"Expenses","amount":-7.5}]}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `"id":"je-1"`)
+	assert.Equal(t, time.Date(2020, 7, 14, 0, 0, 0, 0, time.Local), proc.PostEntryCalls()[0].Entry.Date)
+	code, _ = do("POST", "/journal/entries", `{"date":"2020-07-14",`+
+		`"postings":[{"account":"Liabilities:Card","amount":7.5},{"account":"Expenses","amount":-5}]}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, 1, len(proc.PostEntryCalls()))
+
+	code, _ = do("GET", "/journal/entries?from=2020-07-01&tag=woodrow", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "woodrow", proc.JournalEntriesCalls()[0].Filter.Tags.String())
+
+	code, body = do("GET", "/journal/trial-balance?asOf=2020-07-31", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `[{"account":"Assets:Checking","type":"Asset","balance":10}]`+"\n", body)
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-chi/render"
+	"github.com/mrnbort/summer_break/ingest"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errTooManyParts is returned by formFile when the file is not in the first Service.MaxParts parts
+var errTooManyParts = errors.New("too many multipart parts")
+
+// limitBody rejects requests with body larger than MaxBodySize, or MaxUploadSize for uploaded files and
+// MaxImportSize for archive imports, with 413. Bodies of unknown size are cut at the limit, their handlers
+// get *http.MaxBytesError reading them.
+func (s Service) limitBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := s.MaxBodySize
+		switch {
+		case r.URL.Path == "/import":
+			limit = s.MaxImportSize
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/transactions") &&
+			!strings.HasPrefix(r.Header.Get("Content-Type"), "application/json"):
+			limit = s.MaxUploadSize // CSV or OFX file, JSON arrays are limited as other bodies
+		}
+		if limit <= 0 || r.Body == nil || r.Body == http.NoBody {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.ContentLength > limit {
+			render.Status(r, http.StatusRequestEntityTooLarge)
+			render.JSON(w, r, JSON{"error": fmt.Sprintf("request body is larger than %d bytes", limit)})
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// tooLarge tells if the request failed because of body size, rows or parts limits
+func tooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.As(err, &maxErr) || errors.Is(err, ingest.ErrTooManyRows) || errors.Is(err, errTooManyParts)
+}
+
+// readStatus returns status of the error of reading request body, 413 for exceeded limits, 400 otherwise
+func readStatus(err error) int {
+	if tooLarge(err) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// rateLimiter keeps a token bucket per client. A bucket holds up to burst tokens and gets rate
+// tokens a second, every request takes one. Full buckets are dropped, so idle clients don't take memory.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	pruned  time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// newRateLimiter makes limiter of rate requests a second with burst, at least one, nil if rate is not positive
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = int(math.Ceil(rate))
+	}
+	return &rateLimiter{rate: rate, burst: float64(burst), buckets: map[string]*bucket{}}
+}
+
+// allow takes a token of the client's bucket, if there is none returns false and how long to wait for it
+func (l *rateLimiter) allow(client string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.pruned) >= time.Minute {
+		l.prune(now)
+	}
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, updated: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.updated).Seconds()*l.rate)
+	b.updated = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// prune drops buckets which are full by now, caller should hold the lock
+func (l *rateLimiter) prune(now time.Time) {
+	for client, b := range l.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+	l.pruned = now
+}
+
+// middleware responds with 429 and Retry-After to requests over the client's rate, the client is
+// found by the client func. Nil limiter allows everything.
+func (l *rateLimiter) middleware(client func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if l == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if ok, wait := l.allow(client(r), time.Now()); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				render.Status(r, http.StatusTooManyRequests)
+				render.JSON(w, r, JSON{"error": "rate limit exceeded"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientIP returns address the request came from, proxy headers are not trusted
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// clientName returns the authenticated caller, or the address if auth is disabled
+func clientName(r *http.Request) string {
+	if p, ok := r.Context().Value(principalKey).(principal); ok {
+		return p.Name
+	}
+	return "ip " + clientIP(r)
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_allow(t *testing.T) {
+	assert.Nil(t, newRateLimiter(0, 10), "disabled")
+
+	l := newRateLimiter(2, 3)
+	now := time.Date(2020, 7, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		ok, _ := l.allow("alice", now)
+		assert.True(t, ok, "burst")
+	}
+	ok, wait := l.allow("alice", now)
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+	ok, _ = l.allow("bob", now)
+	assert.True(t, ok, "other client")
+
+	ok, _ = l.allow("alice", now.Add(500*time.Millisecond))
+	assert.True(t, ok, "a token added")
+	ok, wait = l.allow("alice", now.Add(600*time.Millisecond))
+	assert.False(t, ok)
+	assert.Equal(t, 400*time.Millisecond, wait)
+
+	l.allow("alice", now.Add(2*time.Minute))
+	assert.Len(t, l.buckets, 1, "full bucket of bob pruned")
+	assert.Equal(t, 1.0, newRateLimiter(0.5, 0).burst, "burst of rate rounded up")
+}
+
+func TestService_limits(t *testing.T) {
+	var staged []model.Transaction
+	proc := &ProcessorMock{
+		ParseTransactionFunc: func(rec []string) (model.Transaction, error) {
+			return model.Transaction{Amount: 1, Type: model.Income, Memo: rec[3], Date: time.Now()}, nil
+		},
+		ProcessTransactionsFunc: func(ctx context.Context, trs []model.Transaction) ([]model.Transaction, error) {
+			return trs, nil
+		},
+		StageTransactionsFunc: func(ctx context.Context, batchID string, trs []model.Transaction) (string, error) {
+			staged = append(staged, trs...)
+			return "1", nil
+		},
+		CommitBatchFunc: func(ctx context.Context, batchID string) ([]model.Transaction, error) {
+			return staged, nil
+		},
+		DiscardBatchFunc: func(ctx context.Context, batchID string) error {
+			staged = nil
+			return nil
+		},
+	}
+	ts := httptest.NewServer(Service{Processor: proc, MaxBodySize: 64, MaxUploadSize: 1024, MaxImportSize: 4096, MaxRows: 3, MaxParts: 2}.routes())
+	defer ts.Close()
+
+	upload := func(body io.Reader, contentType string) (int, string) {
+		req, err := http.NewRequest("POST", ts.URL+"/transactions", body)
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(data)
+	}
+	csv := func(fields int, lines ...string) (*bytes.Buffer, string) {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		for i := 0; i < fields; i++ {
+			require.NoError(t, writer.WriteField("comment", "skipped"))
+		}
+		file, err := writer.CreateFormFile("file", "test.csv")
+		require.NoError(t, err)
+		_, err = file.Write([]byte(strings.Join(lines, "\n")))
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+		return body, writer.FormDataContentType()
+	}
+	line := "2020-07-01,Income,1,x"
+
+	code, _ := upload(csv(1, line, line, line))
+	assert.Equal(t, http.StatusOK, code)
+
+	code, body := upload(csv(1, line, line, line, line))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, code)
+	assert.Contains(t, body, "too many rows, the limit is 3")
+
+	code, body = upload(csv(2, line))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, code)
+	assert.Contains(t, body, "too many multipart parts")
+
+	long := line + strings.Repeat("x", 400)
+	code, body = upload(csv(0, long, long, long))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, code, "content length over the limit")
+	assert.Contains(t, body, "request body is larger than 1024 bytes")
+
+	b, contentType := csv(0, long, long, long)
+	code, _ = upload(io.MultiReader(b), contentType) // unknown length, sent chunked
+	assert.Equal(t, http.StatusRequestEntityTooLarge, code, "body cut at the limit")
+
+	code, body = upload(strings.NewReader(`[{},{},{},{}]`), "application/json")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, code)
+	assert.Contains(t, body, "too many rows")
+	code, _ = upload(strings.NewReader(`[{},{},{}]`), "application/json")
+	assert.Equal(t, http.StatusOK, code)
+	code, body = upload(strings.NewReader(`[{"memo":"`+strings.Repeat("x", 100)+`"}]`), "application/json")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, code, "JSON isn't an uploaded file")
+	assert.Contains(t, body, "request body is larger than 64 bytes")
+
+	importArchive := func(size int) int {
+		resp, err := http.Post(ts.URL+"/import", "application/x-ndjson", strings.NewReader(strings.Repeat(" ", size)))
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		return resp.StatusCode
+	}
+	assert.Equal(t, http.StatusBadRequest, importArchive(2048), "import has its own limit, the archive is empty")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, importArchive(5000))
+}
+
+func TestService_rateLimit(t *testing.T) {
+	proc := &ProcessorMock{
+		AccountsFunc: func(ctx context.Context) ([]model.Account, error) {
+			return []model.Account{}, nil
+		},
+		APIKeyByHashFunc: func(ctx context.Context, hash string) (model.APIKey, error) {
+			return model.APIKey{ID: "k-1", Name: "sync", Scopes: []string{model.ScopeReadReport}}, nil
+		},
+	}
+	ts := httptest.NewServer(Service{Processor: proc, Auth: true, RateLimit: 1, RateBurst: 2}.routes())
+	defer ts.Close()
+
+	get := func(key string) *http.Response {
+		req, err := http.NewRequest("GET", ts.URL+"/accounts", http.NoBody)
+		require.NoError(t, err)
+		req.Header.Set(APIKeyHeader, key)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close() //nolint
+		return resp
+	}
+	assert.Equal(t, http.StatusOK, get("sb_1").StatusCode)
+	assert.Equal(t, http.StatusOK, get("sb_1").StatusCode)
+	resp := get("sb_1")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get("Retry-After"))
+
+	proc.APIKeyByHashFunc = func(ctx context.Context, hash string) (model.APIKey, error) {
+		return model.APIKey{ID: "k-2", Name: "reports", Scopes: []string{model.ScopeReadReport}}, nil
+	}
+	assert.Equal(t, http.StatusOK, get("sb_2").StatusCode, "limited per client")
+}
+
+func TestService_addrRateLimit(t *testing.T) {
+	proc := &ProcessorMock{
+		APIKeyByHashFunc: func(ctx context.Context, hash string) (model.APIKey, error) {
+			return model.APIKey{}, model.ErrNotFound
+		},
+	}
+	ts := httptest.NewServer(Service{Processor: proc, Auth: true, AddrRateLimit: 1, AddrRateBurst: 2}.routes())
+	defer ts.Close()
+
+	get := func(key string) int {
+		req, err := http.NewRequest("GET", ts.URL+"/accounts", http.NoBody)
+		require.NoError(t, err)
+		req.Header.Set(APIKeyHeader, key)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close() //nolint
+		return resp.StatusCode
+	}
+	assert.Equal(t, http.StatusUnauthorized, get("sb_1"))
+	assert.Equal(t, http.StatusUnauthorized, get("sb_2"))
+	assert.Equal(t, http.StatusTooManyRequests, get("sb_3"), "limited before the key is checked")
+	assert.Len(t, proc.APIKeyByHashCalls(), 2)
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"github.com/mrnbort/summer_break/model"
 	"sync"
+	"time"
 )
 
 // Ensure, that ProcessorMock does implement Processor.
@@ -19,15 +20,138 @@ var _ Processor = &ProcessorMock{}
 //
 //		// make and configure a mocked Processor
 //		mockedProcessor := &ProcessorMock{
-//			GenerateReportFunc: func(ctx context.Context) (model.Report, error) {
+//			APIKeyByHashFunc: func(ctx context.Context, hash string) (model.APIKey, error) {
+//				panic("mock out the APIKeyByHash method")
+//			},
+//			APIKeysFunc: func(ctx context.Context) ([]model.APIKey, error) {
+//				panic("mock out the APIKeys method")
+//			},
+//			AccountsFunc: func(ctx context.Context) ([]model.Account, error) {
+//				panic("mock out the Accounts method")
+//			},
+//			AddAPIKeyFunc: func(ctx context.Context, key model.APIKey) (model.APIKey, error) {
+//				panic("mock out the AddAPIKey method")
+//			},
+//			AddBudgetFunc: func(ctx context.Context, b model.Budget) (model.Budget, error) {
+//				panic("mock out the AddBudget method")
+//			},
+//			AddScheduleFunc: func(ctx context.Context, sch model.Schedule) (model.Schedule, error) {
+//				panic("mock out the AddSchedule method")
+//			},
+//			AddUserFunc: func(ctx context.Context, u model.User) (model.User, error) {
+//				panic("mock out the AddUser method")
+//			},
+//			BalanceFunc: func(ctx context.Context, accounts []string, asOf time.Time) (model.Balance, error) {
+//				panic("mock out the Balance method")
+//			},
+//			BudgetFunc: func(ctx context.Context, id string) (model.Budget, error) {
+//				panic("mock out the Budget method")
+//			},
+//			BudgetReportFunc: func(ctx context.Context, filter model.Filter) (model.BudgetReport, error) {
+//				panic("mock out the BudgetReport method")
+//			},
+//			BudgetsFunc: func(ctx context.Context) ([]model.Budget, error) {
+//				panic("mock out the Budgets method")
+//			},
+//			ChartOfAccountsFunc: func(ctx context.Context) ([]model.ChartAccount, error) {
+//				panic("mock out the ChartOfAccounts method")
+//			},
+//			CommitBatchFunc: func(ctx context.Context, batchID string) ([]model.Transaction, error) {
+//				panic("mock out the CommitBatch method")
+//			},
+//			CompareFunc: func(ctx context.Context, filter model.Filter, previous model.Period, byCategory bool) (model.Comparison, error) {
+//				panic("mock out the Compare method")
+//			},
+//			DeleteAPIKeyFunc: func(ctx context.Context, id string) error {
+//				panic("mock out the DeleteAPIKey method")
+//			},
+//			DeleteBudgetFunc: func(ctx context.Context, id string) error {
+//				panic("mock out the DeleteBudget method")
+//			},
+//			DeleteScheduleFunc: func(ctx context.Context, id string) error {
+//				panic("mock out the DeleteSchedule method")
+//			},
+//			DeleteUserFunc: func(ctx context.Context, name string) error {
+//				panic("mock out the DeleteUser method")
+//			},
+//			DiscardBatchFunc: func(ctx context.Context, batchID string) error {
+//				panic("mock out the DiscardBatch method")
+//			},
+//			ForecastFunc: func(ctx context.Context, req model.ForecastRequest) (model.Forecast, error) {
+//				panic("mock out the Forecast method")
+//			},
+//			GenerateReportFunc: func(ctx context.Context, filter model.Filter) (model.Report, error) {
 //				panic("mock out the GenerateReport method")
 //			},
+//			ImportFunc: func(ctx context.Context, snap model.Snapshot, policy string) (model.ImportResult, error) {
+//				panic("mock out the Import method")
+//			},
+//			JournalEntriesFunc: func(ctx context.Context, filter model.Filter) ([]model.JournalEntry, error) {
+//				panic("mock out the JournalEntries method")
+//			},
 //			ParseTransactionFunc: func(rec []string) (model.Transaction, error) {
 //				panic("mock out the ParseTransaction method")
 //			},
-//			ProcessTransactionsFunc: func(ctx context.Context, transactions []model.Transaction) error {
+//			PostEntryFunc: func(ctx context.Context, entry model.JournalEntry) (model.JournalEntry, error) {
+//				panic("mock out the PostEntry method")
+//			},
+//			ProcessTransactionsFunc: func(ctx context.Context, transactions []model.Transaction) ([]model.Transaction, error) {
 //				panic("mock out the ProcessTransactions method")
 //			},
+//			ProfitAndLossFunc: func(ctx context.Context, filter model.Filter, compare string) (model.PnL, error) {
+//				panic("mock out the ProfitAndLoss method")
+//			},
+//			ReconcileFunc: func(ctx context.Context, account string, st model.Statement, tol model.Tolerance) (model.Reconciliation, error) {
+//				panic("mock out the Reconcile method")
+//			},
+//			SchedulesFunc: func(ctx context.Context) ([]model.Schedule, error) {
+//				panic("mock out the Schedules method")
+//			},
+//			SetChartAccountFunc: func(ctx context.Context, acc model.ChartAccount) (model.ChartAccount, error) {
+//				panic("mock out the SetChartAccount method")
+//			},
+//			SetOpeningBalanceFunc: func(ctx context.Context, account string, amount float64, date time.Time) (model.Account, error) {
+//				panic("mock out the SetOpeningBalance method")
+//			},
+//			SetTaxConfigFunc: func(ctx context.Context, cfg model.TaxConfig) error {
+//				panic("mock out the SetTaxConfig method")
+//			},
+//			SnapshotFunc: func(ctx context.Context) (model.Snapshot, error) {
+//				panic("mock out the Snapshot method")
+//			},
+//			StageTransactionsFunc: func(ctx context.Context, batchID string, transactions []model.Transaction) (string, error) {
+//				panic("mock out the StageTransactions method")
+//			},
+//			TaxConfigFunc: func(ctx context.Context) (model.TaxConfig, error) {
+//				panic("mock out the TaxConfig method")
+//			},
+//			TaxSummaryFunc: func(ctx context.Context, year int, filter model.Filter) (model.TaxSummary, error) {
+//				panic("mock out the TaxSummary method")
+//			},
+//			TransactionFunc: func(ctx context.Context, id string) (model.Transaction, error) {
+//				panic("mock out the Transaction method")
+//			},
+//			TransactionsFunc: func(ctx context.Context, filter model.Filter) ([]model.LedgerLine, error) {
+//				panic("mock out the Transactions method")
+//			},
+//			TrialBalanceFunc: func(ctx context.Context, asOf time.Time) ([]model.ChartBalance, error) {
+//				panic("mock out the TrialBalance method")
+//			},
+//			UpdateBudgetFunc: func(ctx context.Context, b model.Budget) (model.Budget, error) {
+//				panic("mock out the UpdateBudget method")
+//			},
+//			UpdateTransactionFunc: func(ctx context.Context, id string, upd model.TransactionUpdate) (model.Transaction, error) {
+//				panic("mock out the UpdateTransaction method")
+//			},
+//			UpdateUserFunc: func(ctx context.Context, u model.User) (model.User, error) {
+//				panic("mock out the UpdateUser method")
+//			},
+//			UserFunc: func(ctx context.Context, name string) (model.User, error) {
+//				panic("mock out the User method")
+//			},
+//			UsersFunc: func(ctx context.Context) ([]model.User, error) {
+//				panic("mock out the Users method")
+//			},
 //		}
 //
 //		// use mockedProcessor in code that requires Processor
@@ -35,27 +159,313 @@ var _ Processor = &ProcessorMock{}
 //
 //	}
 type ProcessorMock struct {
+	// APIKeyByHashFunc mocks the APIKeyByHash method.
+	APIKeyByHashFunc func(ctx context.Context, hash string) (model.APIKey, error)
+
+	// APIKeysFunc mocks the APIKeys method.
+	APIKeysFunc func(ctx context.Context) ([]model.APIKey, error)
+
+	// AccountsFunc mocks the Accounts method.
+	AccountsFunc func(ctx context.Context) ([]model.Account, error)
+
+	// AddAPIKeyFunc mocks the AddAPIKey method.
+	AddAPIKeyFunc func(ctx context.Context, key model.APIKey) (model.APIKey, error)
+
+	// AddBudgetFunc mocks the AddBudget method.
+	AddBudgetFunc func(ctx context.Context, b model.Budget) (model.Budget, error)
+
+	// AddScheduleFunc mocks the AddSchedule method.
+	AddScheduleFunc func(ctx context.Context, sch model.Schedule) (model.Schedule, error)
+
+	// AddUserFunc mocks the AddUser method.
+	AddUserFunc func(ctx context.Context, u model.User) (model.User, error)
+
+	// BalanceFunc mocks the Balance method.
+	BalanceFunc func(ctx context.Context, accounts []string, asOf time.Time) (model.Balance, error)
+
+	// BudgetFunc mocks the Budget method.
+	BudgetFunc func(ctx context.Context, id string) (model.Budget, error)
+
+	// BudgetReportFunc mocks the BudgetReport method.
+	BudgetReportFunc func(ctx context.Context, filter model.Filter) (model.BudgetReport, error)
+
+	// BudgetsFunc mocks the Budgets method.
+	BudgetsFunc func(ctx context.Context) ([]model.Budget, error)
+
+	// ChartOfAccountsFunc mocks the ChartOfAccounts method.
+	ChartOfAccountsFunc func(ctx context.Context) ([]model.ChartAccount, error)
+
+	// CommitBatchFunc mocks the CommitBatch method.
+	CommitBatchFunc func(ctx context.Context, batchID string) ([]model.Transaction, error)
+
+	// CompareFunc mocks the Compare method.
+	CompareFunc func(ctx context.Context, filter model.Filter, previous model.Period, byCategory bool) (model.Comparison, error)
+
+	// DeleteAPIKeyFunc mocks the DeleteAPIKey method.
+	DeleteAPIKeyFunc func(ctx context.Context, id string) error
+
+	// DeleteBudgetFunc mocks the DeleteBudget method.
+	DeleteBudgetFunc func(ctx context.Context, id string) error
+
+	// DeleteScheduleFunc mocks the DeleteSchedule method.
+	DeleteScheduleFunc func(ctx context.Context, id string) error
+
+	// DeleteUserFunc mocks the DeleteUser method.
+	DeleteUserFunc func(ctx context.Context, name string) error
+
+	// DiscardBatchFunc mocks the DiscardBatch method.
+	DiscardBatchFunc func(ctx context.Context, batchID string) error
+
+	// ForecastFunc mocks the Forecast method.
+	ForecastFunc func(ctx context.Context, req model.ForecastRequest) (model.Forecast, error)
+
 	// GenerateReportFunc mocks the GenerateReport method.
-	GenerateReportFunc func(ctx context.Context) (model.Report, error)
+	GenerateReportFunc func(ctx context.Context, filter model.Filter) (model.Report, error)
+
+	// ImportFunc mocks the Import method.
+	ImportFunc func(ctx context.Context, snap model.Snapshot, policy string) (model.ImportResult, error)
+
+	// JournalEntriesFunc mocks the JournalEntries method.
+	JournalEntriesFunc func(ctx context.Context, filter model.Filter) ([]model.JournalEntry, error)
 
 	// ParseTransactionFunc mocks the ParseTransaction method.
 	ParseTransactionFunc func(rec []string) (model.Transaction, error)
 
+	// PostEntryFunc mocks the PostEntry method.
+	PostEntryFunc func(ctx context.Context, entry model.JournalEntry) (model.JournalEntry, error)
+
 	// ProcessTransactionsFunc mocks the ProcessTransactions method.
-	ProcessTransactionsFunc func(ctx context.Context, transactions []model.Transaction) error
+	ProcessTransactionsFunc func(ctx context.Context, transactions []model.Transaction) ([]model.Transaction, error)
+
+	// ProfitAndLossFunc mocks the ProfitAndLoss method.
+	ProfitAndLossFunc func(ctx context.Context, filter model.Filter, compare string) (model.PnL, error)
+
+	// ReconcileFunc mocks the Reconcile method.
+	ReconcileFunc func(ctx context.Context, account string, st model.Statement, tol model.Tolerance) (model.Reconciliation, error)
+
+	// SchedulesFunc mocks the Schedules method.
+	SchedulesFunc func(ctx context.Context) ([]model.Schedule, error)
+
+	// SetChartAccountFunc mocks the SetChartAccount method.
+	SetChartAccountFunc func(ctx context.Context, acc model.ChartAccount) (model.ChartAccount, error)
+
+	// SetOpeningBalanceFunc mocks the SetOpeningBalance method.
+	SetOpeningBalanceFunc func(ctx context.Context, account string, amount float64, date time.Time) (model.Account, error)
+
+	// SetTaxConfigFunc mocks the SetTaxConfig method.
+	SetTaxConfigFunc func(ctx context.Context, cfg model.TaxConfig) error
+
+	// SnapshotFunc mocks the Snapshot method.
+	SnapshotFunc func(ctx context.Context) (model.Snapshot, error)
+
+	// StageTransactionsFunc mocks the StageTransactions method.
+	StageTransactionsFunc func(ctx context.Context, batchID string, transactions []model.Transaction) (string, error)
+
+	// TaxConfigFunc mocks the TaxConfig method.
+	TaxConfigFunc func(ctx context.Context) (model.TaxConfig, error)
+
+	// TaxSummaryFunc mocks the TaxSummary method.
+	TaxSummaryFunc func(ctx context.Context, year int, filter model.Filter) (model.TaxSummary, error)
+
+	// TransactionFunc mocks the Transaction method.
+	TransactionFunc func(ctx context.Context, id string) (model.Transaction, error)
+
+	// TransactionsFunc mocks the Transactions method.
+	TransactionsFunc func(ctx context.Context, filter model.Filter) ([]model.LedgerLine, error)
+
+	// TrialBalanceFunc mocks the TrialBalance method.
+	TrialBalanceFunc func(ctx context.Context, asOf time.Time) ([]model.ChartBalance, error)
+
+	// UpdateBudgetFunc mocks the UpdateBudget method.
+	UpdateBudgetFunc func(ctx context.Context, b model.Budget) (model.Budget, error)
+
+	// UpdateTransactionFunc mocks the UpdateTransaction method.
+	UpdateTransactionFunc func(ctx context.Context, id string, upd model.TransactionUpdate) (model.Transaction, error)
+
+	// UpdateUserFunc mocks the UpdateUser method.
+	UpdateUserFunc func(ctx context.Context, u model.User) (model.User, error)
+
+	// UserFunc mocks the User method.
+	UserFunc func(ctx context.Context, name string) (model.User, error)
+
+	// UsersFunc mocks the Users method.
+	UsersFunc func(ctx context.Context) ([]model.User, error)
 
 	// calls tracks calls to the methods.
 	calls struct {
+		// APIKeyByHash holds details about calls to the APIKeyByHash method.
+		APIKeyByHash []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Hash is the hash argument value.
+			Hash string
+		}
+		// APIKeys holds details about calls to the APIKeys method.
+		APIKeys []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// Accounts holds details about calls to the Accounts method.
+		Accounts []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// AddAPIKey holds details about calls to the AddAPIKey method.
+		AddAPIKey []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Key is the key argument value.
+			Key model.APIKey
+		}
+		// AddBudget holds details about calls to the AddBudget method.
+		AddBudget []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// B is the b argument value.
+			B model.Budget
+		}
+		// AddSchedule holds details about calls to the AddSchedule method.
+		AddSchedule []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Sch is the sch argument value.
+			Sch model.Schedule
+		}
+		// AddUser holds details about calls to the AddUser method.
+		AddUser []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// U is the u argument value.
+			U model.User
+		}
+		// Balance holds details about calls to the Balance method.
+		Balance []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Accounts is the accounts argument value.
+			Accounts []string
+			// AsOf is the asOf argument value.
+			AsOf time.Time
+		}
+		// Budget holds details about calls to the Budget method.
+		Budget []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Id is the id argument value.
+			Id string
+		}
+		// BudgetReport holds details about calls to the BudgetReport method.
+		BudgetReport []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Filter is the filter argument value.
+			Filter model.Filter
+		}
+		// Budgets holds details about calls to the Budgets method.
+		Budgets []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// ChartOfAccounts holds details about calls to the ChartOfAccounts method.
+		ChartOfAccounts []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// CommitBatch holds details about calls to the CommitBatch method.
+		CommitBatch []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// BatchID is the batchID argument value.
+			BatchID string
+		}
+		// Compare holds details about calls to the Compare method.
+		Compare []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Filter is the filter argument value.
+			Filter model.Filter
+			// Previous is the previous argument value.
+			Previous model.Period
+			// ByCategory is the byCategory argument value.
+			ByCategory bool
+		}
+		// DeleteAPIKey holds details about calls to the DeleteAPIKey method.
+		DeleteAPIKey []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Id is the id argument value.
+			Id string
+		}
+		// DeleteBudget holds details about calls to the DeleteBudget method.
+		DeleteBudget []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Id is the id argument value.
+			Id string
+		}
+		// DeleteSchedule holds details about calls to the DeleteSchedule method.
+		DeleteSchedule []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Id is the id argument value.
+			Id string
+		}
+		// DeleteUser holds details about calls to the DeleteUser method.
+		DeleteUser []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Name is the name argument value.
+			Name string
+		}
+		// DiscardBatch holds details about calls to the DiscardBatch method.
+		DiscardBatch []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// BatchID is the batchID argument value.
+			BatchID string
+		}
+		// Forecast holds details about calls to the Forecast method.
+		Forecast []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Req is the req argument value.
+			Req model.ForecastRequest
+		}
 		// GenerateReport holds details about calls to the GenerateReport method.
 		GenerateReport []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
+			// Filter is the filter argument value.
+			Filter model.Filter
+		}
+		// Import holds details about calls to the Import method.
+		Import []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Snap is the snap argument value.
+			Snap model.Snapshot
+			// Policy is the policy argument value.
+			Policy string
+		}
+		// JournalEntries holds details about calls to the JournalEntries method.
+		JournalEntries []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Filter is the filter argument value.
+			Filter model.Filter
 		}
 		// ParseTransaction holds details about calls to the ParseTransaction method.
 		ParseTransaction []struct {
 			// Rec is the rec argument value.
 			Rec []string
 		}
+		// PostEntry holds details about calls to the PostEntry method.
+		PostEntry []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Entry is the entry argument value.
+			Entry model.JournalEntry
+		}
 		// ProcessTransactions holds details about calls to the ProcessTransactions method.
 		ProcessTransactions []struct {
 			// Ctx is the ctx argument value.
@@ -63,108 +473,1779 @@ type ProcessorMock struct {
 			// Transactions is the transactions argument value.
 			Transactions []model.Transaction
 		}
+		// ProfitAndLoss holds details about calls to the ProfitAndLoss method.
+		ProfitAndLoss []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Filter is the filter argument value.
+			Filter model.Filter
+			// Compare is the compare argument value.
+			Compare string
+		}
+		// Reconcile holds details about calls to the Reconcile method.
+		Reconcile []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Account is the account argument value.
+			Account string
+			// St is the st argument value.
+			St model.Statement
+			// Tol is the tol argument value.
+			Tol model.Tolerance
+		}
+		// Schedules holds details about calls to the Schedules method.
+		Schedules []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// SetChartAccount holds details about calls to the SetChartAccount method.
+		SetChartAccount []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Acc is the acc argument value.
+			Acc model.ChartAccount
+		}
+		// SetOpeningBalance holds details about calls to the SetOpeningBalance method.
+		SetOpeningBalance []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Account is the account argument value.
+			Account string
+			// Amount is the amount argument value.
+			Amount float64
+			// Date is the date argument value.
+			Date time.Time
+		}
+		// SetTaxConfig holds details about calls to the SetTaxConfig method.
+		SetTaxConfig []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Cfg is the cfg argument value.
+			Cfg model.TaxConfig
+		}
+		// Snapshot holds details about calls to the Snapshot method.
+		Snapshot []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// StageTransactions holds details about calls to the StageTransactions method.
+		StageTransactions []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// BatchID is the batchID argument value.
+			BatchID string
+			// Transactions is the transactions argument value.
+			Transactions []model.Transaction
+		}
+		// TaxConfig holds details about calls to the TaxConfig method.
+		TaxConfig []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// TaxSummary holds details about calls to the TaxSummary method.
+		TaxSummary []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Year is the year argument value.
+			Year int
+			// Filter is the filter argument value.
+			Filter model.Filter
+		}
+		// Transaction holds details about calls to the Transaction method.
+		Transaction []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Id is the id argument value.
+			Id string
+		}
+		// Transactions holds details about calls to the Transactions method.
+		Transactions []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Filter is the filter argument value.
+			Filter model.Filter
+		}
+		// TrialBalance holds details about calls to the TrialBalance method.
+		TrialBalance []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// AsOf is the asOf argument value.
+			AsOf time.Time
+		}
+		// UpdateBudget holds details about calls to the UpdateBudget method.
+		UpdateBudget []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// B is the b argument value.
+			B model.Budget
+		}
+		// UpdateTransaction holds details about calls to the UpdateTransaction method.
+		UpdateTransaction []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Id is the id argument value.
+			Id string
+			// Upd is the upd argument value.
+			Upd model.TransactionUpdate
+		}
+		// UpdateUser holds details about calls to the UpdateUser method.
+		UpdateUser []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// U is the u argument value.
+			U model.User
+		}
+		// User holds details about calls to the User method.
+		User []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Name is the name argument value.
+			Name string
+		}
+		// Users holds details about calls to the Users method.
+		Users []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
 	}
+	lockAPIKeyByHash        sync.RWMutex
+	lockAPIKeys             sync.RWMutex
+	lockAccounts            sync.RWMutex
+	lockAddAPIKey           sync.RWMutex
+	lockAddBudget           sync.RWMutex
+	lockAddSchedule         sync.RWMutex
+	lockAddUser             sync.RWMutex
+	lockBalance             sync.RWMutex
+	lockBudget              sync.RWMutex
+	lockBudgetReport        sync.RWMutex
+	lockBudgets             sync.RWMutex
+	lockChartOfAccounts     sync.RWMutex
+	lockCommitBatch         sync.RWMutex
+	lockCompare             sync.RWMutex
+	lockDeleteAPIKey        sync.RWMutex
+	lockDeleteBudget        sync.RWMutex
+	lockDeleteSchedule      sync.RWMutex
+	lockDeleteUser          sync.RWMutex
+	lockDiscardBatch        sync.RWMutex
+	lockForecast            sync.RWMutex
 	lockGenerateReport      sync.RWMutex
+	lockImport              sync.RWMutex
+	lockJournalEntries      sync.RWMutex
 	lockParseTransaction    sync.RWMutex
+	lockPostEntry           sync.RWMutex
 	lockProcessTransactions sync.RWMutex
+	lockProfitAndLoss       sync.RWMutex
+	lockReconcile           sync.RWMutex
+	lockSchedules           sync.RWMutex
+	lockSetChartAccount     sync.RWMutex
+	lockSetOpeningBalance   sync.RWMutex
+	lockSetTaxConfig        sync.RWMutex
+	lockSnapshot            sync.RWMutex
+	lockStageTransactions   sync.RWMutex
+	lockTaxConfig           sync.RWMutex
+	lockTaxSummary          sync.RWMutex
+	lockTransaction         sync.RWMutex
+	lockTransactions        sync.RWMutex
+	lockTrialBalance        sync.RWMutex
+	lockUpdateBudget        sync.RWMutex
+	lockUpdateTransaction   sync.RWMutex
+	lockUpdateUser          sync.RWMutex
+	lockUser                sync.RWMutex
+	lockUsers               sync.RWMutex
 }
 
-// GenerateReport calls GenerateReportFunc.
-func (mock *ProcessorMock) GenerateReport(ctx context.Context) (model.Report, error) {
-	if mock.GenerateReportFunc == nil {
-		panic("ProcessorMock.GenerateReportFunc: method is nil but Processor.GenerateReport was just called")
+// APIKeyByHash calls APIKeyByHashFunc.
+func (mock *ProcessorMock) APIKeyByHash(ctx context.Context, hash string) (model.APIKey, error) {
+	if mock.APIKeyByHashFunc == nil {
+		panic("ProcessorMock.APIKeyByHashFunc: method is nil but Processor.APIKeyByHash was just called")
 	}
 	callInfo := struct {
-		Ctx context.Context
+		Ctx  context.Context
+		Hash string
 	}{
-		Ctx: ctx,
+		Ctx:  ctx,
+		Hash: hash,
 	}
-	mock.lockGenerateReport.Lock()
-	mock.calls.GenerateReport = append(mock.calls.GenerateReport, callInfo)
-	mock.lockGenerateReport.Unlock()
-	return mock.GenerateReportFunc(ctx)
+	mock.lockAPIKeyByHash.Lock()
+	mock.calls.APIKeyByHash = append(mock.calls.APIKeyByHash, callInfo)
+	mock.lockAPIKeyByHash.Unlock()
+	return mock.APIKeyByHashFunc(ctx, hash)
 }
 
-// GenerateReportCalls gets all the calls that were made to GenerateReport.
+// APIKeyByHashCalls gets all the calls that were made to APIKeyByHash.
 // Check the length with:
 //
-//	len(mockedProcessor.GenerateReportCalls())
-func (mock *ProcessorMock) GenerateReportCalls() []struct {
-	Ctx context.Context
+//	len(mockedProcessor.APIKeyByHashCalls())
+func (mock *ProcessorMock) APIKeyByHashCalls() []struct {
+	Ctx  context.Context
+	Hash string
 } {
 	var calls []struct {
-		Ctx context.Context
+		Ctx  context.Context
+		Hash string
 	}
-	mock.lockGenerateReport.RLock()
-	calls = mock.calls.GenerateReport
-	mock.lockGenerateReport.RUnlock()
+	mock.lockAPIKeyByHash.RLock()
+	calls = mock.calls.APIKeyByHash
+	mock.lockAPIKeyByHash.RUnlock()
 	return calls
 }
 
-// ParseTransaction calls ParseTransactionFunc.
-func (mock *ProcessorMock) ParseTransaction(rec []string) (model.Transaction, error) {
-	if mock.ParseTransactionFunc == nil {
-		panic("ProcessorMock.ParseTransactionFunc: method is nil but Processor.ParseTransaction was just called")
+// APIKeys calls APIKeysFunc.
+func (mock *ProcessorMock) APIKeys(ctx context.Context) ([]model.APIKey, error) {
+	if mock.APIKeysFunc == nil {
+		panic("ProcessorMock.APIKeysFunc: method is nil but Processor.APIKeys was just called")
 	}
 	callInfo := struct {
-		Rec []string
+		Ctx context.Context
 	}{
-		Rec: rec,
+		Ctx: ctx,
 	}
-	mock.lockParseTransaction.Lock()
-	mock.calls.ParseTransaction = append(mock.calls.ParseTransaction, callInfo)
-	mock.lockParseTransaction.Unlock()
-	return mock.ParseTransactionFunc(rec)
+	mock.lockAPIKeys.Lock()
+	mock.calls.APIKeys = append(mock.calls.APIKeys, callInfo)
+	mock.lockAPIKeys.Unlock()
+	return mock.APIKeysFunc(ctx)
 }
 
-// ParseTransactionCalls gets all the calls that were made to ParseTransaction.
+// APIKeysCalls gets all the calls that were made to APIKeys.
 // Check the length with:
 //
-//	len(mockedProcessor.ParseTransactionCalls())
-func (mock *ProcessorMock) ParseTransactionCalls() []struct {
-	Rec []string
+//	len(mockedProcessor.APIKeysCalls())
+func (mock *ProcessorMock) APIKeysCalls() []struct {
+	Ctx context.Context
 } {
 	var calls []struct {
-		Rec []string
+		Ctx context.Context
 	}
-	mock.lockParseTransaction.RLock()
-	calls = mock.calls.ParseTransaction
-	mock.lockParseTransaction.RUnlock()
+	mock.lockAPIKeys.RLock()
+	calls = mock.calls.APIKeys
+	mock.lockAPIKeys.RUnlock()
 	return calls
 }
 
-// ProcessTransactions calls ProcessTransactionsFunc.
-func (mock *ProcessorMock) ProcessTransactions(ctx context.Context, transactions []model.Transaction) error {
-	if mock.ProcessTransactionsFunc == nil {
-		panic("ProcessorMock.ProcessTransactionsFunc: method is nil but Processor.ProcessTransactions was just called")
+// Accounts calls AccountsFunc.
+func (mock *ProcessorMock) Accounts(ctx context.Context) ([]model.Account, error) {
+	if mock.AccountsFunc == nil {
+		panic("ProcessorMock.AccountsFunc: method is nil but Processor.Accounts was just called")
 	}
 	callInfo := struct {
-		Ctx          context.Context
-		Transactions []model.Transaction
+		Ctx context.Context
 	}{
-		Ctx:          ctx,
-		Transactions: transactions,
+		Ctx: ctx,
 	}
-	mock.lockProcessTransactions.Lock()
-	mock.calls.ProcessTransactions = append(mock.calls.ProcessTransactions, callInfo)
+	mock.lockAccounts.Lock()
+	mock.calls.Accounts = append(mock.calls.Accounts, callInfo)
+	mock.lockAccounts.Unlock()
+	return mock.AccountsFunc(ctx)
+}
+
+// AccountsCalls gets all the calls that were made to Accounts.
+// Check the length with:
+//
+//	len(mockedProcessor.AccountsCalls())
+func (mock *ProcessorMock) AccountsCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockAccounts.RLock()
+	calls = mock.calls.Accounts
+	mock.lockAccounts.RUnlock()
+	return calls
+}
+
+// AddAPIKey calls AddAPIKeyFunc.
+func (mock *ProcessorMock) AddAPIKey(ctx context.Context, key model.APIKey) (model.APIKey, error) {
+	if mock.AddAPIKeyFunc == nil {
+		panic("ProcessorMock.AddAPIKeyFunc: method is nil but Processor.AddAPIKey was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Key model.APIKey
+	}{
+		Ctx: ctx,
+		Key: key,
+	}
+	mock.lockAddAPIKey.Lock()
+	mock.calls.AddAPIKey = append(mock.calls.AddAPIKey, callInfo)
+	mock.lockAddAPIKey.Unlock()
+	return mock.AddAPIKeyFunc(ctx, key)
+}
+
+// AddAPIKeyCalls gets all the calls that were made to AddAPIKey.
+// Check the length with:
+//
+//	len(mockedProcessor.AddAPIKeyCalls())
+func (mock *ProcessorMock) AddAPIKeyCalls() []struct {
+	Ctx context.Context
+	Key model.APIKey
+} {
+	var calls []struct {
+		Ctx context.Context
+		Key model.APIKey
+	}
+	mock.lockAddAPIKey.RLock()
+	calls = mock.calls.AddAPIKey
+	mock.lockAddAPIKey.RUnlock()
+	return calls
+}
+
+// AddBudget calls AddBudgetFunc.
+func (mock *ProcessorMock) AddBudget(ctx context.Context, b model.Budget) (model.Budget, error) {
+	if mock.AddBudgetFunc == nil {
+		panic("ProcessorMock.AddBudgetFunc: method is nil but Processor.AddBudget was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		B   model.Budget
+	}{
+		Ctx: ctx,
+		B:   b,
+	}
+	mock.lockAddBudget.Lock()
+	mock.calls.AddBudget = append(mock.calls.AddBudget, callInfo)
+	mock.lockAddBudget.Unlock()
+	return mock.AddBudgetFunc(ctx, b)
+}
+
+// AddBudgetCalls gets all the calls that were made to AddBudget.
+// Check the length with:
+//
+//	len(mockedProcessor.AddBudgetCalls())
+func (mock *ProcessorMock) AddBudgetCalls() []struct {
+	Ctx context.Context
+	B   model.Budget
+} {
+	var calls []struct {
+		Ctx context.Context
+		B   model.Budget
+	}
+	mock.lockAddBudget.RLock()
+	calls = mock.calls.AddBudget
+	mock.lockAddBudget.RUnlock()
+	return calls
+}
+
+// AddSchedule calls AddScheduleFunc.
+func (mock *ProcessorMock) AddSchedule(ctx context.Context, sch model.Schedule) (model.Schedule, error) {
+	if mock.AddScheduleFunc == nil {
+		panic("ProcessorMock.AddScheduleFunc: method is nil but Processor.AddSchedule was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Sch model.Schedule
+	}{
+		Ctx: ctx,
+		Sch: sch,
+	}
+	mock.lockAddSchedule.Lock()
+	mock.calls.AddSchedule = append(mock.calls.AddSchedule, callInfo)
+	mock.lockAddSchedule.Unlock()
+	return mock.AddScheduleFunc(ctx, sch)
+}
+
+// AddScheduleCalls gets all the calls that were made to AddSchedule.
+// Check the length with:
+//
+//	len(mockedProcessor.AddScheduleCalls())
+func (mock *ProcessorMock) AddScheduleCalls() []struct {
+	Ctx context.Context
+	Sch model.Schedule
+} {
+	var calls []struct {
+		Ctx context.Context
+		Sch model.Schedule
+	}
+	mock.lockAddSchedule.RLock()
+	calls = mock.calls.AddSchedule
+	mock.lockAddSchedule.RUnlock()
+	return calls
+}
+
+// AddUser calls AddUserFunc.
+func (mock *ProcessorMock) AddUser(ctx context.Context, u model.User) (model.User, error) {
+	if mock.AddUserFunc == nil {
+		panic("ProcessorMock.AddUserFunc: method is nil but Processor.AddUser was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		U   model.User
+	}{
+		Ctx: ctx,
+		U:   u,
+	}
+	mock.lockAddUser.Lock()
+	mock.calls.AddUser = append(mock.calls.AddUser, callInfo)
+	mock.lockAddUser.Unlock()
+	return mock.AddUserFunc(ctx, u)
+}
+
+// AddUserCalls gets all the calls that were made to AddUser.
+// Check the length with:
+//
+//	len(mockedProcessor.AddUserCalls())
+func (mock *ProcessorMock) AddUserCalls() []struct {
+	Ctx context.Context
+	U   model.User
+} {
+	var calls []struct {
+		Ctx context.Context
+		U   model.User
+	}
+	mock.lockAddUser.RLock()
+	calls = mock.calls.AddUser
+	mock.lockAddUser.RUnlock()
+	return calls
+}
+
+// Balance calls BalanceFunc.
+func (mock *ProcessorMock) Balance(ctx context.Context, accounts []string, asOf time.Time) (model.Balance, error) {
+	if mock.BalanceFunc == nil {
+		panic("ProcessorMock.BalanceFunc: method is nil but Processor.Balance was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		Accounts []string
+		AsOf     time.Time
+	}{
+		Ctx:      ctx,
+		Accounts: accounts,
+		AsOf:     asOf,
+	}
+	mock.lockBalance.Lock()
+	mock.calls.Balance = append(mock.calls.Balance, callInfo)
+	mock.lockBalance.Unlock()
+	return mock.BalanceFunc(ctx, accounts, asOf)
+}
+
+// BalanceCalls gets all the calls that were made to Balance.
+// Check the length with:
+//
+//	len(mockedProcessor.BalanceCalls())
+func (mock *ProcessorMock) BalanceCalls() []struct {
+	Ctx      context.Context
+	Accounts []string
+	AsOf     time.Time
+} {
+	var calls []struct {
+		Ctx      context.Context
+		Accounts []string
+		AsOf     time.Time
+	}
+	mock.lockBalance.RLock()
+	calls = mock.calls.Balance
+	mock.lockBalance.RUnlock()
+	return calls
+}
+
+// Budget calls BudgetFunc.
+func (mock *ProcessorMock) Budget(ctx context.Context, id string) (model.Budget, error) {
+	if mock.BudgetFunc == nil {
+		panic("ProcessorMock.BudgetFunc: method is nil but Processor.Budget was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Id  string
+	}{
+		Ctx: ctx,
+		Id:  id,
+	}
+	mock.lockBudget.Lock()
+	mock.calls.Budget = append(mock.calls.Budget, callInfo)
+	mock.lockBudget.Unlock()
+	return mock.BudgetFunc(ctx, id)
+}
+
+// BudgetCalls gets all the calls that were made to Budget.
+// Check the length with:
+//
+//	len(mockedProcessor.BudgetCalls())
+func (mock *ProcessorMock) BudgetCalls() []struct {
+	Ctx context.Context
+	Id  string
+} {
+	var calls []struct {
+		Ctx context.Context
+		Id  string
+	}
+	mock.lockBudget.RLock()
+	calls = mock.calls.Budget
+	mock.lockBudget.RUnlock()
+	return calls
+}
+
+// BudgetReport calls BudgetReportFunc.
+func (mock *ProcessorMock) BudgetReport(ctx context.Context, filter model.Filter) (model.BudgetReport, error) {
+	if mock.BudgetReportFunc == nil {
+		panic("ProcessorMock.BudgetReportFunc: method is nil but Processor.BudgetReport was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Filter model.Filter
+	}{
+		Ctx:    ctx,
+		Filter: filter,
+	}
+	mock.lockBudgetReport.Lock()
+	mock.calls.BudgetReport = append(mock.calls.BudgetReport, callInfo)
+	mock.lockBudgetReport.Unlock()
+	return mock.BudgetReportFunc(ctx, filter)
+}
+
+// BudgetReportCalls gets all the calls that were made to BudgetReport.
+// Check the length with:
+//
+//	len(mockedProcessor.BudgetReportCalls())
+func (mock *ProcessorMock) BudgetReportCalls() []struct {
+	Ctx    context.Context
+	Filter model.Filter
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Filter model.Filter
+	}
+	mock.lockBudgetReport.RLock()
+	calls = mock.calls.BudgetReport
+	mock.lockBudgetReport.RUnlock()
+	return calls
+}
+
+// Budgets calls BudgetsFunc.
+func (mock *ProcessorMock) Budgets(ctx context.Context) ([]model.Budget, error) {
+	if mock.BudgetsFunc == nil {
+		panic("ProcessorMock.BudgetsFunc: method is nil but Processor.Budgets was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockBudgets.Lock()
+	mock.calls.Budgets = append(mock.calls.Budgets, callInfo)
+	mock.lockBudgets.Unlock()
+	return mock.BudgetsFunc(ctx)
+}
+
+// BudgetsCalls gets all the calls that were made to Budgets.
+// Check the length with:
+//
+//	len(mockedProcessor.BudgetsCalls())
+func (mock *ProcessorMock) BudgetsCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockBudgets.RLock()
+	calls = mock.calls.Budgets
+	mock.lockBudgets.RUnlock()
+	return calls
+}
+
+// ChartOfAccounts calls ChartOfAccountsFunc.
+func (mock *ProcessorMock) ChartOfAccounts(ctx context.Context) ([]model.ChartAccount, error) {
+	if mock.ChartOfAccountsFunc == nil {
+		panic("ProcessorMock.ChartOfAccountsFunc: method is nil but Processor.ChartOfAccounts was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockChartOfAccounts.Lock()
+	mock.calls.ChartOfAccounts = append(mock.calls.ChartOfAccounts, callInfo)
+	mock.lockChartOfAccounts.Unlock()
+	return mock.ChartOfAccountsFunc(ctx)
+}
+
+// ChartOfAccountsCalls gets all the calls that were made to ChartOfAccounts.
+// Check the length with:
+//
+//	len(mockedProcessor.ChartOfAccountsCalls())
+func (mock *ProcessorMock) ChartOfAccountsCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockChartOfAccounts.RLock()
+	calls = mock.calls.ChartOfAccounts
+	mock.lockChartOfAccounts.RUnlock()
+	return calls
+}
+
+// CommitBatch calls CommitBatchFunc.
+func (mock *ProcessorMock) CommitBatch(ctx context.Context, batchID string) ([]model.Transaction, error) {
+	if mock.CommitBatchFunc == nil {
+		panic("ProcessorMock.CommitBatchFunc: method is nil but Processor.CommitBatch was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		BatchID string
+	}{
+		Ctx:     ctx,
+		BatchID: batchID,
+	}
+	mock.lockCommitBatch.Lock()
+	mock.calls.CommitBatch = append(mock.calls.CommitBatch, callInfo)
+	mock.lockCommitBatch.Unlock()
+	return mock.CommitBatchFunc(ctx, batchID)
+}
+
+// CommitBatchCalls gets all the calls that were made to CommitBatch.
+// Check the length with:
+//
+//	len(mockedProcessor.CommitBatchCalls())
+func (mock *ProcessorMock) CommitBatchCalls() []struct {
+	Ctx     context.Context
+	BatchID string
+} {
+	var calls []struct {
+		Ctx     context.Context
+		BatchID string
+	}
+	mock.lockCommitBatch.RLock()
+	calls = mock.calls.CommitBatch
+	mock.lockCommitBatch.RUnlock()
+	return calls
+}
+
+// Compare calls CompareFunc.
+func (mock *ProcessorMock) Compare(ctx context.Context, filter model.Filter, previous model.Period, byCategory bool) (model.Comparison, error) {
+	if mock.CompareFunc == nil {
+		panic("ProcessorMock.CompareFunc: method is nil but Processor.Compare was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		Filter     model.Filter
+		Previous   model.Period
+		ByCategory bool
+	}{
+		Ctx:        ctx,
+		Filter:     filter,
+		Previous:   previous,
+		ByCategory: byCategory,
+	}
+	mock.lockCompare.Lock()
+	mock.calls.Compare = append(mock.calls.Compare, callInfo)
+	mock.lockCompare.Unlock()
+	return mock.CompareFunc(ctx, filter, previous, byCategory)
+}
+
+// CompareCalls gets all the calls that were made to Compare.
+// Check the length with:
+//
+//	len(mockedProcessor.CompareCalls())
+func (mock *ProcessorMock) CompareCalls() []struct {
+	Ctx        context.Context
+	Filter     model.Filter
+	Previous   model.Period
+	ByCategory bool
+} {
+	var calls []struct {
+		Ctx        context.Context
+		Filter     model.Filter
+		Previous   model.Period
+		ByCategory bool
+	}
+	mock.lockCompare.RLock()
+	calls = mock.calls.Compare
+	mock.lockCompare.RUnlock()
+	return calls
+}
+
+// DeleteAPIKey calls DeleteAPIKeyFunc.
+func (mock *ProcessorMock) DeleteAPIKey(ctx context.Context, id string) error {
+	if mock.DeleteAPIKeyFunc == nil {
+		panic("ProcessorMock.DeleteAPIKeyFunc: method is nil but Processor.DeleteAPIKey was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Id  string
+	}{
+		Ctx: ctx,
+		Id:  id,
+	}
+	mock.lockDeleteAPIKey.Lock()
+	mock.calls.DeleteAPIKey = append(mock.calls.DeleteAPIKey, callInfo)
+	mock.lockDeleteAPIKey.Unlock()
+	return mock.DeleteAPIKeyFunc(ctx, id)
+}
+
+// DeleteAPIKeyCalls gets all the calls that were made to DeleteAPIKey.
+// Check the length with:
+//
+//	len(mockedProcessor.DeleteAPIKeyCalls())
+func (mock *ProcessorMock) DeleteAPIKeyCalls() []struct {
+	Ctx context.Context
+	Id  string
+} {
+	var calls []struct {
+		Ctx context.Context
+		Id  string
+	}
+	mock.lockDeleteAPIKey.RLock()
+	calls = mock.calls.DeleteAPIKey
+	mock.lockDeleteAPIKey.RUnlock()
+	return calls
+}
+
+// DeleteBudget calls DeleteBudgetFunc.
+func (mock *ProcessorMock) DeleteBudget(ctx context.Context, id string) error {
+	if mock.DeleteBudgetFunc == nil {
+		panic("ProcessorMock.DeleteBudgetFunc: method is nil but Processor.DeleteBudget was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Id  string
+	}{
+		Ctx: ctx,
+		Id:  id,
+	}
+	mock.lockDeleteBudget.Lock()
+	mock.calls.DeleteBudget = append(mock.calls.DeleteBudget, callInfo)
+	mock.lockDeleteBudget.Unlock()
+	return mock.DeleteBudgetFunc(ctx, id)
+}
+
+// DeleteBudgetCalls gets all the calls that were made to DeleteBudget.
+// Check the length with:
+//
+//	len(mockedProcessor.DeleteBudgetCalls())
+func (mock *ProcessorMock) DeleteBudgetCalls() []struct {
+	Ctx context.Context
+	Id  string
+} {
+	var calls []struct {
+		Ctx context.Context
+		Id  string
+	}
+	mock.lockDeleteBudget.RLock()
+	calls = mock.calls.DeleteBudget
+	mock.lockDeleteBudget.RUnlock()
+	return calls
+}
+
+// DeleteSchedule calls DeleteScheduleFunc.
+func (mock *ProcessorMock) DeleteSchedule(ctx context.Context, id string) error {
+	if mock.DeleteScheduleFunc == nil {
+		panic("ProcessorMock.DeleteScheduleFunc: method is nil but Processor.DeleteSchedule was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Id  string
+	}{
+		Ctx: ctx,
+		Id:  id,
+	}
+	mock.lockDeleteSchedule.Lock()
+	mock.calls.DeleteSchedule = append(mock.calls.DeleteSchedule, callInfo)
+	mock.lockDeleteSchedule.Unlock()
+	return mock.DeleteScheduleFunc(ctx, id)
+}
+
+// DeleteScheduleCalls gets all the calls that were made to DeleteSchedule.
+// Check the length with:
+//
+//	len(mockedProcessor.DeleteScheduleCalls())
+func (mock *ProcessorMock) DeleteScheduleCalls() []struct {
+	Ctx context.Context
+	Id  string
+} {
+	var calls []struct {
+		Ctx context.Context
+		Id  string
+	}
+	mock.lockDeleteSchedule.RLock()
+	calls = mock.calls.DeleteSchedule
+	mock.lockDeleteSchedule.RUnlock()
+	return calls
+}
+
+// DeleteUser calls DeleteUserFunc.
+func (mock *ProcessorMock) DeleteUser(ctx context.Context, name string) error {
+	if mock.DeleteUserFunc == nil {
+		panic("ProcessorMock.DeleteUserFunc: method is nil but Processor.DeleteUser was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Name string
+	}{
+		Ctx:  ctx,
+		Name: name,
+	}
+	mock.lockDeleteUser.Lock()
+	mock.calls.DeleteUser = append(mock.calls.DeleteUser, callInfo)
+	mock.lockDeleteUser.Unlock()
+	return mock.DeleteUserFunc(ctx, name)
+}
+
+// DeleteUserCalls gets all the calls that were made to DeleteUser.
+// Check the length with:
+//
+//	len(mockedProcessor.DeleteUserCalls())
+func (mock *ProcessorMock) DeleteUserCalls() []struct {
+	Ctx  context.Context
+	Name string
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Name string
+	}
+	mock.lockDeleteUser.RLock()
+	calls = mock.calls.DeleteUser
+	mock.lockDeleteUser.RUnlock()
+	return calls
+}
+
+// DiscardBatch calls DiscardBatchFunc.
+func (mock *ProcessorMock) DiscardBatch(ctx context.Context, batchID string) error {
+	if mock.DiscardBatchFunc == nil {
+		panic("ProcessorMock.DiscardBatchFunc: method is nil but Processor.DiscardBatch was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		BatchID string
+	}{
+		Ctx:     ctx,
+		BatchID: batchID,
+	}
+	mock.lockDiscardBatch.Lock()
+	mock.calls.DiscardBatch = append(mock.calls.DiscardBatch, callInfo)
+	mock.lockDiscardBatch.Unlock()
+	return mock.DiscardBatchFunc(ctx, batchID)
+}
+
+// DiscardBatchCalls gets all the calls that were made to DiscardBatch.
+// Check the length with:
+//
+//	len(mockedProcessor.DiscardBatchCalls())
+func (mock *ProcessorMock) DiscardBatchCalls() []struct {
+	Ctx     context.Context
+	BatchID string
+} {
+	var calls []struct {
+		Ctx     context.Context
+		BatchID string
+	}
+	mock.lockDiscardBatch.RLock()
+	calls = mock.calls.DiscardBatch
+	mock.lockDiscardBatch.RUnlock()
+	return calls
+}
+
+// Forecast calls ForecastFunc.
+func (mock *ProcessorMock) Forecast(ctx context.Context, req model.ForecastRequest) (model.Forecast, error) {
+	if mock.ForecastFunc == nil {
+		panic("ProcessorMock.ForecastFunc: method is nil but Processor.Forecast was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Req model.ForecastRequest
+	}{
+		Ctx: ctx,
+		Req: req,
+	}
+	mock.lockForecast.Lock()
+	mock.calls.Forecast = append(mock.calls.Forecast, callInfo)
+	mock.lockForecast.Unlock()
+	return mock.ForecastFunc(ctx, req)
+}
+
+// ForecastCalls gets all the calls that were made to Forecast.
+// Check the length with:
+//
+//	len(mockedProcessor.ForecastCalls())
+func (mock *ProcessorMock) ForecastCalls() []struct {
+	Ctx context.Context
+	Req model.ForecastRequest
+} {
+	var calls []struct {
+		Ctx context.Context
+		Req model.ForecastRequest
+	}
+	mock.lockForecast.RLock()
+	calls = mock.calls.Forecast
+	mock.lockForecast.RUnlock()
+	return calls
+}
+
+// GenerateReport calls GenerateReportFunc.
+func (mock *ProcessorMock) GenerateReport(ctx context.Context, filter model.Filter) (model.Report, error) {
+	if mock.GenerateReportFunc == nil {
+		panic("ProcessorMock.GenerateReportFunc: method is nil but Processor.GenerateReport was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Filter model.Filter
+	}{
+		Ctx:    ctx,
+		Filter: filter,
+	}
+	mock.lockGenerateReport.Lock()
+	mock.calls.GenerateReport = append(mock.calls.GenerateReport, callInfo)
+	mock.lockGenerateReport.Unlock()
+	return mock.GenerateReportFunc(ctx, filter)
+}
+
+// GenerateReportCalls gets all the calls that were made to GenerateReport.
+// Check the length with:
+//
+//	len(mockedProcessor.GenerateReportCalls())
+func (mock *ProcessorMock) GenerateReportCalls() []struct {
+	Ctx    context.Context
+	Filter model.Filter
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Filter model.Filter
+	}
+	mock.lockGenerateReport.RLock()
+	calls = mock.calls.GenerateReport
+	mock.lockGenerateReport.RUnlock()
+	return calls
+}
+
+// Import calls ImportFunc.
+func (mock *ProcessorMock) Import(ctx context.Context, snap model.Snapshot, policy string) (model.ImportResult, error) {
+	if mock.ImportFunc == nil {
+		panic("ProcessorMock.ImportFunc: method is nil but Processor.Import was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Snap   model.Snapshot
+		Policy string
+	}{
+		Ctx:    ctx,
+		Snap:   snap,
+		Policy: policy,
+	}
+	mock.lockImport.Lock()
+	mock.calls.Import = append(mock.calls.Import, callInfo)
+	mock.lockImport.Unlock()
+	return mock.ImportFunc(ctx, snap, policy)
+}
+
+// ImportCalls gets all the calls that were made to Import.
+// Check the length with:
+//
+//	len(mockedProcessor.ImportCalls())
+func (mock *ProcessorMock) ImportCalls() []struct {
+	Ctx    context.Context
+	Snap   model.Snapshot
+	Policy string
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Snap   model.Snapshot
+		Policy string
+	}
+	mock.lockImport.RLock()
+	calls = mock.calls.Import
+	mock.lockImport.RUnlock()
+	return calls
+}
+
+// JournalEntries calls JournalEntriesFunc.
+func (mock *ProcessorMock) JournalEntries(ctx context.Context, filter model.Filter) ([]model.JournalEntry, error) {
+	if mock.JournalEntriesFunc == nil {
+		panic("ProcessorMock.JournalEntriesFunc: method is nil but Processor.JournalEntries was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Filter model.Filter
+	}{
+		Ctx:    ctx,
+		Filter: filter,
+	}
+	mock.lockJournalEntries.Lock()
+	mock.calls.JournalEntries = append(mock.calls.JournalEntries, callInfo)
+	mock.lockJournalEntries.Unlock()
+	return mock.JournalEntriesFunc(ctx, filter)
+}
+
+// JournalEntriesCalls gets all the calls that were made to JournalEntries.
+// Check the length with:
+//
+//	len(mockedProcessor.JournalEntriesCalls())
+func (mock *ProcessorMock) JournalEntriesCalls() []struct {
+	Ctx    context.Context
+	Filter model.Filter
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Filter model.Filter
+	}
+	mock.lockJournalEntries.RLock()
+	calls = mock.calls.JournalEntries
+	mock.lockJournalEntries.RUnlock()
+	return calls
+}
+
+// ParseTransaction calls ParseTransactionFunc.
+func (mock *ProcessorMock) ParseTransaction(rec []string) (model.Transaction, error) {
+	if mock.ParseTransactionFunc == nil {
+		panic("ProcessorMock.ParseTransactionFunc: method is nil but Processor.ParseTransaction was just called")
+	}
+	callInfo := struct {
+		Rec []string
+	}{
+		Rec: rec,
+	}
+	mock.lockParseTransaction.Lock()
+	mock.calls.ParseTransaction = append(mock.calls.ParseTransaction, callInfo)
+	mock.lockParseTransaction.Unlock()
+	return mock.ParseTransactionFunc(rec)
+}
+
+// ParseTransactionCalls gets all the calls that were made to ParseTransaction.
+// Check the length with:
+//
+//	len(mockedProcessor.ParseTransactionCalls())
+func (mock *ProcessorMock) ParseTransactionCalls() []struct {
+	Rec []string
+} {
+	var calls []struct {
+		Rec []string
+	}
+	mock.lockParseTransaction.RLock()
+	calls = mock.calls.ParseTransaction
+	mock.lockParseTransaction.RUnlock()
+	return calls
+}
+
+// PostEntry calls PostEntryFunc.
+func (mock *ProcessorMock) PostEntry(ctx context.Context, entry model.JournalEntry) (model.JournalEntry, error) {
+	if mock.PostEntryFunc == nil {
+		panic("ProcessorMock.PostEntryFunc: method is nil but Processor.PostEntry was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Entry model.JournalEntry
+	}{
+		Ctx:   ctx,
+		Entry: entry,
+	}
+	mock.lockPostEntry.Lock()
+	mock.calls.PostEntry = append(mock.calls.PostEntry, callInfo)
+	mock.lockPostEntry.Unlock()
+	return mock.PostEntryFunc(ctx, entry)
+}
+
+// PostEntryCalls gets all the calls that were made to PostEntry.
+// Check the length with:
+//
+//	len(mockedProcessor.PostEntryCalls())
+func (mock *ProcessorMock) PostEntryCalls() []struct {
+	Ctx   context.Context
+	Entry model.JournalEntry
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Entry model.JournalEntry
+	}
+	mock.lockPostEntry.RLock()
+	calls = mock.calls.PostEntry
+	mock.lockPostEntry.RUnlock()
+	return calls
+}
+
+// ProcessTransactions calls ProcessTransactionsFunc.
+func (mock *ProcessorMock) ProcessTransactions(ctx context.Context, transactions []model.Transaction) ([]model.Transaction, error) {
+	if mock.ProcessTransactionsFunc == nil {
+		panic("ProcessorMock.ProcessTransactionsFunc: method is nil but Processor.ProcessTransactions was just called")
+	}
+	callInfo := struct {
+		Ctx          context.Context
+		Transactions []model.Transaction
+	}{
+		Ctx:          ctx,
+		Transactions: transactions,
+	}
+	mock.lockProcessTransactions.Lock()
+	mock.calls.ProcessTransactions = append(mock.calls.ProcessTransactions, callInfo)
 	mock.lockProcessTransactions.Unlock()
 	return mock.ProcessTransactionsFunc(ctx, transactions)
 }
 
-// ProcessTransactionsCalls gets all the calls that were made to ProcessTransactions.
+// ProcessTransactionsCalls gets all the calls that were made to ProcessTransactions.
+// Check the length with:
+//
+//	len(mockedProcessor.ProcessTransactionsCalls())
+func (mock *ProcessorMock) ProcessTransactionsCalls() []struct {
+	Ctx          context.Context
+	Transactions []model.Transaction
+} {
+	var calls []struct {
+		Ctx          context.Context
+		Transactions []model.Transaction
+	}
+	mock.lockProcessTransactions.RLock()
+	calls = mock.calls.ProcessTransactions
+	mock.lockProcessTransactions.RUnlock()
+	return calls
+}
+
+// ProfitAndLoss calls ProfitAndLossFunc.
+func (mock *ProcessorMock) ProfitAndLoss(ctx context.Context, filter model.Filter, compare string) (model.PnL, error) {
+	if mock.ProfitAndLossFunc == nil {
+		panic("ProcessorMock.ProfitAndLossFunc: method is nil but Processor.ProfitAndLoss was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Filter  model.Filter
+		Compare string
+	}{
+		Ctx:     ctx,
+		Filter:  filter,
+		Compare: compare,
+	}
+	mock.lockProfitAndLoss.Lock()
+	mock.calls.ProfitAndLoss = append(mock.calls.ProfitAndLoss, callInfo)
+	mock.lockProfitAndLoss.Unlock()
+	return mock.ProfitAndLossFunc(ctx, filter, compare)
+}
+
+// ProfitAndLossCalls gets all the calls that were made to ProfitAndLoss.
+// Check the length with:
+//
+//	len(mockedProcessor.ProfitAndLossCalls())
+func (mock *ProcessorMock) ProfitAndLossCalls() []struct {
+	Ctx     context.Context
+	Filter  model.Filter
+	Compare string
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Filter  model.Filter
+		Compare string
+	}
+	mock.lockProfitAndLoss.RLock()
+	calls = mock.calls.ProfitAndLoss
+	mock.lockProfitAndLoss.RUnlock()
+	return calls
+}
+
+// Reconcile calls ReconcileFunc.
+func (mock *ProcessorMock) Reconcile(ctx context.Context, account string, st model.Statement, tol model.Tolerance) (model.Reconciliation, error) {
+	if mock.ReconcileFunc == nil {
+		panic("ProcessorMock.ReconcileFunc: method is nil but Processor.Reconcile was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Account string
+		St      model.Statement
+		Tol     model.Tolerance
+	}{
+		Ctx:     ctx,
+		Account: account,
+		St:      st,
+		Tol:     tol,
+	}
+	mock.lockReconcile.Lock()
+	mock.calls.Reconcile = append(mock.calls.Reconcile, callInfo)
+	mock.lockReconcile.Unlock()
+	return mock.ReconcileFunc(ctx, account, st, tol)
+}
+
+// ReconcileCalls gets all the calls that were made to Reconcile.
+// Check the length with:
+//
+//	len(mockedProcessor.ReconcileCalls())
+func (mock *ProcessorMock) ReconcileCalls() []struct {
+	Ctx     context.Context
+	Account string
+	St      model.Statement
+	Tol     model.Tolerance
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Account string
+		St      model.Statement
+		Tol     model.Tolerance
+	}
+	mock.lockReconcile.RLock()
+	calls = mock.calls.Reconcile
+	mock.lockReconcile.RUnlock()
+	return calls
+}
+
+// Schedules calls SchedulesFunc.
+func (mock *ProcessorMock) Schedules(ctx context.Context) ([]model.Schedule, error) {
+	if mock.SchedulesFunc == nil {
+		panic("ProcessorMock.SchedulesFunc: method is nil but Processor.Schedules was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockSchedules.Lock()
+	mock.calls.Schedules = append(mock.calls.Schedules, callInfo)
+	mock.lockSchedules.Unlock()
+	return mock.SchedulesFunc(ctx)
+}
+
+// SchedulesCalls gets all the calls that were made to Schedules.
+// Check the length with:
+//
+//	len(mockedProcessor.SchedulesCalls())
+func (mock *ProcessorMock) SchedulesCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockSchedules.RLock()
+	calls = mock.calls.Schedules
+	mock.lockSchedules.RUnlock()
+	return calls
+}
+
+// SetChartAccount calls SetChartAccountFunc.
+func (mock *ProcessorMock) SetChartAccount(ctx context.Context, acc model.ChartAccount) (model.ChartAccount, error) {
+	if mock.SetChartAccountFunc == nil {
+		panic("ProcessorMock.SetChartAccountFunc: method is nil but Processor.SetChartAccount was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Acc model.ChartAccount
+	}{
+		Ctx: ctx,
+		Acc: acc,
+	}
+	mock.lockSetChartAccount.Lock()
+	mock.calls.SetChartAccount = append(mock.calls.SetChartAccount, callInfo)
+	mock.lockSetChartAccount.Unlock()
+	return mock.SetChartAccountFunc(ctx, acc)
+}
+
+// SetChartAccountCalls gets all the calls that were made to SetChartAccount.
 // Check the length with:
 //
-//	len(mockedProcessor.ProcessTransactionsCalls())
-func (mock *ProcessorMock) ProcessTransactionsCalls() []struct {
+//	len(mockedProcessor.SetChartAccountCalls())
+func (mock *ProcessorMock) SetChartAccountCalls() []struct {
+	Ctx context.Context
+	Acc model.ChartAccount
+} {
+	var calls []struct {
+		Ctx context.Context
+		Acc model.ChartAccount
+	}
+	mock.lockSetChartAccount.RLock()
+	calls = mock.calls.SetChartAccount
+	mock.lockSetChartAccount.RUnlock()
+	return calls
+}
+
+// SetOpeningBalance calls SetOpeningBalanceFunc.
+func (mock *ProcessorMock) SetOpeningBalance(ctx context.Context, account string, amount float64, date time.Time) (model.Account, error) {
+	if mock.SetOpeningBalanceFunc == nil {
+		panic("ProcessorMock.SetOpeningBalanceFunc: method is nil but Processor.SetOpeningBalance was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Account string
+		Amount  float64
+		Date    time.Time
+	}{
+		Ctx:     ctx,
+		Account: account,
+		Amount:  amount,
+		Date:    date,
+	}
+	mock.lockSetOpeningBalance.Lock()
+	mock.calls.SetOpeningBalance = append(mock.calls.SetOpeningBalance, callInfo)
+	mock.lockSetOpeningBalance.Unlock()
+	return mock.SetOpeningBalanceFunc(ctx, account, amount, date)
+}
+
+// SetOpeningBalanceCalls gets all the calls that were made to SetOpeningBalance.
+// Check the length with:
+//
+//	len(mockedProcessor.SetOpeningBalanceCalls())
+func (mock *ProcessorMock) SetOpeningBalanceCalls() []struct {
+	Ctx     context.Context
+	Account string
+	Amount  float64
+	Date    time.Time
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Account string
+		Amount  float64
+		Date    time.Time
+	}
+	mock.lockSetOpeningBalance.RLock()
+	calls = mock.calls.SetOpeningBalance
+	mock.lockSetOpeningBalance.RUnlock()
+	return calls
+}
+
+// SetTaxConfig calls SetTaxConfigFunc.
+func (mock *ProcessorMock) SetTaxConfig(ctx context.Context, cfg model.TaxConfig) error {
+	if mock.SetTaxConfigFunc == nil {
+		panic("ProcessorMock.SetTaxConfigFunc: method is nil but Processor.SetTaxConfig was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Cfg model.TaxConfig
+	}{
+		Ctx: ctx,
+		Cfg: cfg,
+	}
+	mock.lockSetTaxConfig.Lock()
+	mock.calls.SetTaxConfig = append(mock.calls.SetTaxConfig, callInfo)
+	mock.lockSetTaxConfig.Unlock()
+	return mock.SetTaxConfigFunc(ctx, cfg)
+}
+
+// SetTaxConfigCalls gets all the calls that were made to SetTaxConfig.
+// Check the length with:
+//
+//	len(mockedProcessor.SetTaxConfigCalls())
+func (mock *ProcessorMock) SetTaxConfigCalls() []struct {
+	Ctx context.Context
+	Cfg model.TaxConfig
+} {
+	var calls []struct {
+		Ctx context.Context
+		Cfg model.TaxConfig
+	}
+	mock.lockSetTaxConfig.RLock()
+	calls = mock.calls.SetTaxConfig
+	mock.lockSetTaxConfig.RUnlock()
+	return calls
+}
+
+// Snapshot calls SnapshotFunc.
+func (mock *ProcessorMock) Snapshot(ctx context.Context) (model.Snapshot, error) {
+	if mock.SnapshotFunc == nil {
+		panic("ProcessorMock.SnapshotFunc: method is nil but Processor.Snapshot was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockSnapshot.Lock()
+	mock.calls.Snapshot = append(mock.calls.Snapshot, callInfo)
+	mock.lockSnapshot.Unlock()
+	return mock.SnapshotFunc(ctx)
+}
+
+// SnapshotCalls gets all the calls that were made to Snapshot.
+// Check the length with:
+//
+//	len(mockedProcessor.SnapshotCalls())
+func (mock *ProcessorMock) SnapshotCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockSnapshot.RLock()
+	calls = mock.calls.Snapshot
+	mock.lockSnapshot.RUnlock()
+	return calls
+}
+
+// StageTransactions calls StageTransactionsFunc.
+func (mock *ProcessorMock) StageTransactions(ctx context.Context, batchID string, transactions []model.Transaction) (string, error) {
+	if mock.StageTransactionsFunc == nil {
+		panic("ProcessorMock.StageTransactionsFunc: method is nil but Processor.StageTransactions was just called")
+	}
+	callInfo := struct {
+		Ctx          context.Context
+		BatchID      string
+		Transactions []model.Transaction
+	}{
+		Ctx:          ctx,
+		BatchID:      batchID,
+		Transactions: transactions,
+	}
+	mock.lockStageTransactions.Lock()
+	mock.calls.StageTransactions = append(mock.calls.StageTransactions, callInfo)
+	mock.lockStageTransactions.Unlock()
+	return mock.StageTransactionsFunc(ctx, batchID, transactions)
+}
+
+// StageTransactionsCalls gets all the calls that were made to StageTransactions.
+// Check the length with:
+//
+//	len(mockedProcessor.StageTransactionsCalls())
+func (mock *ProcessorMock) StageTransactionsCalls() []struct {
 	Ctx          context.Context
+	BatchID      string
 	Transactions []model.Transaction
 } {
 	var calls []struct {
 		Ctx          context.Context
+		BatchID      string
 		Transactions []model.Transaction
 	}
-	mock.lockProcessTransactions.RLock()
-	calls = mock.calls.ProcessTransactions
-	mock.lockProcessTransactions.RUnlock()
+	mock.lockStageTransactions.RLock()
+	calls = mock.calls.StageTransactions
+	mock.lockStageTransactions.RUnlock()
+	return calls
+}
+
+// TaxConfig calls TaxConfigFunc.
+func (mock *ProcessorMock) TaxConfig(ctx context.Context) (model.TaxConfig, error) {
+	if mock.TaxConfigFunc == nil {
+		panic("ProcessorMock.TaxConfigFunc: method is nil but Processor.TaxConfig was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockTaxConfig.Lock()
+	mock.calls.TaxConfig = append(mock.calls.TaxConfig, callInfo)
+	mock.lockTaxConfig.Unlock()
+	return mock.TaxConfigFunc(ctx)
+}
+
+// TaxConfigCalls gets all the calls that were made to TaxConfig.
+// Check the length with:
+//
+//	len(mockedProcessor.TaxConfigCalls())
+func (mock *ProcessorMock) TaxConfigCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockTaxConfig.RLock()
+	calls = mock.calls.TaxConfig
+	mock.lockTaxConfig.RUnlock()
+	return calls
+}
+
+// TaxSummary calls TaxSummaryFunc.
+func (mock *ProcessorMock) TaxSummary(ctx context.Context, year int, filter model.Filter) (model.TaxSummary, error) {
+	if mock.TaxSummaryFunc == nil {
+		panic("ProcessorMock.TaxSummaryFunc: method is nil but Processor.TaxSummary was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Year   int
+		Filter model.Filter
+	}{
+		Ctx:    ctx,
+		Year:   year,
+		Filter: filter,
+	}
+	mock.lockTaxSummary.Lock()
+	mock.calls.TaxSummary = append(mock.calls.TaxSummary, callInfo)
+	mock.lockTaxSummary.Unlock()
+	return mock.TaxSummaryFunc(ctx, year, filter)
+}
+
+// TaxSummaryCalls gets all the calls that were made to TaxSummary.
+// Check the length with:
+//
+//	len(mockedProcessor.TaxSummaryCalls())
+func (mock *ProcessorMock) TaxSummaryCalls() []struct {
+	Ctx    context.Context
+	Year   int
+	Filter model.Filter
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Year   int
+		Filter model.Filter
+	}
+	mock.lockTaxSummary.RLock()
+	calls = mock.calls.TaxSummary
+	mock.lockTaxSummary.RUnlock()
+	return calls
+}
+
+// Transaction calls TransactionFunc.
+func (mock *ProcessorMock) Transaction(ctx context.Context, id string) (model.Transaction, error) {
+	if mock.TransactionFunc == nil {
+		panic("ProcessorMock.TransactionFunc: method is nil but Processor.Transaction was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Id  string
+	}{
+		Ctx: ctx,
+		Id:  id,
+	}
+	mock.lockTransaction.Lock()
+	mock.calls.Transaction = append(mock.calls.Transaction, callInfo)
+	mock.lockTransaction.Unlock()
+	return mock.TransactionFunc(ctx, id)
+}
+
+// TransactionCalls gets all the calls that were made to Transaction.
+// Check the length with:
+//
+//	len(mockedProcessor.TransactionCalls())
+func (mock *ProcessorMock) TransactionCalls() []struct {
+	Ctx context.Context
+	Id  string
+} {
+	var calls []struct {
+		Ctx context.Context
+		Id  string
+	}
+	mock.lockTransaction.RLock()
+	calls = mock.calls.Transaction
+	mock.lockTransaction.RUnlock()
+	return calls
+}
+
+// Transactions calls TransactionsFunc.
+func (mock *ProcessorMock) Transactions(ctx context.Context, filter model.Filter) ([]model.LedgerLine, error) {
+	if mock.TransactionsFunc == nil {
+		panic("ProcessorMock.TransactionsFunc: method is nil but Processor.Transactions was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Filter model.Filter
+	}{
+		Ctx:    ctx,
+		Filter: filter,
+	}
+	mock.lockTransactions.Lock()
+	mock.calls.Transactions = append(mock.calls.Transactions, callInfo)
+	mock.lockTransactions.Unlock()
+	return mock.TransactionsFunc(ctx, filter)
+}
+
+// TransactionsCalls gets all the calls that were made to Transactions.
+// Check the length with:
+//
+//	len(mockedProcessor.TransactionsCalls())
+func (mock *ProcessorMock) TransactionsCalls() []struct {
+	Ctx    context.Context
+	Filter model.Filter
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Filter model.Filter
+	}
+	mock.lockTransactions.RLock()
+	calls = mock.calls.Transactions
+	mock.lockTransactions.RUnlock()
+	return calls
+}
+
+// TrialBalance calls TrialBalanceFunc.
+func (mock *ProcessorMock) TrialBalance(ctx context.Context, asOf time.Time) ([]model.ChartBalance, error) {
+	if mock.TrialBalanceFunc == nil {
+		panic("ProcessorMock.TrialBalanceFunc: method is nil but Processor.TrialBalance was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		AsOf time.Time
+	}{
+		Ctx:  ctx,
+		AsOf: asOf,
+	}
+	mock.lockTrialBalance.Lock()
+	mock.calls.TrialBalance = append(mock.calls.TrialBalance, callInfo)
+	mock.lockTrialBalance.Unlock()
+	return mock.TrialBalanceFunc(ctx, asOf)
+}
+
+// TrialBalanceCalls gets all the calls that were made to TrialBalance.
+// Check the length with:
+//
+//	len(mockedProcessor.TrialBalanceCalls())
+func (mock *ProcessorMock) TrialBalanceCalls() []struct {
+	Ctx  context.Context
+	AsOf time.Time
+} {
+	var calls []struct {
+		Ctx  context.Context
+		AsOf time.Time
+	}
+	mock.lockTrialBalance.RLock()
+	calls = mock.calls.TrialBalance
+	mock.lockTrialBalance.RUnlock()
+	return calls
+}
+
+// UpdateBudget calls UpdateBudgetFunc.
+func (mock *ProcessorMock) UpdateBudget(ctx context.Context, b model.Budget) (model.Budget, error) {
+	if mock.UpdateBudgetFunc == nil {
+		panic("ProcessorMock.UpdateBudgetFunc: method is nil but Processor.UpdateBudget was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		B   model.Budget
+	}{
+		Ctx: ctx,
+		B:   b,
+	}
+	mock.lockUpdateBudget.Lock()
+	mock.calls.UpdateBudget = append(mock.calls.UpdateBudget, callInfo)
+	mock.lockUpdateBudget.Unlock()
+	return mock.UpdateBudgetFunc(ctx, b)
+}
+
+// UpdateBudgetCalls gets all the calls that were made to UpdateBudget.
+// Check the length with:
+//
+//	len(mockedProcessor.UpdateBudgetCalls())
+func (mock *ProcessorMock) UpdateBudgetCalls() []struct {
+	Ctx context.Context
+	B   model.Budget
+} {
+	var calls []struct {
+		Ctx context.Context
+		B   model.Budget
+	}
+	mock.lockUpdateBudget.RLock()
+	calls = mock.calls.UpdateBudget
+	mock.lockUpdateBudget.RUnlock()
+	return calls
+}
+
+// UpdateTransaction calls UpdateTransactionFunc.
+func (mock *ProcessorMock) UpdateTransaction(ctx context.Context, id string, upd model.TransactionUpdate) (model.Transaction, error) {
+	if mock.UpdateTransactionFunc == nil {
+		panic("ProcessorMock.UpdateTransactionFunc: method is nil but Processor.UpdateTransaction was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Id  string
+		Upd model.TransactionUpdate
+	}{
+		Ctx: ctx,
+		Id:  id,
+		Upd: upd,
+	}
+	mock.lockUpdateTransaction.Lock()
+	mock.calls.UpdateTransaction = append(mock.calls.UpdateTransaction, callInfo)
+	mock.lockUpdateTransaction.Unlock()
+	return mock.UpdateTransactionFunc(ctx, id, upd)
+}
+
+// UpdateTransactionCalls gets all the calls that were made to UpdateTransaction.
+// Check the length with:
+//
+//	len(mockedProcessor.UpdateTransactionCalls())
+func (mock *ProcessorMock) UpdateTransactionCalls() []struct {
+	Ctx context.Context
+	Id  string
+	Upd model.TransactionUpdate
+} {
+	var calls []struct {
+		Ctx context.Context
+		Id  string
+		Upd model.TransactionUpdate
+	}
+	mock.lockUpdateTransaction.RLock()
+	calls = mock.calls.UpdateTransaction
+	mock.lockUpdateTransaction.RUnlock()
+	return calls
+}
+
+// UpdateUser calls UpdateUserFunc.
+func (mock *ProcessorMock) UpdateUser(ctx context.Context, u model.User) (model.User, error) {
+	if mock.UpdateUserFunc == nil {
+		panic("ProcessorMock.UpdateUserFunc: method is nil but Processor.UpdateUser was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		U   model.User
+	}{
+		Ctx: ctx,
+		U:   u,
+	}
+	mock.lockUpdateUser.Lock()
+	mock.calls.UpdateUser = append(mock.calls.UpdateUser, callInfo)
+	mock.lockUpdateUser.Unlock()
+	return mock.UpdateUserFunc(ctx, u)
+}
+
+// UpdateUserCalls gets all the calls that were made to UpdateUser.
+// Check the length with:
+//
+//	len(mockedProcessor.UpdateUserCalls())
+func (mock *ProcessorMock) UpdateUserCalls() []struct {
+	Ctx context.Context
+	U   model.User
+} {
+	var calls []struct {
+		Ctx context.Context
+		U   model.User
+	}
+	mock.lockUpdateUser.RLock()
+	calls = mock.calls.UpdateUser
+	mock.lockUpdateUser.RUnlock()
+	return calls
+}
+
+// User calls UserFunc.
+func (mock *ProcessorMock) User(ctx context.Context, name string) (model.User, error) {
+	if mock.UserFunc == nil {
+		panic("ProcessorMock.UserFunc: method is nil but Processor.User was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Name string
+	}{
+		Ctx:  ctx,
+		Name: name,
+	}
+	mock.lockUser.Lock()
+	mock.calls.User = append(mock.calls.User, callInfo)
+	mock.lockUser.Unlock()
+	return mock.UserFunc(ctx, name)
+}
+
+// UserCalls gets all the calls that were made to User.
+// Check the length with:
+//
+//	len(mockedProcessor.UserCalls())
+func (mock *ProcessorMock) UserCalls() []struct {
+	Ctx  context.Context
+	Name string
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Name string
+	}
+	mock.lockUser.RLock()
+	calls = mock.calls.User
+	mock.lockUser.RUnlock()
+	return calls
+}
+
+// Users calls UsersFunc.
+func (mock *ProcessorMock) Users(ctx context.Context) ([]model.User, error) {
+	if mock.UsersFunc == nil {
+		panic("ProcessorMock.UsersFunc: method is nil but Processor.Users was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockUsers.Lock()
+	mock.calls.Users = append(mock.calls.Users, callInfo)
+	mock.lockUsers.Unlock()
+	return mock.UsersFunc(ctx)
+}
+
+// UsersCalls gets all the calls that were made to Users.
+// Check the length with:
+//
+//	len(mockedProcessor.UsersCalls())
+func (mock *ProcessorMock) UsersCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockUsers.RLock()
+	calls = mock.calls.Users
+	mock.lockUsers.RUnlock()
 	return calls
 }
@@ -0,0 +1,317 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/mrnbort/summer_break/model"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GET /reports/pnl?from=DATE&to=DATE&compare=previous|last-year, profit and loss statement
+// as JSON, CSV, HTML or PDF. Accepts the same account and tag filters as GET /report.
+func (s Service) handleProfitAndLoss(w http.ResponseWriter, r *http.Request) {
+	format := negotiateFormat(r, formatJSON, formatCSV, formatHTML, formatPDF)
+	if format == "" {
+		render.Status(r, http.StatusNotAcceptable)
+		render.JSON(w, r, JSON{"error": "supported formats are json, csv, html and pdf"})
+		return
+	}
+	filter, err := parseFilter(r)
+	if err == nil && (filter.From.IsZero() || filter.To.IsZero()) {
+		err = fmt.Errorf("both from and to dates are required")
+	}
+	if err == nil {
+		err = model.Period{From: filter.From, To: filter.To}.Validate()
+	}
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	compare := r.URL.Query().Get("compare")
+	if compare != "" {
+		if _, err = model.ComparisonPeriod(model.Period{From: filter.From, To: filter.To}, compare); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, JSON{"error": err.Error()})
+			return
+		}
+	}
+
+	pnl, err := s.Processor.ProfitAndLoss(r.Context(), filter, compare)
+	if err != nil {
+		log.Printf("[WARN] can't make profit and loss statement: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+
+	if format == formatJSON {
+		render.JSON(w, r, pnl)
+		return
+	}
+	if err = writeTable(w, format, "pnl", pnlTable(pnl, filter)); err != nil {
+		log.Printf("[WARN] can't write profit and loss statement: %v", err)
+	}
+}
+
+// pnlTable makes table of the statement, with a change column when there is a comparison period
+func pnlTable(pnl model.PnL, filter model.Filter) table {
+	t := table{Title: "Profit and Loss", Header: []string{"Category"}}
+	for _, p := range pnl.Periods {
+		t.Header = append(t.Header, p.Label())
+	}
+	if len(pnl.Periods) > 1 {
+		t.Header = append(t.Header, "Change")
+	}
+	filter.From, filter.To = pnl.Periods[0].From, pnl.Periods[0].To
+	t.Subtitle = filterDescription(filter)
+
+	add := func(section, label string, amounts []float64, total bool) {
+		if len(amounts) > 1 {
+			amounts = append(amounts[:len(amounts):len(amounts)], amounts[0]-amounts[1])
+		}
+		t.Rows = append(t.Rows, reportRow{Section: section, Label: label, Amounts: amounts, Total: total})
+	}
+	totals := func(field func(model.Report) float64) []float64 {
+		res := make([]float64, 0, len(pnl.Totals))
+		for _, r := range pnl.Totals {
+			res = append(res, field(r))
+		}
+		return res
+	}
+	for _, l := range pnl.Income {
+		add("Income", l.Category, l.Amounts, false)
+	}
+	add("Total", "Gross revenue", totals(func(r model.Report) float64 { return r.GrossRevenue }), true)
+	for _, l := range pnl.Expenses {
+		add("Expenses", l.Category, l.Amounts, false)
+	}
+	add("Total", "Expenses", totals(func(r model.Report) float64 { return r.Expenses }), true)
+	add("Net", "Net revenue", totals(func(r model.Report) float64 { return r.NetRevenue }), true)
+	return t
+}
+
+// GET /reports/tax/{year}, tax year summary with totals per tax line and transactions backing them,
+// as JSON or CSV. Accepts the same account and tag filters as GET /report, dates come from the tax year.
+func (s Service) handleTaxSummary(w http.ResponseWriter, r *http.Request) {
+	format := negotiateFormat(r, formatJSON, formatCSV)
+	if format == "" {
+		render.Status(r, http.StatusNotAcceptable)
+		render.JSON(w, r, JSON{"error": "supported formats are json and csv"})
+		return
+	}
+	year, err := strconv.Atoi(chi.URLParam(r, "year"))
+	if err != nil || year < 1 || year > 9999 {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": fmt.Sprintf("invalid year %q", chi.URLParam(r, "year"))})
+		return
+	}
+	filter, err := parseFilter(r)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+
+	summary, err := s.Processor.TaxSummary(r.Context(), year, filter)
+	if err != nil {
+		log.Printf("[WARN] can't make tax summary: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+
+	if format == formatJSON {
+		render.JSON(w, r, summary)
+		return
+	}
+	w.Header().Set("Content-Type", formatTypes[formatCSV])
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="tax-%d.csv"`, year))
+	if err = writeTaxCSV(w, summary); err != nil {
+		log.Printf("[WARN] can't write tax summary: %v", err)
+	}
+}
+
+// GET /reports/tax/config, fiscal year start and mapping of categories to tax lines
+func (s Service) handleTaxConfig(w http.ResponseWriter, r *http.Request) {
+	cfg, err := s.Processor.TaxConfig(r.Context())
+	if err != nil {
+		log.Printf("[WARN] can't get tax config: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	render.JSON(w, r, cfg)
+}
+
+// PUT /reports/tax/config, replaces tax configuration, i.e. {"fiscalYearStart":7,"lines":{"Fuel":"Car and truck expenses"}}
+func (s Service) handleSetTaxConfig(w http.ResponseWriter, r *http.Request) {
+	cfg := model.TaxConfig{}
+	if err := render.DecodeJSON(r.Body, &cfg); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+
+	if err := s.Processor.SetTaxConfig(r.Context(), cfg); err != nil {
+		log.Printf("[WARN] can't set tax config: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	render.JSON(w, r, cfg)
+}
+
+// writeTaxCSV writes transactions of every tax line followed by the line total, and the summary totals at the end
+func writeTaxCSV(w io.Writer, summary model.TaxSummary) error {
+	rows := [][]string{{"Section", "Line", "Date", "ID", "Account", "Category", "Memo", "Amount"}}
+	for _, section := range []struct {
+		name  string
+		lines []model.TaxLine
+	}{{"Income", summary.Income}, {"Deductions", summary.Deductions}, {"Non-deductible", summary.NonDeductible}} {
+		for _, l := range section.lines {
+			for _, tr := range l.Transactions {
+				rows = append(rows, []string{section.name, l.Line, tr.Date.Format(model.DateLayout), tr.ID, tr.Account,
+					tr.CategoryName(), tr.Memo, money(tr.Amount)})
+			}
+			rows = append(rows, []string{section.name, l.Line, "", "", "", "", "Total", money(l.Total)})
+		}
+	}
+	rows = append(rows,
+		[]string{"Summary", "Gross income", "", "", "", "", "", money(summary.GrossIncome)},
+		[]string{"Summary", "Total deductions", "", "", "", "", "", money(summary.TotalDeductions)},
+		[]string{"Summary", "Net profit", "", "", "", "", "", money(summary.NetProfit)})
+	return csv.NewWriter(w).WriteAll(rows)
+}
+
+// GET /reports/forecast?horizon=90d&step=daily|weekly&history=90d&asOf=DATE, cash flow forecast of
+// the accounts (all by default) from recurring schedules and historical averages. Horizon and history
+// are in days ("90d" or "90") or weeks ("12w"), asOf is today by default. Accepts account and tag filters.
+func (s Service) handleForecast(w http.ResponseWriter, r *http.Request) {
+	req := model.ForecastRequest{Step: model.StepDaily}
+	filter, err := parseFilter(r)
+	if err == nil {
+		req.Accounts, req.Tags = filter.Accounts, filter.Tags
+		req.Horizon, err = parseDays(r.URL.Query().Get("horizon"), 90)
+	}
+	if err == nil {
+		req.History, err = parseDays(r.URL.Query().Get("history"), 90)
+	}
+	if err == nil {
+		req.From, err = parseDate(r.URL.Query().Get("asOf"))
+	}
+	if req.From.IsZero() {
+		now := time.Now()
+		req.From = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	}
+	if step := r.URL.Query().Get("step"); step != "" {
+		req.Step = step
+	}
+	if err == nil {
+		err = req.Validate()
+	}
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+
+	res, err := s.Processor.Forecast(r.Context(), req)
+	if err != nil {
+		log.Printf("[WARN] can't make forecast: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	render.JSON(w, r, res)
+}
+
+// parseDays parses number of days like "90d", "90" or "12w", returns def for empty string
+func parseDays(s string, def int) (int, error) {
+	if s == "" {
+		return def, nil
+	}
+	num, mult := s, 1
+	switch {
+	case strings.HasSuffix(s, "d"):
+		num = strings.TrimSuffix(s, "d")
+	case strings.HasSuffix(s, "w"):
+		num, mult = strings.TrimSuffix(s, "w"), 7
+	}
+	n, err := strconv.Atoi(num)
+	if err != nil || n <= 0 || n > math.MaxInt/mult {
+		return 0, fmt.Errorf("invalid number of days %q", s)
+	}
+	return n * mult, nil
+}
+
+// GET /reports/compare?from=DATE&to=DATE&prevFrom=DATE&prevTo=DATE&categories=true, compares totals of two periods.
+// Instead of prevFrom and prevTo, compare=previous|last-year picks the previous period, last-year is the default.
+// Accepts the same account and tag filters as GET /report.
+func (s Service) handleCompare(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r)
+	if err == nil && (filter.From.IsZero() || filter.To.IsZero()) {
+		err = fmt.Errorf("both from and to dates are required")
+	}
+	var previous model.Period
+	if err == nil {
+		err = model.Period{From: filter.From, To: filter.To}.Validate()
+	}
+	if err == nil {
+		previous, err = parsePreviousPeriod(r, model.Period{From: filter.From, To: filter.To})
+	}
+	byCategory := false
+	if err == nil && r.URL.Query().Get("categories") != "" {
+		byCategory, err = strconv.ParseBool(r.URL.Query().Get("categories"))
+	}
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+
+	res, err := s.Processor.Compare(r.Context(), filter, previous, byCategory)
+	if err != nil {
+		log.Printf("[WARN] can't compare periods: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	render.JSON(w, r, res)
+}
+
+// parsePreviousPeriod returns period from prevFrom and prevTo parameters, or the one picked by compare mode
+func parsePreviousPeriod(r *http.Request, current model.Period) (model.Period, error) {
+	from, err := parseDate(r.URL.Query().Get("prevFrom"))
+	if err != nil {
+		return model.Period{}, err
+	}
+	to, err := parseDate(r.URL.Query().Get("prevTo"))
+	if err != nil {
+		return model.Period{}, err
+	}
+	switch {
+	case !from.IsZero() && !to.IsZero():
+		return model.Period{From: from, To: to}, model.Period{From: from, To: to}.Validate()
+	case !from.IsZero() || !to.IsZero():
+		return model.Period{}, fmt.Errorf("both prevFrom and prevTo dates are required")
+	}
+	compare := r.URL.Query().Get("compare")
+	if compare == "" {
+		compare = model.CompareLastYear
+	}
+	return model.ComparisonPeriod(current, compare)
+}
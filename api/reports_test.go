@@ -0,0 +1,320 @@
+package api
+
+import (
+	"context"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestService_handleProfitAndLoss(t *testing.T) {
+	jul := model.Period{From: time.Date(2020, 7, 1, 0, 0, 0, 0, time.Local), To: time.Date(2020, 7, 31, 0, 0, 0, 0, time.Local)}
+	jun := model.Period{From: time.Date(2020, 5, 31, 0, 0, 0, 0, time.Local), To: time.Date(2020, 6, 30, 0, 0, 0, 0, time.Local)}
+	proc := &ProcessorMock{
+		ProfitAndLossFunc: func(ctx context.Context, filter model.Filter, compare string) (model.PnL, error) {
+			return model.PnL{
+				Periods:  []model.Period{jul, jun},
+				Income:   []model.PnLLine{{Category: "Lawns", Amounts: []float64{75, 40}}},
+				Expenses: []model.PnLLine{{Category: "Fuel", Amounts: []float64{18.77, 10}}},
+				Totals:   []model.Report{{GrossRevenue: 75, Expenses: 18.77, NetRevenue: 56.23}, {GrossRevenue: 40, Expenses: 10, NetRevenue: 30}},
+			}, nil
+		},
+	}
+
+	svc := &Service{
+		Processor: proc,
+	}
+
+	ts := httptest.NewServer(svc.routes())
+	defer ts.Close()
+
+	client := http.Client{Timeout: time.Second}
+	get := func(url, accept string) (*http.Response, string) {
+		req, err := http.NewRequest("GET", ts.URL+url, nil)
+		require.NoError(t, err)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(data)
+	}
+
+	t.Run("json", func(t *testing.T) {
+		resp, body := get("/reports/pnl?from=2020-07-01&to=2020-07-31&compare=previous&tag=woodrow", "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, body, `"income":[{"category":"Lawns","amounts":[75,40]}]`)
+		call := proc.ProfitAndLossCalls()[0]
+		assert.Equal(t, model.ComparePrevious, call.Compare)
+		assert.Equal(t, jul.From, call.Filter.From)
+		assert.Equal(t, "woodrow", call.Filter.Tags.String())
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		resp, body := get("/reports/pnl?from=2020-07-01&to=2020-07-31&compare=previous&format=csv", "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/csv", resp.Header.Get("Content-Type"))
+		assert.Equal(t, "Section,Category,2020-07-01..2020-07-31,2020-05-31..2020-06-30,Change\n"+
+			"Income,Lawns,75.00,40.00,35.00\n"+
+			"Total,Gross revenue,75.00,40.00,35.00\n"+
+			"Expenses,Fuel,18.77,10.00,8.77\n"+
+			"Total,Expenses,18.77,10.00,8.77\n"+
+			"Net,Net revenue,56.23,30.00,26.23\n", body)
+	})
+
+	t.Run("html by accept header", func(t *testing.T) {
+		resp, body := get("/reports/pnl?from=2020-07-01&to=2020-07-31", "text/html,application/xhtml+xml")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+		assert.Contains(t, body, "<td>Lawns</td><td>75.00</td><td>40.00</td><td>35.00</td>")
+		assert.Contains(t, body, "<td>Net revenue</td><td>56.23</td><td>30.00</td><td>26.23</td>")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		resp, _ := get("/reports/pnl?from=2020-07-01&to=2020-07-31&format=xml", "")
+		assert.Equal(t, http.StatusNotAcceptable, resp.StatusCode)
+		resp, _ = get("/reports/pnl?from=2020-07-01&to=2020-07-31", "application/xml")
+		assert.Equal(t, http.StatusNotAcceptable, resp.StatusCode)
+		resp, _ = get("/reports/pnl?from=2020-07-01", "")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp, _ = get("/reports/pnl?from=2020-07-01&to=2020-07-31&compare=next", "")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp, _ = get("/reports/pnl?from=2020-07-31&to=2020-07-01", "")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "from after to")
+		assert.Equal(t, 3, len(proc.ProfitAndLossCalls()))
+	})
+}
+
+func TestService_handleTaxSummary(t *testing.T) {
+	fuel := model.Transaction{ID: "3", Account: "default", Date: time.Date(2020, 7, 1, 0, 0, 0, 0, time.Local),
+		Type: model.Expense, Amount: 18.77, Memo: "Fuel"}
+	proc := &ProcessorMock{
+		TaxSummaryFunc: func(ctx context.Context, year int, filter model.Filter) (model.TaxSummary, error) {
+			return model.TaxSummary{
+				Year:            year,
+				Income:          []model.TaxLine{{Line: model.GrossReceiptsLine, Categories: []string{"Lawns"}, Total: 40}},
+				Deductions:      []model.TaxLine{{Line: "Car and truck expenses", Categories: []string{"Fuel"}, Total: 18.77, Transactions: []model.Transaction{fuel}}},
+				GrossIncome:     40,
+				TotalDeductions: 18.77,
+				NetProfit:       21.23,
+			}, nil
+		},
+		TaxConfigFunc: func(ctx context.Context) (model.TaxConfig, error) {
+			return model.TaxConfig{FiscalYearStart: time.July, Lines: map[string]string{"Fuel": "Car and truck expenses"}}, nil
+		},
+		SetTaxConfigFunc: func(ctx context.Context, cfg model.TaxConfig) error {
+			return nil
+		},
+	}
+
+	ts := httptest.NewServer((&Service{Processor: proc}).routes())
+	defer ts.Close()
+	client := http.Client{Timeout: time.Second}
+
+	t.Run("json", func(t *testing.T) {
+		resp, err := client.Get(ts.URL + "/reports/tax/2020?account=card")
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"deductions":[{"line":"Car and truck expenses","categories":["Fuel"],"total":18.77,`)
+		require.Len(t, proc.TaxSummaryCalls(), 1)
+		assert.Equal(t, 2020, proc.TaxSummaryCalls()[0].Year)
+		assert.Equal(t, []string{"card"}, proc.TaxSummaryCalls()[0].Filter.Accounts)
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		resp, err := client.Get(ts.URL + "/reports/tax/2020?format=csv")
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, `attachment; filename="tax-2020.csv"`, resp.Header.Get("Content-Disposition"))
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "Section,Line,Date,ID,Account,Category,Memo,Amount\n"+
+			"Income,Gross receipts,,,,,Total,40.00\n"+
+			"Deductions,Car and truck expenses,2020-07-01,3,default,Fuel,Fuel,18.77\n"+
+			"Deductions,Car and truck expenses,,,,,Total,18.77\n"+
+			"Summary,Gross income,,,,,,40.00\n"+
+			"Summary,Total deductions,,,,,,18.77\n"+
+			"Summary,Net profit,,,,,,21.23\n", string(data))
+	})
+
+	t.Run("bad year", func(t *testing.T) {
+		resp, err := client.Get(ts.URL + "/reports/tax/last")
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Len(t, proc.TaxSummaryCalls(), 2)
+	})
+
+	t.Run("config", func(t *testing.T) {
+		resp, err := client.Get(ts.URL + "/reports/tax/config")
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `{"fiscalYearStart":7,"lines":{"Fuel":"Car and truck expenses"}}`+"\n", string(data))
+
+		req, err := http.NewRequest("PUT", ts.URL+"/reports/tax/config", strings.NewReader(`{"fiscalYearStart":10,"lines":{"Repairs":"Repairs and maintenance"}}`))
+		require.NoError(t, err)
+		resp, err = client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Len(t, proc.SetTaxConfigCalls(), 1)
+		assert.Equal(t, time.October, proc.SetTaxConfigCalls()[0].Cfg.FiscalYearStart)
+
+		req, err = http.NewRequest("PUT", ts.URL+"/reports/tax/config", strings.NewReader(`{"fiscalYearStart":13}`))
+		require.NoError(t, err)
+		resp, err = client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Len(t, proc.SetTaxConfigCalls(), 1)
+	})
+}
+
+func TestService_handleForecast(t *testing.T) {
+	proc := &ProcessorMock{
+		ForecastFunc: func(ctx context.Context, req model.ForecastRequest) (model.Forecast, error) {
+			return model.Forecast{From: req.From.AddDate(0, 0, 1), To: req.From.AddDate(0, 0, req.Horizon), Step: req.Step,
+				StartingBalance: 100}, nil
+		},
+	}
+	ts := httptest.NewServer((&Service{Processor: proc}).routes())
+	defer ts.Close()
+	client := http.Client{Timeout: time.Second}
+
+	get := func(url string) int {
+		resp, err := client.Get(ts.URL + url)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusOK, get("/reports/forecast"))
+	req := proc.ForecastCalls()[0].Req
+	assert.Equal(t, 90, req.Horizon)
+	assert.Equal(t, 90, req.History)
+	assert.Equal(t, model.StepDaily, req.Step)
+	assert.Equal(t, time.Now().Day(), req.From.Day())
+
+	assert.Equal(t, http.StatusOK, get("/reports/forecast?horizon=12w&history=30d&step=weekly&asOf=2020-07-31&account=card&tag=lawn"))
+	req = proc.ForecastCalls()[1].Req
+	tags, err := model.ParseTagExpr("lawn")
+	require.NoError(t, err)
+	assert.Equal(t, model.ForecastRequest{Accounts: []string{"card"}, From: time.Date(2020, 7, 31, 0, 0, 0, 0, time.Local),
+		Horizon: 84, History: 30, Step: model.StepWeekly, Tags: tags}, req)
+
+	assert.Equal(t, http.StatusBadRequest, get("/reports/forecast?horizon=3m"))
+	assert.Equal(t, http.StatusBadRequest, get("/reports/forecast?step=monthly"))
+	assert.Equal(t, http.StatusBadRequest, get("/reports/forecast?asOf=tomorrow"))
+	assert.Equal(t, http.StatusBadRequest, get("/reports/forecast?history=999999999d"))
+	assert.Len(t, proc.ForecastCalls(), 2)
+}
+
+func Test_parseDays(t *testing.T) {
+	tests := []struct {
+		inp   string
+		out   int
+		isErr bool
+	}{
+		{"", 90, false}, {"30", 30, false}, {"30d", 30, false}, {"2w", 14, false}, {"0d", 0, true}, {"3m", 0, true}, {"-1", 0, true},
+		{"9223372036854775807w", 0, true}, {"1317624576693539402w", 0, true},
+	}
+	for _, tt := range tests {
+		out, err := parseDays(tt.inp, 90)
+		if tt.isErr {
+			assert.Error(t, err, tt.inp)
+			continue
+		}
+		assert.Equal(t, tt.out, out, tt.inp)
+	}
+}
+
+func TestService_handleCompare(t *testing.T) {
+	proc := &ProcessorMock{
+		CompareFunc: func(ctx context.Context, filter model.Filter, previous model.Period, byCategory bool) (model.Comparison, error) {
+			return model.NewComparison(
+				model.PeriodReport{Period: model.Period{From: filter.From, To: filter.To}, Report: model.Report{GrossRevenue: 75, NetRevenue: 75}},
+				model.PeriodReport{Period: previous, Report: model.Report{GrossRevenue: 50, NetRevenue: 50}}), nil
+		},
+	}
+	ts := httptest.NewServer((&Service{Processor: proc}).routes())
+	defer ts.Close()
+	client := http.Client{Timeout: time.Second}
+	d := func(y, m, d int) time.Time { return time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.Local) }
+
+	get := func(url string) (int, string) {
+		resp, err := client.Get(ts.URL + url)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(data)
+	}
+
+	code, body := get("/reports/compare?from=2020-07-01&to=2020-07-31")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `"delta":{"absolute":{"grossRevenue":25,"expenses":0,"netRevenue":25},`+
+		`"percent":{"grossRevenue":50,"expenses":null,"netRevenue":50}}`)
+	call := proc.CompareCalls()[0]
+	assert.Equal(t, model.Period{From: d(2019, 7, 1), To: d(2019, 7, 31)}, call.Previous, "last year by default")
+	assert.False(t, call.ByCategory)
+
+	code, _ = get("/reports/compare?from=2020-07-01&to=2020-07-31&compare=previous&categories=true&tag=lawn")
+	assert.Equal(t, http.StatusOK, code)
+	call = proc.CompareCalls()[1]
+	assert.Equal(t, model.Period{From: d(2020, 5, 31), To: d(2020, 6, 30)}, call.Previous)
+	assert.True(t, call.ByCategory)
+	assert.Equal(t, "lawn", call.Filter.Tags.String())
+
+	code, _ = get("/reports/compare?from=2020-07-01&to=2020-07-31&prevFrom=2018-07-01&prevTo=2018-08-31")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, model.Period{From: d(2018, 7, 1), To: d(2018, 8, 31)}, proc.CompareCalls()[2].Previous)
+
+	for _, url := range []string{
+		"/reports/compare?from=2020-07-01",
+		"/reports/compare?from=2020-07-01&to=2020-07-31&prevFrom=2018-07-01",
+		"/reports/compare?from=2020-07-01&to=2020-07-31&compare=next",
+		"/reports/compare?from=2020-07-01&to=2020-07-31&categories=maybe",
+		"/reports/compare?from=2020-07-31&to=2020-07-01",
+		"/reports/compare?from=2020-07-01&to=2020-07-31&prevFrom=2018-08-31&prevTo=2018-07-01",
+	} {
+		code, _ = get(url)
+		assert.Equal(t, http.StatusBadRequest, code, url)
+	}
+	assert.Len(t, proc.CompareCalls(), 3)
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	for _, tt := range []struct{ accept, format string }{
+		{"", formatJSON},
+		{"text/csv", formatCSV},
+		{"text/csv;q=0.5, text/html", formatHTML},
+		{"text/html;q=0.2, application/pdf;q=0.8, */*;q=0.1", formatPDF},
+		{"application/json;q=0, */*", formatCSV},
+		{"application/json;q=0, application/*", formatPDF},
+		{"text/*", formatCSV},
+		{"text/csv;q=0", ""},
+		{"text/csv;q=abc", ""},
+		{"image/png", ""},
+	} {
+		r := httptest.NewRequest("GET", "/reports/pnl", nil)
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+		assert.Equal(t, tt.format, negotiateFormat(r, formatJSON, formatCSV, formatHTML, formatPDF), tt.accept)
+	}
+}
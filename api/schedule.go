@@ -0,0 +1,93 @@
+package api
+
+import (
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/mrnbort/summer_break/model"
+	"log"
+	"net/http"
+)
+
+// GET /schedules, optional "account" parameters leave schedules of the listed accounts only
+func (s Service) handleSchedules(w http.ResponseWriter, r *http.Request) {
+	schedules, err := s.Processor.Schedules(r.Context())
+	if err != nil {
+		log.Printf("[WARN] can't get schedules: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	if accounts := queryAccounts(r); len(accounts) > 0 {
+		res := []model.Schedule{}
+		for _, sch := range schedules {
+			account := sch.Account
+			if account == "" {
+				account = model.DefaultAccount
+			}
+			if model.ContainsString(accounts, account) {
+				res = append(res, sch)
+			}
+		}
+		schedules = res
+	}
+	render.JSON(w, r, schedules)
+}
+
+// POST /schedules, adds recurring transaction, i.e.
+// {"type":"Expense","amount":1200,"memo":"Rent","freq":"monthly","day":1,"start":"2020-07-01","end":"2021-06-30"}
+func (s Service) handleAddSchedule(w http.ResponseWriter, r *http.Request) {
+	req := struct {
+		Account  string       `json:"account"`
+		Type     model.TrType `json:"type"`
+		Amount   float64      `json:"amount"`
+		Memo     string       `json:"memo"`
+		Tags     []string     `json:"tags"`
+		Category string       `json:"category"`
+		Freq     string       `json:"freq"`
+		Interval int          `json:"interval"`
+		Day      int          `json:"day"`
+		Start    string       `json:"start"`
+		End      string       `json:"end"`
+	}{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	sch := model.Schedule{Account: req.Account, Type: req.Type, Amount: req.Amount, Memo: req.Memo, Tags: req.Tags,
+		Category: req.Category, Freq: req.Freq, Interval: req.Interval, Day: req.Day}
+	start, err := parseDate(req.Start)
+	if err == nil {
+		sch.Start = start
+		sch.End, err = parseDate(req.End)
+	}
+	if err == nil {
+		err = sch.Validate()
+	}
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+
+	sch, err = s.Processor.AddSchedule(r.Context(), sch)
+	if err != nil {
+		log.Printf("[WARN] can't add schedule: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, sch)
+}
+
+// DELETE /schedules/{id}, transactions already made from the schedule are kept
+func (s Service) handleDeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	if err := s.Processor.DeleteSchedule(r.Context(), chi.URLParam(r, "id")); err != nil {
+		log.Printf("[WARN] can't delete schedule: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	render.JSON(w, r, JSON{"status": "ok"})
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestService_schedules(t *testing.T) {
+	proc := &ProcessorMock{
+		SchedulesFunc: func(ctx context.Context) ([]model.Schedule, error) {
+			return []model.Schedule{}, nil
+		},
+		AddScheduleFunc: func(ctx context.Context, sch model.Schedule) (model.Schedule, error) {
+			sch.ID, sch.Next = "s-1", sch.Start
+			return sch, nil
+		},
+		DeleteScheduleFunc: func(ctx context.Context, id string) error {
+			return fmt.Errorf("schedule %q: %w", id, model.ErrNotFound)
+		},
+	}
+
+	ts := httptest.NewServer((&Service{Processor: proc}).routes())
+	defer ts.Close()
+
+	client := http.Client{Timeout: time.Second}
+	do := func(method, url, body string) (int, string) {
+		req, err := http.NewRequest(method, ts.URL+url, strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(data)
+	}
+
+	code, body := do("GET", "/schedules", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "[]\n", body)
+
+	code, body = do("POST", "/schedules", `{"type":"Expense","amount":1200,"memo":"Rent","freq":"monthly","day":1,`+
+		`"start":"2020-07-01","end":"2021-06-30"}`)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Contains(t, body, `"id":"s-1"`)
+	require.Len(t, proc.AddScheduleCalls(), 1)
+	sch := proc.AddScheduleCalls()[0].Sch
+	assert.Equal(t, time.Date(2020, 7, 1, 0, 0, 0, 0, time.Local), sch.Start)
+	assert.Equal(t, time.Date(2021, 6, 30, 0, 0, 0, 0, time.Local), sch.End)
+	assert.Equal(t, 1, sch.Day)
+
+	code, _ = do("POST", "/schedules", `{"type":"Expense","amount":1200,"memo":"Rent","freq":"daily","start":"2020-07-01"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = do("POST", "/schedules", `{"type":"Expense","amount":1200,"memo":"Rent","freq":"monthly","start":"07/01/2020"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Len(t, proc.AddScheduleCalls(), 1)
+
+	code, _ = do("DELETE", "/schedules/s-9", "")
+	assert.Equal(t, http.StatusNotFound, code)
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// DefaultSessionTTL is how long login tokens are valid if Service.SessionTTL is not set
+const DefaultSessionTTL = 12 * time.Hour
+
+// tokenHeader is the header of every session token, JWT signed with HMAC-SHA256
+var tokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// claims of the session token
+type claims struct {
+	Subject    string `json:"sub"` // user name
+	IssuedAt   int64  `json:"iat"`
+	Expires    int64  `json:"exp"`
+	Generation int64  `json:"gen,omitempty"` // of the user's tokens when it was issued
+}
+
+// signToken makes session token with the claims
+func signToken(secret []byte, c claims) (string, error) {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	unsigned := tokenHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + signature(secret, unsigned), nil
+}
+
+// parseToken checks signature and expiration of the session token and returns its claims
+func parseToken(secret []byte, token string, now time.Time) (claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != tokenHeader {
+		return claims{}, errors.New("malformed session token")
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(signature(secret, parts[0]+"."+parts[1]))) {
+		return claims{}, errors.New("invalid session token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return claims{}, errors.New("malformed session token")
+	}
+	c := claims{}
+	if err = json.Unmarshal(payload, &c); err != nil {
+		return claims{}, errors.New("malformed session token")
+	}
+	if now.Unix() >= c.Expires {
+		return claims{}, errors.New("session expired")
+	}
+	return c, nil
+}
+
+// isToken tells if the credential is a session token rather than API key
+func isToken(s string) bool {
+	return strings.Count(s, ".") == 2
+}
+
+func signature(secret []byte, s string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(s))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
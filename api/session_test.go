@@ -0,0 +1,34 @@
+package api
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_sessionToken(t *testing.T) {
+	now := time.Now()
+	token, err := signToken([]byte("secret"), claims{Subject: "alice", IssuedAt: now.Unix(), Expires: now.Add(time.Hour).Unix()})
+	require.NoError(t, err)
+	assert.True(t, isToken(token))
+	assert.False(t, isToken("sb_0123"))
+
+	c, err := parseToken([]byte("secret"), token, now)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", c.Subject)
+
+	_, err = parseToken([]byte("other"), token, now)
+	assert.EqualError(t, err, "invalid session token")
+	_, err = parseToken([]byte("secret"), token, now.Add(time.Hour))
+	assert.EqualError(t, err, "session expired")
+
+	parts := strings.Split(token, ".")
+	forged, err := signToken([]byte("secret"), claims{Subject: "bob", Expires: now.Add(time.Hour).Unix()})
+	require.NoError(t, err)
+	_, err = parseToken([]byte("secret"), parts[0]+"."+strings.Split(forged, ".")[1]+"."+parts[2], now)
+	assert.EqualError(t, err, "invalid session token", "payload of another token")
+	_, err = parseToken([]byte("secret"), "a.b", now)
+	assert.Error(t, err)
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/mrnbort/summer_break/model"
+	"golang.org/x/crypto/bcrypt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// minPasswordLength is the shortest password accepted for a user
+const minPasswordLength = 8
+
+// dummyHash is compared with password of unknown user, so login takes the same time for any name
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("summer break dummy"), bcrypt.DefaultCost)
+
+// sessionPrincipal returns the principal of session token, the user is read on every request,
+// so changes of the role and ledgers apply at once, and tokens of deleted users stop working. Tokens issued
+// before the password change or before the user was made again with the same name have other generation.
+func (s Service) sessionPrincipal(ctx context.Context, token string) (principal, error) {
+	c, err := parseToken(s.SessionSecret, token, time.Now())
+	if err != nil {
+		return principal{}, err
+	}
+	u, err := s.Processor.User(ctx, c.Subject)
+	if errors.Is(err, model.ErrNotFound) {
+		return principal{}, errors.New("invalid session token")
+	}
+	if err != nil {
+		return principal{}, err
+	}
+	if c.Generation != u.Generation {
+		return principal{}, errors.New("session token is revoked")
+	}
+	return principal{Name: "user " + u.Name, Scopes: model.RoleScopes(u.Role), Ledgers: u.Ledgers}, nil
+}
+
+// POST /auth/login, checks name and password, i.e. {"name":"alice","password":"..."},
+// and returns session token to send as "Authorization: Bearer <token>"
+func (s Service) handleLogin(w http.ResponseWriter, r *http.Request) {
+	req := struct {
+		Name     string `json:"name"`
+		Password string `json:"password"`
+	}{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+
+	u, err := s.Processor.User(r.Context(), strings.TrimSpace(req.Name))
+	if err != nil && !errors.Is(err, model.ErrNotFound) {
+		log.Printf("[WARN] can't get user: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	hash := []byte(u.PasswordHash)
+	if err != nil {
+		hash = dummyHash
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(req.Password)) != nil || err != nil {
+		log.Printf("[WARN] failed login of %q", req.Name)
+		unauthorized(w, r, errors.New("invalid name or password"))
+		return
+	}
+
+	ttl := s.SessionTTL
+	if ttl <= 0 {
+		ttl = DefaultSessionTTL
+	}
+	now := time.Now()
+	sess := model.Session{Expires: now.Add(ttl).Truncate(time.Second), User: u}
+	sess.Token, err = signToken(s.SessionSecret, claims{Subject: u.Name, IssuedAt: now.Unix(), Expires: sess.Expires.Unix(),
+		Generation: u.Generation})
+	if err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	log.Printf("[INFO] user %s logged in", u.Name)
+	sess.User.PasswordHash = ""
+	render.JSON(w, r, sess)
+}
+
+// GET /auth/me, returns name, scopes and ledgers of the caller
+func (s Service) handleMe(w http.ResponseWriter, r *http.Request) {
+	p, ok := r.Context().Value(principalKey).(principal)
+	if !ok {
+		p = principal{Name: "anonymous", Scopes: []string{model.ScopeAdmin}} // auth is disabled
+	}
+	render.JSON(w, r, p)
+}
+
+// GET /users, lists users without password hashes
+func (s Service) handleUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := s.Processor.Users(r.Context())
+	if err != nil {
+		log.Printf("[WARN] can't get users: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	for i := range users {
+		users[i].PasswordHash = ""
+	}
+	render.JSON(w, r, users)
+}
+
+// POST /users and PUT /users/{name}, adds or updates user,
+// i.e. {"name":"bob","password":"...","role":"bookkeeper","ledgers":["card"]}.
+// Password is required for a new user, the stored one is kept if it's not set on update.
+func (s Service) handleSetUser(w http.ResponseWriter, r *http.Request) {
+	req := struct {
+		Name     string   `json:"name"`
+		Password string   `json:"password"`
+		Role     string   `json:"role"`
+		Ledgers  []string `json:"ledgers"`
+	}{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	name := chi.URLParam(r, "name")
+	if name == "" {
+		name = strings.TrimSpace(req.Name)
+	}
+	u := model.User{Name: name, Role: req.Role, Ledgers: req.Ledgers, Created: time.Now()}
+	err := u.Validate()
+	if err == nil && (req.Password != "" || r.Method == http.MethodPost) && len(req.Password) < minPasswordLength {
+		err = fmt.Errorf("password should have at least %d characters", minPasswordLength)
+	}
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	if req.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, JSON{"error": err.Error()})
+			return
+		}
+		u.PasswordHash = string(hash)
+	}
+
+	if r.Method == http.MethodPost {
+		u, err = s.Processor.AddUser(r.Context(), u)
+	} else {
+		u, err = s.Processor.UpdateUser(r.Context(), u)
+	}
+	if err != nil {
+		log.Printf("[WARN] can't set user: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	log.Printf("[INFO] user %s set with %s role", u.Name, u.Role)
+	u.PasswordHash = ""
+	if r.Method == http.MethodPost {
+		render.Status(r, http.StatusCreated)
+	}
+	render.JSON(w, r, u)
+}
+
+// DELETE /users/{name}, session tokens of the user stop working
+func (s Service) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if err := s.Processor.DeleteUser(r.Context(), name); err != nil {
+		log.Printf("[WARN] can't delete user: %v", err)
+		render.Status(r, errStatus(err))
+		render.JSON(w, r, JSON{"error": err.Error()})
+		return
+	}
+	log.Printf("[INFO] user %s deleted", name)
+	render.JSON(w, r, JSON{"status": "ok"})
+}
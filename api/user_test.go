@@ -0,0 +1,195 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestService_users(t *testing.T) {
+	users := map[string]model.User{}
+	var filters []model.Filter
+	var generation int64
+	proc := &ProcessorMock{
+		APIKeyByHashFunc: func(ctx context.Context, hash string) (model.APIKey, error) {
+			return model.APIKey{}, model.ErrNotFound
+		},
+		UserFunc: func(ctx context.Context, name string) (model.User, error) {
+			u, ok := users[name]
+			if !ok {
+				return model.User{}, model.ErrNotFound
+			}
+			return u, nil
+		},
+		UsersFunc: func(ctx context.Context) ([]model.User, error) {
+			res := []model.User{}
+			for _, u := range users {
+				res = append(res, u)
+			}
+			return res, nil
+		},
+		AddUserFunc: func(ctx context.Context, u model.User) (model.User, error) {
+			if _, ok := users[u.Name]; ok {
+				return model.User{}, model.ErrConflict
+			}
+			generation++
+			u.Generation = generation
+			users[u.Name] = u
+			return u, nil
+		},
+		UpdateUserFunc: func(ctx context.Context, u model.User) (model.User, error) {
+			u.Created, u.Generation = users[u.Name].Created, users[u.Name].Generation
+			if u.PasswordHash == "" {
+				u.PasswordHash = users[u.Name].PasswordHash
+			} else {
+				generation++
+				u.Generation = generation
+			}
+			users[u.Name] = u
+			return u, nil
+		},
+		DeleteUserFunc: func(ctx context.Context, name string) error {
+			delete(users, name)
+			return nil
+		},
+		TransactionsFunc: func(ctx context.Context, filter model.Filter) ([]model.LedgerLine, error) {
+			filters = append(filters, filter)
+			return []model.LedgerLine{}, nil
+		},
+		GenerateReportFunc: func(ctx context.Context, filter model.Filter) (model.Report, error) {
+			filters = append(filters, filter)
+			return model.Report{}, nil
+		},
+		BudgetsFunc: func(ctx context.Context) ([]model.Budget, error) {
+			return []model.Budget{}, nil
+		},
+		AccountsFunc: func(ctx context.Context) ([]model.Account, error) {
+			return []model.Account{{Name: "card"}, {Name: "cash"}}, nil
+		},
+		SchedulesFunc: func(ctx context.Context) ([]model.Schedule, error) {
+			return []model.Schedule{{ID: "s-1", Account: "card"}, {ID: "s-2"}}, nil
+		},
+		TrialBalanceFunc: func(ctx context.Context, asOf time.Time) ([]model.ChartBalance, error) {
+			return []model.ChartBalance{}, nil
+		},
+	}
+
+	ts := httptest.NewServer(Service{Processor: proc, Auth: true, AdminKey: "bootstrap"}.routes())
+	defer ts.Close()
+
+	client := http.Client{Timeout: 5 * time.Second}
+	do := func(method, url, token, body string) (int, string) {
+		req, err := http.NewRequest(method, ts.URL+url, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(data)
+	}
+	login := func(name, password string) string {
+		code, body := do("POST", "/auth/login", "", `{"name":"`+name+`","password":"`+password+`"}`)
+		require.Equal(t, http.StatusOK, code, body)
+		sess := model.Session{}
+		require.NoError(t, json.Unmarshal([]byte(body), &sess))
+		assert.Empty(t, sess.User.PasswordHash)
+		assert.True(t, sess.Expires.After(time.Now()))
+		return sess.Token
+	}
+
+	code, _ := do("POST", "/users", "bootstrap", `{"name":"alice","password":"short","role":"owner"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = do("POST", "/users", "bootstrap", `{"name":"alice","password":"long enough","role":"boss"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, body := do("POST", "/users", "bootstrap", `{"name":"alice","password":"long enough","role":"owner"}`)
+	require.Equal(t, http.StatusCreated, code, body)
+	assert.NotContains(t, body, "passwordHash")
+	assert.NotEqual(t, "long enough", users["alice"].PasswordHash)
+
+	code, _ = do("POST", "/auth/login", "", `{"name":"alice","password":"wrong password"}`)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _ = do("POST", "/auth/login", "", `{"name":"nobody","password":"long enough"}`)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	alice := login("alice", "long enough")
+
+	code, _ = do("POST", "/users", alice, `{"name":"bob","password":"bob's password","role":"viewer","ledgers":["card"]}`)
+	require.Equal(t, http.StatusCreated, code)
+	code, body = do("GET", "/users", alice, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotContains(t, body, "passwordHash")
+	bob := login("bob", "bob's password")
+
+	code, body = do("GET", "/auth/me", bob, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"name":"user bob","scopes":["read-report"],"ledgers":["card"]}`, body)
+	code, _ = do("GET", "/users", bob, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = do("POST", "/accounts/card/transactions", bob, `[]`)
+	assert.Equal(t, http.StatusForbidden, code, "viewer")
+
+	code, _ = do("GET", "/transactions", bob, "")
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = do("GET", "/accounts/card/report", bob, "")
+	assert.Equal(t, http.StatusOK, code)
+	require.Len(t, filters, 2)
+	assert.Equal(t, []string{"card"}, filters[0].Accounts, "limited to granted ledgers")
+	assert.Equal(t, []string{"card"}, filters[1].Accounts)
+	code, body = do("GET", "/transactions?account=card,cash", bob, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.Contains(t, body, "cash")
+	code, _ = do("GET", "/accounts/cash/report", bob, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = do("GET", "/budgets", bob, "")
+	assert.Equal(t, http.StatusOK, code, "settings common to all ledgers")
+	code, body = do("GET", "/accounts", bob, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `"card"`)
+	assert.NotContains(t, body, `"cash"`, "granted ledgers only")
+	code, body = do("GET", "/schedules", bob, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `"s-1"`)
+	assert.NotContains(t, body, `"s-2"`, "schedule of the default ledger")
+	code, body = do("GET", "/journal/trial-balance", bob, "")
+	assert.Equal(t, http.StatusForbidden, code, "all ledgers")
+	assert.Contains(t, body, "works with all of them")
+	code, _ = do("GET", "/journal/trial-balance", alice, "")
+	assert.Equal(t, http.StatusOK, code)
+
+	code, _ = do("PUT", "/users/bob", alice, `{"role":"viewer"}`)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = do("GET", "/journal/trial-balance", bob, "")
+	assert.Equal(t, http.StatusOK, code, "access to all ledgers applies at once")
+	login("bob", "bob's password") // password is kept
+
+	code, _ = do("PUT", "/users/bob", alice, `{"role":"viewer","password":"bob's new password"}`)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = do("GET", "/budgets", bob, "")
+	assert.Equal(t, http.StatusUnauthorized, code, "token issued before the password change")
+	bob = login("bob", "bob's new password")
+	code, _ = do("GET", "/budgets", bob, "")
+	assert.Equal(t, http.StatusOK, code)
+
+	code, _ = do("DELETE", "/users/bob", alice, "")
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = do("GET", "/transactions", bob, "")
+	assert.Equal(t, http.StatusUnauthorized, code, "deleted user")
+	code, _ = do("POST", "/users", alice, `{"name":"bob","role":"viewer","password":"bob's new password"}`)
+	assert.Equal(t, http.StatusCreated, code)
+	code, _ = do("GET", "/budgets", bob, "")
+	assert.Equal(t, http.StatusUnauthorized, code, "token of the deleted user, made again in the same second")
+	code, _ = do("GET", "/transactions", alice+"x", "")
+	assert.Equal(t, http.StatusUnauthorized, code, "forged token")
+}
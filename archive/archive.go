@@ -0,0 +1,189 @@
+// Package archive writes and reads the stored state as a versioned NDJSON archive, used for backups
+// and to move data between stores.
+package archive
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/mrnbort/summer_break/model"
+	"io"
+	"time"
+)
+
+// Version of the archive format, archives of other versions can't be read
+const Version = 1
+
+// ContentType of the archive
+const ContentType = "application/x-ndjson"
+
+// record kinds, every line of the archive is a record
+const (
+	KindHeader      = "header" // the first record, with version and creation time
+	KindAccount     = "account"
+	KindTransaction = "transaction"
+	KindBudget      = "budget"
+	KindSchedule    = "schedule"
+	KindChart       = "chart"    // account of the double-entry chart of accounts
+	KindEntry       = "entry"    // journal entry posted manually
+	KindSettings    = "settings" // tax config and id sequences
+)
+
+// record is a line of the archive
+type record struct {
+	Kind    string          `json:"kind"`
+	Version int             `json:"version,omitempty"` // header only
+	Created *time.Time      `json:"created,omitempty"` // header only
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// settings is the data of the settings record
+type settings struct {
+	Tax            model.TaxConfig `json:"tax"`
+	LastID         int64           `json:"lastId"`
+	LastBudgetID   int64           `json:"lastBudgetId"`
+	LastScheduleID int64           `json:"lastScheduleId"`
+}
+
+// Write writes the snapshot as archive, one record per line
+func Write(w io.Writer, snap model.Snapshot, created time.Time) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	if err := enc.Encode(record{Kind: KindHeader, Version: Version, Created: &created}); err != nil {
+		return err
+	}
+	write := func(kind string, v interface{}) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		return enc.Encode(record{Kind: kind, Data: data})
+	}
+	for _, acc := range snap.Accounts {
+		acc.Transactions = 0 // counted again on import
+		if err := write(KindAccount, acc); err != nil {
+			return err
+		}
+	}
+	for _, tr := range snap.Transactions {
+		if err := write(KindTransaction, tr); err != nil {
+			return err
+		}
+	}
+	for _, b := range snap.Budgets {
+		if err := write(KindBudget, b); err != nil {
+			return err
+		}
+	}
+	for _, sch := range snap.Schedules {
+		if err := write(KindSchedule, sch); err != nil {
+			return err
+		}
+	}
+	for _, acc := range snap.Chart {
+		if err := write(KindChart, acc); err != nil {
+			return err
+		}
+	}
+	for _, entry := range snap.JournalEntries {
+		if err := write(KindEntry, entry); err != nil {
+			return err
+		}
+	}
+	err := write(KindSettings, settings{Tax: snap.Tax, LastID: snap.LastID, LastBudgetID: snap.LastBudgetID,
+		LastScheduleID: snap.LastScheduleID})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// Read reads the archive into snapshot. The header has to be the first record, other records may go
+// in any order.
+func Read(r io.Reader) (model.Snapshot, error) {
+	snap := model.Snapshot{Version: model.SnapshotVersion, Accounts: []model.Account{}, Transactions: []model.Transaction{},
+		Budgets: []model.Budget{}, Schedules: []model.Schedule{}}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line, records := 0, 0
+	for sc.Scan() {
+		line++
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		records++
+		rec := record{}
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return model.Snapshot{}, fmt.Errorf("line %d: %w", line, err)
+		}
+		if records == 1 {
+			if rec.Kind != KindHeader {
+				return model.Snapshot{}, fmt.Errorf("line %d: expected header, got %q", line, rec.Kind)
+			}
+			if rec.Version != Version {
+				return model.Snapshot{}, fmt.Errorf("unsupported archive version %d", rec.Version)
+			}
+			continue
+		}
+		if err := readRecord(rec, &snap); err != nil {
+			return model.Snapshot{}, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return model.Snapshot{}, err
+	}
+	if records == 0 {
+		return model.Snapshot{}, fmt.Errorf("empty archive")
+	}
+	return snap, nil
+}
+
+// readRecord adds data of the record to the snapshot
+func readRecord(rec record, snap *model.Snapshot) error {
+	var err error
+	switch rec.Kind {
+	case KindAccount:
+		acc := model.Account{}
+		if err = json.Unmarshal(rec.Data, &acc); err == nil {
+			snap.Accounts = append(snap.Accounts, acc)
+		}
+	case KindTransaction:
+		tr := model.Transaction{}
+		if err = json.Unmarshal(rec.Data, &tr); err == nil {
+			if err = tr.Validate(); err != nil {
+				return fmt.Errorf("transaction %q: %w", tr.ID, err)
+			}
+			snap.Transactions = append(snap.Transactions, tr)
+		}
+	case KindBudget:
+		b := model.Budget{}
+		if err = json.Unmarshal(rec.Data, &b); err == nil {
+			snap.Budgets = append(snap.Budgets, b)
+		}
+	case KindSchedule:
+		sch := model.Schedule{}
+		if err = json.Unmarshal(rec.Data, &sch); err == nil {
+			snap.Schedules = append(snap.Schedules, sch)
+		}
+	case KindChart:
+		acc := model.ChartAccount{}
+		if err = json.Unmarshal(rec.Data, &acc); err == nil {
+			snap.Chart = append(snap.Chart, acc)
+		}
+	case KindEntry:
+		entry := model.JournalEntry{}
+		if err = json.Unmarshal(rec.Data, &entry); err == nil {
+			snap.JournalEntries = append(snap.JournalEntries, entry)
+		}
+	case KindSettings:
+		s := settings{}
+		if err = json.Unmarshal(rec.Data, &s); err == nil {
+			snap.Tax, snap.LastID, snap.LastBudgetID, snap.LastScheduleID = s.Tax, s.LastID, s.LastBudgetID, s.LastScheduleID
+		}
+	case KindHeader:
+		return fmt.Errorf("unexpected header")
+	default:
+		return fmt.Errorf("unknown record kind %q", rec.Kind)
+	}
+	return err
+}
@@ -0,0 +1,68 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/mrnbort/summer_break/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteRead(t *testing.T) {
+	ctx := context.Background()
+	proc := processor.NewProc()
+	_, err := proc.SetOpeningBalance(ctx, "card", 100, time.Date(2020, 6, 30, 0, 0, 0, 0, time.Local))
+	require.NoError(t, err)
+	_, err = proc.ProcessTransactions(ctx, []model.Transaction{
+		{Account: "card", Date: time.Date(2020, 7, 4, 0, 0, 0, 0, time.Local), Type: model.Income, Amount: 40,
+			Memo: "347 Woodrow", Tags: []string{"woodrow"}, Category: "Lawns"},
+	})
+	require.NoError(t, err)
+	_, err = proc.AddBudget(ctx, model.Budget{Category: "Fuel", Period: model.BudgetMonthly, Amount: 50})
+	require.NoError(t, err)
+	require.NoError(t, proc.SetTaxConfig(ctx, model.TaxConfig{FiscalYearStart: time.April}))
+	snap, err := proc.Snapshot(ctx)
+	require.NoError(t, err)
+	snap.Chart = []model.ChartAccount{{Name: "Equity", Type: model.EquityAccount}}
+	snap.JournalEntries = []model.JournalEntry{{ID: "je-2", Date: time.Date(2020, 7, 5, 0, 0, 0, 0, time.UTC), Memo: "owner",
+		Postings: []model.Posting{{Account: "Assets", Amount: 10}, {Account: "Equity", Amount: -10}}}}
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, Write(buf, snap, time.Date(2020, 8, 1, 12, 0, 0, 0, time.UTC)))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 7)
+	assert.Equal(t, `{"kind":"header","version":1,"created":"2020-08-01T12:00:00Z"}`, lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], `{"kind":"account","data":{"name":"card","transactions":0,"openingBalance":100,`), lines[1])
+	assert.True(t, strings.HasPrefix(lines[2], `{"kind":"transaction","data":{"id":"1","account":"card",`), lines[2])
+	assert.Equal(t, `{"kind":"chart","data":{"name":"Equity","type":"Equity"}}`, lines[4])
+	assert.True(t, strings.HasPrefix(lines[5], `{"kind":"entry","data":{"id":"je-2",`), lines[5])
+	assert.Equal(t, `{"kind":"settings","data":{"tax":{"fiscalYearStart":4,"lines":{}},"lastId":1,"lastBudgetId":1,"lastScheduleId":0}}`, lines[6])
+
+	read, err := Read(buf)
+	require.NoError(t, err)
+	snap.Accounts[0].Transactions = 0
+	assert.Equal(t, snap.Transactions[0].Date.Unix(), read.Transactions[0].Date.Unix())
+	read.Transactions[0].Date = snap.Transactions[0].Date // the same time in other location
+	read.Accounts[0].OpeningDate = snap.Accounts[0].OpeningDate
+	assert.Equal(t, snap, read)
+
+	for _, tt := range []struct{ name, data, err string }{
+		{"empty", "", "empty archive"},
+		{"no header", `{"kind":"transaction","data":{}}`, `line 1: expected header, got "transaction"`},
+		{"version", `{"kind":"header","version":2}`, "unsupported archive version 2"},
+		{"blank lines", "\n\n{\"kind\":\"budget\",\"data\":{}}", `line 3: expected header, got "budget"`},
+		{"bad type", "{\"kind\":\"header\",\"version\":1}\n{\"kind\":\"transaction\",\"data\":{\"id\":\"1\",\"type\":\"Gift\"}}",
+			`line 2: transaction "1": unsupported transaction type "Gift"`},
+		{"unknown kind", "{\"kind\":\"header\",\"version\":1}\n{\"kind\":\"rule\"}", `line 2: unknown record kind "rule"`},
+		{"bad data", "{\"kind\":\"header\",\"version\":1}\n\n{\"kind\":\"budget\",\"data\":[]}", "line 3: json: cannot unmarshal array into Go value of type model.Budget"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(strings.NewReader(tt.data))
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
@@ -0,0 +1,377 @@
+// Package audit keeps the log of changes. Entries are chained and signed with HMAC under a key kept
+// outside the log, and the sequence number and hash of the last entry are kept in a separate head file,
+// so a changed, removed, reordered or forged entry and a truncated log are detected by Verify.
+package audit
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mrnbort/summer_break/model"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrBroken returned when the hash chain of the log doesn't match its entries or the head
+var ErrBroken = errors.New("audit log is broken")
+
+// Config of the log
+type Config struct {
+	Path string // log file, the log is in memory only if not set
+	Head string // file with sequence number and hash of the last entry, Path + ".head" if not set
+	Key  []byte // HMAC key the entries are signed with, required
+}
+
+// Log is the audit log appended to the file, or kept in memory if it's opened without a path. Only the
+// last entry's sequence number and hash are kept for the file, entries are read from it when requested.
+// Safe for concurrent use.
+type Log struct {
+	path    string
+	head    string
+	key     []byte
+	mu      sync.Mutex
+	file    *os.File
+	seq     int64              // of the last entry
+	last    string             // hash of the last entry
+	entries []model.AuditEntry // of the log without file
+	err     error              // of the failed write, the log takes no more entries after it
+}
+
+// errStop stops scan of the log file early
+var errStop = errors.New("stop")
+
+// head is the content of the head file, mac signs seq and hash with the key
+type head struct {
+	Seq  int64  `json:"seq"`
+	Hash string `json:"hash"`
+	MAC  string `json:"mac"`
+}
+
+// Open reads and verifies the log file against the head file, creating both if needed, and opens the log
+// for appending. The log may have entries after the head, i.e. if the head was not written because of
+// a crash, the head is updated to its last entry then.
+func Open(cfg Config) (*Log, error) {
+	if len(cfg.Key) == 0 {
+		return nil, errors.New("audit log needs a key")
+	}
+	l := &Log{path: cfg.Path, head: cfg.Head, key: cfg.Key}
+	if l.path == "" {
+		return l, nil
+	}
+	if l.head == "" {
+		l.head = l.path + ".head"
+	}
+	c, err := l.verify()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", l.path, err)
+	}
+	if l.file, err = os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600); err != nil {
+		return nil, err
+	}
+	l.seq, l.last = c.seq, c.last
+	if err = l.writeHead(); err != nil {
+		_ = l.file.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// Append adds the entry to the log and returns it with sequence number, hashes and time, if it's not set.
+// The entry is synced to the file and the head is updated before Append returns. After a failed write
+// the log returns the error for all the following entries.
+func (l *Log) Append(e model.AuditEntry) (model.AuditEntry, error) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC().Truncate(time.Microsecond)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return model.AuditEntry{}, l.err
+	}
+	e.Seq, e.PrevHash = l.seq+1, l.last
+	var err error
+	if e.Hash, err = l.sign(e); err != nil {
+		return model.AuditEntry{}, err
+	}
+	if l.file != nil {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return model.AuditEntry{}, err
+		}
+		if _, err = l.file.Write(append(data, '\n')); err != nil {
+			l.err = fmt.Errorf("can't write audit log: %w", err)
+			return model.AuditEntry{}, l.err
+		}
+		if err = l.file.Sync(); err != nil {
+			l.err = fmt.Errorf("can't sync audit log: %w", err)
+			return model.AuditEntry{}, l.err
+		}
+	}
+	l.seq, l.last = e.Seq, e.Hash
+	if l.file == nil {
+		l.entries = append(l.entries, e)
+		return e, nil
+	}
+	if err = l.writeHead(); err != nil {
+		l.err = err
+		return model.AuditEntry{}, err
+	}
+	return e, nil
+}
+
+// Begin appends the entry as pending before the change is applied, the change must not be applied
+// if it fails. Its outcome is recorded by Finish.
+func (l *Log) Begin(e model.AuditEntry) (model.AuditEntry, error) {
+	e.Outcome, e.Ref = model.AuditPending, 0
+	return l.Append(e)
+}
+
+// Finish appends the outcome of the change begun with the pending entry, with actor, request, action
+// and resource of the pending one. The outcome is model.AuditDone if it's not set.
+func (l *Log) Finish(pending, e model.AuditEntry) (model.AuditEntry, error) {
+	e.Actor, e.RequestID, e.Action, e.Method = pending.Actor, pending.RequestID, pending.Action, pending.Method
+	e.Resource, e.Ref = pending.Resource, pending.Seq
+	if e.Outcome == "" || e.Outcome == model.AuditPending {
+		e.Outcome = model.AuditDone
+	}
+	return l.Append(e)
+}
+
+// Entries returns entries matching the filter, newest first, at most limit of them if it's positive.
+// The file is read from the start, keeping only the limit of the newest matching entries, so pages of
+// older entries are selected with the filter's Before.
+func (l *Log) Entries(filter model.AuditFilter, limit int) ([]model.AuditEntry, error) {
+	l.mu.Lock()
+	seq, file, entries := l.seq, l.file != nil, l.entries
+	l.mu.Unlock()
+
+	var res []model.AuditEntry
+	next := 0 // in res of the oldest entry, once res has limit of them
+	add := func(e model.AuditEntry) {
+		switch {
+		case !filter.Match(e):
+		case limit <= 0 || len(res) < limit:
+			res = append(res, e)
+		default:
+			res[next], next = e, (next+1)%limit
+		}
+	}
+	if file && seq > 0 {
+		// entries are appended while the file is read, it's read up to the last entry at the start
+		err := scan(l.path, func(e model.AuditEntry) error {
+			if filter.Before > 0 && e.Seq >= filter.Before {
+				return errStop
+			}
+			add(e)
+			if e.Seq >= seq {
+				return errStop
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, e := range entries {
+		add(e)
+	}
+
+	sorted := make([]model.AuditEntry, 0, len(res))
+	for i := len(res) - 1; i >= 0; i-- {
+		sorted = append(sorted, res[(next+i)%len(res)])
+	}
+	return sorted, nil
+}
+
+// Verify checks the hash chain of the log file against its head, or of the entries in memory for the log
+// without file, and returns the number of entries. The file must have all the entries appended to the log.
+func (l *Log) Verify() (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		c := chain{l: l}
+		for _, e := range l.entries {
+			if err := c.add(e); err != nil {
+				return 0, err
+			}
+		}
+		return int(c.seq), nil
+	}
+	c, err := l.verify()
+	if err != nil {
+		return 0, err
+	}
+	if c.seq != l.seq || c.last != l.last {
+		return 0, fmt.Errorf("file has %d entries, %d appended: %w", c.seq, l.seq, ErrBroken)
+	}
+	return int(c.seq), nil
+}
+
+// Head returns sequence number and hash of the last entry, to keep outside the log and compare with later
+func (l *Log) Head() (int64, string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.seq, l.last
+}
+
+// Close closes the log file, the log takes no more entries after it
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file, l.err = nil, errors.New("audit log is closed")
+	return err
+}
+
+// sign returns hex HMAC-SHA256 of the entry's JSON without the hash itself, PrevHash included
+func (l *Log) sign(e model.AuditEntry) (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, l.key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// headMAC returns hex HMAC-SHA256 of the head's sequence number and hash
+func (l *Log) headMAC(h head) string {
+	mac := hmac.New(sha256.New, l.key)
+	mac.Write([]byte("head\n" + strconv.FormatInt(h.Seq, 10) + "\n" + h.Hash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify reads the log file, checks the chain of its entries and that they reach the head, and returns
+// the end of the chain. Missing file is an empty log, missing head file is fine only for the empty log.
+func (l *Log) verify() (chain, error) {
+	h := head{}
+	data, err := os.ReadFile(l.head)
+	noHead := errors.Is(err, os.ErrNotExist)
+	if err != nil && !noHead {
+		return chain{}, err
+	}
+	if !noHead {
+		if err = json.Unmarshal(data, &h); err != nil {
+			return chain{}, fmt.Errorf("head file %s: %v: %w", l.head, err, ErrBroken)
+		}
+		if !hmac.Equal([]byte(h.MAC), []byte(l.headMAC(h))) {
+			return chain{}, fmt.Errorf("head file %s is changed: %w", l.head, ErrBroken)
+		}
+	}
+
+	c, atHead := chain{l: l}, ""
+	err = scan(l.path, func(e model.AuditEntry) error {
+		if e.Seq == h.Seq {
+			atHead = e.Hash
+		}
+		return c.add(e)
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return chain{}, err
+	}
+	switch {
+	case noHead && c.seq > 0:
+		return chain{}, fmt.Errorf("head file %s is missing: %w", l.head, ErrBroken)
+	case h.Seq > c.seq:
+		return chain{}, fmt.Errorf("log has %d entries, head is at %d: %w", c.seq, h.Seq, ErrBroken)
+	case h.Seq > 0 && atHead != h.Hash:
+		return chain{}, fmt.Errorf("entry %d doesn't match the head: %w", h.Seq, ErrBroken)
+	}
+	return c, nil
+}
+
+// chain checks sequence numbers and signatures of the entries added in order
+type chain struct {
+	l    *Log
+	seq  int64  // of the last entry
+	last string // hash of the last entry
+}
+
+// add checks that the entry follows the last one and is signed with the log's key
+func (c *chain) add(e model.AuditEntry) error {
+	if e.Seq != c.seq+1 {
+		return fmt.Errorf("entry %d has sequence number %d: %w", c.seq+1, e.Seq, ErrBroken)
+	}
+	if e.PrevHash != c.last {
+		return fmt.Errorf("entry %d doesn't follow the previous one: %w", e.Seq, ErrBroken)
+	}
+	hash, err := c.l.sign(e)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(hash), []byte(e.Hash)) {
+		return fmt.Errorf("entry %d is changed: %w", e.Seq, ErrBroken)
+	}
+	c.seq, c.last = e.Seq, e.Hash
+	return nil
+}
+
+// writeHead replaces the head file with the last entry, caller should hold the lock
+func (l *Log) writeHead() error {
+	h := head{Seq: l.seq, Hash: l.last}
+	h.MAC = l.headMAC(h)
+	data, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(l.head), filepath.Base(l.head)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("can't write audit log head: %w", err)
+	}
+	defer os.Remove(f.Name()) //nolint
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), l.head)
+	}
+	if err != nil {
+		return fmt.Errorf("can't write audit log head: %w", err)
+	}
+	return nil
+}
+
+// scan reads entries of the log file one by one, one JSON per line, until fn returns an error.
+// Returns nil if fn stops it with errStop.
+func scan(path string, fn func(e model.AuditEntry) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		e := model.AuditEntry{}
+		if err = json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return fmt.Errorf("line %d: %v: %w", line, err, ErrBroken)
+		}
+		if err = fn(e); err != nil {
+			if errors.Is(err, errStop) {
+				return nil
+			}
+			return err
+		}
+	}
+	return sc.Err()
+}
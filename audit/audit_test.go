@@ -0,0 +1,137 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	cfg := Config{Path: path, Key: []byte("secret")}
+	_, err := Open(Config{Path: path})
+	assert.Error(t, err, "no key")
+	l, err := Open(cfg)
+	require.NoError(t, err)
+	entries := func(filter model.AuditFilter, limit int) []model.AuditEntry {
+		res, err := l.Entries(filter, limit)
+		require.NoError(t, err)
+		return res
+	}
+
+	first, err := l.Append(model.AuditEntry{Actor: "user alice", Action: model.AuditUpload, Resource: "/transactions",
+		After: json.RawMessage(`{"accepted":10}`)})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), first.Seq)
+	assert.Empty(t, first.PrevHash)
+	assert.Len(t, first.Hash, 64)
+	second, err := l.Append(model.AuditEntry{Time: time.Date(2020, 7, 1, 0, 0, 0, 0, time.Local), Actor: "user bob",
+		Action: model.AuditUpdate, Resource: "/transactions/1", Before: json.RawMessage(`{"category":""}`),
+		After: json.RawMessage(`{"category":"Fuel"}`)})
+	require.NoError(t, err)
+	assert.Equal(t, first.Hash, second.PrevHash)
+
+	assert.Equal(t, []model.AuditEntry{second, first}, entries(model.AuditFilter{}, 0), "newest first")
+	assert.Equal(t, []model.AuditEntry{second}, entries(model.AuditFilter{}, 1))
+	assert.Equal(t, []model.AuditEntry{first}, entries(model.AuditFilter{Actor: "user alice"}, 0))
+	assert.Equal(t, []model.AuditEntry{second}, entries(model.AuditFilter{Resource: "/transactions/"}, 0))
+	assert.Equal(t, []model.AuditEntry{second}, entries(model.AuditFilter{To: time.Date(2020, 7, 2, 0, 0, 0, 0, time.Local)}, 0))
+	assert.Empty(t, entries(model.AuditFilter{Action: model.AuditDelete}, 0))
+	assert.Equal(t, []model.AuditEntry{first}, entries(model.AuditFilter{Before: 2}, 1), "the next page")
+	assert.Empty(t, entries(model.AuditFilter{Before: 1}, 1))
+
+	n, err := l.Verify()
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	require.NoError(t, l.Close())
+
+	_, err = Open(Config{Path: path, Key: []byte("other")})
+	assert.ErrorIs(t, err, ErrBroken, "signed with other key")
+	l, err = Open(cfg)
+	require.NoError(t, err, "reopened")
+	third, err := l.Begin(model.AuditEntry{Actor: "inbox", Action: model.AuditUpload, Resource: "july.csv",
+		BodySHA256: "abc", BodyBytes: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), third.Seq)
+	assert.Equal(t, model.AuditPending, third.Outcome)
+	assert.Equal(t, second.Hash, third.PrevHash)
+	fourth, err := l.Finish(third, model.AuditEntry{After: json.RawMessage(`{"accepted":3}`)})
+	require.NoError(t, err)
+	assert.Equal(t, model.AuditEntry{Seq: 4, Time: fourth.Time, Actor: "inbox", Action: model.AuditUpload,
+		Resource: "july.csv", Outcome: model.AuditDone, Ref: 3, After: json.RawMessage(`{"accepted":3}`),
+		PrevHash: third.Hash, Hash: fourth.Hash}, fourth)
+	assert.Equal(t, []model.AuditEntry{third}, entries(model.AuditFilter{Outcome: model.AuditPending}, 0))
+	assert.Equal(t, []model.AuditEntry{fourth, third}, entries(model.AuditFilter{}, 2), "the newest, read from the file")
+	assert.Equal(t, []model.AuditEntry{third, second}, entries(model.AuditFilter{Before: 4}, 2))
+	seq, hash := l.Head()
+	assert.Equal(t, int64(4), seq)
+	assert.Equal(t, fourth.Hash, hash)
+
+	// tamper with the file while the log is open
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	tampered := bytes.Replace(data, []byte(`"Fuel"`), []byte(`"Food"`), 1)
+	require.NoError(t, os.WriteFile(path, tampered, 0o600))
+	_, err = l.Verify()
+	assert.ErrorIs(t, err, ErrBroken)
+	assert.Contains(t, err.Error(), "entry 2 is changed")
+	require.NoError(t, l.Close())
+	_, err = Open(cfg)
+	assert.ErrorIs(t, err, ErrBroken)
+
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	require.NoError(t, os.WriteFile(path, append(lines[0], lines[2]...), 0o600))
+	_, err = Open(cfg)
+	assert.ErrorIs(t, err, ErrBroken, "removed entry")
+	require.NoError(t, os.WriteFile(path, bytes.Join(lines[:3], nil), 0o600))
+	_, err = Open(cfg)
+	assert.ErrorIs(t, err, ErrBroken, "removed last entry")
+	assert.Contains(t, err.Error(), "log has 3 entries, head is at 4")
+
+	head, err := os.ReadFile(path + ".head")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path+".head", bytes.Replace(head, []byte(`"seq":4`), []byte(`"seq":3`), 1), 0o600))
+	_, err = Open(cfg)
+	assert.ErrorIs(t, err, ErrBroken, "head is changed")
+	require.NoError(t, os.Remove(path+".head"))
+	_, err = Open(cfg)
+	assert.ErrorIs(t, err, ErrBroken, "head is missing")
+
+	// the log has an entry after the head, i.e. crashed before writing the head
+	dir := t.TempDir()
+	cfg = Config{Path: filepath.Join(dir, "audit.log"), Head: filepath.Join(t.TempDir(), "audit.head"), Key: cfg.Key}
+	l, err = Open(cfg)
+	require.NoError(t, err)
+	_, err = l.Append(model.AuditEntry{Actor: "user alice", Action: model.AuditConfig, Resource: "/users"})
+	require.NoError(t, err)
+	head, err = os.ReadFile(cfg.Head)
+	require.NoError(t, err)
+	_, err = l.Append(model.AuditEntry{Actor: "user alice", Action: model.AuditConfig, Resource: "/keys"})
+	require.NoError(t, err)
+	require.NoError(t, l.Close())
+	require.NoError(t, os.WriteFile(cfg.Head, head, 0o600))
+	l, err = Open(cfg)
+	require.NoError(t, err)
+	seq, _ = l.Head()
+	assert.Equal(t, int64(2), seq)
+	require.NoError(t, l.Close())
+	head, err = os.ReadFile(cfg.Head)
+	require.NoError(t, err)
+	assert.Contains(t, string(head), `"seq":2`, "moved to the last entry")
+	_, err = os.Stat(filepath.Join(dir, "audit.log.head"))
+	assert.ErrorIs(t, err, os.ErrNotExist, "kept apart")
+
+	mem, err := Open(Config{Key: cfg.Key})
+	require.NoError(t, err)
+	_, err = mem.Append(model.AuditEntry{Actor: "user alice", Action: model.AuditConfig, Resource: "/users"})
+	require.NoError(t, err)
+	n, err = mem.Verify()
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
@@ -0,0 +1,41 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"github.com/mrnbort/summer_break/archive"
+	"github.com/mrnbort/summer_break/model"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// Export writes NDJSON archive of the stored state to w, see archive package for its format
+func (c *Client) Export(ctx context.Context, w io.Writer) error {
+	resp, err := c.do(ctx, request{method: http.MethodGet, path: "/export", accept: archive.ContentType})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// Import merges the archive made by Export into the stored state. Records stored with different content are
+// resolved by policy, model.ImportFail, model.ImportSkip or model.ImportReplace. With fail policy conflicting
+// records are listed in Conflicts of *Error matching model.ErrConflict, and nothing is imported.
+func (c *Client) Import(ctx context.Context, r io.Reader, policy string) (model.ImportResult, error) {
+	data, err := io.ReadAll(r) // kept for retries
+	if err != nil {
+		return model.ImportResult{}, err
+	}
+	req := request{method: http.MethodPost, path: "/import", query: url.Values{"policy": {policy}},
+		contentType: archive.ContentType, body: func() (io.Reader, error) { return bytes.NewReader(data), nil }}
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return model.ImportResult{}, err
+	}
+	res := model.ImportResult{}
+	err = decode(resp, &res)
+	return res, err
+}
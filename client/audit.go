@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"github.com/mrnbort/summer_break/model"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Audit returns audit log entries selected by the filter, newest first, at most limit of them if it's positive,
+// the server's default number otherwise. Only dates of From and To are sent, the To day is included. The next
+// page is selected with Before set to the sequence number of the last returned entry.
+func (c *Client) Audit(ctx context.Context, filter model.AuditFilter, limit int) ([]model.AuditEntry, error) {
+	q := url.Values{}
+	if !filter.From.IsZero() {
+		q.Set("from", formatDate(filter.From))
+	}
+	if !filter.To.IsZero() {
+		q.Set("to", formatDate(filter.To))
+	}
+	for k, v := range map[string]string{"actor": filter.Actor, "action": filter.Action,
+		"resource": filter.Resource, "requestId": filter.RequestID, "outcome": filter.Outcome} {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	if filter.Before > 0 {
+		q.Set("before", strconv.FormatInt(filter.Before, 10))
+	}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	var res []model.AuditEntry
+	err := c.call(ctx, http.MethodGet, "/audit", q, nil, &res)
+	return res, err
+}
+
+// VerifyAudit checks the hash chain of the audit log and returns the number of entries,
+// error with status 500 if the log was tampered with
+func (c *Client) VerifyAudit(ctx context.Context) (int, error) {
+	res := struct {
+		Entries int `json:"entries"`
+	}{}
+	err := c.call(ctx, http.MethodGet, "/audit/verify", nil, nil, &res)
+	return res.Entries, err
+}
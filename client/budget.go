@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"github.com/mrnbort/summer_break/model"
+	"net/http"
+	"net/url"
+)
+
+// Budgets returns all budgets sorted by category
+func (c *Client) Budgets(ctx context.Context) ([]model.Budget, error) {
+	var res []model.Budget
+	err := c.call(ctx, http.MethodGet, "/budgets", nil, nil, &res)
+	return res, err
+}
+
+// Budget returns budget with given id
+func (c *Client) Budget(ctx context.Context, id string) (model.Budget, error) {
+	res := model.Budget{}
+	err := c.call(ctx, http.MethodGet, "/budgets/"+url.PathEscape(id), nil, nil, &res)
+	return res, err
+}
+
+// AddBudget adds budget and returns it with assigned id
+func (c *Client) AddBudget(ctx context.Context, b model.Budget) (model.Budget, error) {
+	res := model.Budget{}
+	err := c.call(ctx, http.MethodPost, "/budgets", nil, b, &res)
+	return res, err
+}
+
+// UpdateBudget replaces category, period and amount of the budget with b.ID
+func (c *Client) UpdateBudget(ctx context.Context, b model.Budget) (model.Budget, error) {
+	res := model.Budget{}
+	err := c.call(ctx, http.MethodPut, "/budgets/"+url.PathEscape(b.ID), nil, b, &res)
+	return res, err
+}
+
+// DeleteBudget deletes budget with given id
+func (c *Client) DeleteBudget(ctx context.Context, id string) error {
+	return c.call(ctx, http.MethodDelete, "/budgets/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// Schedules returns all recurring transaction schedules
+func (c *Client) Schedules(ctx context.Context) ([]model.Schedule, error) {
+	var res []model.Schedule
+	err := c.call(ctx, http.MethodGet, "/schedules", nil, nil, &res)
+	return res, err
+}
+
+// AddSchedule adds recurring transaction and returns it with assigned id and the next occurrence
+func (c *Client) AddSchedule(ctx context.Context, sch model.Schedule) (model.Schedule, error) {
+	req := struct {
+		Account  string       `json:"account,omitempty"`
+		Type     model.TrType `json:"type"`
+		Amount   float64      `json:"amount"`
+		Memo     string       `json:"memo"`
+		Tags     []string     `json:"tags,omitempty"`
+		Category string       `json:"category,omitempty"`
+		Freq     string       `json:"freq"`
+		Interval int          `json:"interval,omitempty"`
+		Day      int          `json:"day,omitempty"`
+		Start    string       `json:"start"`
+		End      string       `json:"end,omitempty"`
+	}{Account: sch.Account, Type: sch.Type, Amount: sch.Amount, Memo: sch.Memo, Tags: sch.Tags, Category: sch.Category,
+		Freq: sch.Freq, Interval: sch.Interval, Day: sch.Day, Start: formatDate(sch.Start), End: formatDate(sch.End)}
+	res := model.Schedule{}
+	err := c.call(ctx, http.MethodPost, "/schedules", nil, req, &res)
+	return res, err
+}
+
+// DeleteSchedule deletes schedule with given id, transactions already made from it are kept
+func (c *Client) DeleteSchedule(ctx context.Context, id string) error {
+	return c.call(ctx, http.MethodDelete, "/schedules/"+url.PathEscape(id), nil, nil, nil)
+}
@@ -0,0 +1,250 @@
+// Package client is a typed Go client of the summer break service API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/mrnbort/summer_break/model"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// idempotencyHeader has the key of change request, the service replays the response on retries with the same key
+const idempotencyHeader = "Idempotency-Key"
+
+// apiKeyHeader has API key of the client
+const apiKeyHeader = "X-API-Key"
+
+// default client settings
+const (
+	DefaultRetries    = 3
+	DefaultRetryDelay = 500 * time.Millisecond
+	DefaultTimeout    = time.Minute
+)
+
+// Client calls the service API. Failed requests are retried on network errors, 429 and 502-504 responses,
+// waiting as asked by Retry-After header or increasing delay. Every change request has an idempotency key,
+// kept for its retries, so it's not applied twice if the response is lost.
+type Client struct {
+	BaseURL    string
+	APIKey     string        // sent as X-API-Key header, if set
+	Token      string        // session token set by Login, sent as Authorization header
+	HTTPClient *http.Client  // http.Client with DefaultTimeout if not set
+	Retries    int           // retries of a failed request, DefaultRetries if not set, negative for none
+	RetryDelay time.Duration // delay before the first retry, doubled on every next one, DefaultRetryDelay if not set
+}
+
+// New makes a client of the service at baseURL, i.e. "http://127.0.0.1:8080"
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), HTTPClient: &http.Client{Timeout: DefaultTimeout}}
+}
+
+// Error is an error response of the service. It matches model.ErrNotFound and model.ErrConflict
+// for 404 and 409 responses with errors.Is, 401 and 403 ones are missing or insufficient API key.
+type Error struct {
+	StatusCode int
+	Message    string
+	Errors     []model.LineError // skipped lines of rejected upload
+	Conflicts  []string          // conflicting records of rejected import
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("status %d: %s", e.StatusCode, e.Message)
+}
+
+// Is makes errors.Is(err, model.ErrNotFound) work for responses with matching status
+func (e *Error) Is(target error) bool {
+	switch target {
+	case model.ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case model.ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case model.ErrDoubleEntryDisabled:
+		return e.StatusCode == http.StatusNotImplemented
+	}
+	return false
+}
+
+// request is an API request, body makes a new reader of the request body for every attempt
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	contentType string
+	body        func() (io.Reader, error)
+	once        bool // body can be read only once, the request is not retried
+	accept      string
+}
+
+// jsonRequest makes request with JSON body of v, nil v for no body
+func jsonRequest(method, path string, query url.Values, v interface{}) (request, error) {
+	req := request{method: method, path: path, query: query}
+	if v == nil {
+		return req, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return request{}, err
+	}
+	req.contentType = "application/json"
+	req.body = func() (io.Reader, error) { return bytes.NewReader(data), nil }
+	return req, nil
+}
+
+// call makes the JSON request and decodes the response into out, if it's not nil
+func (c *Client) call(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	req, err := jsonRequest(method, path, query, in)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return resp.Body.Close()
+	}
+	return decode(resp, out)
+}
+
+// do sends the request with retries and returns successful response, error responses are returned as *Error
+func (c *Client) do(ctx context.Context, req request) (*http.Response, error) {
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: DefaultTimeout}
+	}
+	retries := c.Retries
+	if retries == 0 {
+		retries = DefaultRetries
+	}
+	delay := c.RetryDelay
+	if delay <= 0 {
+		delay = DefaultRetryDelay
+	}
+	key := ""
+	if req.method != http.MethodGet {
+		key = newKey()
+	}
+
+	u := c.BaseURL + req.path
+	if len(req.query) > 0 {
+		u += "?" + req.query.Encode()
+	}
+	for attempt := 0; ; attempt++ {
+		var body io.Reader
+		if req.body != nil {
+			var err error
+			if body, err = req.body(); err != nil {
+				return nil, err
+			}
+		}
+		r, err := http.NewRequestWithContext(ctx, req.method, u, body)
+		if err != nil {
+			return nil, err
+		}
+		if req.contentType != "" {
+			r.Header.Set("Content-Type", req.contentType)
+		}
+		if req.accept != "" {
+			r.Header.Set("Accept", req.accept)
+		}
+		if key != "" {
+			r.Header.Set(idempotencyHeader, key)
+		}
+		if c.APIKey != "" {
+			r.Header.Set(apiKeyHeader, c.APIKey)
+		}
+		if c.Token != "" {
+			r.Header.Set("Authorization", "Bearer "+c.Token)
+		}
+
+		resp, err := httpClient.Do(r)
+		wait := delay << uint(attempt)
+		switch {
+		case err != nil && ctx.Err() != nil:
+			return nil, ctx.Err()
+		case err != nil:
+			err = fmt.Errorf("%s %s: %w", req.method, req.path, err)
+		case resp.StatusCode < 300:
+			return resp, nil
+		default:
+			err = responseError(resp)
+			if after := retryAfter(resp); after > 0 {
+				wait = after
+			}
+			if !retryable(resp.StatusCode) {
+				return nil, err
+			}
+		}
+		if attempt >= retries || req.once {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// responseError makes *Error from error response
+func responseError(resp *http.Response) error {
+	defer resp.Body.Close()
+	e := &Error{StatusCode: resp.StatusCode}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	body := struct {
+		Error     string            `json:"error"`
+		Errors    []model.LineError `json:"errors"`
+		Conflicts []string          `json:"conflicts"`
+	}{}
+	if json.Unmarshal(data, &body) == nil {
+		e.Message, e.Errors, e.Conflicts = body.Error, body.Errors, body.Conflicts
+	} else {
+		e.Message = strings.TrimSpace(string(data))
+	}
+	return e
+}
+
+// retryable tells the request failed with status may succeed on retry
+func retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter returns delay from Retry-After header in seconds or HTTP date, zero if there is none
+func retryAfter(resp *http.Response) time.Duration {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// newKey makes random idempotency key
+func newKey() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,291 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/mrnbort/summer_break/api"
+	"github.com/mrnbort/summer_break/audit"
+	"github.com/mrnbort/summer_break/ingest"
+	"github.com/mrnbort/summer_break/jobs"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/mrnbort/summer_break/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func date(y, m, d int) time.Time {
+	return time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.Local)
+}
+
+func TestClient(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	proc := processor.NewProc()
+	manager := &jobs.Manager{Pipeline: ingest.Pipeline{Store: proc}, Dir: t.TempDir()}
+	go func() { _ = manager.Run(ctx) }()
+	ts := httptest.NewServer(api.Service{Processor: proc, Jobs: manager}.Handler())
+	defer ts.Close()
+	c := New(ts.URL + "/")
+
+	f, err := os.Open("../testdata/data.csv")
+	require.NoError(t, err)
+	defer f.Close()
+	res, err := c.UploadCSV(ctx, f)
+	require.NoError(t, err)
+	assert.Equal(t, 10, res.Accepted)
+	assert.Equal(t, 1, res.Rejected)
+	assert.Equal(t, 4, res.Errors[0].Line)
+
+	report, err := c.Report(ctx, model.Filter{To: date(2020, 7, 15)})
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, report.GrossRevenue)
+	assert.InDelta(t, 46.27, report.Expenses, 0.001)
+
+	_, err = c.UploadAccountCSV(ctx, "card", strings.NewReader("bad line\n"))
+	apiErr := &Error{}
+	require.True(t, errors.As(err, &apiErr), "%v", err)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Equal(t, "no valid transactions", apiErr.Message)
+	assert.Len(t, apiErr.Errors, 1)
+
+	flagged, err := c.AddTransactions(ctx, "card", []model.Transaction{
+		{Date: date(2020, 8, 1), Type: model.Expense, Amount: 12, Memo: "Shell", Tags: []string{"truck"}, Category: "Fuel"},
+	})
+	require.NoError(t, err)
+	assert.Len(t, flagged, 1, "old transaction")
+	lines, err := c.Transactions(ctx, model.Filter{Accounts: []string{"card"}, Tags: mustTags(t, "truck")})
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "Fuel", lines[0].Category)
+	assert.Equal(t, -12.0, lines[0].RunningBalance)
+	forecast, err := c.Forecast(ctx, model.ForecastRequest{Accounts: []string{"card"}, Tags: mustTags(t, "truck"),
+		From: date(2020, 8, 1), Horizon: 1, History: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []model.CategoryAverage{{Category: "Fuel", Type: model.Expense, Daily: 12}}, forecast.Averages)
+	forecast, err = c.Forecast(ctx, model.ForecastRequest{Accounts: []string{"card"}, Tags: mustTags(t, "lawn"),
+		From: date(2020, 8, 1), Horizon: 1, History: 1})
+	require.NoError(t, err)
+	assert.Empty(t, forecast.Averages, "no lawn transactions")
+
+	category := "Gas"
+	tr, err := c.UpdateTransaction(ctx, lines[0].ID, model.TransactionUpdate{Category: &category})
+	require.NoError(t, err)
+	assert.Equal(t, "Gas", tr.Category)
+	_, err = c.UpdateTransaction(ctx, "unknown", model.TransactionUpdate{Category: &category})
+	assert.ErrorIs(t, err, model.ErrNotFound)
+
+	_, err = c.SetOpeningBalance(ctx, "card", 100, date(2020, 7, 31))
+	require.NoError(t, err)
+	accounts, err := c.Accounts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"card", "default"}, []string{accounts[0].Name, accounts[1].Name})
+	balance, err := c.Balance(ctx, []string{"card"}, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, 88.0, balance.Balance)
+
+	budget, err := c.AddBudget(ctx, model.Budget{Category: "Gas", Period: model.BudgetMonthly, Amount: 50})
+	require.NoError(t, err)
+	budget.Amount = 60
+	_, err = c.UpdateBudget(ctx, budget)
+	require.NoError(t, err)
+	budgetReport, err := c.BudgetReport(ctx, model.Filter{From: date(2020, 8, 1), To: date(2020, 8, 31)})
+	require.NoError(t, err)
+	assert.Equal(t, 12.0, budgetReport.Total.Actual)
+	require.NoError(t, c.DeleteBudget(ctx, budget.ID))
+	_, err = c.Budget(ctx, budget.ID)
+	assert.ErrorIs(t, err, model.ErrNotFound)
+
+	sch, err := c.AddSchedule(ctx, model.Schedule{Type: model.Expense, Amount: 1200, Memo: "Rent", Freq: model.FreqMonthly,
+		Start: date(2030, 1, 1)})
+	require.NoError(t, err)
+	assert.True(t, date(2030, 1, 1).Equal(sch.Next), sch.Next)
+	require.NoError(t, c.DeleteSchedule(ctx, sch.ID))
+
+	pdf, err := c.Download(ctx, "/report", model.Filter{}, "csv")
+	require.NoError(t, err)
+	data, err := io.ReadAll(pdf)
+	require.NoError(t, err)
+	require.NoError(t, pdf.Close())
+	assert.Contains(t, string(data), "Net revenue")
+
+	job, err := c.UploadAsync(ctx, "cash", strings.NewReader("2020-07-04,Income,40.00,347 Woodrow\n"))
+	require.NoError(t, err)
+	job, err = c.WaitJob(ctx, job.ID, 10*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, model.JobDone, job.Status)
+	_, err = c.CancelJob(ctx, job.ID)
+	assert.ErrorIs(t, err, model.ErrConflict)
+
+	_, err = c.ChartOfAccounts(ctx)
+	assert.ErrorIs(t, err, model.ErrDoubleEntryDisabled)
+	me, err := c.Me(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "anonymous", me.Name, "auth is disabled")
+
+	archive := strings.Builder{}
+	require.NoError(t, c.Export(ctx, &archive))
+	imported, err := c.Import(ctx, strings.NewReader(archive.String()), model.ImportFail)
+	require.NoError(t, err)
+	assert.Equal(t, 0, imported.Added+imported.Replaced+imported.Skipped, "the same state")
+	category = "Fuel"
+	_, err = c.UpdateTransaction(ctx, lines[0].ID, model.TransactionUpdate{Category: &category})
+	require.NoError(t, err)
+	_, err = c.Import(ctx, strings.NewReader(archive.String()), model.ImportFail)
+	require.True(t, errors.As(err, &apiErr), "%v", err)
+	assert.ErrorIs(t, err, model.ErrConflict)
+	assert.Equal(t, []string{"transaction " + lines[0].ID}, apiErr.Conflicts)
+	imported, err = c.Import(ctx, strings.NewReader(archive.String()), model.ImportReplace)
+	require.NoError(t, err)
+	assert.Equal(t, 1, imported.Replaced)
+}
+
+func TestClient_retries(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var calls int32
+	keys := map[string]bool{}
+	bodies := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		keys[r.Header.Get("Idempotency-Key")] = true
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, `{"accepted":1,"rejected":0,"status":"ok"}`)
+	}))
+	defer ts.Close()
+	c := &Client{BaseURL: ts.URL, RetryDelay: time.Millisecond}
+
+	f, err := os.CreateTemp(t.TempDir(), "*.csv")
+	require.NoError(t, err)
+	_, err = f.WriteString("2020-07-04,Income,40.00,347 Woodrow\n")
+	require.NoError(t, err)
+	_, err = f.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	defer f.Close()
+
+	res, err := c.UploadCSV(ctx, f)
+	require.NoError(t, err)
+	assert.Equal(t, 1, res.Accepted)
+	assert.Equal(t, int32(3), calls)
+	assert.Len(t, keys, 1, "the same key for all attempts")
+	assert.NotContains(t, keys, "")
+	require.Len(t, bodies, 3)
+	assert.Contains(t, bodies[2], "347 Woodrow")
+	assert.Equal(t, bodies[0], bodies[2], "the same body for all attempts")
+
+	atomic.StoreInt32(&calls, 0)
+	_, err = c.UploadCSV(ctx, io.MultiReader(strings.NewReader("2020-07-04,Income,40.00,347 Woodrow\n")))
+	assert.Error(t, err, "reader can't be sent again")
+	assert.Equal(t, int32(1), calls)
+
+	atomic.StoreInt32(&calls, -10)
+	c.Retries = 2
+	_, err = c.Accounts(ctx)
+	apiErr := &Error{}
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+	assert.Equal(t, int32(-7), calls, "first attempt and two retries")
+}
+
+func TestClient_apiKeys(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	auditLog, err := audit.Open(audit.Config{Key: []byte("secret")})
+	require.NoError(t, err)
+	ts := httptest.NewServer(api.Service{Processor: processor.NewProc(), Auth: true, AdminKey: "bootstrap", Audit: auditLog}.Handler())
+	defer ts.Close()
+	c := New(ts.URL)
+
+	_, err = c.Accounts(ctx)
+	apiErr := &Error{}
+	require.True(t, errors.As(err, &apiErr), "%v", err)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+
+	c.APIKey = "bootstrap"
+	key, err := c.AddAPIKey(ctx, "reports", []string{model.ScopeReadReport})
+	require.NoError(t, err)
+	assert.NotEmpty(t, key.Key)
+	keys, err := c.APIKeys(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Empty(t, keys[0].Key)
+
+	reader := New(ts.URL)
+	reader.APIKey = key.Key
+	_, err = reader.Accounts(ctx)
+	require.NoError(t, err)
+	_, err = reader.AddTransactions(ctx, "", []model.Transaction{{Date: date(2020, 8, 1), Type: model.Expense, Amount: 12, Memo: "Shell"}})
+	require.True(t, errors.As(err, &apiErr), "%v", err)
+	assert.Equal(t, http.StatusForbidden, apiErr.StatusCode)
+
+	require.NoError(t, c.DeleteAPIKey(ctx, key.ID))
+	_, err = reader.Accounts(ctx)
+	require.True(t, errors.As(err, &apiErr), "%v", err)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+
+	_, err = c.AddUser(ctx, model.User{Name: "bob", Role: model.RoleBookkeeper, Ledgers: []string{"card"}}, "bob's password")
+	require.NoError(t, err)
+	bob := New(ts.URL)
+	_, err = bob.Login(ctx, "bob", "wrong password")
+	require.True(t, errors.As(err, &apiErr), "%v", err)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+	sess, err := bob.Login(ctx, "bob", "bob's password")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"card"}, sess.User.Ledgers)
+	me, err := bob.Me(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, Caller{Name: "user bob", Scopes: []string{model.ScopeReadReport, model.ScopeWriteTransactions},
+		Ledgers: []string{"card"}}, me)
+	_, err = bob.AddTransactions(ctx, "card", []model.Transaction{{Date: date(2020, 8, 1), Type: model.Expense, Amount: 12, Memo: "Shell"}})
+	require.NoError(t, err)
+	_, err = bob.AddTransactions(ctx, "cash", []model.Transaction{{Date: date(2020, 8, 1), Type: model.Expense, Amount: 12, Memo: "Shell"}})
+	require.True(t, errors.As(err, &apiErr), "%v", err)
+	assert.Equal(t, http.StatusForbidden, apiErr.StatusCode)
+	report, err := bob.Report(ctx, model.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, 12.0, report.Expenses)
+
+	_, err = c.UpdateUser(ctx, model.User{Name: "bob", Role: model.RoleViewer}, "")
+	require.NoError(t, err)
+	users, err := c.Users(ctx)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Empty(t, users[0].PasswordHash)
+	require.NoError(t, c.DeleteUser(ctx, "bob"))
+	_, err = bob.Report(ctx, model.Filter{})
+	require.True(t, errors.As(err, &apiErr), "%v", err)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+
+	entries, err := c.Audit(ctx, model.AuditFilter{Actor: "user bob", Outcome: model.AuditDone}, 10)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, model.AuditUpload, entries[0].Action)
+	assert.Equal(t, "/accounts/card/transactions", entries[0].Resource)
+	entries, err = c.Audit(ctx, model.AuditFilter{Action: model.AuditConfig, Resource: "/users", Outcome: model.AuditDone}, 0)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+	n, err := c.VerifyAudit(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 12, n)
+}
+
+func mustTags(t *testing.T, s string) model.TagExpr {
+	expr, err := model.ParseTagExpr(s)
+	require.NoError(t, err)
+	return expr
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"github.com/mrnbort/summer_break/model"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Jobs returns async upload jobs in the order they were submitted
+func (c *Client) Jobs(ctx context.Context) ([]model.Job, error) {
+	var res []model.Job
+	err := c.call(ctx, http.MethodGet, "/jobs", nil, nil, &res)
+	return res, err
+}
+
+// Job returns async upload job with given id
+func (c *Client) Job(ctx context.Context, id string) (model.Job, error) {
+	res := model.Job{}
+	err := c.call(ctx, http.MethodGet, "/jobs/"+url.PathEscape(id), nil, nil, &res)
+	return res, err
+}
+
+// CancelJob cancels queued or running job, nothing of it is stored
+func (c *Client) CancelJob(ctx context.Context, id string) (model.Job, error) {
+	res := model.Job{}
+	err := c.call(ctx, http.MethodDelete, "/jobs/"+url.PathEscape(id), nil, nil, &res)
+	return res, err
+}
+
+// WaitJob polls the job every interval until it's finished
+func (c *Client) WaitJob(ctx context.Context, id string, interval time.Duration) (model.Job, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		job, err := c.Job(ctx, id)
+		if err != nil || job.IsFinished() {
+			return job, err
+		}
+		select {
+		case <-ctx.Done():
+			return job, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package client
+
+import (
+	"context"
+	"github.com/mrnbort/summer_break/model"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ChartOfAccounts returns accounts of the double-entry chart, the service responds with
+// an error matching model.ErrDoubleEntryDisabled if the mode is off
+func (c *Client) ChartOfAccounts(ctx context.Context) ([]model.ChartAccount, error) {
+	var res []model.ChartAccount
+	err := c.call(ctx, http.MethodGet, "/journal/accounts", nil, nil, &res)
+	return res, err
+}
+
+// SetChartAccount adds or updates account in the chart of accounts
+func (c *Client) SetChartAccount(ctx context.Context, acc model.ChartAccount) (model.ChartAccount, error) {
+	res := model.ChartAccount{}
+	err := c.call(ctx, http.MethodPost, "/journal/accounts", nil, acc, &res)
+	return res, err
+}
+
+// JournalEntries returns journal entries matching the filter
+func (c *Client) JournalEntries(ctx context.Context, filter model.Filter) ([]model.JournalEntry, error) {
+	var res []model.JournalEntry
+	err := c.call(ctx, http.MethodGet, "/journal/entries", filterQuery(filter), nil, &res)
+	return res, err
+}
+
+// PostEntry posts balanced journal entry and returns it with assigned id
+func (c *Client) PostEntry(ctx context.Context, entry model.JournalEntry) (model.JournalEntry, error) {
+	req := struct {
+		Date     string          `json:"date"`
+		Memo     string          `json:"memo"`
+		Postings []model.Posting `json:"postings"`
+		Tags     []string        `json:"tags,omitempty"`
+	}{Date: formatDate(entry.Date), Memo: entry.Memo, Postings: entry.Postings, Tags: entry.Tags}
+	res := model.JournalEntry{}
+	err := c.call(ctx, http.MethodPost, "/journal/entries", nil, req, &res)
+	return res, err
+}
+
+// TrialBalance returns balances of chart accounts at the end of asOf day, zero asOf for all entries
+func (c *Client) TrialBalance(ctx context.Context, asOf time.Time) ([]model.ChartBalance, error) {
+	q := url.Values{}
+	if !asOf.IsZero() {
+		q.Set("asOf", formatDate(asOf))
+	}
+	var res []model.ChartBalance
+	err := c.call(ctx, http.MethodGet, "/journal/trial-balance", q, nil, &res)
+	return res, err
+}
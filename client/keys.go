@@ -0,0 +1,31 @@
+package client
+
+import (
+	"context"
+	"github.com/mrnbort/summer_break/model"
+	"net/http"
+	"net/url"
+)
+
+// APIKeys returns all API keys, without the keys themselves
+func (c *Client) APIKeys(ctx context.Context) ([]model.APIKey, error) {
+	var res []model.APIKey
+	err := c.call(ctx, http.MethodGet, "/keys", nil, nil, &res)
+	return res, err
+}
+
+// AddAPIKey makes API key with the scopes, the key is in Key of the result and can't be read again
+func (c *Client) AddAPIKey(ctx context.Context, name string, scopes []string) (model.APIKey, error) {
+	req := struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+	}{Name: name, Scopes: scopes}
+	res := model.APIKey{}
+	err := c.call(ctx, http.MethodPost, "/keys", nil, req, &res)
+	return res, err
+}
+
+// DeleteAPIKey revokes API key with given id
+func (c *Client) DeleteAPIKey(ctx context.Context, id string) error {
+	return c.call(ctx, http.MethodDelete, "/keys/"+url.PathEscape(id), nil, nil, nil)
+}
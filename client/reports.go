@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"github.com/mrnbort/summer_break/model"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Report returns gross revenue, expenses and net revenue of transactions matching the filter
+func (c *Client) Report(ctx context.Context, filter model.Filter) (model.Report, error) {
+	res := model.Report{}
+	err := c.call(ctx, http.MethodGet, "/report", filterQuery(filter), nil, &res)
+	return res, err
+}
+
+// Download returns report rendered by the service in "csv", "html" or "pdf" format. The path is
+// one of the report endpoints, i.e. "/report", "/reports/pnl" or "/reports/budget". Caller should close it.
+func (c *Client) Download(ctx context.Context, path string, filter model.Filter, format string) (io.ReadCloser, error) {
+	q := filterQuery(filter)
+	q.Set("format", format)
+	resp, err := c.do(ctx, request{method: http.MethodGet, path: path, query: q})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// ProfitAndLoss returns profit and loss statement of the period set by filter's From and To,
+// compare is empty, model.ComparePrevious or model.CompareLastYear
+func (c *Client) ProfitAndLoss(ctx context.Context, filter model.Filter, compare string) (model.PnL, error) {
+	q := filterQuery(filter)
+	if compare != "" {
+		q.Set("compare", compare)
+	}
+	res := model.PnL{}
+	err := c.call(ctx, http.MethodGet, "/reports/pnl", q, nil, &res)
+	return res, err
+}
+
+// Compare compares totals of the filter's period with the previous one
+func (c *Client) Compare(ctx context.Context, filter model.Filter, previous model.Period, byCategory bool) (model.Comparison, error) {
+	q := filterQuery(filter)
+	q.Set("prevFrom", formatDate(previous.From))
+	q.Set("prevTo", formatDate(previous.To))
+	q.Set("categories", strconv.FormatBool(byCategory))
+	res := model.Comparison{}
+	err := c.call(ctx, http.MethodGet, "/reports/compare", q, nil, &res)
+	return res, err
+}
+
+// BudgetReport returns budget vs actual expenses of the filter's period, the current month if it has no dates
+func (c *Client) BudgetReport(ctx context.Context, filter model.Filter) (model.BudgetReport, error) {
+	res := model.BudgetReport{}
+	err := c.call(ctx, http.MethodGet, "/reports/budget", filterQuery(filter), nil, &res)
+	return res, err
+}
+
+// Forecast returns cash flow forecast, zero req.From is today and zero horizon and history are 90 days
+func (c *Client) Forecast(ctx context.Context, req model.ForecastRequest) (model.Forecast, error) {
+	q := filterQuery(model.Filter{Accounts: req.Accounts, Tags: req.Tags})
+	if !req.From.IsZero() {
+		q.Set("asOf", formatDate(req.From))
+	}
+	if req.Horizon > 0 {
+		q.Set("horizon", strconv.Itoa(req.Horizon))
+	}
+	if req.History > 0 {
+		q.Set("history", strconv.Itoa(req.History))
+	}
+	if req.Step != "" {
+		q.Set("step", req.Step)
+	}
+	res := model.Forecast{}
+	err := c.call(ctx, http.MethodGet, "/reports/forecast", q, nil, &res)
+	return res, err
+}
+
+// TaxConfig returns fiscal year start and mapping of categories to tax lines
+func (c *Client) TaxConfig(ctx context.Context) (model.TaxConfig, error) {
+	res := model.TaxConfig{}
+	err := c.call(ctx, http.MethodGet, "/reports/tax/config", nil, nil, &res)
+	return res, err
+}
+
+// SetTaxConfig replaces the tax configuration
+func (c *Client) SetTaxConfig(ctx context.Context, cfg model.TaxConfig) (model.TaxConfig, error) {
+	res := model.TaxConfig{}
+	err := c.call(ctx, http.MethodPut, "/reports/tax/config", nil, cfg, &res)
+	return res, err
+}
+
+// TaxSummary returns totals per tax line of the tax year, dates of the filter are ignored
+func (c *Client) TaxSummary(ctx context.Context, year int, filter model.Filter) (model.TaxSummary, error) {
+	q := filterQuery(model.Filter{Accounts: filter.Accounts, Tags: filter.Tags, Flagged: filter.Flagged})
+	res := model.TaxSummary{}
+	err := c.call(ctx, http.MethodGet, "/reports/tax/"+url.PathEscape(strconv.Itoa(year)), q, nil, &res)
+	return res, err
+}
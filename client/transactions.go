@@ -0,0 +1,242 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/mrnbort/summer_break/model"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UploadResult is the result of CSV upload
+type UploadResult struct {
+	Accepted int                 `json:"accepted"`
+	Rejected int                 `json:"rejected"`
+	Errors   []model.LineError   `json:"errors,omitempty"`  // skipped lines
+	Flagged  []model.Transaction `json:"flagged,omitempty"` // first stored transactions with anomaly flags
+	// FlaggedCount is the number of all stored transactions with anomaly flags
+	FlaggedCount int `json:"flaggedCount,omitempty"`
+}
+
+// UploadCSV uploads CSV file to the default account, see UploadAccountCSV
+func (c *Client) UploadCSV(ctx context.Context, r io.Reader) (UploadResult, error) {
+	return c.UploadAccountCSV(ctx, "", r)
+}
+
+// UploadAccountCSV uploads CSV file to the account, empty account for the default one. The file is stored
+// completely or not at all, a file without valid lines is an *Error with skipped lines. Upload of io.Seeker,
+// like os.File, is retried, other readers are sent once.
+func (c *Client) UploadAccountCSV(ctx context.Context, account string, r io.Reader) (UploadResult, error) {
+	res := UploadResult{}
+	err := c.upload(ctx, accountPath(account, "/transactions"), nil, r, &res)
+	return res, err
+}
+
+// UploadAsync uploads CSV file to be ingested in the background and returns the queued job,
+// see Job for its progress. Empty account is the default one.
+func (c *Client) UploadAsync(ctx context.Context, account string, r io.Reader) (model.Job, error) {
+	job := model.Job{}
+	err := c.upload(ctx, accountPath(account, "/transactions"), url.Values{"async": {"true"}}, r, &job)
+	return job, err
+}
+
+// upload sends r as multipart "file" field and decodes the response into out
+func (c *Client) upload(ctx context.Context, path string, query url.Values, r io.Reader, out interface{}) error {
+	seeker, canSeek := r.(io.Seeker)
+	start := int64(0)
+	if canSeek {
+		var err error
+		if start, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			canSeek = false
+		}
+	}
+
+	contentType := multipart.NewWriter(io.Discard) // all attempts share the boundary
+	var pr *io.PipeReader                          // body of the last attempt
+	var written chan struct{}                      // closed when the body of the last attempt is written
+	stop := func() {
+		if pr != nil {
+			pr.Close()
+			<-written
+		}
+	}
+	req := request{method: http.MethodPost, path: path, query: query, contentType: contentType.FormDataContentType(),
+		once: !canSeek}
+	req.body = func() (io.Reader, error) {
+		stop() // the previous attempt should be done with r before it's read again
+		if canSeek {
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return nil, err
+			}
+		}
+		var pw *io.PipeWriter
+		pr, pw = io.Pipe()
+		written = make(chan struct{})
+		mw := multipart.NewWriter(pw)
+		_ = mw.SetBoundary(contentType.Boundary())
+		go func(written chan struct{}) {
+			defer close(written)
+			part, err := mw.CreateFormFile("file", "upload.csv")
+			if err == nil {
+				_, err = io.Copy(part, r)
+			}
+			if err == nil {
+				err = mw.Close()
+			}
+			pw.CloseWithError(err)
+		}(written)
+		return pr, nil
+	}
+
+	resp, err := c.do(ctx, req)
+	stop()
+	if err != nil {
+		return err
+	}
+	return decode(resp, out)
+}
+
+// AddTransactions stores transactions in the account, empty account for the default one. Transactions
+// are validated as CSV lines, so Date, Type, Amount and Memo are required. Returns stored transactions
+// with anomaly flags.
+func (c *Client) AddTransactions(ctx context.Context, account string, transactions []model.Transaction) ([]model.Transaction, error) {
+	type transactionReq struct {
+		Date     string   `json:"date"`
+		Type     string   `json:"type"`
+		Amount   float64  `json:"amount"`
+		Memo     string   `json:"memo"`
+		Tags     []string `json:"tags,omitempty"`
+		Category string   `json:"category,omitempty"`
+	}
+	reqs := make([]transactionReq, 0, len(transactions))
+	for _, tr := range transactions {
+		reqs = append(reqs, transactionReq{Date: formatDate(tr.Date), Type: string(tr.Type), Amount: tr.Amount,
+			Memo: tr.Memo, Tags: tr.Tags, Category: tr.Category})
+	}
+	resp := struct {
+		Flagged []model.Transaction `json:"flagged"`
+	}{}
+	err := c.call(ctx, http.MethodPost, accountPath(account, "/transactions"), nil, reqs, &resp)
+	return resp.Flagged, err
+}
+
+// Transactions lists transactions matching the filter with running balance of their accounts
+func (c *Client) Transactions(ctx context.Context, filter model.Filter) ([]model.LedgerLine, error) {
+	var res []model.LedgerLine
+	err := c.call(ctx, http.MethodGet, "/transactions", filterQuery(filter), nil, &res)
+	return res, err
+}
+
+// UpdateTransaction sets tags and/or category of the transaction
+func (c *Client) UpdateTransaction(ctx context.Context, id string, upd model.TransactionUpdate) (model.Transaction, error) {
+	res := model.Transaction{}
+	err := c.call(ctx, http.MethodPatch, "/transactions/"+url.PathEscape(id), nil, upd, &res)
+	return res, err
+}
+
+// Accounts returns all accounts with their transaction counts
+func (c *Client) Accounts(ctx context.Context) ([]model.Account, error) {
+	var res []model.Account
+	err := c.call(ctx, http.MethodGet, "/accounts", nil, nil, &res)
+	return res, err
+}
+
+// SetOpeningBalance sets opening balance and date of the account, creates the account if needed
+func (c *Client) SetOpeningBalance(ctx context.Context, account string, amount float64, date time.Time) (model.Account, error) {
+	req := struct {
+		OpeningBalance float64 `json:"openingBalance"`
+		OpeningDate    string  `json:"openingDate"`
+	}{OpeningBalance: amount, OpeningDate: formatDate(date)}
+	res := model.Account{}
+	err := c.call(ctx, http.MethodPut, "/accounts/"+url.PathEscape(account), nil, req, &res)
+	return res, err
+}
+
+// Balance returns balance of the accounts, all if empty, at the end of asOf day, zero asOf for all transactions
+func (c *Client) Balance(ctx context.Context, accounts []string, asOf time.Time) (model.Balance, error) {
+	q := filterQuery(model.Filter{Accounts: accounts})
+	if !asOf.IsZero() {
+		q.Set("asOf", formatDate(asOf))
+	}
+	res := model.Balance{}
+	err := c.call(ctx, http.MethodGet, "/balance", q, nil, &res)
+	return res, err
+}
+
+// Reconcile matches bank statement with the account transactions and marks matched ones as cleared
+func (c *Client) Reconcile(ctx context.Context, account string, st model.Statement, tol model.Tolerance) (model.Reconciliation, error) {
+	type lineReq struct {
+		Date   string  `json:"date"`
+		Amount float64 `json:"amount"`
+		Memo   string  `json:"memo"`
+	}
+	req := struct {
+		From           string    `json:"from"`
+		To             string    `json:"to"`
+		ClosingBalance float64   `json:"closingBalance"`
+		Lines          []lineReq `json:"lines"`
+	}{From: formatDate(st.From), To: formatDate(st.To), ClosingBalance: st.ClosingBalance}
+	for _, l := range st.Lines {
+		req.Lines = append(req.Lines, lineReq{Date: formatDate(l.Date), Amount: l.Amount, Memo: l.Memo})
+	}
+	q := url.Values{
+		"amountTolerance": {strconv.FormatFloat(tol.Amount, 'f', -1, 64)},
+		"dateTolerance":   {strconv.Itoa(tol.Days)},
+	}
+	res := model.Reconciliation{}
+	err := c.call(ctx, http.MethodPost, "/accounts/"+url.PathEscape(account)+"/reconcile", q, req, &res)
+	return res, err
+}
+
+// accountPath returns path of the account resource, i.e. /accounts/card/transactions, or the path itself
+// for empty account
+func accountPath(account, path string) string {
+	if account == "" {
+		return path
+	}
+	return "/accounts/" + url.PathEscape(account) + path
+}
+
+// filterQuery makes query parameters of the filter
+func filterQuery(filter model.Filter) url.Values {
+	q := url.Values{}
+	if !filter.From.IsZero() {
+		q.Set("from", formatDate(filter.From))
+	}
+	if !filter.To.IsZero() {
+		q.Set("to", formatDate(filter.To))
+	}
+	if len(filter.Accounts) > 0 {
+		q.Set("account", strings.Join(filter.Accounts, ","))
+	}
+	if !filter.Tags.IsEmpty() {
+		q.Set("tag", filter.Tags.String())
+	}
+	if filter.Flagged {
+		q.Set("flagged", "true")
+	}
+	return q
+}
+
+// formatDate formats date as the service expects it, zero time is an empty string
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(model.DateLayout)
+}
+
+// decode decodes JSON response into out and closes it
+func decode(resp *http.Response, out interface{}) error {
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("can't decode response: %w", err)
+	}
+	return nil
+}
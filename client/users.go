@@ -0,0 +1,72 @@
+package client
+
+import (
+	"context"
+	"github.com/mrnbort/summer_break/model"
+	"net/http"
+	"net/url"
+)
+
+// Caller is the identity the requests are made as
+type Caller struct {
+	Name    string   `json:"name"` // i.e. "key k-1 (reports)", "user alice" or "anonymous" if auth is disabled
+	Scopes  []string `json:"scopes"`
+	Ledgers []string `json:"ledgers,omitempty"` // accounts the caller can access, all of them if empty
+}
+
+// Me returns name, scopes and ledgers of the caller
+func (c *Client) Me(ctx context.Context) (Caller, error) {
+	res := Caller{}
+	err := c.call(ctx, http.MethodGet, "/auth/me", nil, nil, &res)
+	return res, err
+}
+
+// Login checks name and password of the user and keeps the session token, so the next requests are
+// made as the user until the session expires
+func (c *Client) Login(ctx context.Context, name, password string) (model.Session, error) {
+	req := struct {
+		Name     string `json:"name"`
+		Password string `json:"password"`
+	}{Name: name, Password: password}
+	res := model.Session{}
+	if err := c.call(ctx, http.MethodPost, "/auth/login", nil, req, &res); err != nil {
+		return model.Session{}, err
+	}
+	c.Token = res.Token
+	return res, nil
+}
+
+// Users returns all users, without password hashes
+func (c *Client) Users(ctx context.Context) ([]model.User, error) {
+	var res []model.User
+	err := c.call(ctx, http.MethodGet, "/users", nil, nil, &res)
+	return res, err
+}
+
+// AddUser adds user with the password, role and ledgers, empty ledgers for all of them
+func (c *Client) AddUser(ctx context.Context, u model.User, password string) (model.User, error) {
+	res := model.User{}
+	err := c.call(ctx, http.MethodPost, "/users", nil, userRequest(u, password), &res)
+	return res, err
+}
+
+// UpdateUser replaces role and ledgers of the user, and the password if it's not empty
+func (c *Client) UpdateUser(ctx context.Context, u model.User, password string) (model.User, error) {
+	res := model.User{}
+	err := c.call(ctx, http.MethodPut, "/users/"+url.PathEscape(u.Name), nil, userRequest(u, password), &res)
+	return res, err
+}
+
+// DeleteUser deletes user with given name, its sessions end
+func (c *Client) DeleteUser(ctx context.Context, name string) error {
+	return c.call(ctx, http.MethodDelete, "/users/"+url.PathEscape(name), nil, nil, nil)
+}
+
+func userRequest(u model.User, password string) interface{} {
+	return struct {
+		Name     string   `json:"name"`
+		Password string   `json:"password,omitempty"`
+		Role     string   `json:"role"`
+		Ledgers  []string `json:"ledgers,omitempty"`
+	}{Name: u.Name, Password: password, Role: u.Role, Ledgers: u.Ledgers}
+}
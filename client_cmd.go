@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/mrnbort/summer_break/client"
+	"github.com/mrnbort/summer_break/model"
+	"io"
+	"os"
+	"time"
+)
+
+type clientCmd struct {
+	URL     string        `long:"url" env:"SUMMER_BREAK_URL" description:"service URL" default:"http://127.0.0.1:8080"`
+	APIKey  string        `long:"api-key" env:"SUMMER_BREAK_API_KEY" description:"API key, if the service requires it"`
+	Timeout time.Duration `long:"timeout" description:"timeout of the whole command" default:"5m"`
+
+	Upload       clientUploadCmd `command:"upload" description:"upload CSV file"`
+	Report       filterOpts      `command:"report" description:"print report"`
+	Transactions filterOpts      `command:"transactions" description:"list transactions"`
+	Accounts     struct{}        `command:"accounts" description:"list accounts"`
+	Jobs         struct{}        `command:"jobs" description:"list async upload jobs"`
+	Job          clientJobCmd    `command:"job" description:"print async upload job"`
+}
+
+type clientUploadCmd struct {
+	Account string  `long:"account" description:"account of uploaded transactions, the default one if not set"`
+	Async   bool    `long:"async" description:"ingest in the background and wait for the job"`
+	Args    fileArg `positional-args:"yes" required:"yes"`
+}
+
+type clientJobCmd struct {
+	Args struct {
+		ID string `positional-arg-name:"ID"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+// runClient calls the service with the client subcommand and prints the response as JSON
+func runClient(name string, opts clientCmd, out io.Writer) error {
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+	c := client.New(opts.URL)
+	c.APIKey = opts.APIKey
+
+	var res interface{}
+	var err error
+	switch name {
+	case "upload":
+		res, err = clientUpload(ctx, c, opts.Upload)
+	case "report", "transactions":
+		res, err = clientFiltered(ctx, c, name, opts)
+	case "accounts":
+		res, err = c.Accounts(ctx)
+	case "jobs":
+		res, err = c.Jobs(ctx)
+	case "job":
+		res, err = c.Job(ctx, opts.Job.Args.ID)
+	default:
+		return fmt.Errorf("unknown command %q", name)
+	}
+	if err != nil {
+		return err
+	}
+	return printJSON(out, res)
+}
+
+// clientUpload uploads the file, async upload waits for the job to finish
+func clientUpload(ctx context.Context, c *client.Client, opts clientUploadCmd) (interface{}, error) {
+	f, err := os.Open(opts.Args.File)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if !opts.Async {
+		return c.UploadAccountCSV(ctx, opts.Account, f)
+	}
+	job, err := c.UploadAsync(ctx, opts.Account, f)
+	if err != nil {
+		return nil, err
+	}
+	if job, err = c.WaitJob(ctx, job.ID, time.Second); err == nil && job.Status != model.JobDone {
+		return job, fmt.Errorf("job %s %s: %s", job.ID, job.Status, job.Error)
+	}
+	return job, err
+}
+
+// clientFiltered gets report or transactions matching the filter options
+func clientFiltered(ctx context.Context, c *client.Client, name string, opts clientCmd) (interface{}, error) {
+	if name == "report" {
+		filter, err := opts.Report.filter()
+		if err != nil {
+			return nil, err
+		}
+		return c.Report(ctx, filter)
+	}
+	filter, err := opts.Transactions.filter()
+	if err != nil {
+		return nil, err
+	}
+	return c.Transactions(ctx, filter)
+}
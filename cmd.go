@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mrnbort/summer_break/archive"
+	"github.com/mrnbort/summer_break/audit"
+	"github.com/mrnbort/summer_break/ingest"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/mrnbort/summer_break/processor"
+	"github.com/mrnbort/summer_break/store"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// fileArg is the positional FILE argument of commands
+type fileArg struct {
+	File string `positional-arg-name:"FILE" description:"CSV or OFX file, by extension"`
+}
+
+// filterOpts selects transactions of report and export commands
+type filterOpts struct {
+	From     string   `long:"from" description:"first date, YYYY-MM-DD"`
+	To       string   `long:"to" description:"last date, YYYY-MM-DD"`
+	Accounts []string `long:"account" description:"account to include, all if not set, repeatable"`
+}
+
+type importCmd struct {
+	Account string  `long:"account" description:"account of imported transactions" default:"default"`
+	Args    fileArg `positional-args:"yes" required:"yes"`
+}
+
+type reportCmd struct {
+	filterOpts
+	Format string `long:"format" description:"output format" choice:"text" choice:"json" choice:"csv" default:"text"`
+}
+
+type exportCmd struct {
+	filterOpts
+	Format string `long:"format" description:"csv of transactions or ndjson archive of the whole store" choice:"csv" choice:"ndjson" default:"csv"`
+	Output string `short:"o" long:"output" description:"file to write, stdout if not set"`
+}
+
+type validateCmd struct {
+	Args fileArg `positional-args:"yes" required:"yes"`
+}
+
+// runCommand runs the offline command against the store, or client subcommand against the service,
+// writing its output to out
+func runCommand(name string, opts options, out io.Writer) error {
+	ctx := context.Background()
+	switch name {
+	case "import":
+		return importFile(ctx, opts, out)
+	case "report":
+		return printReport(ctx, opts, out)
+	case "export":
+		return exportTransactions(ctx, opts, out)
+	case "validate":
+		return validateFile(ctx, opts, out)
+	}
+	if sub := strings.TrimPrefix(name, "client "); sub != name {
+		return runClient(sub, opts.Client, out)
+	}
+	return fmt.Errorf("unknown command %q", name)
+}
+
+// importFile ingests the file to the store and prints the result, fails if nothing is accepted.
+// The store is locked, so import fails if the server is running with it. The import is recorded
+// in the audit log, if it's set.
+func importFile(ctx context.Context, opts options, out io.Writer) error {
+	if opts.Store == "" {
+		return errors.New("import needs --store to keep transactions in")
+	}
+	unlock, err := store.File{Path: opts.Store}.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock() //nolint
+	proc, err := newProc(opts)
+	if err != nil {
+		return err
+	}
+	auditLog, err := openAudit(opts)
+	if err != nil {
+		return err
+	}
+	var pending model.AuditEntry
+	if auditLog != nil {
+		defer auditLog.Close() //nolint
+		if pending, err = beginImport(auditLog, opts.Import.Args.File); err != nil {
+			return err
+		}
+	}
+
+	res, err := ingestFile(ctx, ingest.Pipeline{Store: proc, ChunkSize: opts.IngestChunkSize, Workers: opts.IngestWorkers},
+		opts.Import.Args.File, opts.Import.Account)
+	if err == nil {
+		if err = printJSON(out, res); err == nil && res.Accepted == 0 {
+			err = errors.New("no valid transactions")
+		}
+	}
+	if err == nil {
+		err = store.File{Path: opts.Store}.Save(ctx, proc)
+	}
+	if auditLog != nil {
+		if e := finishImport(auditLog, pending, opts.Import.Account, res, err); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// beginImport records import of the file as pending in the audit log, with its SHA-256 and size.
+// The actor is the user running the command.
+func beginImport(auditLog *audit.Log, file string) (model.AuditEntry, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return model.AuditEntry{}, err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return model.AuditEntry{}, err
+	}
+	if file, err = filepath.Abs(file); err != nil {
+		return model.AuditEntry{}, err
+	}
+	actor := "cli"
+	if u, e := user.Current(); e == nil {
+		actor = "cli " + u.Username
+	}
+	pending, err := auditLog.Begin(model.AuditEntry{Actor: actor, Action: model.AuditUpload, Resource: file,
+		BodySHA256: hex.EncodeToString(hash.Sum(nil)), BodyBytes: size})
+	if err != nil {
+		return model.AuditEntry{}, fmt.Errorf("can't record import in audit log: %w", err)
+	}
+	return pending, nil
+}
+
+// finishImport records outcome of the import in the audit log, failed if err is not nil
+func finishImport(auditLog *audit.Log, pending model.AuditEntry, account string, res model.IngestResult, err error) error {
+	after := struct {
+		Account string             `json:"account"`
+		Result  model.IngestResult `json:"result"`
+		Error   string             `json:"error,omitempty"`
+	}{Account: account, Result: res}
+	done := model.AuditEntry{}
+	if err != nil {
+		done.Outcome, after.Error = model.AuditFailed, err.Error()
+	}
+	data, err := json.Marshal(after)
+	if err == nil {
+		done.After = data
+		_, err = auditLog.Finish(pending, done)
+	}
+	if err != nil {
+		return fmt.Errorf("can't record import in audit log: %w", err)
+	}
+	return nil
+}
+
+// validateFile parses the file without storing it and prints the result, fails if any line is invalid
+func validateFile(ctx context.Context, opts options, out io.Writer) error {
+	res, err := ingestFile(ctx, ingest.Pipeline{Store: processor.NewProc()}, opts.Validate.Args.File, model.DefaultAccount)
+	if err != nil {
+		return err
+	}
+	res.Flagged = nil // anomalies depend on stored transactions
+	if err = printJSON(out, res); err != nil {
+		return err
+	}
+	if res.Rejected > 0 {
+		return fmt.Errorf("%d invalid lines", res.Rejected)
+	}
+	return nil
+}
+
+// ingestFile runs the file through the pipeline as OFX for .ofx and .qfx files and as CSV otherwise
+func ingestFile(ctx context.Context, pipeline ingest.Pipeline, file, account string) (model.IngestResult, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return model.IngestResult{}, err
+	}
+	defer f.Close()
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".ofx", ".qfx":
+		return pipeline.RunOFX(ctx, f, account)
+	default:
+		return pipeline.Run(ctx, f, account)
+	}
+}
+
+// printReport prints revenue and expenses report of the stored transactions
+func printReport(ctx context.Context, opts options, out io.Writer) error {
+	filter, err := opts.Report.filter()
+	if err != nil {
+		return err
+	}
+	proc, err := newProc(opts)
+	if err != nil {
+		return err
+	}
+	report, err := proc.GenerateReport(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	rows := [][]string{
+		{"Gross revenue", money(report.GrossRevenue)},
+		{"Expenses", money(report.Expenses)},
+		{"Net revenue", money(report.NetRevenue)},
+	}
+	switch opts.Report.Format {
+	case "json":
+		return printJSON(out, report)
+	case "csv":
+		w := csv.NewWriter(out)
+		_ = w.Write([]string{"", "Amount"})
+		_ = w.WriteAll(rows) // flushes
+		return w.Error()
+	default:
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		for _, row := range rows {
+			fmt.Fprintf(w, "%s\t%12s\n", row[0], row[1])
+		}
+		return w.Flush()
+	}
+}
+
+// exportTransactions writes stored transactions as CSV which can be uploaded or imported again,
+// or the whole store as archive which can be imported with POST /import
+func exportTransactions(ctx context.Context, opts options, out io.Writer) error {
+	filter, err := opts.Export.filter()
+	if err != nil {
+		return err
+	}
+	ndjson := opts.Export.Format == "ndjson"
+	if ndjson && (!filter.From.IsZero() || !filter.To.IsZero() || len(filter.Accounts) > 0) {
+		return errors.New("ndjson archive has the whole store, it can't be filtered")
+	}
+	proc, err := newProc(opts)
+	if err != nil {
+		return err
+	}
+
+	if opts.Export.Output != "" {
+		f, err := os.Create(opts.Export.Output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	if ndjson {
+		snap, err := proc.Snapshot(ctx)
+		if err != nil {
+			return err
+		}
+		return archive.Write(out, snap, time.Now())
+	}
+
+	lines, err := proc.Transactions(ctx, filter)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(out)
+	for _, l := range lines {
+		_ = w.Write([]string{l.Date.Format(model.DateLayout), string(l.Type), strconv.FormatFloat(l.Amount, 'f', -1, 64),
+			l.Memo, strings.Join(l.Tags, ";"), l.Category})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// filter makes transaction filter from the options
+func (f filterOpts) filter() (model.Filter, error) {
+	res := model.Filter{Accounts: f.Accounts}
+	var err error
+	for _, d := range []struct {
+		val string
+		dst *time.Time
+	}{{f.From, &res.From}, {f.To, &res.To}} {
+		if d.val == "" {
+			continue
+		}
+		if *d.dst, err = time.ParseInLocation(model.DateLayout, d.val, time.Local); err != nil {
+			return model.Filter{}, fmt.Errorf("invalid date %q: %w", d.val, err)
+		}
+	}
+	return res, nil
+}
+
+func printJSON(out io.Writer, v interface{}) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// money formats amount with two decimals
+func money(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
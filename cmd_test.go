@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"github.com/mrnbort/summer_break/api"
+	"github.com/mrnbort/summer_break/archive"
+	"github.com/mrnbort/summer_break/audit"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/mrnbort/summer_break/processor"
+	"github.com/mrnbort/summer_break/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_runCommand(t *testing.T) {
+	opts := options{Store: filepath.Join(t.TempDir(), "store.json")}
+	out := &bytes.Buffer{}
+
+	opts.Import = importCmd{Account: "card", Args: fileArg{File: "testdata/data.csv"}}
+	require.NoError(t, runCommand("import", opts, out))
+	assert.Contains(t, out.String(), `"accepted": 10`)
+	assert.FileExists(t, opts.Store)
+
+	opts.Import.Args.File = "testdata/statement.ofx"
+	opts.AuditLog = filepath.Join(t.TempDir(), "audit.log")
+	assert.Error(t, runCommand("import", opts, out), "audit log without key")
+	opts.AuditKey = "secret"
+	out.Reset()
+	require.NoError(t, runCommand("import", opts, out))
+	assert.Contains(t, out.String(), `"accepted": 3`)
+
+	auditLog, err := audit.Open(audit.Config{Path: opts.AuditLog, Key: []byte(opts.AuditKey)})
+	require.NoError(t, err)
+	entries, err := auditLog.Entries(model.AuditFilter{}, 0)
+	require.NoError(t, err)
+	require.NoError(t, auditLog.Close())
+	require.Len(t, entries, 2)
+	assert.Equal(t, model.AuditPending, entries[1].Outcome)
+	assert.Contains(t, entries[1].Actor, "cli")
+	assert.True(t, filepath.IsAbs(entries[1].Resource))
+	assert.Len(t, entries[1].BodySHA256, 64)
+	assert.Equal(t, model.AuditDone, entries[0].Outcome)
+	assert.Contains(t, string(entries[0].After), `"account":"card"`)
+	opts.AuditLog, opts.AuditKey = "", ""
+
+	t.Run("report", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		opts := opts
+		opts.Report = reportCmd{filterOpts: filterOpts{To: "2020-07-31"}, Format: "csv"}
+		require.NoError(t, runCommand("report", opts, out))
+		assert.Equal(t, ",Amount\nGross revenue,265.00\nExpenses,119.20\nNet revenue,145.80\n", out.String())
+
+		out.Reset()
+		opts.Report = reportCmd{filterOpts: filterOpts{From: "2020-07-05", Accounts: []string{"card"}}, Format: "json"}
+		require.NoError(t, runCommand("report", opts, out))
+		assert.Contains(t, out.String(), `"expenses": `)
+
+		opts.Report.From = "July"
+		assert.Error(t, runCommand("report", opts, out))
+	})
+
+	t.Run("export", func(t *testing.T) {
+		opts := opts
+		opts.Export = exportCmd{filterOpts: filterOpts{From: "2020-07-12", To: "2020-07-12"}, Output: filepath.Join(t.TempDir(), "out.csv")}
+		require.NoError(t, runCommand("export", opts, &bytes.Buffer{}))
+		data, err := os.ReadFile(opts.Export.Output)
+		require.NoError(t, err)
+		assert.Equal(t, "2020-07-12,Expense,27.5,Repairs,,\n2020-07-12,Expense,27.5,Jim's Repairs,,\n", string(data))
+
+		out := &bytes.Buffer{}
+		opts.Export = exportCmd{Format: "ndjson"}
+		require.NoError(t, runCommand("export", opts, out))
+		snap, err := archive.Read(out)
+		require.NoError(t, err)
+		assert.Len(t, snap.Transactions, 13)
+
+		opts.Export.From = "2020-07-12"
+		assert.Error(t, runCommand("export", opts, out), "archive can't be filtered")
+	})
+
+	t.Run("validate", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		opts := options{Validate: validateCmd{Args: fileArg{File: "testdata/data.csv"}}}
+		err := runCommand("validate", opts, out)
+		assert.EqualError(t, err, "1 invalid lines")
+		assert.Contains(t, out.String(), `"line": 4`)
+
+		opts.Validate.Args.File = "testdata/missing.csv"
+		assert.Error(t, runCommand("validate", opts, out))
+	})
+
+	t.Run("import without store", func(t *testing.T) {
+		opts := opts
+		opts.Store = ""
+		assert.Error(t, runCommand("import", opts, &bytes.Buffer{}))
+	})
+
+	t.Run("import to locked store", func(t *testing.T) {
+		unlock, err := store.File{Path: opts.Store}.Lock()
+		require.NoError(t, err)
+		defer unlock() //nolint
+		assert.ErrorIs(t, runCommand("import", opts, &bytes.Buffer{}), store.ErrLocked, "i.e. the server is running")
+	})
+}
+
+func Test_runClient(t *testing.T) {
+	ts := httptest.NewServer(api.Service{Processor: processor.NewProc()}.Handler())
+	defer ts.Close()
+	opts := options{Client: clientCmd{URL: ts.URL, Timeout: 5 * time.Second}}
+	out := &bytes.Buffer{}
+
+	opts.Client.Upload.Args.File = "testdata/data.csv"
+	require.NoError(t, runCommand("client upload", opts, out))
+	assert.Contains(t, out.String(), `"accepted": 10`)
+
+	out.Reset()
+	opts.Client.Report = filterOpts{To: "2020-07-15"}
+	require.NoError(t, runCommand("client report", opts, out))
+	assert.Contains(t, out.String(), `"grossRevenue": 100`)
+
+	out.Reset()
+	require.NoError(t, runCommand("client accounts", opts, out))
+	assert.Contains(t, out.String(), `"name": "default"`)
+
+	opts.Client.Job.Args.ID = "1"
+	assert.EqualError(t, runCommand("client job", opts, out), `status 404: async uploads are disabled`)
+}
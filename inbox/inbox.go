@@ -0,0 +1,329 @@
+// Package inbox ingests CSV and OFX files dropped into a watched directory.
+package inbox
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mrnbort/summer_break/audit"
+	"github.com/mrnbort/summer_break/ingest"
+	"github.com/mrnbort/summer_break/model"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// subdirectories and files of the inbox
+const (
+	ProcessedDir = "processed"
+	FailedDir    = "failed"
+	StateFile    = ".inbox-state.json"
+	ResultSuffix = ".result.json"
+)
+
+// DefaultInterval is how often the inbox is checked if Watcher.Interval is not set
+const DefaultInterval = 10 * time.Second
+
+// result statuses
+const (
+	StatusProcessed = "processed"
+	StatusFailed    = "failed"
+	StatusDuplicate = "duplicate" // same content was ingested before
+)
+
+// Result is written next to the moved file as <name>.result.json
+type Result struct {
+	File     string             `json:"file"`
+	SHA256   string             `json:"sha256"`
+	Status   string             `json:"status"`
+	Started  time.Time          `json:"started"`
+	Finished time.Time          `json:"finished"`
+	Result   model.IngestResult `json:"result"`
+	Error    string             `json:"error,omitempty"`
+}
+
+// Watcher polls Dir for .csv, .ofx and .qfx files and ingests them with the pipeline. A file is picked up
+// once its size and modification time are the same on two checks in a row, so partially copied files are
+// left alone. Ingested files are moved to processed/ and files with no valid transactions or failed ones
+// to failed/, both with a result sidecar. Hashes of ingested files are kept in the state file in Dir,
+// a file with the same content is not ingested again, i.e. if it was not moved before a restart.
+// Polling is used instead of file system events as those are unreliable on network and mounted folders.
+type Watcher struct {
+	Dir      string
+	Pipeline ingest.Pipeline
+	Account  string        // account of ingested transactions
+	Interval time.Duration // how often to check Dir, DefaultInterval if not set
+	Audit    *audit.Log    // records ingested files, if set
+
+	mu      sync.Mutex
+	pending map[string]fileStat // files seen on the previous check
+	state   map[string]ingested // ingested files by hash, loaded on the first check
+}
+
+// fileStat is what's checked to tell a file is complete
+type fileStat struct {
+	size    int64
+	modTime time.Time
+}
+
+// ingested is a state record of the ingested file
+type ingested struct {
+	File     string    `json:"file"`
+	Finished time.Time `json:"finished"`
+}
+
+// Run checks the inbox every Interval until ctx is canceled
+func (w *Watcher) Run(ctx context.Context) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := w.Scan(ctx); err != nil {
+			log.Printf("[WARN] can't check inbox %s: %v", w.Dir, err)
+		} else if n > 0 {
+			log.Printf("[INFO] ingested %d files from inbox", n)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan processes complete files in the inbox one by one and returns the number of files moved out of it
+func (w *Watcher) Scan(ctx context.Context) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.state == nil {
+		if err := w.load(); err != nil {
+			return 0, err
+		}
+	}
+
+	entries, err := os.ReadDir(w.Dir)
+	if err != nil {
+		return 0, err
+	}
+	seen := map[string]fileStat{}
+	var ready []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || strings.HasPrefix(name, ".") || !supported(name) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue // removed since listed
+		}
+		st := fileStat{size: info.Size(), modTime: info.ModTime()}
+		seen[name] = st
+		if prev, ok := w.pending[name]; ok && prev == st {
+			ready = append(ready, name)
+		}
+	}
+	w.pending = seen
+	sort.Strings(ready)
+
+	count := 0
+	var errs []error
+	for _, name := range ready {
+		if ctx.Err() != nil {
+			return count, ctx.Err()
+		}
+		if err := w.process(ctx, name); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		delete(w.pending, name)
+		count++
+	}
+	return count, errors.Join(errs...)
+}
+
+// supported tells the file has extension the pipeline can read
+func supported(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv", ".ofx", ".qfx":
+		return true
+	}
+	return false
+}
+
+// process ingests the file unless it was ingested before and moves it with the result sidecar.
+// Returns error only if the file can't be read, recorded in the audit log or moved, it stays in the inbox
+// in this case.
+// Caller should hold the lock.
+func (w *Watcher) process(ctx context.Context, name string) error {
+	path := filepath.Join(w.Dir, name)
+	res := Result{File: name, Started: time.Now()}
+	hash, err := fileHash(path)
+	if err != nil {
+		return err
+	}
+	res.SHA256 = hash
+
+	if prev, ok := w.state[hash]; ok {
+		res.Status, res.Error = StatusDuplicate, fmt.Sprintf("same as %s ingested at %s", prev.File, prev.Finished.Format(time.RFC3339))
+		res.Finished = time.Now()
+		log.Printf("[INFO] inbox file %s skipped, same as %s", name, prev.File)
+		return w.move(name, ProcessedDir, res)
+	}
+
+	pending, err := w.begin(res)
+	if err != nil {
+		return err
+	}
+	res.Result, err = w.ingest(ctx, path)
+	res.Finished = time.Now()
+	switch {
+	case err != nil && ctx.Err() != nil:
+		w.finish(pending, res) // stopped, the file will be ingested after restart
+		return err
+	case err != nil:
+		res.Status, res.Error = StatusFailed, err.Error()
+	case res.Result.Accepted == 0:
+		res.Status, res.Error = StatusFailed, "no valid transactions"
+	default:
+		res.Status = StatusProcessed
+		w.state[hash] = ingested{File: name, Finished: res.Finished}
+		if err = w.save(); err != nil {
+			log.Printf("[WARN] can't save inbox state: %v", err)
+		}
+	}
+	w.finish(pending, res)
+	log.Printf("[INFO] inbox file %s %s, %d lines, %d accepted, %d rejected", name, res.Status,
+		res.Result.Lines, res.Result.Accepted, res.Result.Rejected)
+
+	if res.Status == StatusFailed {
+		return w.move(name, FailedDir, res)
+	}
+	return w.move(name, ProcessedDir, res)
+}
+
+// begin records the file as pending in the audit log, if it's set, the file must not be ingested if it fails
+func (w *Watcher) begin(res Result) (model.AuditEntry, error) {
+	if w.Audit == nil {
+		return model.AuditEntry{}, nil
+	}
+	e := model.AuditEntry{Time: res.Started, Actor: "inbox", Action: model.AuditUpload, Resource: "inbox/" + res.File,
+		BodySHA256: res.SHA256}
+	if info, err := os.Stat(filepath.Join(w.Dir, res.File)); err == nil {
+		e.BodyBytes = info.Size()
+	}
+	pending, err := w.Audit.Begin(e)
+	if err != nil {
+		return model.AuditEntry{}, fmt.Errorf("can't record inbox file %s in audit log: %w", res.File, err)
+	}
+	return pending, nil
+}
+
+// finish records outcome of the file in the audit log, if it's set
+func (w *Watcher) finish(pending model.AuditEntry, res Result) {
+	if w.Audit == nil {
+		return
+	}
+	e := model.AuditEntry{Time: res.Finished}
+	if res.Status != StatusProcessed {
+		e.Outcome = model.AuditFailed
+	}
+	var err error
+	if e.After, err = json.Marshal(res); err == nil {
+		_, err = w.Audit.Finish(pending, e)
+	}
+	if err != nil {
+		log.Printf("[WARN] can't record outcome of inbox file %s in audit log: %v", res.File, err)
+	}
+}
+
+// ingest runs the file through the pipeline as CSV or OFX depending on its extension
+func (w *Watcher) ingest(ctx context.Context, path string) (model.IngestResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return model.IngestResult{}, err
+	}
+	defer f.Close()
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return w.Pipeline.Run(ctx, f, w.Account)
+	}
+	return w.Pipeline.RunOFX(ctx, f, w.Account)
+}
+
+// move moves the file to the subdirectory and writes its result next to it.
+// A timestamp is added to the name if the subdirectory has such file already.
+func (w *Watcher) move(name, subdir string, res Result) error {
+	dir := filepath.Join(w.Dir, subdir)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return err
+	}
+	dest := filepath.Join(dir, name)
+	if _, err := os.Stat(dest); err == nil {
+		ext := filepath.Ext(name)
+		dest = filepath.Join(dir, strings.TrimSuffix(name, ext)+"."+res.Finished.Format("20060102T150405.000")+ext)
+	}
+
+	data, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(dest+ResultSuffix, data, 0o640); err != nil {
+		return err
+	}
+	return os.Rename(filepath.Join(w.Dir, name), dest)
+}
+
+// load reads the state file, missing file is an empty state. Caller should hold the lock.
+func (w *Watcher) load() error {
+	state := map[string]ingested{}
+	data, err := os.ReadFile(filepath.Join(w.Dir, StateFile))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("can't read inbox state: %w", err)
+	default:
+		if err = json.Unmarshal(data, &state); err != nil {
+			return fmt.Errorf("can't parse inbox state: %w", err)
+		}
+	}
+	w.state = state
+	return nil
+}
+
+// save writes the state file through a temporary one, so it's never partially written.
+// Caller should hold the lock.
+func (w *Watcher) save() error {
+	data, err := json.Marshal(w.state)
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(w.Dir, StateFile+".tmp")
+	if err = os.WriteFile(tmp, data, 0o640); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(w.Dir, StateFile))
+}
+
+// fileHash returns hex sha256 of the file content
+func fileHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
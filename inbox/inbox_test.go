@@ -0,0 +1,141 @@
+package inbox
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/mrnbort/summer_break/audit"
+	"github.com/mrnbort/summer_break/ingest"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/mrnbort/summer_break/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const data = "2020-07-01,Expense,18.77,Fuel\nbad line\n2020-07-04,Income,40.00,347 Woodrow\n"
+
+func TestWatcher_Scan(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dir := t.TempDir()
+	proc := processor.NewProc()
+	auditLog, err := audit.Open(audit.Config{Key: []byte("secret")})
+	require.NoError(t, err)
+	w := &Watcher{Dir: dir, Pipeline: ingest.Pipeline{Store: proc}, Account: "card", Audit: auditLog}
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+	write("july.csv", data)
+	write("empty.csv", "bad line\n")
+	write("notes.txt", "not a statement")
+	ofx, err := os.ReadFile("../testdata/statement.ofx")
+	require.NoError(t, err)
+	write("statement.QFX", string(ofx))
+
+	n, err := w.Scan(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n, "files are new, may be incomplete")
+
+	n, err = w.Scan(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.FileExists(t, filepath.Join(dir, "notes.txt"))
+	assert.NoFileExists(t, filepath.Join(dir, "july.csv"))
+	assert.FileExists(t, filepath.Join(dir, ProcessedDir, "july.csv"))
+	assert.FileExists(t, filepath.Join(dir, ProcessedDir, "statement.QFX"))
+	assert.FileExists(t, filepath.Join(dir, FailedDir, "empty.csv"))
+
+	res := readResult(t, filepath.Join(dir, ProcessedDir, "july.csv"))
+	assert.Equal(t, StatusProcessed, res.Status)
+	assert.Equal(t, "july.csv", res.File)
+	assert.Len(t, res.SHA256, 64)
+	assert.Equal(t, 3, res.Result.Lines)
+	assert.Equal(t, 2, res.Result.Accepted)
+	assert.Equal(t, []model.LineError{{Line: 2, Error: "wrong number of fields, expected at least 4, got 1"}}, res.Result.Errors)
+	res = readResult(t, filepath.Join(dir, FailedDir, "empty.csv"))
+	assert.Equal(t, StatusFailed, res.Status)
+	assert.Equal(t, "no valid transactions", res.Error)
+
+	report, err := proc.GenerateReport(ctx, model.Filter{Accounts: []string{"card"}})
+	require.NoError(t, err)
+	assert.Equal(t, 80.0, report.GrossRevenue)
+	assert.InDelta(t, 65.04, report.Expenses, 0.001, "csv and ofx expenses")
+
+	auditEntries := func(filter model.AuditFilter) []model.AuditEntry {
+		entries, err := auditLog.Entries(filter, 0)
+		require.NoError(t, err)
+		return entries
+	}
+	assert.Len(t, auditEntries(model.AuditFilter{Outcome: model.AuditPending}), 3)
+	assert.Len(t, auditEntries(model.AuditFilter{Outcome: model.AuditFailed, Resource: "inbox/empty.csv"}), 1)
+	entries := auditEntries(model.AuditFilter{Outcome: model.AuditDone})
+	require.Len(t, entries, 2, "ingested files")
+	assert.Equal(t, "inbox", entries[0].Actor)
+	assert.Equal(t, model.AuditUpload, entries[0].Action)
+	pending := auditEntries(model.AuditFilter{Resource: entries[0].Resource})[1]
+	assert.Equal(t, pending.Seq, entries[0].Ref)
+	assert.Len(t, pending.BodySHA256, 64)
+	assert.ElementsMatch(t, []string{"inbox/july.csv", "inbox/statement.QFX"}, []string{entries[0].Resource, entries[1].Resource})
+
+	// same content again after restart
+	write("july-copy.csv", data)
+	w = &Watcher{Dir: dir, Pipeline: ingest.Pipeline{Store: proc}, Account: "card"}
+	_, err = w.Scan(ctx)
+	require.NoError(t, err)
+	n, err = w.Scan(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	res = readResult(t, filepath.Join(dir, ProcessedDir, "july-copy.csv"))
+	assert.Equal(t, StatusDuplicate, res.Status)
+	assert.Contains(t, res.Error, "same as july.csv")
+	report, err = proc.GenerateReport(ctx, model.Filter{Accounts: []string{"card"}})
+	require.NoError(t, err)
+	assert.Equal(t, 80.0, report.GrossRevenue, "not ingested again")
+
+	// different content with the same name
+	write("july.csv", "2020-07-05,Income,1.00,Tip\n")
+	_, err = w.Scan(ctx)
+	require.NoError(t, err)
+	n, err = w.Scan(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	files, err := filepath.Glob(filepath.Join(dir, ProcessedDir, "july.*.csv"))
+	require.NoError(t, err)
+	require.Len(t, files, 1, "renamed to not overwrite processed file")
+	assert.Equal(t, StatusProcessed, readResult(t, files[0]).Status)
+}
+
+func TestWatcher_growingFile(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dir := t.TempDir()
+	w := &Watcher{Dir: dir, Pipeline: ingest.Pipeline{Store: processor.NewProc()}}
+	path := filepath.Join(dir, "july.csv")
+	require.NoError(t, os.WriteFile(path, []byte("2020-07-01,Expense,18.77,Fuel\n"), 0o600))
+	_, err := w.Scan(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+	n, err := w.Scan(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n, "changed since the previous check")
+
+	n, err = w.Scan(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 2, readResult(t, filepath.Join(dir, ProcessedDir, "july.csv")).Result.Accepted)
+}
+
+// readResult reads the sidecar of the file
+func readResult(t *testing.T, path string) Result {
+	data, err := os.ReadFile(path + ResultSuffix)
+	require.NoError(t, err)
+	res := Result{}
+	require.NoError(t, json.Unmarshal(data, &res))
+	return res
+}
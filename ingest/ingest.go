@@ -0,0 +1,293 @@
+// Package ingest streams transactions from CSV and OFX files into the store with bounded memory.
+package ingest
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/mrnbort/summer_break/model"
+	"io"
+	"sort"
+	"sync"
+)
+
+// default pipeline settings
+const (
+	DefaultChunkSize = 1000
+	DefaultWorkers   = 4
+	MaxErrors        = 100 // line errors kept in the result, the rest are only counted
+	MaxFlagged       = 100 // flagged transactions kept in the result, the rest are only counted
+)
+
+// ErrTooManyRows is returned when the file has more records than Pipeline.MaxRows, nothing of it is stored
+var ErrTooManyRows = errors.New("too many rows")
+
+// Store parses transactions and keeps them staged until the whole file is read
+type Store interface {
+	ParseTransaction(rec []string) (model.Transaction, error)
+	StageTransactions(ctx context.Context, batchID string, transactions []model.Transaction) (string, error)
+	CommitBatch(ctx context.Context, batchID string) ([]model.Transaction, error)
+	DiscardBatch(ctx context.Context, batchID string) error
+}
+
+// Pipeline reads CSV records in chunks, parses chunks in parallel and stages them in the store in file
+// order. The whole file is committed as one batch, so it's either stored completely or not at all.
+// Lines which can't be parsed are skipped and reported, errors of the store drop the batch.
+// The reader waits while 2*Workers chunks are in flight, so memory doesn't depend on the file size.
+type Pipeline struct {
+	Store     Store
+	ChunkSize int // records parsed and staged at once, DefaultChunkSize if not set
+	Workers   int // parallel parsers, DefaultWorkers if not set
+	MaxRows   int // records of the file, including malformed ones, unlimited if not set
+
+	Progress func(res model.IngestResult) // called after every staged chunk if set
+}
+
+// chunk is a part of the file, numbered in the file order
+type chunk struct {
+	seq          int
+	read         int   // records and malformed lines
+	lines        []int // line of every record
+	records      [][]string
+	transactions []model.Transaction
+	errors       []model.LineError
+}
+
+// recordReader reads records of a file one by one with their lines. Malformed records are
+// returned as *badLine error, reading continues after them.
+type recordReader interface {
+	Read() (record []string, line int, err error)
+}
+
+// badLine is an error of a malformed line
+type badLine struct {
+	model.LineError
+}
+
+func (e *badLine) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.LineError.Error)
+}
+
+// csvRecords reads records of CSV file
+type csvRecords struct {
+	reader *csv.Reader
+}
+
+func newCSVRecords(r io.Reader) *csvRecords {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // tags and category columns are optional
+	return &csvRecords{reader: reader}
+}
+
+func (c *csvRecords) Read() ([]string, int, error) {
+	record, err := c.reader.Read()
+	var perr *csv.ParseError
+	if errors.As(err, &perr) {
+		return nil, perr.StartLine, &badLine{model.LineError{Line: perr.StartLine, Error: perr.Err.Error()}}
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	line, _ := c.reader.FieldPos(0)
+	return record, line, nil
+}
+
+// Run ingests CSV records from the reader to the account, empty account keeps the store's default.
+// Returns result with counts and errors of lines, and error if the batch can't be stored.
+func (p Pipeline) Run(ctx context.Context, r io.Reader, account string) (model.IngestResult, error) {
+	return p.run(ctx, newCSVRecords(r), account)
+}
+
+// RunOFX ingests transactions of OFX (or QFX) statement from the reader like Run does with CSV
+func (p Pipeline) RunOFX(ctx context.Context, r io.Reader, account string) (model.IngestResult, error) {
+	return p.run(ctx, newOFXRecords(r), account)
+}
+
+// run ingests records of the file, see Run
+func (p Pipeline) run(ctx context.Context, records recordReader, account string) (model.IngestResult, error) {
+	size, workers := p.ChunkSize, p.Workers
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	slots := make(chan struct{}, 2*workers) // chunks in flight
+	todo, done := make(chan *chunk), make(chan *chunk)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(todo)
+		readErr <- p.read(ctx, records, size, slots, todo)
+	}()
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range todo {
+				p.parse(c, account)
+				select {
+				case done <- c:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	res := model.IngestResult{}
+	batchID := ""
+	var err error
+	pending, next := map[int]*chunk{}, 0
+	for c := range done {
+		pending[c.seq] = c
+		for c, ok := pending[next]; ok; c, ok = pending[next] {
+			delete(pending, next)
+			next++
+			<-slots
+			if err != nil {
+				continue
+			}
+			if err = p.stage(ctx, &batchID, c, &res); err != nil {
+				cancel()
+				continue
+			}
+			if p.Progress != nil {
+				p.Progress(res)
+			}
+		}
+	}
+	if err == nil {
+		err = <-readErr
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		if batchID != "" {
+			if e := p.Store.DiscardBatch(context.Background(), batchID); e != nil {
+				err = errors.Join(err, e)
+			}
+		}
+		return res, err
+	}
+	if batchID == "" {
+		return res, nil
+	}
+	stored, err := p.Store.CommitBatch(ctx, batchID)
+	if err != nil {
+		_ = p.Store.DiscardBatch(context.Background(), batchID) // in case it wasn't committed at all
+		res.Accepted = 0
+		return res, err
+	}
+	res.Accepted = len(stored)
+	for _, tr := range stored {
+		if len(tr.Flags) == 0 {
+			continue
+		}
+		if res.FlaggedCount++; len(res.Flagged) < MaxFlagged {
+			res.Flagged = append(res.Flagged, tr)
+		}
+	}
+	return res, nil
+}
+
+// read splits records into chunks and sends them to parsers, waiting for a free slot before
+// every chunk. Malformed lines are reported in the chunk, other read errors stop reading.
+func (p Pipeline) read(ctx context.Context, records recordReader, size int, slots chan<- struct{}, todo chan<- *chunk) error {
+	c := &chunk{}
+	send := func() error {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		select {
+		case todo <- c:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		c = &chunk{seq: c.seq + 1}
+		return nil
+	}
+	for rows := 1; ; rows++ {
+		record, line, err := records.Read()
+		if err == io.EOF {
+			break
+		}
+		if p.MaxRows > 0 && rows > p.MaxRows {
+			return fmt.Errorf("%w, the limit is %d", ErrTooManyRows, p.MaxRows)
+		}
+		var bad *badLine
+		switch {
+		case errors.As(err, &bad):
+			c.errors = append(c.errors, bad.LineError)
+		case err != nil:
+			return fmt.Errorf("can't read file: %w", err)
+		default:
+			c.lines, c.records = append(c.lines, line), append(c.records, record)
+		}
+		c.read++
+		if c.read >= size {
+			if err := send(); err != nil {
+				return err
+			}
+		}
+	}
+	if c.read > 0 {
+		return send()
+	}
+	return nil
+}
+
+// parse makes transactions of the chunk's records, errors of the chunk are sorted by line
+func (p Pipeline) parse(c *chunk, account string) {
+	for i, record := range c.records {
+		if len(record) < 4 {
+			c.errors = append(c.errors, model.LineError{Line: c.lines[i],
+				Error: fmt.Sprintf("wrong number of fields, expected at least 4, got %d", len(record))})
+			continue
+		}
+		tr, err := p.Store.ParseTransaction(record)
+		if err != nil {
+			c.errors = append(c.errors, model.LineError{Line: c.lines[i], Error: err.Error()})
+			continue
+		}
+		if account != "" {
+			tr.Account = account
+		}
+		c.transactions = append(c.transactions, tr)
+	}
+	c.records = nil
+	sort.Slice(c.errors, func(i, j int) bool { return c.errors[i].Line < c.errors[j].Line })
+}
+
+// stage adds parsed transactions of the chunk to the batch, starting it if needed, and counts the chunk
+func (p Pipeline) stage(ctx context.Context, batchID *string, c *chunk, res *model.IngestResult) error {
+	res.Lines += c.read
+	res.Rejected += len(c.errors)
+	for _, e := range c.errors {
+		if len(res.Errors) < MaxErrors {
+			res.Errors = append(res.Errors, e)
+		}
+	}
+	if len(c.transactions) == 0 {
+		return nil
+	}
+	id, err := p.Store.StageTransactions(ctx, *batchID, c.transactions)
+	if err != nil {
+		return fmt.Errorf("can't stage transactions of lines %d-%d: %w", c.lines[0], c.lines[len(c.lines)-1], err)
+	}
+	*batchID = id
+	res.Accepted += len(c.transactions)
+	return nil
+}
@@ -0,0 +1,208 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/mrnbort/summer_break/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPipeline_Run(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	lines := []string{}
+	for i := 1; i <= 100; i++ {
+		switch {
+		case i%10 == 3:
+			lines = append(lines, "bad line")
+		case i%25 == 0:
+			lines = append(lines, fmt.Sprintf("2020-07-%02d,Expense,xyz,line %d", i%28+1, i))
+		default:
+			lines = append(lines, fmt.Sprintf("2020-07-%02d,Expense,%d,line %d", i%28+1, i, i))
+		}
+	}
+	proc := processor.NewProc()
+	p := Pipeline{Store: proc, ChunkSize: 7, Workers: 3}
+	res, err := p.Run(ctx, strings.NewReader(strings.Join(lines, "\n")), "card")
+	require.NoError(t, err)
+	assert.Equal(t, 100, res.Lines)
+	assert.Equal(t, 86, res.Accepted)
+	assert.Equal(t, 14, res.Rejected)
+	require.Len(t, res.Errors, 14)
+	for i := 1; i < len(res.Errors); i++ {
+		assert.Less(t, res.Errors[i-1].Line, res.Errors[i].Line, "errors in line order")
+	}
+	assert.Equal(t, model.LineError{Line: 3, Error: "wrong number of fields, expected at least 4, got 1"}, res.Errors[0])
+	assert.Equal(t, 25, res.Errors[3].Line)
+	assert.Contains(t, res.Errors[3].Error, "incorrect amount value")
+
+	trs, err := proc.Transactions(ctx, model.Filter{})
+	require.NoError(t, err)
+	require.Len(t, trs, 86)
+	ids := map[string]string{}
+	for _, tr := range trs {
+		assert.Equal(t, "card", tr.Account)
+		ids[tr.Memo] = tr.ID
+	}
+	assert.Equal(t, "1", ids["line 1"], "ids in file order")
+	assert.Equal(t, "2", ids["line 2"])
+	assert.Equal(t, "86", ids["line 99"])
+
+	t.Run("too many errors", func(t *testing.T) {
+		res, err := p.Run(ctx, strings.NewReader(strings.Repeat("bad\n", MaxErrors+10)), "card")
+		require.NoError(t, err)
+		assert.Equal(t, MaxErrors+10, res.Rejected)
+		assert.Len(t, res.Errors, MaxErrors)
+		assert.Equal(t, 0, res.Accepted)
+	})
+
+	t.Run("store error", func(t *testing.T) {
+		csv := "2020-07-01,Expense,1,x\n2020-07-02,Expense,2,y\n"
+		res, err := p.Run(ctx, strings.NewReader(csv), "Bad Account")
+		require.Error(t, err)
+		assert.Equal(t, 0, res.Accepted)
+
+		store := &fakeStore{Store: proc, commitErr: errors.New("oh oh")}
+		_, err = Pipeline{Store: store, ChunkSize: 1}.Run(ctx, strings.NewReader(csv), "card")
+		require.EqualError(t, err, "oh oh")
+		trs, err := proc.Transactions(ctx, model.Filter{})
+		require.NoError(t, err)
+		assert.Len(t, trs, 86, "nothing stored")
+	})
+
+	t.Run("too many rows", func(t *testing.T) {
+		lim := p
+		lim.MaxRows = 99
+		_, err := lim.Run(ctx, strings.NewReader(strings.Join(lines, "\n")), "card")
+		require.ErrorIs(t, err, ErrTooManyRows)
+		trs, err := proc.Transactions(ctx, model.Filter{})
+		require.NoError(t, err)
+		assert.Len(t, trs, 86, "nothing stored")
+
+		lim.MaxRows = 100
+		res, err := lim.Run(ctx, strings.NewReader(strings.Join(lines, "\n")), "cash")
+		require.NoError(t, err)
+		assert.Equal(t, 86, res.Accepted)
+	})
+
+	t.Run("too many flagged", func(t *testing.T) {
+		csv := strings.Repeat("2020-07-01,Expense,1,old\n", MaxFlagged+50)
+		res, err := p.Run(ctx, strings.NewReader(csv), "old")
+		require.NoError(t, err)
+		assert.Equal(t, MaxFlagged+50, res.Accepted)
+		assert.Equal(t, MaxFlagged+50, res.FlaggedCount, "old dates")
+		assert.Len(t, res.Flagged, MaxFlagged)
+	})
+}
+
+func TestPipeline_RunBackpressure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	unblock := make(chan struct{})
+	store := &fakeStore{Store: processor.NewProc(), stageBlock: unblock}
+	src := &endless{}
+	errs := make(chan error)
+	go func() {
+		_, err := Pipeline{Store: store, ChunkSize: 10, Workers: 2}.Run(ctx, src, "card")
+		errs <- err
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	read := atomic.LoadInt64(&src.lines)
+	assert.Greater(t, read, int64(0))
+	assert.Less(t, read, int64(1000), "reader waits for the store")
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, read, atomic.LoadInt64(&src.lines))
+
+	cancel()
+	close(unblock)
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&store.discarded))
+}
+
+// fakeStore wraps the store to block staging or fail commits
+type fakeStore struct {
+	Store
+	stageBlock <-chan struct{}
+	commitErr  error
+	discarded  int64
+}
+
+func (s *fakeStore) StageTransactions(ctx context.Context, batchID string, trs []model.Transaction) (string, error) {
+	id, err := s.Store.StageTransactions(ctx, batchID, trs)
+	if s.stageBlock != nil {
+		<-s.stageBlock
+	}
+	return id, err
+}
+
+func (s *fakeStore) CommitBatch(ctx context.Context, batchID string) ([]model.Transaction, error) {
+	if s.commitErr != nil {
+		return nil, s.commitErr
+	}
+	return s.Store.CommitBatch(ctx, batchID)
+}
+
+func (s *fakeStore) DiscardBatch(ctx context.Context, batchID string) error {
+	atomic.AddInt64(&s.discarded, 1)
+	return s.Store.DiscardBatch(ctx, batchID)
+}
+
+// endless is a reader of endless CSV lines
+type endless struct {
+	lines int64
+	buf   []byte
+}
+
+func (e *endless) Read(p []byte) (int, error) {
+	for len(e.buf) < len(p) {
+		n := atomic.AddInt64(&e.lines, 1)
+		e.buf = append(e.buf, fmt.Sprintf("2020-07-01,Expense,%d,line\n", n)...)
+	}
+	n := copy(p, e.buf)
+	e.buf = e.buf[n:]
+	return n, nil
+}
+
+var _ io.Reader = &endless{}
+
+func TestPipeline_RunOFX(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	f, err := os.Open("../testdata/statement.ofx")
+	require.NoError(t, err)
+	defer f.Close()
+
+	proc := processor.NewProc()
+	res, err := Pipeline{Store: proc, ChunkSize: 2}.RunOFX(ctx, f, "checking")
+	require.NoError(t, err)
+	assert.Equal(t, 4, res.Lines)
+	assert.Equal(t, 3, res.Accepted)
+	assert.Equal(t, []model.LineError{{Line: 30, Error: `invalid DTPOSTED "2020"`}}, res.Errors)
+
+	lines, err := proc.Transactions(ctx, model.Filter{})
+	require.NoError(t, err)
+	require.Len(t, lines, 3)
+	tr := lines[0].Transaction
+	assert.Equal(t, []interface{}{"checking", time.Date(2020, 7, 1, 0, 0, 0, 0, time.Local), model.Expense, 18.77, "Shell"},
+		[]interface{}{tr.Account, tr.Date, tr.Type, tr.Amount, tr.Memo})
+	tr = lines[1].Transaction
+	assert.Equal(t, []interface{}{model.Income, 40.0, "347 Woodrow"}, []interface{}{tr.Type, tr.Amount, tr.Memo})
+	assert.Equal(t, "Jim's Repairs", lines[2].Memo)
+
+	res, err = Pipeline{Store: proc}.RunOFX(ctx, strings.NewReader("<OFX><STMTTRN><TRNAMT>1"), "checking")
+	require.NoError(t, err)
+	assert.Equal(t, []model.LineError{{Line: 1, Error: "unterminated transaction"}}, res.Errors)
+}
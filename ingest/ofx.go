@@ -0,0 +1,87 @@
+package ingest
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/mrnbort/summer_break/model"
+	"html"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ofxRecords reads STMTTRN elements of OFX statement as CSV-like records of date, type, amount and memo.
+// Both SGML (OFX 1.x, leaf elements without end tags) and XML (OFX 2.x) forms are supported.
+// Negative amounts are expenses, the memo is the payee name or the memo if there is no name.
+type ofxRecords struct {
+	reader  *bufio.Reader
+	line    int               // newlines read so far
+	trn     map[string]string // elements of the current transaction, nil outside of it
+	trnLine int               // line of the current transaction start
+	tag     string            // element the next text belongs to
+}
+
+func newOFXRecords(r io.Reader) *ofxRecords {
+	return &ofxRecords{reader: bufio.NewReader(r)}
+}
+
+func (o *ofxRecords) Read() ([]string, int, error) {
+	for {
+		text, err := o.reader.ReadString('<')
+		o.line += strings.Count(text, "\n")
+		if o.trn != nil && o.tag != "" {
+			o.trn[o.tag] = html.UnescapeString(strings.TrimSpace(strings.TrimSuffix(text, "<")))
+		}
+		o.tag = ""
+		if err == io.EOF && o.trn != nil {
+			o.trn = nil
+			return nil, o.trnLine, &badLine{model.LineError{Line: o.trnLine, Error: "unterminated transaction"}}
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		name, err := o.reader.ReadString('>')
+		o.line += strings.Count(name, "\n")
+		if err != nil {
+			return nil, 0, err
+		}
+		name = strings.ToUpper(strings.TrimSpace(strings.TrimSuffix(name, ">")))
+		switch {
+		case name == "STMTTRN":
+			o.trn, o.trnLine = map[string]string{}, o.line+1
+		case name == "/STMTTRN" && o.trn != nil:
+			rec, err := ofxRecord(o.trn)
+			o.trn = nil
+			if err != nil {
+				return nil, o.trnLine, &badLine{model.LineError{Line: o.trnLine, Error: err.Error()}}
+			}
+			return rec, o.trnLine, nil
+		case !strings.HasPrefix(name, "/"):
+			o.tag = name
+		}
+	}
+}
+
+// ofxRecord makes record of date, type, amount and memo from transaction elements
+func ofxRecord(trn map[string]string) ([]string, error) {
+	posted := trn["DTPOSTED"]
+	if len(posted) < 8 {
+		return nil, fmt.Errorf("invalid DTPOSTED %q", posted)
+	}
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(trn["TRNAMT"], ",", "."), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRNAMT %q", trn["TRNAMT"])
+	}
+	tp := model.Income
+	if amount < 0 {
+		tp = model.Expense
+	}
+	memo := trn["NAME"]
+	if memo == "" {
+		memo = trn["MEMO"]
+	}
+	date := posted[0:4] + "-" + posted[4:6] + "-" + posted[6:8]
+	return []string{date, string(tp), strconv.FormatFloat(math.Abs(amount), 'f', -1, 64), memo}, nil
+}
@@ -0,0 +1,329 @@
+// Package jobs ingests uploaded files in the background.
+package jobs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mrnbort/summer_break/audit"
+	"github.com/mrnbort/summer_break/ingest"
+	"github.com/mrnbort/summer_break/model"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// default manager settings
+const (
+	DefaultWorkers   = 2
+	DefaultQueueSize = 100
+	DefaultRetention = 24 * time.Hour
+)
+
+// ErrQueueFull returned when too many jobs are waiting to be processed
+var ErrQueueFull = errors.New("too many queued jobs")
+
+// Manager keeps uploads in files and ingests them with the pipeline in a pool of workers.
+// Jobs are kept in memory, finished ones are dropped after Retention.
+type Manager struct {
+	Pipeline  ingest.Pipeline // its Progress is replaced to update jobs and Store is wrapped to commit them
+	Dir       string          // directory for uploaded files, os.TempDir() if not set
+	Workers   int             // jobs processed at once, DefaultWorkers if not set
+	QueueSize int             // jobs waiting to be processed, DefaultQueueSize if not set
+	Retention time.Duration   // how long finished jobs are kept, DefaultRetention if not set
+	Audit     *audit.Log      // records ingestion of the jobs, if set
+
+	once   sync.Once
+	mu     sync.Mutex
+	jobs   map[string]*job
+	queue  chan *job
+	lastID int64
+}
+
+// job is a job with its uploaded file
+type job struct {
+	model.Job
+	file   string
+	sha256 string // of the file
+	size   int64
+	cancel context.CancelFunc // set while running
+	commit bool               // set when the batch is being committed, the job can't be canceled after that
+}
+
+// jobStore is the store of the job's pipeline, it marks the job before committing the batch,
+// so a canceled job is never committed and a committed job is never canceled
+type jobStore struct {
+	ingest.Store
+	m *Manager
+	j *job
+}
+
+// CommitBatch commits the batch unless the job is canceled, the commit is not interrupted by cancellation
+func (s jobStore) CommitBatch(_ context.Context, batchID string) ([]model.Transaction, error) {
+	s.m.mu.Lock()
+	canceled := s.j.Status == model.JobCanceled
+	s.j.commit = !canceled
+	s.m.mu.Unlock()
+	if canceled {
+		return nil, context.Canceled
+	}
+	return s.Store.CommitBatch(context.Background(), batchID)
+}
+
+func (m *Manager) init() {
+	m.once.Do(func() {
+		size := m.QueueSize
+		if size <= 0 {
+			size = DefaultQueueSize
+		}
+		m.jobs = map[string]*job{}
+		m.queue = make(chan *job, size)
+	})
+}
+
+// Run processes queued jobs until ctx is canceled. Jobs running or queued at that time fail,
+// files of the queued ones are removed.
+func (m *Manager) Run(ctx context.Context) error {
+	m.init()
+	workers := m.Workers
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case j := <-m.queue:
+					m.process(ctx, j)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	m.drain()
+	return ctx.Err()
+}
+
+// drain fails jobs left in the queue and removes their files
+func (m *Manager) drain() {
+	for {
+		select {
+		case j := <-m.queue:
+			m.mu.Lock()
+			if j.Status == model.JobQueued {
+				j.Status, j.Error, j.Finished = model.JobFailed, "stopped before processing", time.Now()
+			}
+			m.mu.Unlock()
+			if err := os.Remove(j.file); err != nil {
+				log.Printf("[WARN] can't remove upload of job %s: %v", j.ID, err)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// Submit stores the uploaded file and queues it for ingestion to the account on behalf of the actor.
+// Returns the queued job or ErrQueueFull if the queue is full.
+func (m *Manager) Submit(account, actor string, r io.Reader) (model.Job, error) {
+	m.init()
+	m.prune(time.Now())
+
+	f, err := os.CreateTemp(m.Dir, "upload-*.csv")
+	if err != nil {
+		return model.Job{}, fmt.Errorf("can't store upload: %w", err)
+	}
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, hash), r)
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return model.Job{}, fmt.Errorf("can't store upload: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastID++
+	j := &job{Job: model.Job{ID: strconv.FormatInt(m.lastID, 10), Account: account, Actor: actor,
+		Status: model.JobQueued, Created: time.Now()}, file: f.Name(), sha256: hex.EncodeToString(hash.Sum(nil)), size: size}
+	select {
+	case m.queue <- j:
+	default:
+		_ = os.Remove(f.Name())
+		return model.Job{}, ErrQueueFull
+	}
+	m.jobs[j.ID] = j
+	return j.Job, nil
+}
+
+// Job returns the job with given id
+func (m *Manager) Job(id string) (model.Job, error) {
+	m.init()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return model.Job{}, fmt.Errorf("job %q: %w", id, model.ErrNotFound)
+	}
+	return j.Job, nil
+}
+
+// Jobs returns all kept jobs in the order they were submitted
+func (m *Manager) Jobs() []model.Job {
+	m.init()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res := make([]model.Job, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		res = append(res, j.Job)
+	}
+	sort.Slice(res, func(i, j int) bool { // ids are increasing numbers
+		a, b := res[i].ID, res[j].ID
+		return len(a) < len(b) || len(a) == len(b) && a < b
+	})
+	return res
+}
+
+// Cancel cancels queued or running job, nothing of the canceled job is stored.
+// Returns model.ErrConflict if the job is finished or its transactions are being stored.
+func (m *Manager) Cancel(id string) (model.Job, error) {
+	m.init()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return model.Job{}, fmt.Errorf("job %q: %w", id, model.ErrNotFound)
+	}
+	if j.IsFinished() {
+		return model.Job{}, fmt.Errorf("job %q is %s: %w", id, j.Status, model.ErrConflict)
+	}
+	if j.commit {
+		return model.Job{}, fmt.Errorf("job %q is being stored: %w", id, model.ErrConflict)
+	}
+	if j.cancel != nil {
+		j.cancel()
+	}
+	j.Status, j.Finished = model.JobCanceled, time.Now()
+	return j.Job, nil
+}
+
+// process ingests the job's file unless it's canceled, the file is removed after that
+func (m *Manager) process(ctx context.Context, j *job) {
+	defer func() {
+		if err := os.Remove(j.file); err != nil {
+			log.Printf("[WARN] can't remove upload of job %s: %v", j.ID, err)
+		}
+	}()
+
+	m.mu.Lock()
+	if j.Status == model.JobCanceled {
+		m.mu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	j.Status, j.Started, j.cancel = model.JobRunning, time.Now(), cancel
+	m.mu.Unlock()
+
+	pending, err := m.begin(j)
+	res := model.IngestResult{}
+	if err == nil {
+		res, err = m.ingest(ctx, j)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j.Result, j.cancel = res, nil
+	if j.Status != model.JobCanceled { // canceled while running otherwise
+		j.Finished = time.Now()
+		switch {
+		case err != nil:
+			j.Status, j.Error = model.JobFailed, err.Error()
+		case res.Accepted == 0:
+			j.Status, j.Error = model.JobFailed, "no valid transactions"
+		default:
+			j.Status = model.JobDone
+		}
+		log.Printf("[INFO] job %s %s, %d lines, %d accepted, %d rejected", j.ID, j.Status, res.Lines, res.Accepted, res.Rejected)
+	}
+	if pending.Seq > 0 {
+		m.finish(pending, j.Job)
+	}
+}
+
+// begin records the job's ingestion as pending in the audit log, if it's set,
+// the job must not be ingested if it fails
+func (m *Manager) begin(j *job) (model.AuditEntry, error) {
+	if m.Audit == nil {
+		return model.AuditEntry{}, nil
+	}
+	pending, err := m.Audit.Begin(model.AuditEntry{Actor: j.Actor, Action: model.AuditUpload, Resource: "/jobs/" + j.ID,
+		BodySHA256: j.sha256, BodyBytes: j.size})
+	if err != nil {
+		log.Printf("[WARN] can't record job %s in audit log: %v", j.ID, err)
+		return model.AuditEntry{}, fmt.Errorf("can't record in audit log: %w", err)
+	}
+	return pending, nil
+}
+
+// finish records outcome of the job in the audit log
+func (m *Manager) finish(pending model.AuditEntry, j model.Job) {
+	done := model.AuditEntry{}
+	if j.Status != model.JobDone {
+		done.Outcome = model.AuditFailed
+	}
+	var err error
+	if done.After, err = json.Marshal(j); err == nil {
+		_, err = m.Audit.Finish(pending, done)
+	}
+	if err != nil {
+		log.Printf("[WARN] can't record outcome of job %s in audit log: %v", j.ID, err)
+	}
+}
+
+// ingest runs the job's file through the pipeline, updating the job's progress
+func (m *Manager) ingest(ctx context.Context, j *job) (model.IngestResult, error) {
+	f, err := os.Open(j.file)
+	if err != nil {
+		return model.IngestResult{}, fmt.Errorf("can't open upload: %w", err)
+	}
+	defer f.Close()
+
+	p := m.Pipeline
+	p.Store = jobStore{Store: p.Store, m: m, j: j}
+	p.Progress = func(res model.IngestResult) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		j.Result = res
+	}
+	return p.Run(ctx, f, j.Account)
+}
+
+// prune drops jobs finished more than Retention ago
+func (m *Manager) prune(now time.Time) {
+	retention := m.Retention
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, j := range m.jobs {
+		if j.IsFinished() && now.Sub(j.Finished) > retention {
+			delete(m.jobs, id)
+		}
+	}
+}
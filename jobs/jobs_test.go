@@ -0,0 +1,213 @@
+package jobs
+
+import (
+	"context"
+	"github.com/mrnbort/summer_break/audit"
+	"github.com/mrnbort/summer_break/ingest"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/mrnbort/summer_break/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+const data = "2020-07-01,Expense,18.77,Fuel\nbad line\n2020-07-04,Income,40.00,347 Woodrow\n"
+
+func TestManager(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	proc := processor.NewProc()
+	dir := t.TempDir()
+	auditLog, err := audit.Open(audit.Config{Key: []byte("secret")})
+	require.NoError(t, err)
+	m := &Manager{Pipeline: ingest.Pipeline{Store: proc}, Dir: dir, Audit: auditLog}
+
+	job, err := m.Submit("card", "user bob", strings.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, "1", job.ID)
+	assert.Equal(t, "user bob", job.Actor)
+	assert.Equal(t, model.JobQueued, job.Status)
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 1, "upload is stored")
+
+	empty, err := m.Submit("card", "user bob", strings.NewReader("bad line\n"))
+	require.NoError(t, err)
+	canceled, err := m.Submit("card", "user bob", strings.NewReader(data))
+	require.NoError(t, err)
+	canceled, err = m.Cancel(canceled.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.JobCanceled, canceled.Status)
+	_, err = m.Cancel(canceled.ID)
+	assert.ErrorIs(t, err, model.ErrConflict, "already canceled")
+
+	go func() { _ = m.Run(ctx) }()
+	job = waitJob(t, m, job.ID)
+	assert.Equal(t, model.JobDone, job.Status)
+	assert.Equal(t, 3, job.Result.Lines)
+	assert.Equal(t, 2, job.Result.Accepted)
+	assert.Equal(t, 1, job.Result.Rejected)
+	assert.Equal(t, []model.LineError{{Line: 2, Error: "wrong number of fields, expected at least 4, got 1"}}, job.Result.Errors)
+	assert.False(t, job.Started.IsZero())
+	assert.False(t, job.Finished.Before(job.Started))
+
+	empty = waitJob(t, m, empty.ID)
+	assert.Equal(t, model.JobFailed, empty.Status)
+	assert.Equal(t, "no valid transactions", empty.Error)
+
+	report, err := proc.GenerateReport(ctx, model.Filter{Accounts: []string{"card"}})
+	require.NoError(t, err)
+	assert.Equal(t, model.Report{GrossRevenue: 40, Expenses: 18.77, NetRevenue: 21.23}, report, "canceled job not stored")
+
+	auditEntries := func(filter model.AuditFilter) []model.AuditEntry {
+		entries, err := auditLog.Entries(filter, 0)
+		require.NoError(t, err)
+		return entries
+	}
+	entries := auditEntries(model.AuditFilter{Resource: "/jobs/1"})
+	require.Len(t, entries, 2, "pending and done")
+	assert.Equal(t, model.AuditDone, entries[0].Outcome)
+	assert.Equal(t, "user bob", entries[0].Actor)
+	assert.Equal(t, entries[1].Seq, entries[0].Ref)
+	assert.Contains(t, string(entries[0].After), `"accepted":2`)
+	assert.Equal(t, int64(len(data)), entries[1].BodyBytes)
+	assert.Len(t, entries[1].BodySHA256, 64)
+	assert.Len(t, auditEntries(model.AuditFilter{Resource: "/jobs/2", Outcome: model.AuditFailed}), 1)
+	assert.Empty(t, auditEntries(model.AuditFilter{Resource: "/jobs/3"}), "canceled before it started")
+
+	assert.Eventually(t, func() bool {
+		files, err := os.ReadDir(dir)
+		return err == nil && len(files) == 0
+	}, time.Second, 10*time.Millisecond, "uploads removed")
+
+	list := m.Jobs()
+	require.Len(t, list, 3)
+	assert.Equal(t, []string{"1", "2", "3"}, []string{list[0].ID, list[1].ID, list[2].ID})
+	_, err = m.Job("unknown")
+	assert.ErrorIs(t, err, model.ErrNotFound)
+
+	m.prune(time.Now().Add(DefaultRetention + time.Minute))
+	assert.Empty(t, m.Jobs())
+}
+
+func TestManager_CancelRunning(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	proc := processor.NewProc()
+	started := make(chan struct{})
+	m := &Manager{Pipeline: ingest.Pipeline{Store: &blockingStore{Store: proc, started: started}, ChunkSize: 1}}
+	go func() { _ = m.Run(ctx) }()
+	job, err := m.Submit("card", "user bob", strings.NewReader(data))
+	require.NoError(t, err)
+
+	<-started
+	job, err = m.Cancel(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.JobCanceled, job.Status)
+	job = waitJob(t, m, job.ID)
+	assert.Equal(t, model.JobCanceled, job.Status)
+
+	accounts, err := proc.Accounts(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, accounts, "nothing stored")
+}
+
+func TestManager_CancelCommitting(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	proc := processor.NewProc()
+	store := &committingStore{Store: proc, committing: make(chan struct{}), release: make(chan struct{})}
+	m := &Manager{Pipeline: ingest.Pipeline{Store: store}, Dir: t.TempDir()}
+	go func() { _ = m.Run(ctx) }()
+	job, err := m.Submit("card", "user bob", strings.NewReader(data))
+	require.NoError(t, err)
+
+	<-store.committing
+	_, err = m.Cancel(job.ID)
+	assert.ErrorIs(t, err, model.ErrConflict, "being stored")
+	close(store.release)
+	job = waitJob(t, m, job.ID)
+	assert.Equal(t, model.JobDone, job.Status)
+	accounts, err := proc.Accounts(ctx)
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	assert.Equal(t, 2, accounts[0].Transactions)
+}
+
+func TestManager_Shutdown(t *testing.T) {
+	m := &Manager{Pipeline: ingest.Pipeline{Store: processor.NewProc()}, Dir: t.TempDir()}
+	for i := 0; i < 3; i++ {
+		_, err := m.Submit("card", "user bob", strings.NewReader(data))
+		require.NoError(t, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, m.Run(ctx), context.Canceled)
+	for _, job := range m.Jobs() {
+		assert.Equal(t, model.JobFailed, job.Status, "job %s", job.ID)
+		assert.False(t, job.Finished.IsZero())
+	}
+	files, err := os.ReadDir(m.Dir)
+	require.NoError(t, err)
+	assert.Empty(t, files, "uploads removed")
+}
+
+func TestManager_QueueFull(t *testing.T) {
+	m := &Manager{Pipeline: ingest.Pipeline{Store: processor.NewProc()}, QueueSize: 1, Dir: t.TempDir()}
+	_, err := m.Submit("card", "user bob", strings.NewReader(data))
+	require.NoError(t, err)
+	_, err = m.Submit("card", "user bob", strings.NewReader(data))
+	assert.ErrorIs(t, err, ErrQueueFull)
+	files, err := os.ReadDir(m.Dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 1, "rejected upload removed")
+}
+
+// waitJob waits for the job to finish and returns it
+func waitJob(t *testing.T, m *Manager, id string) model.Job {
+	var job model.Job
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = m.Job(id)
+		require.NoError(t, err)
+		return job.IsFinished()
+	}, 3*time.Second, 10*time.Millisecond)
+	return job
+}
+
+// blockingStore stages transactions until ctx is canceled
+type blockingStore struct {
+	ingest.Store
+	started chan struct{}
+}
+
+func (s *blockingStore) StageTransactions(ctx context.Context, batchID string, trs []model.Transaction) (string, error) {
+	id, err := s.Store.StageTransactions(ctx, batchID, trs)
+	if err != nil {
+		return "", err
+	}
+	if batchID == "" {
+		close(s.started)
+	}
+	<-ctx.Done()
+	return id, ctx.Err()
+}
+
+// committingStore waits for release before committing a batch
+type committingStore struct {
+	ingest.Store
+	committing chan struct{}
+	release    chan struct{}
+}
+
+func (s *committingStore) CommitBatch(ctx context.Context, batchID string) ([]model.Transaction, error) {
+	close(s.committing)
+	<-s.release
+	return s.Store.CommitBatch(ctx, batchID)
+}
@@ -2,11 +2,19 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/jessevdk/go-flags"
 	"github.com/mrnbort/summer_break/api"
+	"github.com/mrnbort/summer_break/audit"
+	"github.com/mrnbort/summer_break/inbox"
+	"github.com/mrnbort/summer_break/ingest"
+	"github.com/mrnbort/summer_break/jobs"
+	"github.com/mrnbort/summer_break/model"
 	"github.com/mrnbort/summer_break/processor"
+	"github.com/mrnbort/summer_break/scheduler"
+	"github.com/mrnbort/summer_break/store"
 	"log"
 	"os"
 	"os/signal"
@@ -18,12 +26,49 @@ type options struct {
 	Port             string        `short:"p" long:"port" description:"port" default:":8080"`
 	HTTPReadTimeout  time.Duration `long:"http-read-timeout" description:"timeout for read HTTP requests" default:"5s"`
 	HTTPWriteTimeout time.Duration `long:"http-write-timeout" description:"timeout for write HTTP requests" default:"30s"`
+	DoubleEntry      bool          `long:"double-entry" description:"enable double-entry ledger"`
+	DefaultAsset     string        `long:"default-asset-account" description:"asset account for uploaded transactions in double-entry mode" default:"Assets:Checking"`
+	TaxConfig        string        `long:"tax-config" description:"JSON file with fiscal year start and category to tax line mapping"`
+	ScheduleInterval time.Duration `long:"schedule-interval" description:"how often to add due recurring transactions" default:"1m"`
+	CheckAggregates  bool          `long:"check-aggregates" description:"compare running report totals with a full rescan, log mismatches"`
+	IngestChunkSize  int           `long:"ingest-chunk-size" description:"transactions of uploaded file staged at once" default:"1000"`
+	IngestWorkers    int           `long:"ingest-workers" description:"parallel parsers of uploaded file" default:"4"`
+	JobWorkers       int           `long:"job-workers" description:"async uploads processed at once" default:"2"`
+	JobDir           string        `long:"job-dir" description:"directory for async uploads waiting to be processed, system temp dir if not set"`
+	InboxDir         string        `long:"inbox-dir" description:"directory watched for CSV and OFX files to ingest, disabled if not set"`
+	InboxInterval    time.Duration `long:"inbox-interval" description:"how often to check the inbox directory" default:"10s"`
+	InboxAccount     string        `long:"inbox-account" description:"account of transactions ingested from the inbox directory" default:"default"`
+	Store            string        `long:"store" description:"JSON file to keep the data in between runs, in memory only if not set"`
+	StoreInterval    time.Duration `long:"store-interval" description:"how often the server saves data to the store file" default:"1m"`
+	Auth             bool          `long:"auth" description:"require API key, enabled by --admin-key as well"`
+	AdminKey         string        `long:"admin-key" env:"SUMMER_BREAK_ADMIN_KEY" description:"bootstrap API key with admin scope, enables --auth"`
+	SessionSecret    string        `long:"session-secret" env:"SUMMER_BREAK_SESSION_SECRET" description:"secret to sign login sessions with, random if not set"`
+	SessionTTL       time.Duration `long:"session-ttl" description:"how long login sessions last" default:"12h"`
+	AuditLog         string        `long:"audit-log" description:"file of the signed, hash-chained audit log of changes, disabled if not set"`
+	AuditHead        string        `long:"audit-head" description:"file with the last entry of the audit log, better kept apart from the log, <audit-log>.head if not set"`
+	AuditKey         string        `long:"audit-key" env:"SUMMER_BREAK_AUDIT_KEY" description:"secret the audit log entries are signed with, required with --audit-log"`
+	MaxBodyMB        int64         `long:"max-body-mb" description:"maximum size of request body in MB, except uploads and imports, 0 for no limit" default:"32"`
+	MaxUploadMB      int64         `long:"max-upload-mb" description:"maximum size of uploaded CSV or OFX file in MB, 0 for no limit" default:"0"`
+	MaxImportMB      int64         `long:"max-import-mb" description:"maximum size of imported archive in MB, 0 for no limit" default:"1024"`
+	MaxUploadRows    int           `long:"max-upload-rows" description:"maximum rows of uploaded file, 0 for no limit" default:"1000000"`
+	MaxUploadParts   int           `long:"max-upload-parts" description:"maximum multipart parts read looking for uploaded file, 0 for no limit" default:"10"`
+	RateLimit        float64       `long:"rate-limit" description:"requests a second per client, 0 for no limit" default:"0"`
+	RateBurst        int           `long:"rate-burst" description:"requests a client can make at once, rate limit rounded up if 0" default:"0"`
+	AddrRateLimit    float64       `long:"addr-rate-limit" description:"requests a second per address, checked before authentication, 0 for no limit" default:"0"`
+	AddrRateBurst    int           `long:"addr-rate-burst" description:"requests an address can make at once, address rate limit rounded up if 0" default:"0"`
+
+	Import   importCmd   `command:"import" description:"import CSV or OFX file to the store"`
+	Report   reportCmd   `command:"report" description:"print report of stored transactions"`
+	Export   exportCmd   `command:"export" description:"write stored transactions as CSV or the store as archive"`
+	Validate validateCmd `command:"validate" description:"check CSV or OFX file without storing it"`
+	Client   clientCmd   `command:"client" description:"call the running service"`
 }
 
 func main() {
 
 	var opts options
 	p := flags.NewParser(&opts, flags.PrintErrors|flags.PassDoubleDash|flags.HelpFlag)
+	p.SubcommandsOptional = true // the server runs without a command
 	if _, err := p.Parse(); err != nil {
 		if err.(*flags.Error).Type != flags.ErrHelp {
 			fmt.Printf("%v", err)
@@ -31,19 +76,73 @@ func main() {
 		os.Exit(1)
 	}
 
+	if p.Active != nil {
+		name := p.Active.Name
+		for cmd := p.Active.Active; cmd != nil; cmd = cmd.Active {
+			name += " " + cmd.Name
+		}
+		if err := runCommand(name, opts, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if err := run(opts); err != nil {
 		log.Panicf("[ERROR] %v", err)
 	}
 }
 
 func run(opts options) error {
-	transactions := processor.NewProc()
+	if opts.Store != "" {
+		unlock, err := store.File{Path: opts.Store}.Lock()
+		if err != nil {
+			return err
+		}
+		defer unlock() //nolint
+	}
+	transactions, err := newProc(opts)
+	if err != nil {
+		return err
+	}
+
+	auditLog, err := openAudit(opts)
+	if err != nil {
+		return err
+	}
+	if auditLog != nil {
+		defer auditLog.Close() //nolint
+	}
+
+	pipeline := ingest.Pipeline{Store: transactions, ChunkSize: opts.IngestChunkSize, Workers: opts.IngestWorkers,
+		MaxRows: opts.MaxUploadRows}
+	jobManager := &jobs.Manager{Pipeline: pipeline, Dir: opts.JobDir, Workers: opts.JobWorkers, Audit: auditLog}
 
 	apiService := api.Service{
 		Processor:    transactions,
 		Port:         opts.Port,
 		ReadTimeOut:  opts.HTTPReadTimeout,
 		WriteTimeOut: opts.HTTPWriteTimeout,
+
+		IngestChunkSize: opts.IngestChunkSize,
+		IngestWorkers:   opts.IngestWorkers,
+		Jobs:            jobManager,
+
+		Auth:          opts.Auth || opts.AdminKey != "",
+		AdminKey:      opts.AdminKey,
+		SessionSecret: []byte(opts.SessionSecret),
+		SessionTTL:    opts.SessionTTL,
+		Audit:         auditLog,
+
+		MaxBodySize:   opts.MaxBodyMB << 20,
+		MaxUploadSize: opts.MaxUploadMB << 20,
+		MaxImportSize: opts.MaxImportMB << 20,
+		MaxRows:       opts.MaxUploadRows,
+		MaxParts:      opts.MaxUploadParts,
+		RateLimit:     opts.RateLimit,
+		RateBurst:     opts.RateBurst,
+		AddrRateLimit: opts.AddrRateLimit,
+		AddrRateBurst: opts.AddrRateBurst,
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -55,7 +154,46 @@ func run(opts options) error {
 		cancel()
 	}()
 
-	if err := apiService.Run(ctx); err != nil {
+	sched := scheduler.Scheduler{Store: transactions, Interval: opts.ScheduleInterval}
+	go func() {
+		if err := sched.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("[WARN] scheduler failed: %v", err)
+		}
+	}()
+
+	saved := make(chan struct{}) // closed when the store is saved on shutdown
+	if opts.Store == "" {
+		close(saved)
+	} else {
+		storeFile := store.File{Path: opts.Store}
+		go func() {
+			defer close(saved)
+			if err := storeFile.Run(ctx, transactions, opts.StoreInterval); err != nil && !errors.Is(err, context.Canceled) {
+				log.Printf("[WARN] can't save store: %v", err)
+			}
+		}()
+	}
+
+	go func() {
+		if err := jobManager.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("[WARN] job manager failed: %v", err)
+		}
+	}()
+
+	if opts.InboxDir != "" {
+		watcher := &inbox.Watcher{Dir: opts.InboxDir, Pipeline: pipeline, Account: opts.InboxAccount,
+			Interval: opts.InboxInterval, Audit: auditLog}
+		go func() {
+			if err := watcher.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				log.Printf("[WARN] inbox watcher failed: %v", err)
+			}
+		}()
+	}
+
+	err = apiService.Run(ctx)
+	cancel()
+	<-saved
+	if err != nil {
 		if errors.Is(err, context.Canceled) {
 			log.Printf("summer break service canceled")
 			return nil
@@ -64,3 +202,53 @@ func run(opts options) error {
 	}
 	return nil
 }
+
+// newProc makes the processor with data loaded from the store file, if any, and configured by options
+func newProc(opts options) (*processor.Proc, error) {
+	proc := processor.NewProc()
+	proc.SetAggregateCheck(opts.CheckAggregates)
+	if opts.Store != "" {
+		if err := (store.File{Path: opts.Store}).Load(context.Background(), proc); err != nil {
+			return nil, fmt.Errorf("can't load store: %w", err)
+		}
+	}
+	if opts.DoubleEntry {
+		if err := proc.EnableDoubleEntry(opts.DefaultAsset); err != nil {
+			return nil, fmt.Errorf("can't enable double-entry mode: %w", err)
+		}
+	}
+	if opts.TaxConfig != "" {
+		if err := loadTaxConfig(proc, opts.TaxConfig); err != nil {
+			return nil, fmt.Errorf("can't load tax config: %w", err)
+		}
+	}
+	return proc, nil
+}
+
+// openAudit opens the audit log configured by options, nil if it's disabled
+func openAudit(opts options) (*audit.Log, error) {
+	if opts.AuditLog == "" {
+		return nil, nil
+	}
+	if opts.AuditKey == "" {
+		return nil, errors.New("audit log needs --audit-key")
+	}
+	l, err := audit.Open(audit.Config{Path: opts.AuditLog, Head: opts.AuditHead, Key: []byte(opts.AuditKey)})
+	if err != nil {
+		return nil, fmt.Errorf("can't open audit log: %w", err)
+	}
+	return l, nil
+}
+
+// loadTaxConfig reads tax configuration from JSON file and sets it
+func loadTaxConfig(proc *processor.Proc, file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	cfg := model.TaxConfig{}
+	if err = json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("can't parse %s: %w", file, err)
+	}
+	return proc.SetTaxConfig(context.Background(), cfg)
+}
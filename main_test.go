@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"testing"
 	"time"
 )
@@ -28,6 +29,7 @@ func Test_run(t *testing.T) {
 	}()
 
 	client := http.Client{Timeout: 3 * time.Second}
+	waitForServer(t, "http://localhost:8081/report")
 	file, err := os.Open("testdata/data.csv")
 	if err != nil {
 		t.Fatalf("Failed to open CSV file: %v", err)
@@ -55,7 +57,9 @@ func Test_run(t *testing.T) {
 		assert.Equal(t, http.StatusOK, resp.StatusCode)
 		data, err := io.ReadAll(resp.Body)
 		require.NoError(t, err)
-		assert.Equal(t, `{"status":"ok"}`+"\n", string(data))
+		assert.True(t, strings.HasPrefix(string(data), `{"accepted":10,"errors":[{"line":4,`), string(data))
+		assert.Contains(t, string(data), `"flagged":[`, "test data is more than two years old")
+		assert.Contains(t, string(data), `"rejected":1,"status":"ok"}`)
 	})
 
 	t.Run("successful get", func(t *testing.T) {
@@ -77,3 +81,15 @@ func Test_run(t *testing.T) {
 	defer cancel()
 	signal.NotifyContext(ctx, os.Interrupt)
 }
+
+// waitForServer waits until the server started by run responds
+func waitForServer(t *testing.T, url string) {
+	for i := 0; i < 100; i++ {
+		if resp, err := http.Get(url); err == nil {
+			resp.Body.Close() //nolint
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("server at %s didn't start", url)
+}
@@ -0,0 +1,10 @@
+package model
+
+// anomaly flags of ingested transactions
+const (
+	FlagOutlier    = "outlier"     // amount is far outside of the usual amounts of its category
+	FlagDuplicate  = "duplicate"   // same account, date, type, amount and memo as another transaction
+	FlagFutureDate = "future-date" // dated after today
+	FlagOldDate    = "old-date"    // dated more than two years ago
+	FlagNewPayee   = "new-payee"   // memo never seen before
+)
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// API key scopes, a key is allowed endpoints of its scopes and admin is allowed all of them
+const (
+	ScopeReadReport        = "read-report"        // listing transactions, reports, budgets and jobs
+	ScopeWriteTransactions = "write-transactions" // uploads and changes of transactions, accounts, budgets and schedules
+	ScopeAdmin             = "admin"              // API keys, tax and chart of accounts config, export and import
+)
+
+// APIKey is a key to call the API with. Only SHA-256 hash of the key is stored, the key itself
+// is returned once, when it's made.
+type APIKey struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Prefix  string    `json:"prefix"`         // the first characters of the key, to tell keys apart
+	Hash    string    `json:"hash,omitempty"` // hex SHA-256 of the key
+	Scopes  []string  `json:"scopes"`
+	Created time.Time `json:"created"`
+	Key     string    `json:"key,omitempty"` // the key, only in response to its creation
+}
+
+// Validate checks the key has name and known scopes
+func (k APIKey) Validate() error {
+	if strings.TrimSpace(k.Name) == "" {
+		return fmt.Errorf("key name is required")
+	}
+	if len(k.Scopes) == 0 {
+		return fmt.Errorf("key should have at least one scope")
+	}
+	for _, s := range k.Scopes {
+		if err := ValidateScope(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HasScope tells if the key is allowed endpoints of the scope
+func (k APIKey) HasScope(scope string) bool {
+	return HasScope(k.Scopes, scope)
+}
+
+// HasScope tells if scopes allow endpoints of the scope, admin scope allows all of them
+func HasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateScope checks the scope is one of ScopeReadReport, ScopeWriteTransactions or ScopeAdmin
+func ValidateScope(scope string) error {
+	switch scope {
+	case ScopeReadReport, ScopeWriteTransactions, ScopeAdmin:
+		return nil
+	}
+	return fmt.Errorf("unknown scope %q, expected %q, %q or %q", scope, ScopeReadReport, ScopeWriteTransactions, ScopeAdmin)
+}
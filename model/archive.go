@@ -0,0 +1,29 @@
+package model
+
+import "fmt"
+
+// import conflict policies, applied to imported records which are already stored
+const (
+	ImportFail    = "fail"    // nothing is imported if any record conflicts
+	ImportSkip    = "skip"    // stored records are kept
+	ImportReplace = "replace" // stored records are replaced by imported ones
+)
+
+// ValidateImportPolicy checks the policy is one of ImportFail, ImportSkip or ImportReplace
+func ValidateImportPolicy(policy string) error {
+	switch policy {
+	case ImportFail, ImportSkip, ImportReplace:
+		return nil
+	}
+	return fmt.Errorf("unknown import policy %q, expected fail, skip or replace", policy)
+}
+
+// ImportResult has numbers of imported records. Records are transactions, accounts, budgets, schedules
+// and the tax config; a record equal to the stored one is counted as unchanged, not as a conflict.
+type ImportResult struct {
+	Added     int      `json:"added"`
+	Replaced  int      `json:"replaced"`
+	Skipped   int      `json:"skipped"`
+	Unchanged int      `json:"unchanged"`
+	Conflicts []string `json:"conflicts,omitempty"` // conflicting records, i.e. "transaction 12", with fail policy
+}
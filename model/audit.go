@@ -0,0 +1,79 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// audit actions
+const (
+	AuditUpload = "upload" // transactions uploaded as file or JSON
+	AuditImport = "import" // archive imported
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+	AuditConfig = "config" // tax config, chart of accounts, API keys and users
+)
+
+// outcomes of audited changes. A change is recorded as pending before it's applied and its outcome
+// is recorded after, so a change without recorded outcome was interrupted or its outcome couldn't be written.
+const (
+	AuditPending = "pending"
+	AuditDone    = "done"
+	AuditFailed  = "failed"
+)
+
+// AuditEntry is a record of the audit log. Every entry has signed hash of its content and the previous entry's
+// hash, so a changed or removed entry breaks the chain.
+type AuditEntry struct {
+	Seq        int64           `json:"seq"`
+	Time       time.Time       `json:"time"`
+	Actor      string          `json:"actor"` // i.e. "user alice", "key k-1 (bank sync)" or "inbox"
+	RequestID  string          `json:"requestId,omitempty"`
+	Action     string          `json:"action"`
+	Method     string          `json:"method,omitempty"`
+	Resource   string          `json:"resource"` // path of the request or the ingested file
+	Outcome    string          `json:"outcome,omitempty"`
+	Ref        int64           `json:"ref,omitempty"`    // sequence number of the pending entry of the outcome
+	Status     int             `json:"status,omitempty"` // of the response
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	BodySHA256 string          `json:"bodySha256,omitempty"` // of the request body or the ingested file
+	BodyBytes  int64           `json:"bodyBytes,omitempty"`
+	PrevHash   string          `json:"prevHash"`
+	Hash       string          `json:"hash"`
+}
+
+// AuditFilter selects audit entries, empty fields match everything
+type AuditFilter struct {
+	From      time.Time
+	To        time.Time // exclusive
+	Actor     string
+	Action    string
+	Resource  string // prefix of the resource
+	RequestID string
+	Outcome   string
+	Before    int64 // sequence number, only older entries are selected
+}
+
+// Match tells if the entry is selected by the filter
+func (f AuditFilter) Match(e AuditEntry) bool {
+	switch {
+	case !f.From.IsZero() && e.Time.Before(f.From):
+		return false
+	case !f.To.IsZero() && !e.Time.Before(f.To):
+		return false
+	case f.Actor != "" && e.Actor != f.Actor:
+		return false
+	case f.Action != "" && e.Action != f.Action:
+		return false
+	case f.RequestID != "" && e.RequestID != f.RequestID:
+		return false
+	case f.Outcome != "" && e.Outcome != f.Outcome:
+		return false
+	case f.Before > 0 && e.Seq >= f.Before:
+		return false
+	}
+	return strings.HasPrefix(e.Resource, f.Resource)
+}
@@ -0,0 +1,81 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// budget periods
+const (
+	BudgetMonthly   = "monthly"
+	BudgetQuarterly = "quarterly"
+	BudgetYearly    = "yearly"
+)
+
+// Budget is a planned spending for expense category per calendar month, quarter or year
+type Budget struct {
+	ID       string  `json:"id"`
+	Category string  `json:"category"`
+	Period   string  `json:"period"`
+	Amount   float64 `json:"amount"`
+}
+
+// Validate checks budget has category, known period and positive amount
+func (b Budget) Validate() error {
+	if strings.TrimSpace(b.Category) == "" {
+		return fmt.Errorf("budget category is required")
+	}
+	switch b.Period {
+	case BudgetMonthly, BudgetQuarterly, BudgetYearly:
+	default:
+		return fmt.Errorf("unsupported budget period %q, expected %q, %q or %q", b.Period, BudgetMonthly, BudgetQuarterly, BudgetYearly)
+	}
+	if b.Amount <= 0 {
+		return fmt.Errorf("budget amount should be positive, got %v", b.Amount)
+	}
+	return nil
+}
+
+// AmountFor returns budgeted amount for the date range. Budget periods partially covered
+// by the range are prorated by days, i.e. half of June gets half of the monthly amount.
+func (b Budget) AmountFor(p Period) float64 {
+	months := map[string]int{BudgetMonthly: 1, BudgetQuarterly: 3, BudgetYearly: 12}[b.Period]
+	if months == 0 || p.To.Before(p.From) {
+		return 0
+	}
+	// start of the budget period containing p.From
+	start := time.Date(p.From.Year(), p.From.Month()-(p.From.Month()-1)%time.Month(months), 1, 0, 0, 0, 0, p.From.Location())
+	res := 0.0
+	for !start.After(p.To) {
+		end := start.AddDate(0, months, -1)
+		from, to := start, end
+		if p.From.After(from) {
+			from = p.From
+		}
+		if p.To.Before(to) {
+			to = p.To
+		}
+		res += b.Amount * float64(days(from, to)) / float64(days(start, end))
+		start = start.AddDate(0, months, 0)
+	}
+	return res
+}
+
+// BudgetReport compares actual expenses with budgets for the period
+type BudgetReport struct {
+	Period Period       `json:"period"`
+	Lines  []BudgetLine `json:"lines"`
+	Total  BudgetLine   `json:"total"`
+}
+
+// BudgetLine is budget vs actual of a category. Variance is budgeted minus actual,
+// negative for over-budget category.
+type BudgetLine struct {
+	Category    string  `json:"category"`
+	Budgeted    float64 `json:"budgeted"`
+	Actual      float64 `json:"actual"`
+	Variance    float64 `json:"variance"`
+	PercentUsed float64 `json:"percentUsed"`
+	OverBudget  bool    `json:"overBudget"`
+}
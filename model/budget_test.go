@@ -0,0 +1,38 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestBudget_AmountFor(t *testing.T) {
+	d := func(y, m, d int) time.Time { return time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.Local) }
+	tests := []struct {
+		name   string
+		budget Budget
+		period Period
+		out    float64
+	}{
+		{"month of monthly", Budget{Period: BudgetMonthly, Amount: 100}, Period{d(2020, 7, 1), d(2020, 7, 31)}, 100},
+		{"quarter of monthly", Budget{Period: BudgetMonthly, Amount: 100}, Period{d(2020, 7, 1), d(2020, 9, 30)}, 300},
+		{"half of june", Budget{Period: BudgetMonthly, Amount: 100}, Period{d(2020, 6, 1), d(2020, 6, 15)}, 50},
+		{"across months", Budget{Period: BudgetMonthly, Amount: 310}, Period{d(2020, 7, 17), d(2020, 8, 15)}, 150 + 150},
+		{"month of quarterly", Budget{Period: BudgetQuarterly, Amount: 91}, Period{d(2020, 4, 1), d(2020, 4, 30)}, 30},
+		{"year of yearly", Budget{Period: BudgetYearly, Amount: 1200}, Period{d(2021, 1, 1), d(2021, 12, 31)}, 1200},
+		{"two years of quarterly", Budget{Period: BudgetQuarterly, Amount: 10}, Period{d(2020, 1, 1), d(2021, 12, 31)}, 80},
+		{"empty range", Budget{Period: BudgetMonthly, Amount: 100}, Period{d(2020, 7, 2), d(2020, 7, 1)}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.out, tt.budget.AmountFor(tt.period), 0.0001)
+		})
+	}
+}
+
+func TestBudget_Validate(t *testing.T) {
+	assert.NoError(t, Budget{Category: "Fuel", Period: BudgetMonthly, Amount: 100}.Validate())
+	assert.Error(t, Budget{Category: " ", Period: BudgetMonthly, Amount: 100}.Validate())
+	assert.Error(t, Budget{Category: "Fuel", Period: "weekly", Amount: 100}.Validate())
+	assert.Error(t, Budget{Category: "Fuel", Period: BudgetYearly, Amount: 0}.Validate())
+}
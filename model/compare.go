@@ -0,0 +1,75 @@
+package model
+
+import "math"
+
+// Comparison compares totals of two periods, i.e. a month and the same month last year
+type Comparison struct {
+	Current    PeriodReport     `json:"current"`
+	Previous   PeriodReport     `json:"previous"`
+	Delta      Delta            `json:"delta"`
+	Categories []CategoryChange `json:"categories,omitempty"`
+}
+
+// PeriodReport is a report of the period
+type PeriodReport struct {
+	Period
+	Report
+}
+
+// Delta is the change from the previous period to the current one
+type Delta struct {
+	Absolute Report        `json:"absolute"`
+	Percent  PercentChange `json:"percent"`
+}
+
+// PercentChange is percentage change of report totals, nil if the previous total is zero
+type PercentChange struct {
+	GrossRevenue *float64 `json:"grossRevenue"`
+	Expenses     *float64 `json:"expenses"`
+	NetRevenue   *float64 `json:"netRevenue"`
+}
+
+// CategoryChange is the change of category totals, percent is nil if the previous total is zero
+type CategoryChange struct {
+	Category string   `json:"category"`
+	Type     TrType   `json:"type"`
+	Current  float64  `json:"current"`
+	Previous float64  `json:"previous"`
+	Absolute float64  `json:"absolute"`
+	Percent  *float64 `json:"percent"`
+}
+
+// NewComparison makes comparison of the reports with absolute and percentage deltas
+func NewComparison(current, previous PeriodReport) Comparison {
+	return Comparison{
+		Current:  current,
+		Previous: previous,
+		Delta: Delta{
+			Absolute: Report{
+				GrossRevenue: RoundCents(current.GrossRevenue - previous.GrossRevenue),
+				Expenses:     RoundCents(current.Expenses - previous.Expenses),
+				NetRevenue:   RoundCents(current.NetRevenue - previous.NetRevenue),
+			},
+			Percent: PercentChange{
+				GrossRevenue: PercentDelta(current.GrossRevenue, previous.GrossRevenue),
+				Expenses:     PercentDelta(current.Expenses, previous.Expenses),
+				NetRevenue:   PercentDelta(current.NetRevenue, previous.NetRevenue),
+			},
+		},
+	}
+}
+
+// PercentDelta returns change from previous to current in percents of the previous absolute value,
+// rounded to hundredths. Returns nil if previous is zero.
+func PercentDelta(current, previous float64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	res := RoundCents((current - previous) / math.Abs(previous) * 100)
+	return &res
+}
+
+// RoundCents rounds amount to cents
+func RoundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
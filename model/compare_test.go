@@ -0,0 +1,41 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestPercentDelta(t *testing.T) {
+	tests := []struct {
+		name              string
+		current, previous float64
+		out               *float64
+	}{
+		{"growth", 150, 100, ptr(50)},
+		{"decline", 75, 100, ptr(-25)},
+		{"from negative", -50, -100, ptr(50)},
+		{"to negative", -50, 100, ptr(-150)},
+		{"rounded", 1, 3, ptr(-66.67)},
+		{"from zero", 100, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.out, PercentDelta(tt.current, tt.previous))
+		})
+	}
+}
+
+func TestNewComparison(t *testing.T) {
+	res := NewComparison(PeriodReport{Report: Report{GrossRevenue: 75, Expenses: 18.77, NetRevenue: 56.23}},
+		PeriodReport{Report: Report{GrossRevenue: 50, NetRevenue: 50}})
+	assert.Equal(t, Report{GrossRevenue: 25, Expenses: 18.77, NetRevenue: 6.23}, res.Delta.Absolute)
+	require.NotNil(t, res.Delta.Percent.GrossRevenue)
+	assert.Equal(t, 50.0, *res.Delta.Percent.GrossRevenue)
+	assert.Nil(t, res.Delta.Percent.Expenses)
+	assert.Equal(t, 12.46, *res.Delta.Percent.NetRevenue)
+}
+
+func ptr(v float64) *float64 {
+	return &v
+}
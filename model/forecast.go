@@ -0,0 +1,74 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// forecast series steps
+const (
+	StepDaily  = "daily"
+	StepWeekly = "weekly"
+)
+
+// MaxForecastDays is the longest horizon and history of the forecast
+const MaxForecastDays = 3660
+
+// ForecastZ is the z-score of confidence bands of the forecast, 80% of outcomes are expected within them
+const ForecastZ = 1.2816
+
+// ForecastRequest defines what and how far to forecast
+type ForecastRequest struct {
+	Accounts []string  // all accounts if empty
+	From     time.Time // the last known day, forecast starts the day after
+	Horizon  int       // number of days to forecast
+	History  int       // number of days before From to take averages from
+	Step     string    // StepDaily or StepWeekly
+	Tags     TagExpr   // history and schedules to project, all of them if empty
+}
+
+// Validate checks horizon, history and step
+func (r ForecastRequest) Validate() error {
+	if r.Horizon <= 0 || r.Horizon > MaxForecastDays {
+		return fmt.Errorf("horizon should be 1-%d days, got %d", MaxForecastDays, r.Horizon)
+	}
+	if r.History <= 0 || r.History > MaxForecastDays {
+		return fmt.Errorf("history should be 1-%d days, got %d", MaxForecastDays, r.History)
+	}
+	if r.Step != StepDaily && r.Step != StepWeekly {
+		return fmt.Errorf("unsupported step %q, expected %q or %q", r.Step, StepDaily, StepWeekly)
+	}
+	if r.From.IsZero() {
+		return fmt.Errorf("forecast start is required")
+	}
+	return nil
+}
+
+// Forecast is projected cash flow. Income and expenses are scheduled transactions plus daily averages
+// of other transactions by category, Low and High are confidence bands of the balance.
+type Forecast struct {
+	From            time.Time         `json:"from"`
+	To              time.Time         `json:"to"`
+	Step            string            `json:"step"`
+	StartingBalance float64           `json:"startingBalance"`
+	Averages        []CategoryAverage `json:"averages"`
+	Points          []ForecastPoint   `json:"points"`
+}
+
+// CategoryAverage is daily average of unscheduled transactions of a category over the history period
+type CategoryAverage struct {
+	Category string  `json:"category"`
+	Type     TrType  `json:"type"`
+	Daily    float64 `json:"daily"`
+}
+
+// ForecastPoint is income and expenses of a day or week and the balance at its end
+type ForecastPoint struct {
+	Date      time.Time `json:"date"` // the last day of the step
+	Income    float64   `json:"income"`
+	Expenses  float64   `json:"expenses"`
+	Scheduled float64   `json:"scheduled"` // signed total of scheduled transactions, included in income and expenses
+	Balance   float64   `json:"balance"`
+	Low       float64   `json:"low"`
+	High      float64   `json:"high"`
+}
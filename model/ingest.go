@@ -0,0 +1,18 @@
+package model
+
+// LineError is a rejected line of an uploaded file
+type LineError struct {
+	Line  int    `json:"line"` // 1-based
+	Error string `json:"error"`
+}
+
+// IngestResult is the outcome of an uploaded file ingestion
+type IngestResult struct {
+	Lines    int           `json:"lines"`    // records read
+	Accepted int           `json:"accepted"` // stored transactions
+	Rejected int           `json:"rejected"` // lines which can't be parsed
+	Errors   []LineError   `json:"errors,omitempty"`
+	Flagged  []Transaction `json:"flagged,omitempty"` // first stored transactions with anomaly flags
+	// FlaggedCount is the number of all stored transactions with anomaly flags
+	FlaggedCount int `json:"flaggedCount,omitempty"`
+}
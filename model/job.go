@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// statuses of ingest jobs
+const (
+	JobQueued   = "queued"
+	JobRunning  = "running"
+	JobDone     = "done"
+	JobFailed   = "failed"
+	JobCanceled = "canceled"
+)
+
+// Job is an upload ingested in the background
+type Job struct {
+	ID       string       `json:"id"`
+	Account  string       `json:"account"`
+	Actor    string       `json:"actor,omitempty"` // who submitted the job
+	Status   string       `json:"status"`
+	Created  time.Time    `json:"created"`
+	Started  time.Time    `json:"started"`
+	Finished time.Time    `json:"finished"`
+	Result   IngestResult `json:"result"` // progress while running, counts of the batch when done
+	Error    string       `json:"error,omitempty"`
+}
+
+// IsFinished checks if the job won't change anymore
+func (j Job) IsFinished() bool {
+	return j.Status == JobDone || j.Status == JobFailed || j.Status == JobCanceled
+}
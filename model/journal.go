@@ -0,0 +1,98 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// ErrDoubleEntryDisabled returned by double-entry operations when the mode is off
+var ErrDoubleEntryDisabled = errors.New("double-entry mode is disabled")
+
+// AccountType is a type of account in the chart of accounts
+type AccountType string
+
+// enum of all account types
+const (
+	AssetAccount     AccountType = AccountType("Asset")
+	LiabilityAccount AccountType = AccountType("Liability")
+	EquityAccount    AccountType = AccountType("Equity")
+	IncomeAccount    AccountType = AccountType("Income")
+	ExpenseAccount   AccountType = AccountType("Expense")
+)
+
+// ChartAccount is an account in the chart of accounts, like "Assets:Checking" or "Expenses:Fuel"
+type ChartAccount struct {
+	Name   string      `json:"name"`
+	Type   AccountType `json:"type"`
+	Ledger string      `json:"ledger,omitempty"` // transactions of this ledger are posted against the account
+}
+
+// Validate checks account name and type
+func (a ChartAccount) Validate() error {
+	if strings.TrimSpace(a.Name) == "" {
+		return fmt.Errorf("empty account name")
+	}
+	switch a.Type {
+	case AssetAccount, LiabilityAccount, EquityAccount, IncomeAccount, ExpenseAccount:
+	default:
+		return fmt.Errorf("unsupported account type %q", a.Type)
+	}
+	if a.Ledger != "" {
+		return ValidateAccount(a.Ledger)
+	}
+	return nil
+}
+
+// Posting is a debit (positive amount) or credit (negative amount) of a chart account
+type Posting struct {
+	Account string  `json:"account"`
+	Amount  float64 `json:"amount"`
+}
+
+// JournalEntry is a set of postings with zero sum. Entries made for uploaded transactions
+// keep transaction's id, ledger, tags and flags.
+type JournalEntry struct {
+	ID            string    `json:"id"`
+	Date          time.Time `json:"date"`
+	Memo          string    `json:"memo"`
+	Postings      []Posting `json:"postings"`
+	TransactionID string    `json:"transactionId,omitempty"`
+	Ledger        string    `json:"ledger,omitempty"`
+	Tags          []string  `json:"tags,omitempty"`
+	Category      string    `json:"category,omitempty"`
+	Flags         []string  `json:"flags,omitempty"` // anomalies of the transaction
+}
+
+// Transaction returns transaction-like view of the entry, to match it with Filter
+func (e JournalEntry) Transaction() Transaction {
+	return Transaction{ID: e.TransactionID, Account: e.Ledger, Date: e.Date, Memo: e.Memo, Tags: e.Tags,
+		Category: e.Category, Flags: e.Flags}
+}
+
+// Validate checks that entry has at least two postings and they are balanced to a cent
+func (e JournalEntry) Validate() error {
+	if len(e.Postings) < 2 {
+		return fmt.Errorf("entry needs at least two postings")
+	}
+	sum := 0.0
+	for _, p := range e.Postings {
+		if p.Amount == 0 {
+			return fmt.Errorf("zero posting to %q", p.Account)
+		}
+		sum += p.Amount
+	}
+	if math.Abs(sum) >= 0.005 {
+		return fmt.Errorf("entry is not balanced, postings sum to %.2f", sum)
+	}
+	return nil
+}
+
+// ChartBalance is the balance of a chart account, debit positive
+type ChartBalance struct {
+	Account string      `json:"account"`
+	Type    AccountType `json:"type"`
+	Balance float64     `json:"balance"`
+}
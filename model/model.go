@@ -1,6 +1,13 @@
 package model
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+)
 
 // TrType represents transaction type
 type TrType string
@@ -11,12 +18,107 @@ const (
 	Income  TrType = TrType("Income")
 )
 
+// DateLayout is the format of dates in requests and csv files
+const DateLayout = "2006-01-02"
+
+// DefaultAccount is an account for transactions uploaded without one
+const DefaultAccount = "default"
+
+// ErrNotFound returned when requested item doesn't exist
+var ErrNotFound = errors.New("not found")
+
+// ErrConflict returned when operation conflicts with the stored data
+var ErrConflict = errors.New("conflict")
+
+var accountRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
 // Transaction creates a transaction to save
 type Transaction struct {
-	Date   time.Time
-	Type   TrType
-	Amount float64
-	Memo   string
+	ID       string    `json:"id"`
+	Account  string    `json:"account"`
+	Date     time.Time `json:"date"`
+	Type     TrType    `json:"type"`
+	Amount   float64   `json:"amount"`
+	Memo     string    `json:"memo"`
+	Tags     []string  `json:"tags,omitempty"`
+	Category string    `json:"category,omitempty"`
+	Cleared  bool      `json:"cleared"`         // matched with bank statement
+	Flags    []string  `json:"flags,omitempty"` // anomalies found at ingest, see FlagOutlier and others
+}
+
+// CategoryName returns transaction category, memo for uncategorized transactions
+func (t Transaction) CategoryName() string {
+	if t.Category != "" {
+		return t.Category
+	}
+	return t.Memo
+}
+
+// HasTag checks if transaction is tagged with the given tag, case-insensitive
+func (t Transaction) HasTag(tag string) bool {
+	for _, tg := range t.Tags {
+		if strings.EqualFold(tg, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// TransactionUpdate has changes to the stored transaction, nil fields are not changed
+type TransactionUpdate struct {
+	Tags     *[]string `json:"tags"`
+	Category *string   `json:"category"`
+}
+
+// Account is a named ledger, like checking account, credit card or cash box
+type Account struct {
+	Name           string    `json:"name"`
+	Transactions   int       `json:"transactions"`
+	OpeningBalance float64   `json:"openingBalance"`
+	OpeningDate    time.Time `json:"openingDate"`
+}
+
+// Balance of accounts as of the given date
+type Balance struct {
+	AsOf     time.Time          `json:"asOf"`
+	Balance  float64            `json:"balance"`
+	Accounts map[string]float64 `json:"accounts"`
+}
+
+// LedgerLine is a transaction with the account balance after it
+type LedgerLine struct {
+	Transaction
+	RunningBalance float64 `json:"runningBalance"`
+}
+
+// ValidateAccount checks that account name is lower-case letters, digits, '-' and '_'
+func ValidateAccount(name string) error {
+	if !accountRe.MatchString(name) {
+		return fmt.Errorf("invalid account name %q", name)
+	}
+	return nil
+}
+
+// Validate checks type, amount and date of the transaction, the fields ParseTransaction requires
+func (t Transaction) Validate() error {
+	if t.Type != Income && t.Type != Expense {
+		return fmt.Errorf("unsupported transaction type %q", t.Type)
+	}
+	if math.IsNaN(t.Amount) || math.IsInf(t.Amount, 0) {
+		return fmt.Errorf("incorrect amount value %v", t.Amount)
+	}
+	if t.Date.IsZero() {
+		return errors.New("no date")
+	}
+	return nil
+}
+
+// Signed returns transaction amount as it affects account balance, negative for expenses
+func (t Transaction) Signed() float64 {
+	if t.Type == Expense {
+		return -t.Amount
+	}
+	return t.Amount
 }
 
 // Report with revenue and expenses to return to user
@@ -25,3 +127,40 @@ type Report struct {
 	Expenses     float64 `json:"expenses"`
 	NetRevenue   float64 `json:"netRevenue"`
 }
+
+// Filter narrows down the set of transactions a report is built from.
+// Zero value matches everything.
+type Filter struct {
+	Accounts []string  // empty for all accounts
+	From     time.Time // inclusive, zero for no lower limit
+	To       time.Time // inclusive, zero for no upper limit
+	Tags     TagExpr
+	Flagged  bool // only transactions with anomaly flags
+}
+
+// Match checks if transaction passes the filter
+func (f Filter) Match(t Transaction) bool {
+	if len(f.Accounts) > 0 && !ContainsString(f.Accounts, t.Account) {
+		return false
+	}
+	if !f.From.IsZero() && t.Date.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && t.Date.After(f.To) {
+		return false
+	}
+	if f.Flagged && len(t.Flags) == 0 {
+		return false
+	}
+	return f.Tags.Match(t)
+}
+
+// ContainsString checks if the list has the string
+func ContainsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
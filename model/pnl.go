@@ -0,0 +1,66 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// comparison modes of profit and loss statement
+const (
+	ComparePrevious = "previous"  // period of the same length right before
+	CompareLastYear = "last-year" // same period a year before
+)
+
+// Period is a date range, both ends inclusive
+type Period struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// Label returns period in "2020-07-01..2020-07-31" form
+func (p Period) Label() string {
+	return p.From.Format(DateLayout) + ".." + p.To.Format(DateLayout)
+}
+
+// Validate checks that the period doesn't end before it starts
+func (p Period) Validate() error {
+	if p.From.After(p.To) {
+		return fmt.Errorf("period %s ends before it starts", p.Label())
+	}
+	return nil
+}
+
+// days returns number of days in the date range, both ends inclusive
+func days(from, to time.Time) int {
+	return int(to.Sub(from).Hours()/24+0.5) + 1
+}
+
+// ComparisonPeriod returns the period to compare the given one with
+func ComparisonPeriod(p Period, mode string) (Period, error) {
+	if err := p.Validate(); err != nil {
+		return Period{}, err
+	}
+	switch mode {
+	case ComparePrevious:
+		n := days(p.From, p.To)
+		return Period{From: p.From.AddDate(0, 0, -n), To: p.From.AddDate(0, 0, -1)}, nil
+	case CompareLastYear:
+		return Period{From: p.From.AddDate(-1, 0, 0), To: p.To.AddDate(-1, 0, 0)}, nil
+	}
+	return Period{}, fmt.Errorf("unsupported comparison %q, expected %q or %q", mode, ComparePrevious, CompareLastYear)
+}
+
+// PnL is a profit and loss statement. Amounts of lines and totals are aligned with periods,
+// the first period is the requested one, the second one (if any) is for comparison.
+type PnL struct {
+	Periods  []Period  `json:"periods"`
+	Income   []PnLLine `json:"income"`
+	Expenses []PnLLine `json:"expenses"`
+	Totals   []Report  `json:"totals"`
+}
+
+// PnLLine is a category line of the profit and loss statement
+type PnLLine struct {
+	Category string    `json:"category"`
+	Amounts  []float64 `json:"amounts"`
+}
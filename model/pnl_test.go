@@ -0,0 +1,37 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestComparisonPeriod(t *testing.T) {
+	d := func(y, m, d int) time.Time { return time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.Local) }
+	tests := []struct {
+		name  string
+		inp   Period
+		mode  string
+		out   Period
+		isErr bool
+	}{
+		{"previous month", Period{d(2020, 7, 1), d(2020, 7, 31)}, ComparePrevious, Period{d(2020, 5, 31), d(2020, 6, 30)}, false},
+		{"previous quarter", Period{d(2020, 4, 1), d(2020, 6, 30)}, ComparePrevious, Period{d(2020, 1, 1), d(2020, 3, 31)}, false},
+		{"previous day", Period{d(2020, 3, 1), d(2020, 3, 1)}, ComparePrevious, Period{d(2020, 2, 29), d(2020, 2, 29)}, false},
+		{"last year", Period{d(2020, 7, 1), d(2020, 9, 30)}, CompareLastYear, Period{d(2019, 7, 1), d(2019, 9, 30)}, false},
+		{"unknown", Period{d(2020, 7, 1), d(2020, 9, 30)}, "next", Period{}, true},
+		{"ends before it starts", Period{d(2020, 7, 31), d(2020, 7, 1)}, ComparePrevious, Period{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := ComparisonPeriod(tt.inp, tt.mode)
+			if tt.isErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.out, out)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// MaxToleranceDays is the largest date tolerance of reconciliation, a year
+const MaxToleranceDays = 366
+
+// Statement is a bank statement to reconcile account transactions against
+type Statement struct {
+	From           time.Time       `json:"from"`
+	To             time.Time       `json:"to"`
+	ClosingBalance float64         `json:"closingBalance"`
+	Lines          []StatementLine `json:"lines"`
+}
+
+// StatementLine is a single line of the bank statement, negative amount for withdrawals
+type StatementLine struct {
+	Date   time.Time `json:"date"`
+	Amount float64   `json:"amount"`
+	Memo   string    `json:"memo"`
+}
+
+// Tolerance defines how close statement line and transaction should be to match
+type Tolerance struct {
+	Amount float64 // max absolute difference of amounts
+	Days   int     // max difference of dates in days
+}
+
+// Validate checks that amount tolerance is not negative and date tolerance is 0-MaxToleranceDays
+func (t Tolerance) Validate() error {
+	if t.Amount < 0 || math.IsNaN(t.Amount) || math.IsInf(t.Amount, 0) {
+		return fmt.Errorf("invalid amount tolerance %v", t.Amount)
+	}
+	if t.Days < 0 || t.Days > MaxToleranceDays {
+		return fmt.Errorf("invalid date tolerance %d, expected 0-%d days", t.Days, MaxToleranceDays)
+	}
+	return nil
+}
+
+// Match is a statement line matched with a transaction
+type Match struct {
+	Line        StatementLine `json:"line"`
+	Transaction Transaction   `json:"transaction"`
+}
+
+// Reconciliation is the result of matching statement with account transactions
+type Reconciliation struct {
+	Account               string          `json:"account"`
+	From                  time.Time       `json:"from"`
+	To                    time.Time       `json:"to"`
+	Matched               []Match         `json:"matched"`
+	UnmatchedLines        []StatementLine `json:"unmatchedLines"`        // on statement, but not in the ledger
+	UnmatchedTransactions []Transaction   `json:"unmatchedTransactions"` // in the ledger, but not on statement
+	ClosingBalance        float64         `json:"closingBalance"`        // from statement
+	LedgerBalance         float64         `json:"ledgerBalance"`         // at the end of statement period
+	Difference            float64         `json:"difference"`            // statement minus ledger
+}
@@ -0,0 +1,117 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// schedule frequencies
+const (
+	FreqWeekly  = "weekly"
+	FreqMonthly = "monthly"
+	FreqYearly  = "yearly"
+)
+
+// Schedule is a recurring transaction, like rent or subscription, repeated every Interval weeks,
+// months or years from Start until End. Monthly schedules repeat on Day of month, clamped to the
+// month's last day, yearly ones on the Start's month and day.
+type Schedule struct {
+	ID       string    `json:"id"`
+	Account  string    `json:"account,omitempty"`
+	Type     TrType    `json:"type"`
+	Amount   float64   `json:"amount"`
+	Memo     string    `json:"memo"`
+	Tags     []string  `json:"tags,omitempty"`
+	Category string    `json:"category,omitempty"`
+	Freq     string    `json:"freq"`
+	Interval int       `json:"interval"`      // every N periods, 1 if not set
+	Day      int       `json:"day,omitempty"` // day of month of monthly schedule, the start's day if not set
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`   // the last possible date, zero for open-ended schedule
+	Count    int       `json:"count"` // number of occurrences already turned into transactions
+	Next     time.Time `json:"next"`  // date of the next occurrence, zero if the schedule is over
+}
+
+// Validate checks schedule fields
+func (s Schedule) Validate() error {
+	switch s.Freq {
+	case FreqWeekly, FreqMonthly, FreqYearly:
+	default:
+		return fmt.Errorf("unsupported frequency %q, expected %q, %q or %q", s.Freq, FreqWeekly, FreqMonthly, FreqYearly)
+	}
+	if s.Type != Income && s.Type != Expense {
+		return fmt.Errorf("unsupported transaction type %q", s.Type)
+	}
+	if s.Amount <= 0 {
+		return fmt.Errorf("amount should be positive, got %v", s.Amount)
+	}
+	if s.Interval < 0 {
+		return fmt.Errorf("invalid interval %d", s.Interval)
+	}
+	if s.Day < 0 || s.Day > 31 || (s.Day != 0 && s.Freq != FreqMonthly) {
+		return fmt.Errorf("invalid day %d, only monthly schedules can have day of month 1-31", s.Day)
+	}
+	if s.Start.IsZero() {
+		return fmt.Errorf("start date is required")
+	}
+	if !s.End.IsZero() && s.End.Before(s.Start) {
+		return fmt.Errorf("end date %s is before start date %s", s.End.Format(DateLayout), s.Start.Format(DateLayout))
+	}
+	if s.Account != "" {
+		return ValidateAccount(s.Account)
+	}
+	return nil
+}
+
+// Occurrence returns date of the n-th occurrence, starting from 0.
+// Returns false if the schedule ends before it.
+func (s Schedule) Occurrence(n int) (time.Time, bool) {
+	interval := s.Interval
+	if interval == 0 {
+		interval = 1
+	}
+	var res time.Time
+	switch s.Freq {
+	case FreqWeekly:
+		res = s.Start.AddDate(0, 0, 7*interval*n)
+	case FreqMonthly:
+		day := s.Day
+		if day == 0 {
+			day = s.Start.Day()
+		}
+		first := 0
+		if dayOfMonth(s.Start.Year(), s.Start.Month(), day, s.Start.Location()).Before(s.Start) {
+			first = 1 // day of the start's month has passed
+		}
+		res = dayOfMonth(s.Start.Year(), s.Start.Month()+time.Month(first+interval*n), day, s.Start.Location())
+	case FreqYearly:
+		res = dayOfMonth(s.Start.Year()+interval*n, s.Start.Month(), s.Start.Day(), s.Start.Location())
+	default:
+		return time.Time{}, false
+	}
+	if !s.End.IsZero() && res.After(s.End) {
+		return time.Time{}, false
+	}
+	return res, true
+}
+
+// Transaction makes transaction of the n-th occurrence. Its id is derived from the schedule's id
+// and n, so the same occurrence can't be stored twice.
+func (s Schedule) Transaction(n int) (Transaction, bool) {
+	date, ok := s.Occurrence(n)
+	if !ok {
+		return Transaction{}, false
+	}
+	return Transaction{ID: s.ID + "-" + strconv.Itoa(n+1), Account: s.Account, Date: date, Type: s.Type, Amount: s.Amount,
+		Memo: s.Memo, Tags: s.Tags, Category: s.Category}, true
+}
+
+// dayOfMonth returns the day of the month, clamped to the month's last day. Month can be out of 1-12 range.
+func dayOfMonth(year int, month time.Month, day int, loc *time.Location) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
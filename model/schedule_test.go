@@ -0,0 +1,72 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestSchedule_Occurrence(t *testing.T) {
+	d := func(y, m, d int) time.Time { return time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.Local) }
+	tests := []struct {
+		name string
+		sch  Schedule
+		out  []time.Time
+	}{
+		{"weekly", Schedule{Freq: FreqWeekly, Start: d(2020, 7, 1)}, []time.Time{d(2020, 7, 1), d(2020, 7, 8), d(2020, 7, 15)}},
+		{"biweekly", Schedule{Freq: FreqWeekly, Interval: 2, Start: d(2020, 7, 1)}, []time.Time{d(2020, 7, 1), d(2020, 7, 15), d(2020, 7, 29)}},
+		{"monthly on start day", Schedule{Freq: FreqMonthly, Start: d(2020, 7, 15)}, []time.Time{d(2020, 7, 15), d(2020, 8, 15), d(2020, 9, 15)}},
+		{"monthly on day after start", Schedule{Freq: FreqMonthly, Day: 20, Start: d(2020, 7, 15)},
+			[]time.Time{d(2020, 7, 20), d(2020, 8, 20), d(2020, 9, 20)}},
+		{"monthly on day before start", Schedule{Freq: FreqMonthly, Day: 1, Start: d(2020, 7, 15)},
+			[]time.Time{d(2020, 8, 1), d(2020, 9, 1), d(2020, 10, 1)}},
+		{"monthly on 31st", Schedule{Freq: FreqMonthly, Day: 31, Start: d(2020, 1, 1)}, []time.Time{d(2020, 1, 31), d(2020, 2, 29), d(2020, 3, 31)}},
+		{"quarterly", Schedule{Freq: FreqMonthly, Interval: 3, Start: d(2020, 11, 30)}, []time.Time{d(2020, 11, 30), d(2021, 2, 28), d(2021, 5, 30)}},
+		{"yearly on leap day", Schedule{Freq: FreqYearly, Start: d(2020, 2, 29)}, []time.Time{d(2020, 2, 29), d(2021, 2, 28), d(2022, 2, 28)}},
+		{"with end", Schedule{Freq: FreqMonthly, Start: d(2020, 7, 1), End: d(2020, 8, 31)}, []time.Time{d(2020, 7, 1), d(2020, 8, 1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out []time.Time
+			for n := 0; n < 3; n++ {
+				date, ok := tt.sch.Occurrence(n)
+				if !ok {
+					break
+				}
+				out = append(out, date)
+			}
+			assert.Equal(t, tt.out, out)
+		})
+	}
+}
+
+func TestSchedule_Transaction(t *testing.T) {
+	sch := Schedule{ID: "s-1", Account: "card", Type: Expense, Amount: 1200, Memo: "Rent", Freq: FreqMonthly,
+		Start: time.Date(2020, 7, 1, 0, 0, 0, 0, time.Local), End: time.Date(2020, 7, 31, 0, 0, 0, 0, time.Local)}
+	tr, ok := sch.Transaction(0)
+	require.True(t, ok)
+	assert.Equal(t, Transaction{ID: "s-1-1", Account: "card", Date: sch.Start, Type: Expense, Amount: 1200, Memo: "Rent"}, tr)
+	_, ok = sch.Transaction(1)
+	assert.False(t, ok)
+}
+
+func TestSchedule_Validate(t *testing.T) {
+	valid := Schedule{Type: Expense, Amount: 10, Freq: FreqMonthly, Day: 31, Start: time.Date(2020, 7, 1, 0, 0, 0, 0, time.Local)}
+	assert.NoError(t, valid.Validate())
+
+	for name, fn := range map[string]func(s *Schedule){
+		"daily":         func(s *Schedule) { s.Freq = "daily" },
+		"no type":       func(s *Schedule) { s.Type = "" },
+		"zero amount":   func(s *Schedule) { s.Amount = 0 },
+		"bad day":       func(s *Schedule) { s.Day = 32 },
+		"weekly day":    func(s *Schedule) { s.Freq, s.Day = FreqWeekly, 3 },
+		"no start":      func(s *Schedule) { s.Start = time.Time{} },
+		"end too early": func(s *Schedule) { s.End = s.Start.AddDate(0, 0, -1) },
+		"bad account":   func(s *Schedule) { s.Account = "Bad Name" },
+	} {
+		s := valid
+		fn(&s)
+		assert.Error(t, s.Validate(), name)
+	}
+}
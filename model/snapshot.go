@@ -0,0 +1,25 @@
+package model
+
+// SnapshotVersion is the version of the Snapshot format
+const SnapshotVersion = 1
+
+// Snapshot is the whole stored state, used to persist it between runs. Of the double-entry mode journal
+// only the chart of accounts and entries posted manually are included, entries of transactions are made
+// again when it's enabled.
+type Snapshot struct {
+	Version        int            `json:"version"`
+	Accounts       []Account      `json:"accounts"` // opening balances, transaction counts are ignored
+	Transactions   []Transaction  `json:"transactions"`
+	Budgets        []Budget       `json:"budgets"`
+	Schedules      []Schedule     `json:"schedules"`
+	Tax            TaxConfig      `json:"tax"`
+	LastID         int64          `json:"lastId"` // last generated ids, so new ones continue the sequence
+	LastBudgetID   int64          `json:"lastBudgetId"`
+	LastScheduleID int64          `json:"lastScheduleId"`
+	APIKeys        []APIKey       `json:"apiKeys,omitempty"` // hashed keys, not included in export archives
+	LastAPIKeyID   int64          `json:"lastApiKeyId,omitempty"`
+	Users          []User         `json:"users,omitempty"`          // with password hashes, not included in export archives
+	LastGeneration int64          `json:"lastGeneration,omitempty"` // of users' session tokens
+	Chart          []ChartAccount `json:"chart,omitempty"`
+	JournalEntries []JournalEntry `json:"journalEntries,omitempty"` // posted manually
+}
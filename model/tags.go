@@ -0,0 +1,207 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TagExpr is a boolean expression over transaction tags, like `woodrow AND (lawn OR hedge)`.
+// Zero value is an empty expression and matches every transaction.
+type TagExpr struct {
+	op       string // "AND", "OR" or empty for a single tag
+	tag      string
+	operands []TagExpr
+}
+
+// NormalizeTags trims and lower-cases tags, drops empty ones and duplicates, keeps the order
+func NormalizeTags(tags []string) []string {
+	res := []string{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		res = append(res, tag)
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	return res
+}
+
+// ParseTagExpr parses tag expression. Tags are combined with AND/OR (case-insensitive),
+// AND binds tighter than OR, parentheses can be used for grouping and
+// tags with spaces can be double-quoted, i.e. `"347 woodrow" OR pleasant`.
+func ParseTagExpr(s string) (TagExpr, error) {
+	tokens, err := tokenizeTagExpr(s)
+	if err != nil {
+		return TagExpr{}, err
+	}
+	if len(tokens) == 0 {
+		return TagExpr{}, nil
+	}
+	p := tagExprParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return TagExpr{}, err
+	}
+	if p.pos < len(p.tokens) {
+		return TagExpr{}, fmt.Errorf("unexpected %q in tag expression", p.tokens[p.pos])
+	}
+	return expr, nil
+}
+
+// And combines expressions with AND, empty expressions are ignored
+func And(exprs ...TagExpr) TagExpr {
+	res := TagExpr{op: "AND"}
+	for _, e := range exprs {
+		if !e.IsEmpty() {
+			res.operands = append(res.operands, e)
+		}
+	}
+	switch len(res.operands) {
+	case 0:
+		return TagExpr{}
+	case 1:
+		return res.operands[0]
+	}
+	return res
+}
+
+// IsEmpty checks if expression has no conditions
+func (e TagExpr) IsEmpty() bool {
+	return e.op == "" && e.tag == ""
+}
+
+// Match checks if transaction tags satisfy the expression
+func (e TagExpr) Match(t Transaction) bool {
+	switch e.op {
+	case "AND":
+		for _, o := range e.operands {
+			if !o.Match(t) {
+				return false
+			}
+		}
+		return true
+	case "OR":
+		for _, o := range e.operands {
+			if o.Match(t) {
+				return true
+			}
+		}
+		return false
+	}
+	if e.tag == "" {
+		return true
+	}
+	return t.HasTag(e.tag)
+}
+
+// String returns expression in the canonical form
+func (e TagExpr) String() string {
+	if e.op == "" {
+		if strings.ContainsAny(e.tag, " ()") {
+			return `"` + e.tag + `"`
+		}
+		return e.tag
+	}
+	parts := make([]string, 0, len(e.operands))
+	for _, o := range e.operands {
+		s := o.String()
+		if o.op != "" {
+			s = "(" + s + ")"
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, " "+e.op+" ")
+}
+
+type tagExprParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *tagExprParser) parseOr() (TagExpr, error) {
+	return p.parseBinary("OR", p.parseAnd)
+}
+
+func (p *tagExprParser) parseAnd() (TagExpr, error) {
+	return p.parseBinary("AND", p.parseTerm)
+}
+
+func (p *tagExprParser) parseBinary(op string, next func() (TagExpr, error)) (TagExpr, error) {
+	first, err := next()
+	if err != nil {
+		return TagExpr{}, err
+	}
+	res := TagExpr{op: op, operands: []TagExpr{first}}
+	for p.pos < len(p.tokens) && strings.EqualFold(p.tokens[p.pos], op) {
+		p.pos++
+		operand, err := next()
+		if err != nil {
+			return TagExpr{}, err
+		}
+		res.operands = append(res.operands, operand)
+	}
+	if len(res.operands) == 1 {
+		return first, nil
+	}
+	return res, nil
+}
+
+func (p *tagExprParser) parseTerm() (TagExpr, error) {
+	if p.pos >= len(p.tokens) {
+		return TagExpr{}, fmt.Errorf("unexpected end of tag expression")
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+	switch {
+	case tok == "(":
+		expr, err := p.parseOr()
+		if err != nil {
+			return TagExpr{}, err
+		}
+		if p.pos >= len(p.tokens) || p.tokens[p.pos] != ")" {
+			return TagExpr{}, fmt.Errorf("missing closing parenthesis in tag expression")
+		}
+		p.pos++
+		return expr, nil
+	case tok == ")", strings.EqualFold(tok, "AND"), strings.EqualFold(tok, "OR"):
+		return TagExpr{}, fmt.Errorf("unexpected %q in tag expression", tok)
+	}
+	return TagExpr{tag: strings.ToLower(strings.Trim(tok, `"`))}, nil
+}
+
+func tokenizeTagExpr(s string) ([]string, error) {
+	var tokens []string
+	var cur strings.Builder
+	flush := func() {
+		if cur.Len() > 0 {
+			tokens = append(tokens, cur.String())
+			cur.Reset()
+		}
+	}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case ' ', '\t':
+			flush()
+		case '(', ')':
+			flush()
+			tokens = append(tokens, string(c))
+		case '"':
+			flush()
+			end := strings.IndexByte(s[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quote in tag expression")
+			}
+			tokens = append(tokens, `"`+strings.TrimSpace(s[i+1:i+1+end])+`"`)
+			i += end + 1
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	flush()
+	return tokens, nil
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestParseTagExpr(t *testing.T) {
+	tests := []struct {
+		name  string
+		inp   string
+		str   string
+		match map[string]bool // comma separated tags -> expected match
+		isErr bool
+	}{
+		{"empty", "", "", map[string]bool{"": true, "woodrow": true}, false},
+		{"single", "Woodrow", "woodrow", map[string]bool{"woodrow": true, "pleasant": false}, false},
+		{"or", "woodrow OR pleasant", "woodrow OR pleasant",
+			map[string]bool{"woodrow": true, "pleasant": true, "maple": false}, false},
+		{"and binds tighter", "woodrow and lawn or pleasant", "(woodrow AND lawn) OR pleasant",
+			map[string]bool{"woodrow": false, "woodrow,lawn": true, "pleasant": true}, false},
+		{"parens", "woodrow AND (lawn OR hedge)", "woodrow AND (lawn OR hedge)",
+			map[string]bool{"woodrow,hedge": true, "hedge": false, "woodrow": false}, false},
+		{"quoted", `"347 Woodrow" OR maple`, `"347 woodrow" OR maple`,
+			map[string]bool{"347 woodrow": true, "woodrow": false}, false},
+		{"dangling op", "woodrow AND", "", nil, true},
+		{"missing paren", "(woodrow OR lawn", "", nil, true},
+		{"extra paren", "woodrow)", "", nil, true},
+		{"unterminated quote", `"woodrow`, "", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := ParseTagExpr(tt.inp)
+			if tt.isErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.str, expr.String())
+			for tags, exp := range tt.match {
+				tr := Transaction{}
+				if tags != "" {
+					tr.Tags = strings.Split(tags, ",")
+				}
+				assert.Equal(t, exp, expr.Match(tr), "tags %q", tags)
+			}
+		})
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	assert.Equal(t, []string{"woodrow", "lawn"}, NormalizeTags([]string{" Woodrow", "lawn", "", "WOODROW"}))
+	assert.Nil(t, NormalizeTags([]string{" ", ""}))
+}
@@ -0,0 +1,96 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GrossReceiptsLine is the tax line for income of categories not mapped to any line
+const GrossReceiptsLine = "Gross receipts"
+
+// TaxConfig defines tax year and how categories are reported for taxes
+type TaxConfig struct {
+	FiscalYearStart time.Month        `json:"fiscalYearStart"` // first month of the tax year, January if not set
+	Lines           map[string]string `json:"lines"`           // category to tax line, i.e. "Fuel": "Car and truck expenses"
+}
+
+// Validate checks fiscal year start month and tax line names. Categories are matched case-insensitively,
+// so ones differing only in case or surrounding spaces are rejected.
+func (c TaxConfig) Validate() error {
+	if c.FiscalYearStart < 0 || c.FiscalYearStart > time.December {
+		return fmt.Errorf("invalid fiscal year start month %d", c.FiscalYearStart)
+	}
+	categories := make([]string, 0, len(c.Lines))
+	for category := range c.Lines {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	seen := map[string]string{}
+	for _, category := range categories {
+		line := c.Lines[category]
+		if strings.TrimSpace(category) == "" {
+			return fmt.Errorf("empty category mapped to tax line %q", line)
+		}
+		if strings.TrimSpace(line) == "" {
+			return fmt.Errorf("empty tax line for category %q", category)
+		}
+		key := strings.ToLower(strings.TrimSpace(category))
+		if other, ok := seen[key]; ok {
+			return fmt.Errorf("categories %q and %q differ only in case", other, category)
+		}
+		seen[key] = category
+	}
+	return nil
+}
+
+// TaxYear returns the period of the tax year. A fiscal year not starting in January
+// is named by the year it ends in, i.e. with July start 2021 is 2020-07-01..2021-06-30.
+func (c TaxConfig) TaxYear(year int) Period {
+	start := c.FiscalYearStart
+	if start == 0 {
+		start = time.January
+	}
+	from := time.Date(year, start, 1, 0, 0, 0, 0, time.Local)
+	if start != time.January {
+		from = from.AddDate(-1, 0, 0)
+	}
+	return Period{From: from, To: from.AddDate(1, 0, -1)}
+}
+
+// Line returns tax line of the category, case-insensitive. Returns empty string for unmapped category.
+// Categories of the valid config differ not only in case, so at most one of them matches.
+func (c TaxConfig) Line(category string) string {
+	category = strings.TrimSpace(category)
+	if line, ok := c.Lines[category]; ok {
+		return line
+	}
+	for k, line := range c.Lines {
+		if strings.EqualFold(strings.TrimSpace(k), category) {
+			return line
+		}
+	}
+	return ""
+}
+
+// TaxSummary is a tax year summary with totals per tax line. Expenses of categories
+// not mapped to a tax line are not deductible and listed separately.
+type TaxSummary struct {
+	Year            int       `json:"year"`
+	Period          Period    `json:"period"`
+	Income          []TaxLine `json:"income"`
+	Deductions      []TaxLine `json:"deductions"`
+	NonDeductible   []TaxLine `json:"nonDeductible"` // a line per unmapped expense category
+	GrossIncome     float64   `json:"grossIncome"`
+	TotalDeductions float64   `json:"totalDeductions"`
+	NetProfit       float64   `json:"netProfit"`
+}
+
+// TaxLine is a line of the tax summary with transactions backing it, ordered by date
+type TaxLine struct {
+	Line         string        `json:"line"`
+	Categories   []string      `json:"categories"`
+	Total        float64       `json:"total"`
+	Transactions []Transaction `json:"transactions"`
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestTaxConfig_TaxYear(t *testing.T) {
+	d := func(y, m, d int) time.Time { return time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.Local) }
+	tests := []struct {
+		name  string
+		start time.Month
+		year  int
+		out   Period
+	}{
+		{"default calendar year", 0, 2020, Period{d(2020, 1, 1), d(2020, 12, 31)}},
+		{"calendar year", time.January, 2021, Period{d(2021, 1, 1), d(2021, 12, 31)}},
+		{"july start", time.July, 2021, Period{d(2020, 7, 1), d(2021, 6, 30)}},
+		{"october start", time.October, 2020, Period{d(2019, 10, 1), d(2020, 9, 30)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.out, TaxConfig{FiscalYearStart: tt.start}.TaxYear(tt.year))
+		})
+	}
+}
+
+func TestTaxConfig_Validate(t *testing.T) {
+	assert.NoError(t, TaxConfig{FiscalYearStart: time.July, Lines: map[string]string{"Fuel": "Car and truck expenses"}}.Validate())
+	assert.Error(t, TaxConfig{FiscalYearStart: 13}.Validate())
+	assert.Error(t, TaxConfig{Lines: map[string]string{"Fuel": " "}}.Validate())
+	assert.Error(t, TaxConfig{Lines: map[string]string{"": "Supplies"}}.Validate())
+	assert.EqualError(t, TaxConfig{Lines: map[string]string{"fuel": "Supplies", "Fuel ": "Car and truck expenses"}}.Validate(),
+		`categories "Fuel " and "fuel" differ only in case`)
+}
+
+func TestTaxConfig_Line(t *testing.T) {
+	cfg := TaxConfig{Lines: map[string]string{"Fuel": "Car and truck expenses", "Repairs": "Repairs and maintenance"}}
+	assert.Equal(t, "Car and truck expenses", cfg.Line("Fuel"))
+	assert.Equal(t, "Repairs and maintenance", cfg.Line(" repairs"))
+	assert.Equal(t, "", cfg.Line("Lawns"))
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// user roles, every role is allowed endpoints of its API key scopes
+const (
+	RoleOwner      = "owner"      // admin scope
+	RoleBookkeeper = "bookkeeper" // read-report and write-transactions scopes
+	RoleViewer     = "viewer"     // read-report scope
+)
+
+// User is a person logging in with password. Only bcrypt hash of the password is stored.
+type User struct {
+	Name         string    `json:"name"`
+	Role         string    `json:"role"`
+	Ledgers      []string  `json:"ledgers,omitempty"`      // accounts the user can access, all of them if empty
+	PasswordHash string    `json:"passwordHash,omitempty"` // bcrypt hash of the password
+	Created      time.Time `json:"created"`
+	Generation   int64     `json:"generation,omitempty"` // of session tokens, unique in the store, changed with the password to revoke the older ones
+}
+
+// Session is the result of login, the token is sent as "Authorization: Bearer <token>" until it expires
+type Session struct {
+	Token   string    `json:"token"`
+	Expires time.Time `json:"expires"`
+	User    User      `json:"user"`
+}
+
+// Validate checks the user has name, known role and valid ledger names
+func (u User) Validate() error {
+	if strings.TrimSpace(u.Name) == "" {
+		return fmt.Errorf("user name is required")
+	}
+	if RoleScopes(u.Role) == nil {
+		return fmt.Errorf("unknown role %q, expected %q, %q or %q", u.Role, RoleOwner, RoleBookkeeper, RoleViewer)
+	}
+	for _, l := range u.Ledgers {
+		if err := ValidateAccount(l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RoleScopes returns API key scopes of the role, nil for unknown role
+func RoleScopes(role string) []string {
+	switch role {
+	case RoleOwner:
+		return []string{ScopeAdmin}
+	case RoleBookkeeper:
+		return []string{ScopeReadReport, ScopeWriteTransactions}
+	case RoleViewer:
+		return []string{ScopeReadReport}
+	}
+	return nil
+}
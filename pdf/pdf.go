@@ -0,0 +1,148 @@
+// Package pdf makes simple PDF documents with text and lines, enough to print a tabular report.
+// It uses the standard Helvetica fonts, so nothing has to be embedded.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Letter page size in points
+const (
+	PageWidth  = 612.0
+	PageHeight = 792.0
+)
+
+// Doc is a PDF document being built, page by page
+type Doc struct {
+	pages []*bytes.Buffer
+}
+
+// New makes a document with a single empty page
+func New() *Doc {
+	d := &Doc{}
+	d.AddPage()
+	return d
+}
+
+// AddPage starts a new page, all further drawing goes to it
+func (d *Doc) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+// Pages returns number of pages in the document
+func (d *Doc) Pages() int {
+	return len(d.pages)
+}
+
+// Text draws text with its baseline starting at x, y. Coordinates are from the bottom left corner.
+func (d *Doc) Text(x, y, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(d.page(), "BT /%s %s Tf %s %s Td (%s) Tj ET\n", font, num(size), num(x), num(y), escape(s))
+}
+
+// TextRight draws text ending at x
+func (d *Doc) TextRight(x, y, size float64, bold bool, s string) {
+	d.Text(x-TextWidth(s, size, bold), y, size, bold, s)
+}
+
+// Line draws a thin line
+func (d *Doc) Line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(d.page(), "0.5 w %s %s m %s %s l S\n", num(x1), num(y1), num(x2), num(y2))
+}
+
+// WriteTo writes the document in PDF format
+func (d *Doc) WriteTo(w io.Writer) (int64, error) {
+	// objects: 1 catalog, 2 pages, 3 and 4 fonts, then a page and its content for each page
+	var objects []string
+	objects = append(objects, "<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, 0, len(d.pages))
+	for i := range d.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 5+2*i))
+	}
+	objects = append(objects, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	objects = append(objects,
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, p := range d.pages {
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] "+
+				"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", num(PageWidth), num(PageHeight), 6+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", p.Len(), p.String()))
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.WriteTo(w)
+}
+
+// TextWidth estimates width of the text in points. Digits and common punctuation
+// use exact Helvetica metrics (same for bold), so columns of numbers can be right-aligned.
+func TextWidth(s string, size float64, bold bool) float64 {
+	units, letters := 0, 0
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			units += 556
+		case c == '.' || c == ',' || c == ' ':
+			units += 278
+		case c == '-' || c == '(' || c == ')':
+			units += 333
+		case c >= 'A' && c <= 'Z':
+			letters += 667
+		case c == 'i' || c == 'l' || c == 'j':
+			letters += 222
+		default:
+			letters += 556
+		}
+	}
+	if bold {
+		letters = letters * 110 / 100
+	}
+	return float64(units+letters) * size / 1000
+}
+
+func (d *Doc) page() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+// escape makes PDF string literal content, characters outside of Latin-1 are replaced with '?'
+func escape(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(c)
+		case c < 32 || c > 255:
+			b.WriteByte('?')
+		case c > 126:
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+func num(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
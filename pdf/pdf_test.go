@@ -0,0 +1,51 @@
+package pdf
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestDoc_WriteTo(t *testing.T) {
+	d := New()
+	d.Text(72, 720, 16, true, "Report (July)")
+	d.TextRight(540, 700, 10, false, "1,234.50")
+	d.Line(72, 695, 540, 695)
+	d.AddPage()
+	d.Text(72, 720, 10, false, "Café")
+	assert.Equal(t, 2, d.Pages())
+
+	buf := &bytes.Buffer{}
+	_, err := d.WriteTo(buf)
+	require.NoError(t, err)
+	out := buf.String()
+
+	assert.Regexp(t, `^%PDF-1\.4\n`, out)
+	assert.Contains(t, out, "/Count 2")
+	assert.Contains(t, out, `BT /F2 16 Tf 72 720 Td (Report \(July\)) Tj ET`)
+	assert.Contains(t, out, `(Caf\351) Tj`)
+	assert.Contains(t, out, "0.5 w 72 695 m 540 695 l S")
+
+	// xref offsets point to the objects
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindStringSubmatch(out)
+	require.Len(t, m, 2)
+	xref, err := strconv.Atoi(m[1])
+	require.NoError(t, err)
+	assert.Equal(t, "xref", out[xref:xref+4])
+	offsets := regexp.MustCompile(`(\d{10}) 00000 n`).FindAllStringSubmatch(out, -1)
+	require.Len(t, offsets, 8)
+	for i, o := range offsets {
+		off, err := strconv.Atoi(o[1])
+		require.NoError(t, err)
+		assert.Equal(t, strconv.Itoa(i+1)+" 0 obj", out[off:off+len(strconv.Itoa(i+1))+6])
+	}
+}
+
+func TestTextWidth(t *testing.T) {
+	assert.InDelta(t, 19.46, TextWidth("12.5", 10, false), 0.001)
+	assert.InDelta(t, 25.57, TextWidth("-1234", 10, true), 0.001)
+	assert.Greater(t, TextWidth("Total", 10, true), TextWidth("Total", 10, false))
+}
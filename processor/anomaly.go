@@ -0,0 +1,140 @@
+package processor
+
+import (
+	"github.com/mrnbort/summer_break/model"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// anomaly detection thresholds
+const (
+	outlierSigma   = 3.0 // amounts further from the category mean than this many standard deviations are outliers
+	outlierMinSeen = 5   // categories with fewer known amounts have no outliers
+	oldDateYears   = 2   // transactions older than this are flagged
+)
+
+// anomalyDetector flags unusual transactions against statistics of the ledgers, transactions checked
+// before are counted once they are added to their ledger
+type anomalyDetector struct {
+	today      time.Time
+	ledgers    map[string]*ledger
+	hasHistory bool // new payees are flagged only if there were stored transactions before
+}
+
+// anomalyStats are statistics of a ledger's transactions used by anomaly checks. They are kept up to date
+// as transactions are added and removed, like rollup, so checks don't rescan stored transactions.
+type anomalyStats struct {
+	amounts map[string]*amountStats // by category key
+	payees  map[string]int          // transaction counts by payee key
+	seen    map[string]int          // transaction counts by duplicate key
+}
+
+// amountStats keeps count, sum and sum of squares of amounts to get their mean and standard deviation
+type amountStats struct {
+	n          int
+	sum, sumSq float64
+}
+
+// newAnomalyDetector makes detector over the stored transactions, caller should hold the lock
+func (p *Proc) newAnomalyDetector(now time.Time) *anomalyDetector {
+	d := &anomalyDetector{
+		today:   time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local),
+		ledgers: p.ledgers,
+	}
+	for _, l := range p.ledgers {
+		if l.transactions.len() > 0 {
+			d.hasHistory = true
+			break
+		}
+	}
+	return d
+}
+
+// check returns anomaly flags of the transaction, it should be added to its ledger before the next check
+func (d *anomalyDetector) check(tr model.Transaction) []string {
+	var flags []string
+	amounts, knownPayee := amountStats{}, false
+	for _, l := range d.ledgers {
+		if l.stats == nil {
+			continue
+		}
+		if s, ok := l.stats.amounts[categoryKey(tr)]; ok {
+			amounts.n, amounts.sum, amounts.sumSq = amounts.n+s.n, amounts.sum+s.sum, amounts.sumSq+s.sumSq
+		}
+		knownPayee = knownPayee || l.stats.payees[payeeKey(tr)] > 0
+	}
+	if amounts.n >= outlierMinSeen {
+		mean := amounts.sum / float64(amounts.n)
+		std := math.Sqrt(math.Max(amounts.sumSq/float64(amounts.n)-mean*mean, 0))
+		if math.Abs(tr.Amount-mean) > outlierSigma*math.Max(std, 0.1*math.Abs(mean)) {
+			flags = append(flags, model.FlagOutlier)
+		}
+	}
+	if l, ok := d.ledgers[tr.Account]; ok && l.stats != nil && l.stats.seen[duplicateKey(tr)] > 0 {
+		flags = append(flags, model.FlagDuplicate)
+	}
+	if tr.Date.After(d.today) {
+		flags = append(flags, model.FlagFutureDate)
+	}
+	if tr.Date.Before(d.today.AddDate(-oldDateYears, 0, 0)) {
+		flags = append(flags, model.FlagOldDate)
+	}
+	if d.hasHistory && !knownPayee {
+		flags = append(flags, model.FlagNewPayee)
+	}
+	return flags
+}
+
+func newAnomalyStats() *anomalyStats {
+	return &anomalyStats{amounts: map[string]*amountStats{}, payees: map[string]int{}, seen: map[string]int{}}
+}
+
+// add counts the transaction in the statistics
+func (s *anomalyStats) add(tr model.Transaction) {
+	s.count(tr, 1)
+}
+
+// remove takes the transaction out of the statistics
+func (s *anomalyStats) remove(tr model.Transaction) {
+	s.count(tr, -1)
+}
+
+// count adds the transaction to the statistics, sign is 1 to add and -1 to remove
+func (s *anomalyStats) count(tr model.Transaction, sign int) {
+	key := categoryKey(tr)
+	a, ok := s.amounts[key]
+	if !ok {
+		a = &amountStats{}
+		s.amounts[key] = a
+	}
+	a.n += sign
+	a.sum += float64(sign) * tr.Amount
+	a.sumSq += float64(sign) * tr.Amount * tr.Amount
+	if a.n <= 0 {
+		delete(s.amounts, key)
+	}
+	countKey(s.payees, payeeKey(tr), sign)
+	countKey(s.seen, duplicateKey(tr), sign)
+}
+
+// countKey changes count of the key by sign, keys with no count left are removed
+func countKey(m map[string]int, key string, sign int) {
+	if m[key] += sign; m[key] <= 0 {
+		delete(m, key)
+	}
+}
+
+func categoryKey(tr model.Transaction) string {
+	return string(tr.Type) + "|" + strings.ToLower(strings.TrimSpace(tr.CategoryName()))
+}
+
+func payeeKey(tr model.Transaction) string {
+	return strings.ToLower(strings.TrimSpace(tr.Memo))
+}
+
+func duplicateKey(tr model.Transaction) string {
+	return strings.Join([]string{tr.Account, tr.Date.Format(model.DateLayout), string(tr.Type),
+		strconv.FormatFloat(tr.Amount, 'f', 2, 64), payeeKey(tr)}, "|")
+}
@@ -0,0 +1,71 @@
+package processor
+
+import (
+	"context"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestProc_ProcessTransactionsFlags(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	day := func(n int) time.Time { return today.AddDate(0, 0, n) }
+
+	proc := NewProc()
+	stored, err := proc.ProcessTransactions(ctx, []model.Transaction{
+		{Date: day(-30), Type: model.Expense, Amount: 18, Memo: "Shell", Category: "Fuel"},
+		{Date: day(-25), Type: model.Expense, Amount: 22, Memo: "Shell", Category: "Fuel"},
+		{Date: day(-20), Type: model.Expense, Amount: 20, Memo: "Shell", Category: "Fuel"},
+		{Date: day(-15), Type: model.Expense, Amount: 19, Memo: "Shell", Category: "Fuel"},
+		{Date: day(-10), Type: model.Expense, Amount: 21, Memo: "Shell", Category: "Fuel"},
+		{Date: day(-10), Type: model.Income, Amount: 40, Memo: "347 Woodrow"},
+	})
+	require.NoError(t, err)
+	require.Len(t, stored, 6)
+	assert.Equal(t, "1", stored[0].ID)
+	for _, tr := range stored {
+		assert.Empty(t, tr.Flags, "nothing to compare the first upload with")
+	}
+
+	stored, err = proc.ProcessTransactions(ctx, []model.Transaction{
+		{Date: day(-5), Type: model.Expense, Amount: 20.5, Memo: "Shell", Category: "Fuel"},
+		{Date: day(-4), Type: model.Expense, Amount: 95, Memo: "shell", Category: "fuel"},
+		{Date: day(-10), Type: model.Income, Amount: 40, Memo: "347 woodrow "},
+		{Date: day(3), Type: model.Income, Amount: 40, Memo: "347 Woodrow"},
+		{Date: today.AddDate(-3, 0, 0), Type: model.Income, Amount: 40, Memo: "347 Woodrow"},
+		{Date: day(-1), Type: model.Income, Amount: 35, Memo: "219 Pleasant"},
+		{Date: day(-1), Type: model.Income, Amount: 35, Memo: "219 Pleasant"},
+		{Account: "card", Date: day(-1), Type: model.Income, Amount: 35, Memo: "219 Pleasant"},
+	})
+	require.NoError(t, err)
+	require.Len(t, stored, 8)
+	assert.Empty(t, stored[0].Flags)
+	assert.Equal(t, []string{model.FlagOutlier}, stored[1].Flags)
+	assert.Equal(t, []string{model.FlagDuplicate}, stored[2].Flags)
+	assert.Equal(t, []string{model.FlagFutureDate}, stored[3].Flags)
+	assert.Equal(t, []string{model.FlagOldDate}, stored[4].Flags)
+	assert.Equal(t, []string{model.FlagNewPayee}, stored[5].Flags)
+	assert.Equal(t, []string{model.FlagDuplicate}, stored[6].Flags)
+	assert.Empty(t, stored[7].Flags, "duplicates are within account")
+
+	lines, err := proc.Transactions(ctx, model.Filter{Flagged: true})
+	require.NoError(t, err)
+	assert.Len(t, lines, 6)
+
+	stats := proc.ledgers[model.DefaultAccount].stats
+	assert.Equal(t, 7, stats.amounts[categoryKey(stored[1])].n, "kept up to date on upload")
+	category := "Repairs"
+	_, err = proc.UpdateTransaction(ctx, stored[1].ID, model.TransactionUpdate{Category: &category})
+	require.NoError(t, err)
+	assert.Equal(t, 6, stats.amounts[categoryKey(stored[0])].n, "moved to the new category")
+	assert.InDelta(t, 120.5, stats.amounts[categoryKey(stored[0])].sum, 0.001)
+	stored[1].Category = "Repairs"
+	assert.Equal(t, 1, stats.amounts[categoryKey(stored[1])].n)
+	assert.Equal(t, 1, stats.seen[duplicateKey(stored[1])])
+}
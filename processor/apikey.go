@@ -0,0 +1,88 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mrnbort/summer_break/model"
+	"strconv"
+)
+
+// APIKeys returns all API keys in order they were added
+func (p *Proc) APIKeys(ctx context.Context) ([]model.APIKey, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return append([]model.APIKey{}, p.apiKeys...), nil
+}
+
+// AddAPIKey stores API key with hash of the key and returns it with assigned id, the key itself is not stored
+func (p *Proc) AddAPIKey(ctx context.Context, key model.APIKey) (model.APIKey, error) {
+	select {
+	case <-ctx.Done():
+		return model.APIKey{}, ctx.Err()
+	default:
+	}
+
+	if err := key.Validate(); err != nil {
+		return model.APIKey{}, err
+	}
+	if key.Hash == "" {
+		return model.APIKey{}, errors.New("key hash is required")
+	}
+	key.Key = ""
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, k := range p.apiKeys {
+		if k.Hash == key.Hash {
+			return model.APIKey{}, fmt.Errorf("key with the same hash: %w", model.ErrConflict)
+		}
+	}
+	p.lastAPIKeyID++
+	key.ID = "k-" + strconv.FormatInt(p.lastAPIKeyID, 10)
+	p.apiKeys = append(p.apiKeys, key)
+	return key, nil
+}
+
+// APIKeyByHash returns API key with the hash, model.ErrNotFound if there is none
+func (p *Proc) APIKeyByHash(ctx context.Context, hash string) (model.APIKey, error) {
+	select {
+	case <-ctx.Done():
+		return model.APIKey{}, ctx.Err()
+	default:
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, k := range p.apiKeys {
+		if k.Hash == hash {
+			return k, nil
+		}
+	}
+	return model.APIKey{}, fmt.Errorf("api key: %w", model.ErrNotFound)
+}
+
+// DeleteAPIKey removes API key with given id, it can't be used anymore
+func (p *Proc) DeleteAPIKey(ctx context.Context, id string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, k := range p.apiKeys {
+		if k.ID == id {
+			p.apiKeys = append(p.apiKeys[:i], p.apiKeys[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("api key %q: %w", id, model.ErrNotFound)
+}
@@ -0,0 +1,50 @@
+package processor
+
+import (
+	"context"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestProc_APIKeys(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	proc := NewProc()
+	key, err := proc.AddAPIKey(ctx, model.APIKey{Name: "reports", Hash: "h1", Scopes: []string{model.ScopeReadReport},
+		Key: "secret"})
+	require.NoError(t, err)
+	assert.Equal(t, "k-1", key.ID)
+	assert.Empty(t, key.Key, "the key is not stored")
+	_, err = proc.AddAPIKey(ctx, model.APIKey{Name: "copy", Hash: "h1", Scopes: []string{model.ScopeAdmin}})
+	assert.ErrorIs(t, err, model.ErrConflict)
+	_, err = proc.AddAPIKey(ctx, model.APIKey{Name: "bad", Hash: "h2", Scopes: []string{"root"}})
+	assert.Error(t, err)
+	_, err = proc.AddAPIKey(ctx, model.APIKey{Name: "no hash", Scopes: []string{model.ScopeAdmin}})
+	assert.Error(t, err)
+
+	found, err := proc.APIKeyByHash(ctx, "h1")
+	require.NoError(t, err)
+	assert.Equal(t, key, found)
+	_, err = proc.APIKeyByHash(ctx, "h2")
+	assert.ErrorIs(t, err, model.ErrNotFound)
+
+	snap, err := proc.Snapshot(ctx)
+	require.NoError(t, err)
+	restored := NewProc()
+	require.NoError(t, restored.Restore(ctx, snap))
+	keys, err := restored.APIKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []model.APIKey{key}, keys)
+	key, err = restored.AddAPIKey(ctx, model.APIKey{Name: "uploads", Hash: "h2", Scopes: []string{model.ScopeWriteTransactions}})
+	require.NoError(t, err)
+	assert.Equal(t, "k-2", key.ID, "ids continue after restore")
+
+	require.NoError(t, proc.DeleteAPIKey(ctx, "k-1"))
+	assert.ErrorIs(t, proc.DeleteAPIKey(ctx, "k-1"), model.ErrNotFound)
+	_, err = proc.APIKeyByHash(ctx, "h1")
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
@@ -0,0 +1,99 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"github.com/mrnbort/summer_break/model"
+	"strconv"
+)
+
+// batch keeps staged transactions of an upload until it's committed
+type batch struct {
+	transactions []model.Transaction
+	ids          map[string]bool // explicit ids of staged transactions
+}
+
+// StageTransactions adds transactions to the staged batch with given id, empty id starts a new batch.
+// Staged transactions are checked like the ones passed to ProcessTransactions but are not stored until
+// the batch is committed. Returns id of the batch.
+func (p *Proc) StageTransactions(ctx context.Context, batchID string, transactions []model.Transaction) (string, error) {
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	default:
+	}
+
+	if err := validateAccounts(transactions); err != nil {
+		return "", err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.batches == nil {
+		p.batches = map[string]*batch{}
+	}
+	if batchID == "" {
+		p.lastBatchID++
+		batchID = strconv.FormatInt(p.lastBatchID, 10)
+		p.batches[batchID] = &batch{ids: map[string]bool{}}
+	}
+	b, ok := p.batches[batchID]
+	if !ok {
+		return "", fmt.Errorf("batch %q: %w", batchID, model.ErrNotFound)
+	}
+	for _, tr := range transactions {
+		if b.ids[tr.ID] {
+			return "", fmt.Errorf("transaction %q: %w", tr.ID, model.ErrConflict)
+		}
+	}
+	ids := map[string]bool{}
+	if err := p.checkNew(transactions, ids); err != nil {
+		return "", err
+	}
+	for id := range ids {
+		b.ids[id] = true
+	}
+	b.transactions = append(b.transactions, transactions...)
+	return batchID, nil
+}
+
+// CommitBatch stores all staged transactions of the batch at once and returns them as stored, like
+// ProcessTransactions. The batch is removed even if it can't be stored.
+func (p *Proc) CommitBatch(ctx context.Context, batchID string) ([]model.Transaction, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	b, ok := p.batches[batchID]
+	if !ok {
+		return nil, fmt.Errorf("batch %q: %w", batchID, model.ErrNotFound)
+	}
+	delete(p.batches, batchID)
+	// ids could be taken since the transactions were staged
+	ids := map[string]bool{}
+	if err := p.checkNew(b.transactions, ids); err != nil {
+		return nil, err
+	}
+	return p.store(b.transactions, ids), nil
+}
+
+// DiscardBatch drops the staged batch without storing its transactions
+func (p *Proc) DiscardBatch(ctx context.Context, batchID string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.batches[batchID]; !ok {
+		return fmt.Errorf("batch %q: %w", batchID, model.ErrNotFound)
+	}
+	delete(p.batches, batchID)
+	return nil
+}
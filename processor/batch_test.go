@@ -0,0 +1,65 @@
+package processor
+
+import (
+	"context"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestProc_StageTransactions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	proc := NewProc()
+	batch, err := proc.StageTransactions(ctx, "", []model.Transaction{{Date: date(2020, 7, 1), Type: model.Expense, Amount: 18.77, Memo: "Fuel"}})
+	require.NoError(t, err)
+	assert.Equal(t, "1", batch)
+	_, err = proc.StageTransactions(ctx, batch, []model.Transaction{{ID: "a", Account: "card", Date: date(2020, 7, 4), Type: model.Income, Amount: 40}})
+	require.NoError(t, err)
+
+	report, err := proc.GenerateReport(ctx, model.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, model.Report{}, report, "staged transactions are not stored")
+
+	_, err = proc.StageTransactions(ctx, batch, []model.Transaction{{ID: "a", Type: model.Income, Amount: 1}})
+	assert.ErrorIs(t, err, model.ErrConflict, "id staged before")
+	_, err = proc.StageTransactions(ctx, batch, []model.Transaction{{Account: "Bad Name", Type: model.Income, Amount: 1}})
+	assert.Error(t, err)
+	_, err = proc.StageTransactions(ctx, "unknown", []model.Transaction{{Type: model.Income, Amount: 1}})
+	assert.ErrorIs(t, err, model.ErrNotFound)
+
+	stored, err := proc.CommitBatch(ctx, batch)
+	require.NoError(t, err)
+	require.Len(t, stored, 2)
+	assert.Equal(t, "1", stored[0].ID)
+	assert.Equal(t, "a", stored[1].ID)
+	report, err = proc.GenerateReport(ctx, model.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, model.Report{GrossRevenue: 40, Expenses: 18.77, NetRevenue: 21.23}, report)
+	_, err = proc.CommitBatch(ctx, batch)
+	assert.ErrorIs(t, err, model.ErrNotFound, "committed batch is removed")
+
+	t.Run("conflict on commit", func(t *testing.T) {
+		batch, err := proc.StageTransactions(ctx, "", []model.Transaction{{ID: "b", Type: model.Income, Amount: 1}})
+		require.NoError(t, err)
+		_, err = proc.ProcessTransactions(ctx, []model.Transaction{{ID: "b", Type: model.Income, Amount: 2}})
+		require.NoError(t, err)
+		_, err = proc.CommitBatch(ctx, batch)
+		assert.ErrorIs(t, err, model.ErrConflict)
+		accounts, err := proc.Accounts(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 2, accounts[1].Transactions)
+	})
+
+	t.Run("discard", func(t *testing.T) {
+		batch, err := proc.StageTransactions(ctx, "", []model.Transaction{{Type: model.Income, Amount: 1}})
+		require.NoError(t, err)
+		require.NoError(t, proc.DiscardBatch(ctx, batch))
+		assert.ErrorIs(t, proc.DiscardBatch(ctx, batch), model.ErrNotFound)
+		_, err = proc.CommitBatch(ctx, batch)
+		assert.ErrorIs(t, err, model.ErrNotFound)
+	})
+}
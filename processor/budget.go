@@ -0,0 +1,181 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"github.com/mrnbort/summer_break/model"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Budgets returns all budgets sorted by category
+func (p *Proc) Budgets(ctx context.Context) ([]model.Budget, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	res := make([]model.Budget, len(p.budgets))
+	copy(res, p.budgets)
+	sort.Slice(res, func(i, j int) bool { return res[i].Category < res[j].Category })
+	return res, nil
+}
+
+// Budget returns budget with given id
+func (p *Proc) Budget(ctx context.Context, id string) (model.Budget, error) {
+	select {
+	case <-ctx.Done():
+		return model.Budget{}, ctx.Err()
+	default:
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	i := p.findBudget(id)
+	if i < 0 {
+		return model.Budget{}, fmt.Errorf("budget %q: %w", id, model.ErrNotFound)
+	}
+	return p.budgets[i], nil
+}
+
+// AddBudget adds budget for a category and returns it with assigned id.
+// A category can have only one budget.
+func (p *Proc) AddBudget(ctx context.Context, b model.Budget) (model.Budget, error) {
+	select {
+	case <-ctx.Done():
+		return model.Budget{}, ctx.Err()
+	default:
+	}
+
+	b.Category = strings.TrimSpace(b.Category)
+	if err := b.Validate(); err != nil {
+		return model.Budget{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.checkBudgetCategory("", b.Category); err != nil {
+		return model.Budget{}, err
+	}
+	p.lastBudgetID++
+	b.ID = "b-" + strconv.FormatInt(p.lastBudgetID, 10)
+	p.budgets = append(p.budgets, b)
+	return b, nil
+}
+
+// UpdateBudget replaces category, period and amount of the budget with b.ID
+func (p *Proc) UpdateBudget(ctx context.Context, b model.Budget) (model.Budget, error) {
+	select {
+	case <-ctx.Done():
+		return model.Budget{}, ctx.Err()
+	default:
+	}
+
+	b.Category = strings.TrimSpace(b.Category)
+	if err := b.Validate(); err != nil {
+		return model.Budget{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	i := p.findBudget(b.ID)
+	if i < 0 {
+		return model.Budget{}, fmt.Errorf("budget %q: %w", b.ID, model.ErrNotFound)
+	}
+	if err := p.checkBudgetCategory(b.ID, b.Category); err != nil {
+		return model.Budget{}, err
+	}
+	p.budgets[i] = b
+	return b, nil
+}
+
+// DeleteBudget removes budget with given id
+func (p *Proc) DeleteBudget(ctx context.Context, id string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	i := p.findBudget(id)
+	if i < 0 {
+		return fmt.Errorf("budget %q: %w", id, model.ErrNotFound)
+	}
+	p.budgets = append(p.budgets[:i], p.budgets[i+1:]...)
+	return nil
+}
+
+// BudgetReport compares expenses of transactions matching the filter with budgets prorated
+// to the filter's period. Categories are matched case-insensitively, expenses of categories
+// without budget are not included.
+func (p *Proc) BudgetReport(ctx context.Context, filter model.Filter) (model.BudgetReport, error) {
+	select {
+	case <-ctx.Done():
+		return model.BudgetReport{}, ctx.Err()
+	default:
+	}
+
+	if filter.From.IsZero() || filter.To.IsZero() {
+		return model.BudgetReport{}, fmt.Errorf("budget report needs both ends of the period")
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	totals, err := p.aggregate(filter)
+	if err != nil {
+		return model.BudgetReport{}, err
+	}
+	period := model.Period{From: filter.From, To: filter.To}
+	res := model.BudgetReport{Period: period, Lines: make([]model.BudgetLine, 0, len(p.budgets)), Total: model.BudgetLine{Category: "Total"}}
+	for _, b := range p.budgets {
+		line := model.BudgetLine{Category: b.Category, Budgeted: model.RoundCents(b.AmountFor(period))}
+		for category, amount := range totals.expenses {
+			if strings.EqualFold(category, b.Category) {
+				line.Actual += amount
+			}
+		}
+		res.Lines = append(res.Lines, budgetLine(line))
+		res.Total.Budgeted += line.Budgeted
+		res.Total.Actual += line.Actual
+	}
+	sort.Slice(res.Lines, func(i, j int) bool { return res.Lines[i].Category < res.Lines[j].Category })
+	res.Total = budgetLine(res.Total)
+	return res, nil
+}
+
+// budgetLine fills variance, percent used and over-budget flag of the line
+func budgetLine(line model.BudgetLine) model.BudgetLine {
+	line.Actual = model.RoundCents(line.Actual)
+	line.Variance = model.RoundCents(line.Budgeted - line.Actual)
+	if line.Budgeted > 0 {
+		line.PercentUsed = model.RoundCents(line.Actual / line.Budgeted * 100)
+	}
+	line.OverBudget = line.Actual > line.Budgeted
+	return line
+}
+
+// findBudget returns index of the budget with given id or -1, caller should hold the lock
+func (p *Proc) findBudget(id string) int {
+	for i, b := range p.budgets {
+		if b.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkBudgetCategory returns ErrConflict if a budget other than id has the category, caller should hold the lock
+func (p *Proc) checkBudgetCategory(id, category string) error {
+	for _, b := range p.budgets {
+		if b.ID != id && strings.EqualFold(b.Category, category) {
+			return fmt.Errorf("category %q already has budget %s: %w", category, b.ID, model.ErrConflict)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package processor
+
+import (
+	"context"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestProc_Budgets(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	proc := NewProc()
+	fuel, err := proc.AddBudget(ctx, model.Budget{Category: " Fuel ", Period: model.BudgetMonthly, Amount: 50})
+	require.NoError(t, err)
+	assert.Equal(t, model.Budget{ID: "b-1", Category: "Fuel", Period: model.BudgetMonthly, Amount: 50}, fuel)
+	_, err = proc.AddBudget(ctx, model.Budget{Category: "fuel", Period: model.BudgetYearly, Amount: 500})
+	require.ErrorIs(t, err, model.ErrConflict)
+	_, err = proc.AddBudget(ctx, model.Budget{Category: "Fuel", Period: "daily", Amount: 500})
+	require.Error(t, err)
+	repairs, err := proc.AddBudget(ctx, model.Budget{Category: "Repairs", Period: model.BudgetQuarterly, Amount: 60})
+	require.NoError(t, err)
+
+	repairs.Amount = 30
+	_, err = proc.UpdateBudget(ctx, repairs)
+	require.NoError(t, err)
+	repairs.Category = "Fuel"
+	_, err = proc.UpdateBudget(ctx, repairs)
+	require.ErrorIs(t, err, model.ErrConflict)
+	_, err = proc.UpdateBudget(ctx, model.Budget{ID: "b-9", Category: "Fuel", Period: model.BudgetMonthly, Amount: 1})
+	require.ErrorIs(t, err, model.ErrNotFound)
+
+	b, err := proc.Budget(ctx, "b-2")
+	require.NoError(t, err)
+	assert.Equal(t, 30.0, b.Amount)
+
+	lunch, err := proc.AddBudget(ctx, model.Budget{Category: "Lunch", Period: model.BudgetMonthly, Amount: 10})
+	require.NoError(t, err)
+	require.NoError(t, proc.DeleteBudget(ctx, lunch.ID))
+	require.ErrorIs(t, proc.DeleteBudget(ctx, lunch.ID), model.ErrNotFound)
+	_, err = proc.Budget(ctx, lunch.ID)
+	require.ErrorIs(t, err, model.ErrNotFound)
+
+	budgets, err := proc.Budgets(ctx)
+	require.NoError(t, err)
+	require.Len(t, budgets, 2)
+	assert.Equal(t, "Fuel", budgets[0].Category)
+	assert.Equal(t, "Repairs", budgets[1].Category)
+}
+
+func TestProc_BudgetReport(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	proc := NewProc()
+	_, err := proc.ProcessTransactions(ctx, []model.Transaction{
+		{Date: date(2020, 7, 1), Type: model.Expense, Amount: 18.77, Memo: "Fuel"},
+		{Date: date(2020, 7, 4), Type: model.Income, Amount: 40, Memo: "347 Woodrow"},
+		{Date: date(2020, 7, 12), Type: model.Expense, Amount: 27.5, Memo: "Repairs"},
+		{Date: date(2020, 7, 20), Type: model.Expense, Amount: 40, Memo: "gas", Category: "fuel"},
+		{Date: date(2020, 8, 2), Type: model.Expense, Amount: 30, Memo: "Fuel"},
+	})
+	require.NoError(t, err)
+	_, err = proc.AddBudget(ctx, model.Budget{Category: "Fuel", Period: model.BudgetMonthly, Amount: 50})
+	require.NoError(t, err)
+	_, err = proc.AddBudget(ctx, model.Budget{Category: "Repairs", Period: model.BudgetQuarterly, Amount: 90})
+	require.NoError(t, err)
+
+	res, err := proc.BudgetReport(ctx, model.Filter{From: date(2020, 7, 1), To: date(2020, 7, 31)})
+	require.NoError(t, err)
+	assert.Equal(t, []model.BudgetLine{
+		{Category: "Fuel", Budgeted: 50, Actual: 58.77, Variance: -8.77, PercentUsed: 117.54, OverBudget: true},
+		{Category: "Repairs", Budgeted: 30.33, Actual: 27.5, Variance: 2.83, PercentUsed: 90.67},
+	}, res.Lines)
+	assert.Equal(t, model.BudgetLine{Category: "Total", Budgeted: 80.33, Actual: 86.27, Variance: -5.94,
+		PercentUsed: 107.39, OverBudget: true}, res.Total)
+
+	_, err = proc.BudgetReport(ctx, model.Filter{From: date(2020, 7, 1)})
+	require.Error(t, err)
+}
@@ -0,0 +1,157 @@
+package processor
+
+import (
+	"context"
+	"github.com/mrnbort/summer_break/model"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Forecast projects income, expenses and balance of the accounts for req.Horizon days after req.From.
+// Each day gets occurrences of recurring schedules plus daily averages by category of other transactions
+// from req.History days up to req.From. Confidence bands grow with the square root of the number of days,
+// from the standard deviation of daily net amounts of those other transactions. Tags select the transactions
+// and schedules to project, the starting balance is the balance of the accounts.
+func (p *Proc) Forecast(ctx context.Context, req model.ForecastRequest) (model.Forecast, error) {
+	select {
+	case <-ctx.Done():
+		return model.Forecast{}, ctx.Err()
+	default:
+	}
+
+	if err := req.Validate(); err != nil {
+		return model.Forecast{}, err
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	accounts := req.Accounts
+	if len(accounts) == 0 {
+		accounts = p.accountNames()
+	}
+	ledgers, err := p.selectLedgers(accounts)
+	if err != nil {
+		return model.Forecast{}, err
+	}
+
+	res := model.Forecast{From: req.From.AddDate(0, 0, 1), To: req.From.AddDate(0, 0, req.Horizon), Step: req.Step}
+	histFrom := req.From.AddDate(0, 0, 1-req.History)
+	daily := make([]float64, req.History) // net amount of every history day
+	income, expenses := map[string]float64{}, map[string]float64{}
+	for _, l := range ledgers {
+		res.StartingBalance += l.balance(req.From)
+		l.transactions.between(histFrom, req.From, func(tr *model.Transaction) {
+			if p.scheduled(tr.ID) || !req.Tags.Match(*tr) {
+				return
+			}
+			daily[dayIndex(histFrom, tr.Date)] += tr.Signed()
+			switch tr.Type {
+			case model.Income:
+				income[tr.CategoryName()] += tr.Amount
+			case model.Expense:
+				expenses[tr.CategoryName()] += tr.Amount
+			}
+		})
+	}
+	avgIncome, avgExpenses := 0.0, 0.0
+	for category, total := range income {
+		avgIncome += total / float64(req.History)
+		res.Averages = append(res.Averages, model.CategoryAverage{Category: category, Type: model.Income, Daily: model.RoundCents(total / float64(req.History))})
+	}
+	for category, total := range expenses {
+		avgExpenses += total / float64(req.History)
+		res.Averages = append(res.Averages, model.CategoryAverage{Category: category, Type: model.Expense, Daily: model.RoundCents(total / float64(req.History))})
+	}
+	sort.Slice(res.Averages, func(i, j int) bool {
+		if res.Averages[i].Type != res.Averages[j].Type {
+			return res.Averages[i].Type == model.Income
+		}
+		return res.Averages[i].Category < res.Averages[j].Category
+	})
+	sigma := stdDev(daily)
+
+	// scheduled transactions by day of the horizon
+	scheduled := make([]model.ForecastPoint, req.Horizon)
+	for _, sch := range p.schedules {
+		account := sch.Account
+		if account == "" {
+			account = model.DefaultAccount
+		}
+		if !model.ContainsString(accounts, account) {
+			continue
+		}
+		for n := sch.Count; ; n++ {
+			tr, ok := sch.Transaction(n)
+			if !ok || tr.Date.After(res.To) {
+				break
+			}
+			if !tr.Date.After(req.From) {
+				continue // due, but not materialized yet
+			}
+			if !req.Tags.Match(tr) {
+				break // occurrences have tags of the schedule
+			}
+			point := &scheduled[dayIndex(res.From, tr.Date)]
+			point.Scheduled += tr.Signed()
+			if tr.Type == model.Income {
+				point.Income += tr.Amount
+			} else {
+				point.Expenses += tr.Amount
+			}
+		}
+	}
+
+	balance := res.StartingBalance
+	var point model.ForecastPoint
+	for day := 0; day < req.Horizon; day++ {
+		point.Income += avgIncome + scheduled[day].Income
+		point.Expenses += avgExpenses + scheduled[day].Expenses
+		point.Scheduled += scheduled[day].Scheduled
+		balance += avgIncome - avgExpenses + scheduled[day].Scheduled
+		if req.Step == model.StepWeekly && (day+1)%7 != 0 && day != req.Horizon-1 {
+			continue
+		}
+		band := model.ForecastZ * sigma * math.Sqrt(float64(day+1))
+		point.Date = res.From.AddDate(0, 0, day)
+		point.Income, point.Expenses, point.Scheduled = model.RoundCents(point.Income), model.RoundCents(point.Expenses), model.RoundCents(point.Scheduled)
+		point.Balance, point.Low, point.High = model.RoundCents(balance), model.RoundCents(balance-band), model.RoundCents(balance+band)
+		res.Points = append(res.Points, point)
+		point = model.ForecastPoint{}
+	}
+	res.StartingBalance = model.RoundCents(res.StartingBalance)
+	return res, nil
+}
+
+// scheduled checks if transaction was made from a recurring schedule, caller should hold the lock
+func (p *Proc) scheduled(id string) bool {
+	for _, sch := range p.schedules {
+		if strings.HasPrefix(id, sch.ID+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// dayIndex returns number of days from the start to the date
+func dayIndex(start, date time.Time) int {
+	return int(date.Sub(start).Hours()/24 + 0.5)
+}
+
+// stdDev returns population standard deviation of the values
+func stdDev(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(variance / float64(len(values)))
+}
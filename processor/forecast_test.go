@@ -0,0 +1,87 @@
+package processor
+
+import (
+	"context"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestProc_Forecast(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	proc := NewProc()
+	_, err := proc.AddSchedule(ctx, model.Schedule{Type: model.Expense, Amount: 100, Memo: "Rent", Freq: model.FreqMonthly,
+		Start: date(2020, 7, 1)})
+	require.NoError(t, err)
+	_, err = proc.ProcessTransactions(ctx, []model.Transaction{
+		{ID: "s-1-1", Date: date(2020, 7, 1), Type: model.Expense, Amount: 100, Memo: "Rent"},
+		{Date: date(2020, 7, 4), Type: model.Income, Amount: 155, Memo: "347 Woodrow", Category: "Lawns"},
+		{Date: date(2020, 7, 12), Type: model.Expense, Amount: 62, Memo: "Fuel"},
+		{Date: date(2020, 7, 20), Type: model.Income, Amount: 155, Memo: "219 Pleasant", Category: "Lawns"},
+		{Date: date(2020, 6, 20), Type: model.Income, Amount: 1000, Memo: "Snow", Category: "Snow removal"},
+		{Account: "card", Date: date(2020, 7, 20), Type: model.Expense, Amount: 31, Memo: "Lunch"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, proc.AdvanceSchedule(ctx, "s-1", 1))
+
+	req := model.ForecastRequest{Accounts: []string{model.DefaultAccount}, From: date(2020, 7, 31), Horizon: 14, History: 31,
+		Step: model.StepDaily}
+	res, err := proc.Forecast(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, date(2020, 8, 1), res.From)
+	assert.Equal(t, date(2020, 8, 14), res.To)
+	assert.Equal(t, 1148.0, res.StartingBalance)
+	assert.Equal(t, []model.CategoryAverage{{Category: "Lawns", Type: model.Income, Daily: 10},
+		{Category: "Fuel", Type: model.Expense, Daily: 2}}, res.Averages, "rent is scheduled, snow is too old")
+	require.Len(t, res.Points, 14)
+	assert.Equal(t, model.ForecastPoint{Date: date(2020, 8, 1), Income: 10, Expenses: 102, Scheduled: -100, Balance: 1056,
+		Low: res.Points[0].Low, High: res.Points[0].High}, res.Points[0])
+	assert.Equal(t, 1160.0, res.Points[13].Balance)
+	assert.Less(t, res.Points[0].Low, res.Points[0].Balance)
+	assert.Greater(t, res.Points[0].High, res.Points[0].Balance)
+	assert.Greater(t, res.Points[13].High-res.Points[13].Low, res.Points[0].High-res.Points[0].Low, "bands widen")
+
+	req.Step = model.StepWeekly
+	res, err = proc.Forecast(ctx, req)
+	require.NoError(t, err)
+	require.Len(t, res.Points, 2)
+	assert.Equal(t, date(2020, 8, 7), res.Points[0].Date)
+	assert.Equal(t, 70.0, res.Points[0].Income)
+	assert.Equal(t, 114.0, res.Points[0].Expenses)
+	assert.Equal(t, 1104.0, res.Points[0].Balance)
+
+	// all accounts include card lunches
+	req.Accounts, req.Horizon = nil, 10
+	res, err = proc.Forecast(ctx, req)
+	require.NoError(t, err)
+	require.Len(t, res.Points, 2)
+	assert.Equal(t, date(2020, 8, 10), res.Points[1].Date)
+	assert.Equal(t, 1117.0, res.StartingBalance)
+	assert.Equal(t, 1117.0+10*7-100, res.Points[1].Balance)
+
+	// lawns only, rent and fuel have no tag
+	lines, err := proc.Transactions(ctx, model.Filter{From: date(2020, 7, 4), To: date(2020, 7, 4)})
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+	_, err = proc.UpdateTransaction(ctx, lines[0].ID, model.TransactionUpdate{Tags: &[]string{"lawn"}})
+	require.NoError(t, err)
+	req.Step = model.StepDaily
+	req.Tags, err = model.ParseTagExpr("lawn")
+	require.NoError(t, err)
+	res, err = proc.Forecast(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, []model.CategoryAverage{{Category: "Lawns", Type: model.Income, Daily: 5}}, res.Averages)
+	assert.Equal(t, 1117.0, res.StartingBalance)
+	assert.Equal(t, model.ForecastPoint{Date: date(2020, 8, 1), Income: 5, Balance: 1122, Low: res.Points[0].Low,
+		High: res.Points[0].High}, res.Points[0], "rent isn't tagged")
+
+	_, err = proc.Forecast(ctx, model.ForecastRequest{From: date(2020, 7, 31), Horizon: 10, History: 31, Step: "monthly"})
+	require.Error(t, err)
+	_, err = proc.Forecast(ctx, model.ForecastRequest{Accounts: []string{"unknown"}, From: date(2020, 7, 31), Horizon: 10,
+		History: 31, Step: model.StepDaily})
+	require.ErrorIs(t, err, model.ErrNotFound)
+}
@@ -0,0 +1,240 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/mrnbort/summer_break/model"
+	"reflect"
+	"strconv"
+)
+
+// Import merges the snapshot into the stored state. Ids are sequences of the store the snapshot comes from,
+// so transactions, schedules and manual journal entries are matched by content, and the new ones get ids of
+// this store. Accounts and chart accounts are matched by name, budgets by category; a record stored with
+// different content is resolved by policy.
+// With model.ImportFail policy nothing is stored if there are conflicts, they are listed in the result
+// and model.ErrConflict is returned. Import is not supported in double-entry mode.
+func (p *Proc) Import(ctx context.Context, snap model.Snapshot, policy string) (model.ImportResult, error) {
+	select {
+	case <-ctx.Done():
+		return model.ImportResult{}, ctx.Err()
+	default:
+	}
+
+	if err := model.ValidateImportPolicy(policy); err != nil {
+		return model.ImportResult{}, err
+	}
+	if snap.Version != model.SnapshotVersion {
+		return model.ImportResult{}, fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+	for _, tr := range snap.Transactions {
+		if err := tr.Validate(); err != nil {
+			return model.ImportResult{}, fmt.Errorf("transaction %q: %w", tr.ID, err)
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.journal != nil {
+		return model.ImportResult{}, fmt.Errorf("import in double-entry mode: %w", model.ErrDoubleEntryDisabled)
+	}
+	cur := p.snapshot()
+	m := merger{policy: policy}
+
+	accounts := map[string]int{}
+	for i, acc := range cur.Accounts {
+		acc.Transactions, cur.Accounts[i].Transactions = 0, 0 // counts are not compared
+		accounts[acc.Name] = i
+	}
+	for _, acc := range snap.Accounts {
+		acc.Transactions = 0
+		i, ok := accounts[acc.Name]
+		if !ok {
+			cur.Accounts = append(cur.Accounts, acc)
+			m.res.Added++
+			continue
+		}
+		if m.resolve("account "+acc.Name, cur.Accounts[i], acc) {
+			cur.Accounts[i] = acc
+		}
+	}
+
+	transactions, ids := map[string][]int{}, map[string]bool{}
+	for i, tr := range cur.Transactions {
+		key := transactionKey(tr)
+		transactions[key] = append(transactions[key], i)
+		ids[tr.ID] = true
+	}
+	for _, tr := range snap.Transactions {
+		key := transactionKey(tr)
+		if len(transactions[key]) == 0 {
+			for tr.ID = ""; tr.ID == ""; { // ids of other store may be taken
+				cur.LastID++
+				if id := strconv.FormatInt(cur.LastID, 10); !ids[id] {
+					tr.ID = id
+				}
+			}
+			ids[tr.ID] = true
+			cur.Transactions = append(cur.Transactions, tr)
+			m.res.Added++
+			continue
+		}
+		i := transactions[key][0]
+		transactions[key] = transactions[key][1:] // a stored transaction is matched once
+		tr.ID = cur.Transactions[i].ID
+		if m.resolve("transaction "+tr.ID, cur.Transactions[i], tr) {
+			cur.Transactions[i] = tr
+		}
+	}
+
+	budgets := map[string]int{}
+	for i, b := range cur.Budgets {
+		budgets[b.Category] = i
+	}
+	for _, b := range snap.Budgets {
+		i, ok := budgets[b.Category]
+		if !ok {
+			cur.LastBudgetID++
+			b.ID = "b-" + strconv.FormatInt(cur.LastBudgetID, 10) // ids of other store may be taken
+			cur.Budgets = append(cur.Budgets, b)
+			m.res.Added++
+			continue
+		}
+		b.ID = cur.Budgets[i].ID
+		if m.resolve("budget "+b.Category, cur.Budgets[i], b) {
+			cur.Budgets[i] = b
+		}
+	}
+
+	schedules := map[string][]int{}
+	for i, sch := range cur.Schedules {
+		key := scheduleKey(sch)
+		schedules[key] = append(schedules[key], i)
+	}
+	for _, sch := range snap.Schedules {
+		key := scheduleKey(sch)
+		if len(schedules[key]) == 0 {
+			cur.LastScheduleID++
+			sch.ID = "s-" + strconv.FormatInt(cur.LastScheduleID, 10)
+			cur.Schedules = append(cur.Schedules, sch)
+			m.res.Added++
+			continue
+		}
+		i := schedules[key][0]
+		schedules[key] = schedules[key][1:]
+		sch.ID = cur.Schedules[i].ID
+		if m.resolve("schedule "+sch.ID, cur.Schedules[i], sch) {
+			cur.Schedules[i] = sch
+		}
+	}
+
+	chart := map[string]int{}
+	for i, acc := range cur.Chart {
+		chart[acc.Name] = i
+	}
+	for _, acc := range snap.Chart {
+		i, ok := chart[acc.Name]
+		if !ok {
+			chart[acc.Name] = len(cur.Chart)
+			cur.Chart = append(cur.Chart, acc)
+			m.res.Added++
+			continue
+		}
+		if m.resolve("chart account "+acc.Name, cur.Chart[i], acc) {
+			cur.Chart[i] = acc
+		}
+	}
+
+	entries := map[string]int{}
+	for _, entry := range cur.JournalEntries {
+		entries[entryKey(entry)]++
+	}
+	for _, entry := range snap.JournalEntries {
+		if key := entryKey(entry); entries[key] > 0 {
+			entries[key]--
+			m.res.Unchanged++
+			continue
+		}
+		cur.JournalEntries = append(cur.JournalEntries, entry) // ids are given when double-entry mode is enabled
+		m.res.Added++
+	}
+
+	switch {
+	case reflect.DeepEqual(snap.Tax, model.TaxConfig{}):
+	case reflect.DeepEqual(cur.Tax, model.TaxConfig{}):
+		cur.Tax = snap.Tax
+		m.res.Added++
+	case m.resolve("tax config", cur.Tax, snap.Tax):
+		cur.Tax = snap.Tax
+	}
+
+	if len(m.res.Conflicts) > 0 {
+		return m.res, fmt.Errorf("%d records are already stored: %w", len(m.res.Conflicts), model.ErrConflict)
+	}
+	if err := p.restore(cur); err != nil {
+		return model.ImportResult{}, err
+	}
+	return m.res, nil
+}
+
+// merger resolves conflicts of imported records by policy and counts the results
+type merger struct {
+	policy string
+	res    model.ImportResult
+}
+
+// resolve compares stored and imported record and tells if the imported one should replace it
+func (m *merger) resolve(name string, stored, imported interface{}) bool {
+	if sameJSON(stored, imported) {
+		m.res.Unchanged++
+		return false
+	}
+	switch m.policy {
+	case model.ImportReplace:
+		m.res.Replaced++
+		return true
+	case model.ImportSkip:
+		m.res.Skipped++
+	default:
+		m.res.Conflicts = append(m.res.Conflicts, name)
+	}
+	return false
+}
+
+// sameJSON tells if a and b have the same JSON, so time zones of dates decoded from an archive
+// and nil vs empty slices don't make a difference
+func sameJSON(a, b interface{}) bool {
+	da, errA := json.Marshal(a)
+	db, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(da, db)
+}
+
+// transactionKey identifies transaction by its content, ids are sequences of the store and can't be
+// matched between stores. Transactions of the same key differ by tags, category and flags.
+func transactionKey(tr model.Transaction) string {
+	if tr.Account == "" {
+		tr.Account = model.DefaultAccount
+	}
+	return fmt.Sprintf("%s|%d|%s|%v|%s", tr.Account, tr.Date.UnixNano(), tr.Type, tr.Amount, tr.Memo)
+}
+
+// scheduleKey identifies schedule by what and when it repeats, schedules of the same key differ by tags,
+// category, end and the occurrences made
+func scheduleKey(sch model.Schedule) string {
+	if sch.Account == "" {
+		sch.Account = model.DefaultAccount
+	}
+	return fmt.Sprintf("%s|%s|%v|%s|%s|%d|%d|%d", sch.Account, sch.Type, sch.Amount, sch.Memo, sch.Freq,
+		sch.Interval, sch.Day, sch.Start.UnixNano())
+}
+
+// entryKey identifies manual journal entry by its content, its id is given again when double-entry
+// mode is enabled
+func entryKey(entry model.JournalEntry) string {
+	entry.ID = ""
+	entry.Date = entry.Date.UTC()
+	data, _ := json.Marshal(entry) // entry has nothing json can't encode
+	return string(data)
+}
@@ -0,0 +1,159 @@
+package processor
+
+import (
+	"context"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestProc_Import(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	src := NewProc()
+	_, err := src.ProcessTransactions(ctx, []model.Transaction{
+		{Account: "card", Date: date(2020, 7, 1), Type: model.Expense, Amount: 18.77, Memo: "Shell"},
+		{Account: "card", Date: date(2020, 7, 4), Type: model.Income, Amount: 40, Memo: "347 Woodrow"},
+	})
+	require.NoError(t, err)
+	_, err = src.AddBudget(ctx, model.Budget{Category: "Fuel", Period: model.BudgetMonthly, Amount: 50})
+	require.NoError(t, err)
+	snap, err := src.Snapshot(ctx)
+	require.NoError(t, err)
+
+	newDst := func(t *testing.T) *Proc {
+		dst := NewProc()
+		_, err := dst.ProcessTransactions(ctx, []model.Transaction{
+			{Account: "card", Date: date(2020, 7, 1), Type: model.Expense, Amount: 18.77, Memo: "Shell"}, // the same as imported
+			{Account: "cash", Date: date(2020, 7, 2), Type: model.Expense, Amount: 5, Memo: "Coffee"},    // takes id 2
+			{Account: "card", Date: date(2020, 7, 4), Type: model.Income, Amount: 40, Memo: "347 Woodrow", Category: "Lawns"},
+		})
+		require.NoError(t, err)
+		_, err = dst.AddBudget(ctx, model.Budget{Category: "Food", Period: model.BudgetMonthly, Amount: 100})
+		require.NoError(t, err)
+		return dst
+	}
+	report := func(t *testing.T, p *Proc) model.Report {
+		res, err := p.GenerateReport(ctx, model.Filter{})
+		require.NoError(t, err)
+		return res
+	}
+
+	t.Run("empty store", func(t *testing.T) {
+		dst := NewProc()
+		res, err := dst.Import(ctx, snap, model.ImportFail)
+		require.NoError(t, err)
+		assert.Equal(t, model.ImportResult{Added: 4}, res, "account, two transactions and budget")
+		assert.Equal(t, report(t, src), report(t, dst))
+		res, err = dst.Import(ctx, snap, model.ImportFail)
+		require.NoError(t, err)
+		assert.Equal(t, model.ImportResult{Unchanged: 4}, res, "imported again")
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		dst := newDst(t)
+		res, err := dst.Import(ctx, snap, model.ImportFail)
+		assert.ErrorIs(t, err, model.ErrConflict)
+		assert.Equal(t, []string{"transaction 3"}, res.Conflicts, "matched by content, not by id")
+		assert.Equal(t, 23.77, report(t, dst).Expenses, "nothing imported")
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		dst := newDst(t)
+		res, err := dst.Import(ctx, snap, model.ImportSkip)
+		require.NoError(t, err)
+		assert.Equal(t, model.ImportResult{Added: 1, Skipped: 1, Unchanged: 2}, res)
+		assert.Equal(t, model.Report{GrossRevenue: 40, Expenses: 23.77, NetRevenue: 16.23}, report(t, dst))
+		tr, err := dst.Transaction(ctx, "3")
+		require.NoError(t, err)
+		assert.Equal(t, "Lawns", tr.Category, "kept")
+		budgets, err := dst.Budgets(ctx)
+		require.NoError(t, err)
+		require.Len(t, budgets, 2)
+		assert.Equal(t, "Fuel", budgets[1].Category)
+		assert.Equal(t, "b-2", budgets[1].ID, "imported budget gets new id")
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		dst := newDst(t)
+		res, err := dst.Import(ctx, snap, model.ImportReplace)
+		require.NoError(t, err)
+		assert.Equal(t, model.ImportResult{Added: 1, Replaced: 1, Unchanged: 2}, res)
+		tr, err := dst.Transaction(ctx, "3")
+		require.NoError(t, err)
+		assert.Empty(t, tr.Category, "replaced, keeping the stored id")
+		tr, err = dst.Transaction(ctx, "2")
+		require.NoError(t, err)
+		assert.Equal(t, "Coffee", tr.Memo, "transaction with the same id in the other store isn't touched")
+		stored, err := dst.ProcessTransactions(ctx, []model.Transaction{{Type: model.Income, Amount: 1}})
+		require.NoError(t, err)
+		assert.Equal(t, "4", stored[0].ID)
+	})
+
+	t.Run("other store", func(t *testing.T) {
+		other := NewProc()
+		_, err := other.ProcessTransactions(ctx, []model.Transaction{
+			{Account: "card", Date: date(2020, 8, 1), Type: model.Expense, Amount: 12, Memo: "Parking"},
+			{Account: "card", Date: date(2020, 8, 1), Type: model.Expense, Amount: 12, Memo: "Parking"}, // paid twice
+		})
+		require.NoError(t, err)
+		_, err = other.AddSchedule(ctx, model.Schedule{Type: model.Expense, Amount: 900, Memo: "Rent", Freq: model.FreqMonthly,
+			Start: date(2020, 8, 1)})
+		require.NoError(t, err)
+		otherSnap, err := other.Snapshot(ctx)
+		require.NoError(t, err)
+		otherSnap.Chart = []model.ChartAccount{{Name: "Equity", Type: model.EquityAccount}}
+		otherSnap.JournalEntries = []model.JournalEntry{{ID: "je-3", Date: date(2020, 8, 2), Memo: "owner",
+			Postings: []model.Posting{{Account: "Assets", Amount: 10}, {Account: "Equity", Amount: -10}}}}
+
+		dst := newDst(t)
+		_, err = dst.AddSchedule(ctx, model.Schedule{Type: model.Expense, Amount: 15, Memo: "Music", Freq: model.FreqMonthly,
+			Start: date(2020, 7, 1)})
+		require.NoError(t, err)
+		res, err := dst.Import(ctx, otherSnap, model.ImportFail)
+		require.NoError(t, err, "ids 1, 2 and s-1 of the other store are no conflicts")
+		assert.Equal(t, model.ImportResult{Added: 5, Unchanged: 1}, res, "two transactions, schedule, chart account and entry")
+
+		trs, err := dst.Transactions(ctx, model.Filter{From: date(2020, 8, 1)})
+		require.NoError(t, err)
+		require.Len(t, trs, 2)
+		assert.ElementsMatch(t, []string{"4", "5"}, []string{trs[0].ID, trs[1].ID}, "new ids")
+		schedules, err := dst.Schedules(ctx)
+		require.NoError(t, err)
+		require.Len(t, schedules, 2)
+		assert.Equal(t, "Music", schedules[0].Memo)
+		assert.Equal(t, "s-2", schedules[1].ID)
+
+		snap, err := dst.Snapshot(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, otherSnap.Chart, snap.Chart)
+		require.Len(t, snap.JournalEntries, 1)
+		res, err = dst.Import(ctx, otherSnap, model.ImportFail)
+		require.NoError(t, err)
+		assert.Equal(t, model.ImportResult{Unchanged: 6}, res, "imported again")
+	})
+
+	t.Run("bad requests", func(t *testing.T) {
+		_, err := NewProc().Import(ctx, snap, "merge")
+		assert.Error(t, err)
+		bad := snap
+		bad.Version = 42
+		_, err = NewProc().Import(ctx, bad, model.ImportFail)
+		assert.Error(t, err)
+
+		for _, tr := range []model.Transaction{
+			{ID: "9", Date: time.Now(), Type: "Gift", Amount: 1},
+			{ID: "9", Date: time.Now(), Type: model.Income, Amount: math.NaN()},
+			{ID: "9", Type: model.Income, Amount: 1},
+		} {
+			bad = snap
+			bad.Transactions = []model.Transaction{tr}
+			_, err = NewProc().Import(ctx, bad, model.ImportFail)
+			assert.Error(t, err, "invalid transaction %+v", tr)
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package processor
+
+import (
+	"github.com/mrnbort/summer_break/model"
+	"sort"
+	"time"
+)
+
+// maxChunk is the size at which a chunk of the index is split in two
+const maxChunk = 512
+
+// index keeps transactions ordered by date in sorted chunks, so range scans skip the chunks before
+// the range and inserts, in order or not, move at most a chunk of pointers. Transactions of the same
+// date keep the insert order.
+type index struct {
+	chunks [][]*model.Transaction // non-empty, ordered by date, dates of a chunk are not after the next one's
+	byID   map[string]*model.Transaction
+	size   int
+}
+
+// insert adds copy of the transaction after all transactions of the same or earlier date
+func (x *index) insert(tr model.Transaction) {
+	if x.byID == nil {
+		x.byID = map[string]*model.Transaction{}
+	}
+	t := &tr
+	x.byID[tr.ID] = t
+	x.size++
+	if len(x.chunks) == 0 {
+		x.chunks = [][]*model.Transaction{{t}}
+		return
+	}
+	// the first chunk ending after the date, or the last one
+	ci := sort.Search(len(x.chunks), func(i int) bool { c := x.chunks[i]; return c[len(c)-1].Date.After(tr.Date) })
+	if ci == len(x.chunks) {
+		ci--
+	}
+	c := x.chunks[ci]
+	pos := sort.Search(len(c), func(i int) bool { return c[i].Date.After(tr.Date) })
+	c = append(c, nil)
+	copy(c[pos+1:], c[pos:])
+	c[pos] = t
+	if len(c) < maxChunk {
+		x.chunks[ci] = c
+		return
+	}
+	half := len(c) / 2
+	left, right := c[:half:half], append([]*model.Transaction(nil), c[half:]...)
+	x.chunks = append(x.chunks, nil)
+	copy(x.chunks[ci+2:], x.chunks[ci+1:])
+	x.chunks[ci], x.chunks[ci+1] = left, right
+}
+
+// get returns the transaction with given id or nil
+func (x *index) get(id string) *model.Transaction {
+	return x.byID[id]
+}
+
+// len returns number of transactions
+func (x *index) len() int {
+	return x.size
+}
+
+// each calls fn for every transaction in date order
+func (x *index) each(fn func(tr *model.Transaction)) {
+	x.between(time.Time{}, time.Time{}, fn)
+}
+
+// between calls fn for transactions from the start to the end (both inclusive) in date order,
+// zero start or end means no limit
+func (x *index) between(from, to time.Time, fn func(tr *model.Transaction)) {
+	ci, pos := 0, 0
+	if !from.IsZero() {
+		ci = sort.Search(len(x.chunks), func(i int) bool { c := x.chunks[i]; return !c[len(c)-1].Date.Before(from) })
+		if ci < len(x.chunks) {
+			c := x.chunks[ci]
+			pos = sort.Search(len(c), func(i int) bool { return !c[i].Date.Before(from) })
+		}
+	}
+	for ; ci < len(x.chunks); ci, pos = ci+1, 0 {
+		for _, tr := range x.chunks[ci][pos:] {
+			if !to.IsZero() && tr.Date.After(to) {
+				return
+			}
+			fn(tr)
+		}
+	}
+}
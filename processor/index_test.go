@@ -0,0 +1,68 @@
+package processor
+
+import (
+	"fmt"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+)
+
+// list returns copies of all transactions of the index in date order
+func (x *index) list() []model.Transaction {
+	var res []model.Transaction
+	x.each(func(tr *model.Transaction) { res = append(res, *tr) })
+	return res
+}
+
+func TestIndex(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	x := index{}
+	var expected []model.Transaction
+	for i := 0; i < 5000; i++ {
+		tr := model.Transaction{ID: fmt.Sprint(i), Date: date(2020, 1, 1).AddDate(0, 0, rnd.Intn(365))}
+		if i%1000 == 0 {
+			tr.Date = time.Time{}
+		}
+		x.insert(tr)
+		expected = append(expected, tr)
+	}
+	sort.SliceStable(expected, func(i, j int) bool { return expected[i].Date.Before(expected[j].Date) })
+	assert.Equal(t, 5000, x.len())
+	assert.Greater(t, len(x.chunks), 10)
+	require.Equal(t, expected, x.list(), "ordered by date, insert order kept within a day")
+	for _, c := range x.chunks {
+		assert.NotEmpty(t, c)
+		assert.Less(t, len(c), maxChunk)
+	}
+
+	between := func(from, to time.Time) []model.Transaction {
+		var res []model.Transaction
+		x.between(from, to, func(tr *model.Transaction) { res = append(res, *tr) })
+		return res
+	}
+	for _, tt := range []struct{ from, to time.Time }{
+		{date(2020, 3, 1), date(2020, 3, 31)},
+		{date(2020, 12, 31), time.Time{}},
+		{time.Time{}, date(2020, 1, 1)},
+		{date(2021, 1, 1), time.Time{}},
+		{date(2020, 5, 5), date(2020, 5, 5)},
+	} {
+		var want []model.Transaction
+		for _, tr := range expected {
+			if (tt.from.IsZero() || !tr.Date.Before(tt.from)) && (tt.to.IsZero() || !tr.Date.After(tt.to)) {
+				want = append(want, tr)
+			}
+		}
+		assert.Equal(t, want, between(tt.from, tt.to), "%v - %v", tt.from, tt.to)
+	}
+
+	tr := x.get("42")
+	require.NotNil(t, tr)
+	tr.Cleared = true
+	assert.True(t, x.get("42").Cleared, "stored transaction changed")
+	assert.Nil(t, x.get("unknown"))
+}
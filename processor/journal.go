@@ -0,0 +1,364 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"github.com/mrnbort/summer_break/model"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// default income and expense accounts for uploaded transactions
+const (
+	incomeAccount  = "Income"
+	expenseAccount = "Expenses"
+)
+
+// journal keeps the chart of accounts and journal entries of the double-entry mode
+type journal struct {
+	defaultAsset string // account uploaded transactions are posted against, unless their ledger is mapped
+	accounts     map[string]model.ChartAccount
+	entries      []model.JournalEntry
+	byDate       []int          // entry indexes ordered by date, entries of the same date in the posting order
+	byTxID       map[string]int // transaction id to entry index
+	lastID       int64
+}
+
+// savedJournal is the chart of accounts and manually posted entries restored from snapshot before double-entry
+// mode is enabled. They are added to the journal when it's enabled and kept in snapshots until then.
+type savedJournal struct {
+	chart   []model.ChartAccount
+	entries []model.JournalEntry
+}
+
+// EnableDoubleEntry turns on double-entry mode. Every stored and new transaction gets a journal entry
+// against defaultAsset account (or the account mapped to its ledger) and "Income" or "Expenses" account,
+// and reports are derived from postings to income and expense accounts. The chart of accounts and
+// manual entries of the restored snapshot are added, the manual entries get new ids after the entries
+// of transactions.
+func (p *Proc) EnableDoubleEntry(defaultAsset string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	j := &journal{defaultAsset: defaultAsset, accounts: map[string]model.ChartAccount{}, byTxID: map[string]int{}}
+	for _, acc := range []model.ChartAccount{
+		{Name: defaultAsset, Type: model.AssetAccount},
+		{Name: incomeAccount, Type: model.IncomeAccount},
+		{Name: expenseAccount, Type: model.ExpenseAccount},
+	} {
+		if err := acc.Validate(); err != nil {
+			return err
+		}
+		j.accounts[acc.Name] = acc
+	}
+	for _, acc := range p.saved.chart {
+		if old, ok := j.accounts[acc.Name]; ok && old.Type != acc.Type {
+			return fmt.Errorf("account %q is %s, stored as %s: %w", acc.Name, old.Type, acc.Type, model.ErrConflict)
+		}
+		j.accounts[acc.Name] = acc
+	}
+
+	var err error
+	for _, name := range p.accountNames() {
+		p.ledgers[name].transactions.each(func(tr *model.Transaction) {
+			if err == nil {
+				err = j.addTransaction(*tr)
+			}
+		})
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range p.saved.entries {
+		for _, posting := range entry.Postings {
+			if _, ok := j.accounts[posting.Account]; !ok {
+				return fmt.Errorf("entry %s, account %q: %w", entry.ID, posting.Account, model.ErrNotFound)
+			}
+		}
+		j.add(entry)
+	}
+	p.journal, p.saved = j, savedJournal{}
+	return nil
+}
+
+// ChartOfAccounts returns all accounts of the double-entry chart sorted by name
+func (p *Proc) ChartOfAccounts(ctx context.Context) ([]model.ChartAccount, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.journal == nil {
+		return nil, model.ErrDoubleEntryDisabled
+	}
+	return p.journal.chart(), nil
+}
+
+// SetChartAccount adds or updates account in the chart of accounts. Type of the account
+// can't be changed once it has postings.
+func (p *Proc) SetChartAccount(ctx context.Context, acc model.ChartAccount) (model.ChartAccount, error) {
+	select {
+	case <-ctx.Done():
+		return model.ChartAccount{}, ctx.Err()
+	default:
+	}
+
+	if err := acc.Validate(); err != nil {
+		return model.ChartAccount{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.journal == nil {
+		return model.ChartAccount{}, model.ErrDoubleEntryDisabled
+	}
+	if old, ok := p.journal.accounts[acc.Name]; ok && old.Type != acc.Type && p.journal.hasPostings(acc.Name) {
+		return model.ChartAccount{}, fmt.Errorf("can't change type of account %q with postings: %w", acc.Name, model.ErrConflict)
+	}
+	if acc.Ledger != "" {
+		for _, other := range p.journal.accounts {
+			if other.Ledger == acc.Ledger && other.Name != acc.Name {
+				return model.ChartAccount{}, fmt.Errorf("ledger %q is already mapped to %q: %w", acc.Ledger, other.Name, model.ErrConflict)
+			}
+		}
+	}
+	p.journal.accounts[acc.Name] = acc
+	return acc, nil
+}
+
+// PostEntry adds balanced journal entry, i.e. a transfer, refund or loan payment
+func (p *Proc) PostEntry(ctx context.Context, entry model.JournalEntry) (model.JournalEntry, error) {
+	select {
+	case <-ctx.Done():
+		return model.JournalEntry{}, ctx.Err()
+	default:
+	}
+
+	if err := entry.Validate(); err != nil {
+		return model.JournalEntry{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.journal == nil {
+		return model.JournalEntry{}, model.ErrDoubleEntryDisabled
+	}
+	for _, posting := range entry.Postings {
+		if _, ok := p.journal.accounts[posting.Account]; !ok {
+			return model.JournalEntry{}, fmt.Errorf("account %q: %w", posting.Account, model.ErrNotFound)
+		}
+	}
+	entry.TransactionID, entry.Ledger, entry.Flags = "", "", nil
+	entry.Tags = model.NormalizeTags(entry.Tags)
+	return p.journal.add(entry), nil
+}
+
+// JournalEntries returns entries matching the filter ordered by date
+func (p *Proc) JournalEntries(ctx context.Context, filter model.Filter) ([]model.JournalEntry, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.journal == nil {
+		return nil, model.ErrDoubleEntryDisabled
+	}
+	res := []model.JournalEntry{}
+	p.journal.between(filter, func(e *model.JournalEntry) {
+		res = append(res, *e)
+	})
+	return res, nil
+}
+
+// TrialBalance returns balances of all chart accounts at the end of asOf day, zero asOf for all entries
+func (p *Proc) TrialBalance(ctx context.Context, asOf time.Time) ([]model.ChartBalance, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.journal == nil {
+		return nil, model.ErrDoubleEntryDisabled
+	}
+	balances := map[string]float64{}
+	p.journal.between(model.Filter{To: asOf}, func(e *model.JournalEntry) {
+		for _, posting := range e.Postings {
+			balances[posting.Account] += posting.Amount
+		}
+	})
+	res := make([]model.ChartBalance, 0, len(p.journal.accounts))
+	for name, acc := range p.journal.accounts {
+		res = append(res, model.ChartBalance{Account: name, Type: acc.Type, Balance: balances[name]})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Account < res[j].Account })
+	return res, nil
+}
+
+// aggregate adds up postings to income and expense accounts of the entries matching the filter.
+// Category is the account name, or the entry's category for the default income and expense accounts.
+func (j *journal) aggregate(filter model.Filter, res *totals) {
+	j.between(filter, func(e *model.JournalEntry) {
+		for _, posting := range e.Postings {
+			category := posting.Account
+			if category == incomeAccount || category == expenseAccount {
+				category = e.Transaction().CategoryName()
+			}
+			switch j.accounts[posting.Account].Type {
+			case model.IncomeAccount:
+				res.add(category, model.Income, -posting.Amount) // income is credited
+			case model.ExpenseAccount:
+				res.add(category, model.Expense, posting.Amount)
+			}
+		}
+	})
+}
+
+// between calls fn for entries matching the filter in date order, using the date index for the filter's
+// dates. Entries of transactions are matched by their ledger, manual entries by ledgers of the chart
+// accounts they post to.
+func (j *journal) between(filter model.Filter, fn func(e *model.JournalEntry)) {
+	start := 0
+	if !filter.From.IsZero() {
+		start = sort.Search(len(j.byDate), func(i int) bool { return !j.entries[j.byDate[i]].Date.Before(filter.From) })
+	}
+	ledgers := filter.Accounts
+	filter.Accounts = nil
+	for _, i := range j.byDate[start:] {
+		e := &j.entries[i]
+		if !filter.To.IsZero() && e.Date.After(filter.To) {
+			return
+		}
+		if len(ledgers) > 0 && !j.postsTo(*e, ledgers) {
+			continue
+		}
+		if filter.Match(e.Transaction()) {
+			fn(e)
+		}
+	}
+}
+
+// postsTo tells if the entry posts to any of the ledgers. Entry of a transaction posts to its ledger,
+// manual entry to ledgers of the chart accounts of its postings; the default asset account stands for
+// the ledgers not mapped to other accounts.
+func (j *journal) postsTo(e model.JournalEntry, ledgers []string) bool {
+	if e.TransactionID != "" {
+		return model.ContainsString(ledgers, e.Ledger)
+	}
+	for _, posting := range e.Postings {
+		if acc := j.accounts[posting.Account]; acc.Ledger != "" && model.ContainsString(ledgers, acc.Ledger) {
+			return true
+		}
+		if posting.Account != j.defaultAsset {
+			continue
+		}
+		for _, ledger := range ledgers {
+			if !j.mapped(ledger) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mapped tells if the ledger is mapped to a chart account
+func (j *journal) mapped(ledger string) bool {
+	for _, acc := range j.accounts {
+		if acc.Ledger == ledger {
+			return true
+		}
+	}
+	return false
+}
+
+// entryFor makes journal entry for uploaded transaction
+func (j *journal) entryFor(tr model.Transaction) (model.JournalEntry, error) {
+	asset := j.defaultAsset
+	for _, acc := range j.accounts {
+		if acc.Ledger != "" && acc.Ledger == tr.Account {
+			asset = acc.Name
+			break
+		}
+	}
+	entry := model.JournalEntry{Date: tr.Date, Memo: tr.Memo, TransactionID: tr.ID, Ledger: tr.Account, Tags: tr.Tags,
+		Category: tr.Category, Flags: tr.Flags}
+	switch tr.Type {
+	case model.Income:
+		entry.Postings = []model.Posting{{Account: asset, Amount: tr.Amount}, {Account: incomeAccount, Amount: -tr.Amount}}
+	case model.Expense:
+		entry.Postings = []model.Posting{{Account: expenseAccount, Amount: tr.Amount}, {Account: asset, Amount: -tr.Amount}}
+	default:
+		return model.JournalEntry{}, fmt.Errorf("unsupported transaction type %q", tr.Type)
+	}
+	return entry, nil
+}
+
+// addTransaction posts journal entry for uploaded transaction
+func (j *journal) addTransaction(tr model.Transaction) error {
+	entry, err := j.entryFor(tr)
+	if err != nil {
+		return err
+	}
+	j.byTxID[tr.ID] = len(j.entries)
+	j.add(entry)
+	return nil
+}
+
+// add appends entry assigning a new id to it and adds it to the date index after entries of the same date
+func (j *journal) add(entry model.JournalEntry) model.JournalEntry {
+	j.lastID++
+	entry.ID = "je-" + strconv.FormatInt(j.lastID, 10)
+	j.entries = append(j.entries, entry)
+	pos := sort.Search(len(j.byDate), func(i int) bool { return j.entries[j.byDate[i]].Date.After(entry.Date) })
+	j.byDate = append(j.byDate, 0)
+	copy(j.byDate[pos+1:], j.byDate[pos:])
+	j.byDate[pos] = len(j.entries) - 1
+	return entry
+}
+
+// update syncs tags and category of the entry made for the transaction
+func (j *journal) update(tr model.Transaction) {
+	if i, ok := j.byTxID[tr.ID]; ok {
+		j.entries[i].Tags, j.entries[i].Category = tr.Tags, tr.Category
+	}
+}
+
+// chart returns accounts sorted by name
+func (j *journal) chart() []model.ChartAccount {
+	res := make([]model.ChartAccount, 0, len(j.accounts))
+	for _, acc := range j.accounts {
+		res = append(res, acc)
+	}
+	sort.Slice(res, func(i, k int) bool { return res[i].Name < res[k].Name })
+	return res
+}
+
+// manual returns entries posted manually, not made for transactions
+func (j *journal) manual() []model.JournalEntry {
+	var res []model.JournalEntry
+	for _, e := range j.entries {
+		if e.TransactionID == "" {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+func (j *journal) hasPostings(account string) bool {
+	for _, e := range j.entries {
+		for _, posting := range e.Postings {
+			if posting.Account == account {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package processor
+
+import (
+	"context"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestProc_DoubleEntry(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	proc := NewProc()
+	_, err := proc.ChartOfAccounts(ctx)
+	require.ErrorIs(t, err, model.ErrDoubleEntryDisabled)
+
+	_, err = proc.ProcessTransactions(ctx, []model.Transaction{
+		{Date: date(2020, 7, 1), Type: model.Expense, Amount: 18.77, Memo: "Fuel"},
+		{Date: date(2020, 7, 4), Type: model.Income, Amount: 40, Memo: "347 Woodrow", Tags: []string{"woodrow"}},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, proc.EnableDoubleEntry("Assets:Checking"))
+	_, err = proc.SetChartAccount(ctx, model.ChartAccount{Name: "Liabilities:Card", Type: model.LiabilityAccount, Ledger: "card"})
+	require.NoError(t, err)
+	_, err = proc.SetChartAccount(ctx, model.ChartAccount{Name: "Assets:Savings", Type: model.AssetAccount})
+	require.NoError(t, err)
+	_, err = proc.SetChartAccount(ctx, model.ChartAccount{Name: "Assets:Checking", Type: model.EquityAccount})
+	require.Error(t, err, "type of used account can't be changed")
+	_, err = proc.SetChartAccount(ctx, model.ChartAccount{Name: "Liabilities:Other", Type: model.LiabilityAccount, Ledger: "card"})
+	require.Error(t, err, "ledger is already mapped")
+
+	_, err = proc.ProcessTransactions(ctx, []model.Transaction{
+		{Account: "card", Date: date(2020, 7, 12), Type: model.Expense, Amount: 27.5, Memo: "Repairs"},
+	})
+	require.NoError(t, err)
+	_, err = proc.ProcessTransactions(ctx, []model.Transaction{{Date: date(2020, 7, 12), Type: "Transfer", Amount: 1}})
+	require.Error(t, err)
+
+	// refund of the repairs and a transfer to savings
+	_, err = proc.PostEntry(ctx, model.JournalEntry{Date: date(2020, 7, 14), Memo: "Repairs refund", Postings: []model.Posting{
+		{Account: "Liabilities:Card", Amount: 7.5}, {Account: "Expenses", Amount: -7.5}}})
+	require.NoError(t, err)
+	_, err = proc.PostEntry(ctx, model.JournalEntry{Date: date(2020, 7, 15), Memo: "Savings", Postings: []model.Posting{
+		{Account: "Assets:Savings", Amount: 20}, {Account: "Assets:Checking", Amount: -20}}})
+	require.NoError(t, err)
+	_, err = proc.PostEntry(ctx, model.JournalEntry{Date: date(2020, 7, 15), Postings: []model.Posting{
+		{Account: "Assets:Savings", Amount: 20}, {Account: "Assets:Checking", Amount: -10}}})
+	require.Error(t, err, "not balanced")
+	_, err = proc.PostEntry(ctx, model.JournalEntry{Date: date(2020, 7, 15), Postings: []model.Posting{
+		{Account: "Assets:Unknown", Amount: 20}, {Account: "Assets:Checking", Amount: -20}}})
+	require.ErrorIs(t, err, model.ErrNotFound)
+
+	report, err := proc.GenerateReport(ctx, model.Filter{})
+	require.NoError(t, err)
+	assert.InDelta(t, 40, report.GrossRevenue, 0.0001)
+	assert.InDelta(t, 38.77, report.Expenses, 0.0001)
+	assert.InDelta(t, 1.23, report.NetRevenue, 0.0001)
+
+	report, err = proc.GenerateReport(ctx, model.Filter{Accounts: []string{"card"}})
+	require.NoError(t, err)
+	assert.InDelta(t, 20, report.Expenses, 0.0001, "manual refund posted to the card account")
+	report, err = proc.GenerateReport(ctx, model.Filter{Accounts: []string{model.DefaultAccount}})
+	require.NoError(t, err)
+	assert.InDelta(t, 18.77, report.Expenses, 0.0001, "refund isn't posted to the default asset account")
+	entries, err := proc.JournalEntries(ctx, model.Filter{Accounts: []string{model.DefaultAccount}, From: date(2020, 7, 4)})
+	require.NoError(t, err)
+	require.Len(t, entries, 2, "transaction and transfer to savings")
+	assert.Equal(t, "Savings", entries[1].Memo)
+
+	_, err = proc.UpdateTransaction(ctx, "1", model.TransactionUpdate{Tags: &[]string{"truck"}})
+	require.NoError(t, err)
+	expr, err := model.ParseTagExpr("truck OR woodrow")
+	require.NoError(t, err)
+	report, err = proc.GenerateReport(ctx, model.Filter{Tags: expr})
+	require.NoError(t, err)
+	assert.Equal(t, model.Report{GrossRevenue: 40, Expenses: 18.77, NetRevenue: 21.23}, report)
+
+	tb, err := proc.TrialBalance(ctx, time.Time{})
+	require.NoError(t, err)
+	balances := map[string]float64{}
+	total := 0.0
+	for _, b := range tb {
+		balances[b.Account] = b.Balance
+		total += b.Balance
+	}
+	assert.InDelta(t, 0, total, 0.0001)
+	assert.InDelta(t, 1.23, balances["Assets:Checking"], 0.0001)
+	assert.InDelta(t, -20, balances["Liabilities:Card"], 0.0001)
+	assert.InDelta(t, 20, balances["Assets:Savings"], 0.0001)
+	assert.InDelta(t, -40, balances["Income"], 0.0001)
+
+	entries, err = proc.JournalEntries(ctx, model.Filter{From: date(2020, 7, 12)})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "3", entries[0].TransactionID)
+	assert.Equal(t, "Liabilities:Card", entries[0].Postings[1].Account)
+
+	chart, err := proc.ChartOfAccounts(ctx)
+	require.NoError(t, err)
+	assert.Len(t, chart, 5)
+
+	stored, err := proc.ProcessTransactions(ctx, []model.Transaction{
+		{Account: "card", Date: date(2020, 7, 12), Type: model.Expense, Amount: 27.5, Memo: "Repairs"},
+	})
+	require.NoError(t, err)
+	require.Contains(t, stored[0].Flags, model.FlagDuplicate)
+	flagged, err := proc.Transactions(ctx, model.Filter{Flagged: true})
+	require.NoError(t, err)
+	entries, err = proc.JournalEntries(ctx, model.Filter{Flagged: true})
+	require.NoError(t, err)
+	require.Len(t, entries, len(flagged), "manual entries have no flags")
+	assert.Equal(t, stored[0].ID, entries[len(entries)-1].TransactionID)
+	assert.Equal(t, stored[0].Flags, entries[len(entries)-1].Flags)
+}
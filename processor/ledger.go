@@ -0,0 +1,137 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"github.com/mrnbort/summer_break/model"
+	"time"
+)
+
+// ledger keeps transactions of a single account
+type ledger struct {
+	transactions   index // ordered by date
+	openingBalance float64
+	openingDate    time.Time // transactions before it are already counted in the opening balance
+	rollup         *rollup   // running totals of transactions
+	stats          *anomalyStats
+}
+
+// add appends the transaction to the ledger and counts it in the running totals and anomaly statistics
+func (l *ledger) add(tr model.Transaction) {
+	if l.rollup == nil {
+		l.rollup, l.stats = newRollup(), newAnomalyStats()
+	}
+	l.transactions.insert(tr)
+	l.rollup.add(tr)
+	l.stats.add(tr)
+}
+
+// account returns account summary of the ledger
+func (l *ledger) account(name string) model.Account {
+	return model.Account{
+		Name:           name,
+		Transactions:   l.transactions.len(),
+		OpeningBalance: l.openingBalance,
+		OpeningDate:    l.openingDate,
+	}
+}
+
+// balance calculates the account balance at the end of asOf day, zero asOf for all transactions.
+// The balance before the opening date is not known, it's zero.
+func (l *ledger) balance(asOf time.Time) float64 {
+	if !asOf.IsZero() && asOf.Before(l.openingDate) {
+		return 0
+	}
+	res := l.openingBalance
+	l.transactions.between(l.openingDate, asOf, func(tr *model.Transaction) {
+		res += tr.Signed()
+	})
+	return res
+}
+
+// SetOpeningBalance sets the opening balance and date of the account, creates the account if needed
+func (p *Proc) SetOpeningBalance(ctx context.Context, account string, amount float64, date time.Time) (model.Account, error) {
+	select {
+	case <-ctx.Done():
+		return model.Account{}, ctx.Err()
+	default:
+	}
+
+	if err := model.ValidateAccount(account); err != nil {
+		return model.Account{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ledgers == nil {
+		p.ledgers, p.byID = map[string]*ledger{}, map[string]string{}
+	}
+	l, ok := p.ledgers[account]
+	if !ok {
+		l = &ledger{}
+		p.ledgers[account] = l
+	}
+	l.openingBalance, l.openingDate = amount, date
+	return l.account(account), nil
+}
+
+// Balance returns balances of the given accounts (all if empty) at the end of asOf day, an account named
+// more than once is counted once. Zero asOf includes all transactions.
+func (p *Proc) Balance(ctx context.Context, accounts []string, asOf time.Time) (model.Balance, error) {
+	select {
+	case <-ctx.Done():
+		return model.Balance{}, ctx.Err()
+	default:
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	accounts = unique(accounts)
+	if len(accounts) == 0 {
+		accounts = p.accountNames()
+	}
+	res := model.Balance{AsOf: asOf, Accounts: map[string]float64{}}
+	for _, name := range accounts {
+		l, ok := p.ledgers[name]
+		if !ok {
+			return model.Balance{}, fmt.Errorf("account %q: %w", name, model.ErrNotFound)
+		}
+		res.Accounts[name] = l.balance(asOf)
+		res.Balance += res.Accounts[name]
+	}
+	return res, nil
+}
+
+// Transactions returns transactions matching the filter ordered by account and date, with the
+// running balance of the account. Filtered out transactions still count in the running balance.
+func (p *Proc) Transactions(ctx context.Context, filter model.Filter) ([]model.LedgerLine, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	accounts := unique(filter.Accounts)
+	if len(accounts) == 0 {
+		accounts = p.accountNames()
+	}
+	res := []model.LedgerLine{}
+	for _, name := range accounts {
+		l, ok := p.ledgers[name]
+		if !ok {
+			return nil, fmt.Errorf("account %q: %w", name, model.ErrNotFound)
+		}
+		balance := l.openingBalance
+		l.transactions.between(time.Time{}, filter.To, func(tr *model.Transaction) {
+			if !tr.Date.Before(l.openingDate) {
+				balance += tr.Signed()
+			}
+			if filter.Match(*tr) {
+				res = append(res, model.LedgerLine{Transaction: *tr, RunningBalance: balance})
+			}
+		})
+	}
+	return res, nil
+}
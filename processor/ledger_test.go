@@ -0,0 +1,106 @@
+package processor
+
+import (
+	"context"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestProc_Balance(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	proc := NewProc()
+	_, err := proc.ProcessTransactions(ctx, []model.Transaction{
+		{Date: date(2020, 6, 20), Type: model.Income, Amount: 500, Memo: "before opening"},
+		{Date: date(2020, 7, 4), Type: model.Income, Amount: 40, Memo: "347 Woodrow"},
+		{Date: date(2020, 7, 1), Type: model.Expense, Amount: 18.77, Memo: "Fuel"},
+		{Date: date(2020, 7, 6), Type: model.Income, Amount: 35, Memo: "219 Pleasant"},
+		{Account: "card", Date: date(2020, 7, 2), Type: model.Expense, Amount: 10, Memo: "Fuel"},
+	})
+	require.NoError(t, err)
+
+	acc, err := proc.SetOpeningBalance(ctx, model.DefaultAccount, 100, date(2020, 7, 1))
+	require.NoError(t, err)
+	assert.Equal(t, model.Account{Name: model.DefaultAccount, Transactions: 4, OpeningBalance: 100,
+		OpeningDate: date(2020, 7, 1)}, acc)
+	_, err = proc.SetOpeningBalance(ctx, "Bad Name", 100, date(2020, 7, 1))
+	require.Error(t, err)
+
+	balance, err := proc.Balance(ctx, []string{model.DefaultAccount}, date(2020, 7, 4))
+	require.NoError(t, err)
+	assert.InDelta(t, 121.23, balance.Balance, 0.0001)
+
+	balance, err = proc.Balance(ctx, nil, time.Time{})
+	require.NoError(t, err)
+	assert.InDelta(t, 146.23, balance.Balance, 0.0001)
+	assert.InDelta(t, -10, balance.Accounts["card"], 0.0001)
+
+	balance, err = proc.Balance(ctx, []string{model.DefaultAccount, model.DefaultAccount}, date(2020, 7, 4))
+	require.NoError(t, err)
+	assert.InDelta(t, 121.23, balance.Balance, 0.0001, "counted once")
+	balance, err = proc.Balance(ctx, []string{model.DefaultAccount}, date(2020, 6, 30))
+	require.NoError(t, err)
+	assert.Zero(t, balance.Balance, "before the opening date")
+	balance, err = proc.Balance(ctx, []string{model.DefaultAccount}, date(2020, 7, 1))
+	require.NoError(t, err)
+	assert.InDelta(t, 81.23, balance.Balance, 0.0001, "at the end of the opening day")
+
+	_, err = proc.Balance(ctx, []string{"unknown"}, time.Time{})
+	assert.ErrorIs(t, err, model.ErrNotFound)
+
+	_, err = proc.SetOpeningBalance(ctx, "cash", 50, time.Time{})
+	require.NoError(t, err)
+	balance, err = proc.Balance(ctx, []string{"cash"}, time.Time{})
+	require.NoError(t, err)
+	assert.InDelta(t, 50, balance.Balance, 0.0001)
+}
+
+func TestProc_Transactions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	proc := NewProc()
+	_, err := proc.ProcessTransactions(ctx, []model.Transaction{
+		{Date: date(2020, 7, 4), Type: model.Income, Amount: 40, Memo: "347 Woodrow", Tags: []string{"woodrow"}},
+		{Date: date(2020, 7, 1), Type: model.Expense, Amount: 18.77, Memo: "Fuel"},
+		{Date: date(2020, 7, 6), Type: model.Income, Amount: 35, Memo: "219 Pleasant"},
+		{Account: "card", Date: date(2020, 7, 2), Type: model.Expense, Amount: 10, Memo: "Fuel"},
+	})
+	require.NoError(t, err)
+	_, err = proc.SetOpeningBalance(ctx, model.DefaultAccount, 100, time.Time{})
+	require.NoError(t, err)
+
+	lines, err := proc.Transactions(ctx, model.Filter{})
+	require.NoError(t, err)
+	require.Len(t, lines, 4)
+	assert.Equal(t, "card", lines[0].Account)
+	assert.InDelta(t, -10, lines[0].RunningBalance, 0.0001)
+	assert.Equal(t, []string{"Fuel", "347 Woodrow", "219 Pleasant"},
+		[]string{lines[1].Memo, lines[2].Memo, lines[3].Memo})
+	assert.InDelta(t, 81.23, lines[1].RunningBalance, 0.0001)
+	assert.InDelta(t, 121.23, lines[2].RunningBalance, 0.0001)
+	assert.InDelta(t, 156.23, lines[3].RunningBalance, 0.0001)
+
+	expr, err := model.ParseTagExpr("woodrow")
+	require.NoError(t, err)
+	lines, err = proc.Transactions(ctx, model.Filter{Accounts: []string{model.DefaultAccount}, Tags: expr})
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+	assert.InDelta(t, 121.23, lines[0].RunningBalance, 0.0001)
+
+	lines, err = proc.Transactions(ctx, model.Filter{From: date(2020, 7, 5)})
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "219 Pleasant", lines[0].Memo)
+
+	_, err = proc.Transactions(ctx, model.Filter{Accounts: []string{"unknown"}})
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+func date(y, m, d int) time.Time {
+	return time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.Local)
+}
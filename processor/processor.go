@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/mrnbort/summer_break/model"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -12,34 +13,140 @@ import (
 
 // Proc allows to access transaction data
 type Proc struct {
-	mu           sync.RWMutex
-	transactions []model.Transaction
+	mu      sync.RWMutex
+	ledgers map[string]*ledger // per-account transactions, isolated from each other
+	byID    map[string]string  // transaction id to account name
+	lastID  int64
+	journal *journal // nil unless double-entry mode is enabled
+	saved   savedJournal
+	tax     model.TaxConfig
+
+	checkAggregates bool // compare running totals with a full rescan on every report
+
+	budgets      []model.Budget
+	lastBudgetID int64
+
+	schedules      []model.Schedule
+	lastScheduleID int64
+
+	batches     map[string]*batch // staged uploads by id
+	lastBatchID int64
+
+	apiKeys      []model.APIKey
+	lastAPIKeyID int64
+
+	users          []model.User
+	lastGeneration int64 // of users' session tokens, never reused so tokens of deleted users don't come back
 }
 
-// NewProc initiates and returns a slice for transaction data
+// NewProc initiates and returns an empty transaction storage
 func NewProc() *Proc {
-	var transact []model.Transaction
-	return &Proc{transactions: transact}
+	return &Proc{ledgers: map[string]*ledger{}, byID: map[string]string{}}
 }
 
-// ProcessTransactions adds new transactions to the transaction slice, thread-safe
-func (p *Proc) ProcessTransactions(ctx context.Context, transactions []model.Transaction) error {
+// ProcessTransactions adds new transactions to the ledgers of their accounts, thread-safe, and returns
+// them as stored, with ids and anomaly flags. Transactions without account go to model.DefaultAccount,
+// transactions without id get a new one. Returns model.ErrConflict and stores nothing if any of the given
+// ids is already stored.
+func (p *Proc) ProcessTransactions(ctx context.Context, transactions []model.Transaction) ([]model.Transaction, error) {
 	// check ctx will be needed in case of non-memory (slow) storage
 	select {
 	case <-ctx.Done():
-		return ctx.Err()
+		return nil, ctx.Err()
 	default:
 	}
 
+	if err := validateAccounts(transactions); err != nil {
+		return nil, err
+	}
+
 	p.mu.Lock()
-	p.transactions = append(p.transactions, transactions...)
-	p.mu.Unlock()
+	defer p.mu.Unlock()
+	ids := map[string]bool{}
+	if err := p.checkNew(transactions, ids); err != nil {
+		return nil, err
+	}
+	return p.store(transactions, ids), nil
+}
 
+// validateAccounts checks account names of transactions, empty ones are allowed
+func validateAccounts(transactions []model.Transaction) error {
+	for _, tr := range transactions {
+		if tr.Account == "" {
+			continue
+		}
+		if err := model.ValidateAccount(tr.Account); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// ParseTransaction parses input csv record
+// checkNew checks that transactions can be stored: their ids are neither stored nor in ids, and in
+// double-entry mode they can be posted. Adds their ids to ids, caller should hold the lock.
+func (p *Proc) checkNew(transactions []model.Transaction, ids map[string]bool) error {
+	for _, tr := range transactions {
+		if tr.ID == "" {
+			continue
+		}
+		if _, ok := p.byID[tr.ID]; ok || ids[tr.ID] {
+			return fmt.Errorf("transaction %q: %w", tr.ID, model.ErrConflict)
+		}
+		ids[tr.ID] = true
+	}
+	if p.journal != nil {
+		// check all transactions can be posted before storing any of them
+		for _, tr := range transactions {
+			if _, err := p.journal.entryFor(tr); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// store adds checked transactions to the ledgers and returns them as stored. New ids skip the given
+// explicit ids of transactions, caller should hold the lock.
+func (p *Proc) store(transactions []model.Transaction, ids map[string]bool) []model.Transaction {
+	if p.ledgers == nil {
+		p.ledgers, p.byID = map[string]*ledger{}, map[string]string{}
+	}
+	detector := p.newAnomalyDetector(time.Now())
+	res := make([]model.Transaction, 0, len(transactions))
+	for _, tr := range transactions {
+		for tr.ID == "" { // skip ids taken by transactions stored with explicit ids
+			p.lastID++
+			if id := strconv.FormatInt(p.lastID, 10); !ids[id] && p.byID[id] == "" {
+				tr.ID = id
+			}
+		}
+		if tr.Account == "" {
+			tr.Account = model.DefaultAccount
+		}
+		tr.Tags = model.NormalizeTags(tr.Tags)
+		tr.Category = strings.TrimSpace(tr.Category)
+		tr.Flags = detector.check(tr)
+		l, ok := p.ledgers[tr.Account]
+		if !ok {
+			l = &ledger{}
+			p.ledgers[tr.Account] = l
+		}
+		l.add(tr)
+		p.byID[tr.ID] = tr.Account
+		if p.journal != nil {
+			_ = p.journal.addTransaction(tr) // can't fail, checked before
+		}
+		res = append(res, tr)
+	}
+	return res
+}
+
+// ParseTransaction parses input csv record. The optional 5th field has
+// semicolon-separated tags, i.e. "347 woodrow;lawn", the optional 6th one has category.
 func (p *Proc) ParseTransaction(rec []string) (model.Transaction, error) {
+	if len(rec) < 4 {
+		return model.Transaction{}, fmt.Errorf("expected at least 4 fields, got %d", len(rec))
+	}
 	amount, err := strconv.ParseFloat(strings.TrimSpace(rec[2]), 64)
 	if err != nil {
 		return model.Transaction{}, fmt.Errorf("incorrect amount value %q: %w", rec[2], err)
@@ -50,34 +157,130 @@ func (p *Proc) ParseTransaction(rec []string) (model.Transaction, error) {
 		Amount: amount,
 		Memo:   strings.TrimSpace(rec[3]),
 	}
-	transaction.Date, err = time.ParseInLocation("2006-01-02", rec[0], time.Local) // use the current location
+	transaction.Date, err = time.ParseInLocation(model.DateLayout, rec[0], time.Local) // use the current location
 	if err != nil {
 		return model.Transaction{}, fmt.Errorf("invalid date %q: %w", rec[0], err)
 	}
+	if len(rec) > 4 {
+		transaction.Tags = model.NormalizeTags(strings.Split(rec[4], ";"))
+	}
+	if len(rec) > 5 {
+		transaction.Category = strings.TrimSpace(rec[5])
+	}
 	return transaction, nil
 }
 
-// GenerateReport calculates revenue and expenses from transaction data and returns them
-func (p *Proc) GenerateReport(ctx context.Context) (model.Report, error) {
+// UpdateTransaction changes tags and/or category of the transaction with given id
+// and returns the updated transaction
+func (p *Proc) UpdateTransaction(ctx context.Context, id string, upd model.TransactionUpdate) (model.Transaction, error) {
 	select {
 	case <-ctx.Done():
-		return model.Report{}, ctx.Err()
+		return model.Transaction{}, ctx.Err()
+	default:
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	tr := p.find(id)
+	if tr == nil {
+		return model.Transaction{}, fmt.Errorf("transaction %q: %w", id, model.ErrNotFound)
+	}
+	if upd.Tags != nil {
+		tr.Tags = model.NormalizeTags(*upd.Tags)
+	}
+	if upd.Category != nil {
+		l := p.ledgers[tr.Account]
+		l.rollup.remove(*tr)
+		l.stats.remove(*tr)
+		tr.Category = strings.TrimSpace(*upd.Category)
+		l.rollup.add(*tr) // count it again with the new category
+		l.stats.add(*tr)
+	}
+	if p.journal != nil {
+		p.journal.update(*tr)
+	}
+	return *tr, nil
+}
+
+// Transaction returns stored transaction with given id
+func (p *Proc) Transaction(ctx context.Context, id string) (model.Transaction, error) {
+	select {
+	case <-ctx.Done():
+		return model.Transaction{}, ctx.Err()
+	default:
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	tr := p.find(id)
+	if tr == nil {
+		return model.Transaction{}, fmt.Errorf("transaction %q: %w", id, model.ErrNotFound)
+	}
+	return *tr, nil
+}
+
+// Accounts returns all accounts with their transaction counts, sorted by name
+func (p *Proc) Accounts(ctx context.Context) ([]model.Account, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	default:
 	}
 
-	res := model.Report{}
 	p.mu.RLock()
 	defer p.mu.RUnlock()
-	for _, transaction := range p.transactions {
-		switch transaction.Type {
-		case model.Expense:
-			res.Expenses += transaction.Amount
-		case model.Income:
-			res.GrossRevenue += transaction.Amount
-		default:
-			return model.Report{}, fmt.Errorf("unsupported transaction type %q", transaction.Type)
+	res := make([]model.Account, 0, len(p.ledgers))
+	for _, name := range p.accountNames() {
+		res = append(res, p.ledgers[name].account(name))
+	}
+	return res, nil
+}
+
+// find returns pointer to the stored transaction with given id or nil, caller should hold the lock
+func (p *Proc) find(id string) *model.Transaction {
+	l, ok := p.ledgers[p.byID[id]]
+	if !ok {
+		return nil
+	}
+	return l.transactions.get(id)
+}
+
+// accountNames returns sorted names of all accounts, caller should hold the lock
+func (p *Proc) accountNames() []string {
+	res := make([]string, 0, len(p.ledgers))
+	for name := range p.ledgers {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// selectLedgers returns ledgers of given accounts or all ledgers if no accounts given, an account named
+// more than once is selected once. Caller should hold the lock.
+func (p *Proc) selectLedgers(accounts []string) ([]*ledger, error) {
+	if len(accounts) == 0 {
+		accounts = p.accountNames()
+	}
+	accounts = unique(accounts)
+	res := make([]*ledger, 0, len(accounts))
+	for _, name := range accounts {
+		l, ok := p.ledgers[name]
+		if !ok {
+			return nil, fmt.Errorf("account %q: %w", name, model.ErrNotFound)
 		}
+		res = append(res, l)
 	}
-	res.NetRevenue = res.GrossRevenue - res.Expenses
 	return res, nil
 }
+
+// unique returns names without repeated ones, in the order of their first occurrence
+func unique(names []string) []string {
+	res, seen := make([]string, 0, len(names)), make(map[string]bool, len(names))
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			res = append(res, name)
+		}
+	}
+	return res
+}
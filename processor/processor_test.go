@@ -5,7 +5,7 @@ import (
 	"github.com/mrnbort/summer_break/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"sync"
+	"math/rand"
 	"testing"
 	"time"
 )
@@ -35,6 +35,20 @@ func TestProc_ParseTransactions(t *testing.T) {
 			Type:   model.Income,
 			Amount: 35.00,
 		}, false},
+		{"with tags", []string{"2020-07-04", "Income", "40.00", "347 Woodrow", "Woodrow; lawn;"}, model.Transaction{
+			Date:   time.Date(2020, 7, 4, 0, 0, 0, 0, time.Local),
+			Memo:   "347 Woodrow",
+			Type:   model.Income,
+			Amount: 40.00,
+			Tags:   []string{"woodrow", "lawn"},
+		}, false},
+		{"with category", []string{"2020-07-01", "Expense", "18.77", "Shell", "", " Fuel "}, model.Transaction{
+			Date:     time.Date(2020, 7, 1, 0, 0, 0, 0, time.Local),
+			Memo:     "Shell",
+			Type:     model.Expense,
+			Amount:   18.77,
+			Category: "Fuel",
+		}, false},
 		{"wrong day", []string{"2020-07-BAD", "Income", "35.00", "219 Pleasant"}, model.Transaction{}, true},
 		{"wrong amount", []string{"2020-07-06", "Income", "xyz35.00", "219 Pleasant"}, model.Transaction{}, true},
 		{"too little fields", []string{"2020-07-06", "Income", "xyz35.00"}, model.Transaction{}, true},
@@ -52,6 +66,8 @@ func TestProc_ParseTransactions(t *testing.T) {
 			assert.Equal(t, out.Type, tt.out.Type)
 			assert.Equal(t, out.Memo, tt.out.Memo)
 			assert.Equal(t, out.Date, tt.out.Date)
+			assert.Equal(t, out.Tags, tt.out.Tags)
+			assert.Equal(t, out.Category, tt.out.Category)
 		})
 	}
 }
@@ -62,7 +78,7 @@ func TestProc_ProcessTransactions(t *testing.T) {
 
 	proc := &Proc{}
 
-	err := proc.ProcessTransactions(ctx, []model.Transaction{
+	_, err := proc.ProcessTransactions(ctx, []model.Transaction{
 		{
 			Date:   time.Date(2020, 7, 1, 0, 0, 0, 0, time.Local),
 			Memo:   "Fuel",
@@ -74,10 +90,46 @@ func TestProc_ProcessTransactions(t *testing.T) {
 			Memo:   "347 Woodrow",
 			Type:   model.Income,
 			Amount: 40.00,
+			Tags:   []string{"Woodrow"},
 		},
 	})
 	require.NoError(t, err)
-	assert.Len(t, proc.transactions, 2)
+	stored := proc.ledgers[model.DefaultAccount].transactions.list()
+	assert.Len(t, stored, 2)
+	assert.Equal(t, "1", stored[0].ID)
+	assert.Equal(t, "2", stored[1].ID)
+	assert.Equal(t, model.DefaultAccount, stored[1].Account)
+	assert.Equal(t, []string{"woodrow"}, stored[1].Tags)
+
+	_, err = proc.ProcessTransactions(ctx, []model.Transaction{
+		{Account: "card", Type: model.Expense, Amount: 10, Memo: "Fuel"},
+		{Account: "cash", Type: model.Income, Amount: 50, Memo: "19 Maple Dr."},
+	})
+	require.NoError(t, err)
+	assert.Len(t, proc.ledgers, 3)
+	assert.Equal(t, 2, proc.ledgers[model.DefaultAccount].transactions.len())
+	assert.Equal(t, "3", proc.ledgers["card"].transactions.list()[0].ID)
+
+	_, err = proc.ProcessTransactions(ctx, []model.Transaction{{Account: "Bad Name", Type: model.Income, Amount: 1}})
+	require.Error(t, err)
+
+	// explicit ids are kept, new ids skip them
+	_, err = proc.ProcessTransactions(ctx, []model.Transaction{{ID: "5", Account: "card", Type: model.Expense, Amount: 1}})
+	require.NoError(t, err)
+	_, err = proc.ProcessTransactions(ctx, []model.Transaction{{Account: "card", Type: model.Expense, Amount: 2},
+		{Account: "card", Type: model.Expense, Amount: 3}})
+	require.NoError(t, err)
+	assert.Equal(t, "6", proc.ledgers["card"].transactions.list()[2].ID)
+	assert.Equal(t, "7", proc.ledgers["card"].transactions.list()[3].ID)
+	_, err = proc.ProcessTransactions(ctx, []model.Transaction{{Account: "cash", Type: model.Expense, Amount: 4},
+		{ID: "5", Account: "cash", Type: model.Expense, Amount: 5}})
+	require.ErrorIs(t, err, model.ErrConflict)
+	assert.Equal(t, 1, proc.ledgers["cash"].transactions.len(), "nothing stored on conflict")
+
+	accounts, err := proc.Accounts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []model.Account{{Name: "card", Transactions: 4}, {Name: "cash", Transactions: 1},
+		{Name: model.DefaultAccount, Transactions: 2}}, accounts)
 }
 
 func TestProc_GenerateReport(t *testing.T) {
@@ -96,24 +148,148 @@ func TestProc_GenerateReport(t *testing.T) {
 			Memo:   "347 Woodrow",
 			Type:   model.Income,
 			Amount: 40.00,
+			Tags:   []string{"woodrow", "lawn"},
 		},
 		{
 			Date:   time.Date(2020, 7, 6, 0, 0, 0, 0, time.Local),
 			Memo:   "219 Pleasant",
 			Type:   model.Income,
 			Amount: 35.00,
+			Tags:   []string{"pleasant"},
 		},
 	}
 
-	proc := &Proc{
-		transactions: transactions,
-		mu:           sync.RWMutex{},
-	}
+	proc := NewProc()
+	_, err := proc.ProcessTransactions(ctx, transactions)
+	require.NoError(t, err)
 
-	report, err := proc.GenerateReport(ctx)
+	report, err := proc.GenerateReport(ctx, model.Filter{})
 	require.NoError(t, err)
 
 	assert.InDelta(t, 75.00, report.GrossRevenue, 0.0001)
 	assert.InDelta(t, 18.77, report.Expenses, 0.0001)
 	assert.InDelta(t, 56.23, report.NetRevenue, 0.0001)
+
+	expr, err := model.ParseTagExpr("woodrow OR pleasant")
+	require.NoError(t, err)
+	report, err = proc.GenerateReport(ctx, model.Filter{Tags: expr})
+	require.NoError(t, err)
+	assert.InDelta(t, 75.00, report.GrossRevenue, 0.0001)
+	assert.InDelta(t, 0, report.Expenses, 0.0001)
+
+	expr, err = model.ParseTagExpr("woodrow AND pleasant")
+	require.NoError(t, err)
+	report, err = proc.GenerateReport(ctx, model.Filter{Tags: expr})
+	require.NoError(t, err)
+	assert.Equal(t, model.Report{}, report)
+
+	_, err = proc.ProcessTransactions(ctx, []model.Transaction{
+		{Account: "card", Type: model.Expense, Amount: 10, Memo: "Fuel"},
+		{Account: "cash", Type: model.Income, Amount: 50, Memo: "19 Maple Dr."},
+	})
+	require.NoError(t, err)
+
+	report, err = proc.GenerateReport(ctx, model.Filter{Accounts: []string{"card"}})
+	require.NoError(t, err)
+	assert.Equal(t, model.Report{Expenses: 10, NetRevenue: -10}, report)
+
+	report, err = proc.GenerateReport(ctx, model.Filter{Accounts: []string{"card", "cash"}})
+	require.NoError(t, err)
+	assert.Equal(t, model.Report{GrossRevenue: 50, Expenses: 10, NetRevenue: 40}, report)
+
+	report, err = proc.GenerateReport(ctx, model.Filter{})
+	require.NoError(t, err)
+	assert.InDelta(t, 125.00, report.GrossRevenue, 0.0001)
+
+	_, err = proc.GenerateReport(ctx, model.Filter{Accounts: []string{"unknown"}})
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+func TestProc_UpdateTransaction(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	proc := NewProc()
+	_, err := proc.ProcessTransactions(ctx, []model.Transaction{{Type: model.Income, Amount: 40, Memo: "347 Woodrow"}})
+	require.NoError(t, err)
+
+	tr, err := proc.UpdateTransaction(ctx, "1", model.TransactionUpdate{Tags: &[]string{"Woodrow", "lawn"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"woodrow", "lawn"}, tr.Tags)
+	assert.Equal(t, "", tr.Category)
+
+	category := " Rental income"
+	tr, err = proc.UpdateTransaction(ctx, "1", model.TransactionUpdate{Category: &category})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"woodrow", "lawn"}, tr.Tags)
+	assert.Equal(t, "Rental income", tr.Category)
+	assert.Equal(t, []string{"woodrow", "lawn"}, proc.ledgers[model.DefaultAccount].transactions.list()[0].Tags)
+	stored, err := proc.Transaction(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, tr, stored)
+
+	_, err = proc.UpdateTransaction(ctx, "2", model.TransactionUpdate{Tags: &[]string{"woodrow"}})
+	assert.ErrorIs(t, err, model.ErrNotFound)
+	_, err = proc.Transaction(ctx, "2")
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+const benchTransactions = 1_000_000
+
+// benchData makes n transactions of random dates over three years, in random order
+func benchData(n int) []model.Transaction {
+	rnd := rand.New(rand.NewSource(1))
+	res := make([]model.Transaction, n)
+	for i := range res {
+		res[i] = model.Transaction{Date: date(2018, 1, 1).AddDate(0, 0, rnd.Intn(3*365)), Type: model.Expense,
+			Amount: float64(rnd.Intn(10000)) / 100, Memo: "Fuel", Tags: []string{"car"}}
+		if i%3 == 0 {
+			res[i].Type, res[i].Memo, res[i].Tags = model.Income, "347 Woodrow", []string{"woodrow"}
+		}
+	}
+	return res
+}
+
+// BenchmarkProc_rangeScan compares a month report with a tag filter, which has to look at transactions,
+// made from the date index and from a plain unsorted slice of the same transactions
+func BenchmarkProc_rangeScan(b *testing.B) {
+	ctx := context.Background()
+	transactions := benchData(benchTransactions)
+	proc := NewProc()
+	_, err := proc.ProcessTransactions(ctx, transactions)
+	require.NoError(b, err)
+	expr, err := model.ParseTagExpr("woodrow")
+	require.NoError(b, err)
+	filter := model.Filter{From: date(2019, 6, 1), To: date(2019, 6, 30), Tags: expr}
+
+	b.Run("index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, err := proc.GenerateReport(ctx, filter)
+			require.NoError(b, err)
+		}
+	})
+
+	b.Run("slice", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			res := newTotals()
+			for _, tr := range transactions {
+				if filter.Match(tr) {
+					require.NoError(b, res.add(tr.CategoryName(), tr.Type, tr.Amount))
+				}
+			}
+		}
+	})
+}
+
+// BenchmarkIndex_insert adds out of order transactions to the index of 1M transactions
+func BenchmarkIndex_insert(b *testing.B) {
+	x := index{}
+	for _, tr := range benchData(benchTransactions) {
+		x.insert(tr)
+	}
+	batch := benchData(b.N)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x.insert(batch[i])
+	}
 }
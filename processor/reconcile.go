@@ -0,0 +1,91 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"github.com/mrnbort/summer_break/model"
+	"math"
+	"time"
+)
+
+// Reconcile matches statement lines with uncleared transactions of the account, marks matched
+// transactions as cleared and reports unmatched items on both sides and the balance difference.
+// Each line is matched with the closest by date transaction of the same direction within the tolerance.
+func (p *Proc) Reconcile(ctx context.Context, account string, st model.Statement, tol model.Tolerance) (model.Reconciliation, error) {
+	select {
+	case <-ctx.Done():
+		return model.Reconciliation{}, ctx.Err()
+	default:
+	}
+
+	if !st.To.IsZero() && st.To.Before(st.From) {
+		return model.Reconciliation{}, fmt.Errorf("statement period ends before it starts")
+	}
+	if err := tol.Validate(); err != nil {
+		return model.Reconciliation{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	l, ok := p.ledgers[account]
+	if !ok {
+		return model.Reconciliation{}, fmt.Errorf("account %q: %w", account, model.ErrNotFound)
+	}
+
+	slack := time.Duration(tol.Days) * 24 * time.Hour
+	inPeriod := func(d time.Time) bool {
+		return (st.From.IsZero() || !d.Before(st.From)) && (st.To.IsZero() || !d.After(st.To))
+	}
+
+	// candidates are uncleared transactions which can match statement lines
+	var candidates []*model.Transaction
+	from, to := st.From, st.To
+	if !from.IsZero() {
+		from = from.Add(-slack)
+	}
+	if !to.IsZero() {
+		to = to.Add(slack)
+	}
+	l.transactions.between(from, to, func(tr *model.Transaction) {
+		if !tr.Cleared {
+			candidates = append(candidates, tr)
+		}
+	})
+
+	res := model.Reconciliation{Account: account, From: st.From, To: st.To, ClosingBalance: st.ClosingBalance,
+		Matched: []model.Match{}, UnmatchedLines: []model.StatementLine{}, UnmatchedTransactions: []model.Transaction{}}
+	used := map[*model.Transaction]bool{}
+	for _, line := range st.Lines {
+		var best *model.Transaction
+		bestDiff := time.Duration(math.MaxInt64)
+		for _, tr := range candidates {
+			if used[tr] || math.Abs(tr.Signed()-line.Amount) > tol.Amount {
+				continue
+			}
+			diff := tr.Date.Sub(line.Date)
+			if diff < 0 {
+				diff = -diff
+			}
+			if diff <= slack && diff < bestDiff {
+				best, bestDiff = tr, diff
+			}
+		}
+		if best == nil {
+			res.UnmatchedLines = append(res.UnmatchedLines, line)
+			continue
+		}
+		used[best] = true
+		best.Cleared = true
+		res.Matched = append(res.Matched, model.Match{Line: line, Transaction: *best})
+	}
+
+	for _, tr := range candidates {
+		if !used[tr] && inPeriod(tr.Date) {
+			res.UnmatchedTransactions = append(res.UnmatchedTransactions, *tr)
+		}
+	}
+
+	res.LedgerBalance = l.balance(st.To)
+	res.Difference = math.Round((res.ClosingBalance-res.LedgerBalance)*100) / 100
+	return res, nil
+}
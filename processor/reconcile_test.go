@@ -0,0 +1,71 @@
+package processor
+
+import (
+	"context"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestProc_Reconcile(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	proc := NewProc()
+	_, err := proc.ProcessTransactions(ctx, []model.Transaction{
+		{Date: date(2020, 7, 1), Type: model.Expense, Amount: 18.77, Memo: "Fuel"},
+		{Date: date(2020, 7, 4), Type: model.Income, Amount: 40, Memo: "347 Woodrow"},
+		{Date: date(2020, 7, 22), Type: model.Income, Amount: 40, Memo: "347 Woodrow"},
+		{Date: date(2020, 7, 25), Type: model.Expense, Amount: 14.21, Memo: "Fuel"},
+		{Date: date(2020, 8, 2), Type: model.Expense, Amount: 5, Memo: "next period"},
+	})
+	require.NoError(t, err)
+	_, err = proc.SetOpeningBalance(ctx, model.DefaultAccount, 100, date(2020, 7, 1))
+	require.NoError(t, err)
+
+	st := model.Statement{
+		From:           date(2020, 7, 1),
+		To:             date(2020, 7, 31),
+		ClosingBalance: 150,
+		Lines: []model.StatementLine{
+			{Date: date(2020, 7, 3), Amount: -18.77, Memo: "SHELL"},     // two days late
+			{Date: date(2020, 7, 23), Amount: 40, Memo: "DEPOSIT"},      // closest to the 22nd, not the 4th
+			{Date: date(2020, 7, 28), Amount: -3.50, Memo: "BANK FEE"},  // not in the ledger
+			{Date: date(2020, 7, 10), Amount: 40.01, Memo: "DEPOSIT 2"}, // too far from the 4th
+		},
+	}
+	res, err := proc.Reconcile(ctx, model.DefaultAccount, st, model.Tolerance{Amount: 0.01, Days: 3})
+	require.NoError(t, err)
+
+	require.Len(t, res.Matched, 2)
+	assert.Equal(t, "1", res.Matched[0].Transaction.ID)
+	assert.Equal(t, "3", res.Matched[1].Transaction.ID)
+	assert.True(t, res.Matched[1].Transaction.Cleared)
+	require.Len(t, res.UnmatchedLines, 2)
+	assert.Equal(t, "BANK FEE", res.UnmatchedLines[0].Memo)
+	require.Len(t, res.UnmatchedTransactions, 2)
+	assert.Equal(t, "2", res.UnmatchedTransactions[0].ID)
+	assert.Equal(t, "4", res.UnmatchedTransactions[1].ID)
+	assert.InDelta(t, 147.02, res.LedgerBalance, 0.0001)
+	assert.InDelta(t, 2.98, res.Difference, 0.0001)
+
+	lines, err := proc.Transactions(ctx, model.Filter{})
+	require.NoError(t, err)
+	assert.True(t, lines[0].Cleared)
+	assert.False(t, lines[1].Cleared)
+
+	// cleared transactions are not matched again
+	res, err = proc.Reconcile(ctx, model.DefaultAccount, model.Statement{Lines: st.Lines[:1]}, model.Tolerance{Days: 3})
+	require.NoError(t, err)
+	assert.Empty(t, res.Matched)
+	assert.Len(t, res.UnmatchedLines, 1)
+
+	_, err = proc.Reconcile(ctx, model.DefaultAccount, st, model.Tolerance{Days: 200000})
+	assert.Error(t, err, "date tolerance over a year")
+	_, err = proc.Reconcile(ctx, "unknown", st, model.Tolerance{})
+	assert.ErrorIs(t, err, model.ErrNotFound)
+	_, err = proc.Reconcile(ctx, model.DefaultAccount, model.Statement{From: date(2020, 7, 31), To: date(2020, 7, 1)}, model.Tolerance{})
+	assert.Error(t, err)
+}
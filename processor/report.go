@@ -0,0 +1,187 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"github.com/mrnbort/summer_break/model"
+	"sort"
+)
+
+// totals are revenue and expenses, overall and per category
+type totals struct {
+	report   model.Report
+	income   map[string]float64
+	expenses map[string]float64
+	lines    map[string]int // transactions by type and category, kept by running totals only
+}
+
+func newTotals() *totals {
+	return &totals{income: map[string]float64{}, expenses: map[string]float64{}}
+}
+
+// add counts amount of the given type in the category
+func (t *totals) add(category string, tp model.TrType, amount float64) error {
+	switch tp {
+	case model.Expense:
+		t.report.Expenses += amount
+		t.expenses[category] += amount
+	case model.Income:
+		t.report.GrossRevenue += amount
+		t.income[category] += amount
+	default:
+		return fmt.Errorf("unsupported transaction type %q", tp)
+	}
+	t.report.NetRevenue = t.report.GrossRevenue - t.report.Expenses
+	return nil
+}
+
+// aggregate sums up revenue and expenses of transactions matching the filter, caller should hold the lock.
+// Date-ranged and unfiltered sums come from running totals of the ledgers, tag and flag filters need a rescan.
+// In double-entry mode they are derived from postings to income and expense accounts.
+func (p *Proc) aggregate(filter model.Filter) (*totals, error) {
+	ledgers, err := p.selectLedgers(filter.Accounts)
+	if err != nil {
+		return nil, err
+	}
+	res := newTotals()
+	if p.journal != nil {
+		p.journal.aggregate(filter, res)
+		return res, nil
+	}
+	for _, l := range ledgers {
+		if err := l.sum(filter, res, p.checkAggregates); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// GenerateReport calculates revenue and expenses from transactions matching the filter and returns them.
+// In double-entry mode they are derived from postings to income and expense accounts.
+func (p *Proc) GenerateReport(ctx context.Context, filter model.Filter) (model.Report, error) {
+	select {
+	case <-ctx.Done():
+		return model.Report{}, ctx.Err()
+	default:
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	res, err := p.aggregate(filter)
+	if err != nil {
+		return model.Report{}, err
+	}
+	return res.report, nil
+}
+
+// ProfitAndLoss makes profit and loss statement for the filter's period with income and expense lines
+// by category. Optional compare mode adds a column for the previous period or the same period last year.
+func (p *Proc) ProfitAndLoss(ctx context.Context, filter model.Filter, compare string) (model.PnL, error) {
+	select {
+	case <-ctx.Done():
+		return model.PnL{}, ctx.Err()
+	default:
+	}
+
+	if filter.From.IsZero() || filter.To.IsZero() {
+		return model.PnL{}, fmt.Errorf("profit and loss statement needs both ends of the period")
+	}
+	periods := []model.Period{{From: filter.From, To: filter.To}}
+	if compare != "" {
+		prev, err := model.ComparisonPeriod(periods[0], compare)
+		if err != nil {
+			return model.PnL{}, err
+		}
+		periods = append(periods, prev)
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	var columns []*totals
+	for _, period := range periods {
+		f := filter
+		f.From, f.To = period.From, period.To
+		res, err := p.aggregate(f)
+		if err != nil {
+			return model.PnL{}, err
+		}
+		columns = append(columns, res)
+	}
+
+	res := model.PnL{Periods: periods}
+	res.Income = pnlLines(columns, func(t *totals) map[string]float64 { return t.income })
+	res.Expenses = pnlLines(columns, func(t *totals) map[string]float64 { return t.expenses })
+	for _, c := range columns {
+		res.Totals = append(res.Totals, c.report)
+	}
+	return res, nil
+}
+
+// pnlLines makes statement lines for all categories found in any of the columns, sorted by category
+func pnlLines(columns []*totals, amounts func(*totals) map[string]float64) []model.PnLLine {
+	categories := map[string]bool{}
+	for _, c := range columns {
+		for category := range amounts(c) {
+			categories[category] = true
+		}
+	}
+	res := make([]model.PnLLine, 0, len(categories))
+	for category := range categories {
+		line := model.PnLLine{Category: category}
+		for _, c := range columns {
+			line.Amounts = append(line.Amounts, amounts(c)[category])
+		}
+		res = append(res, line)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Category < res[j].Category })
+	return res
+}
+
+// Compare compares totals of transactions matching the filter in its period with the previous period.
+// With byCategory it also compares totals of every income and expense category.
+func (p *Proc) Compare(ctx context.Context, filter model.Filter, previous model.Period, byCategory bool) (model.Comparison, error) {
+	select {
+	case <-ctx.Done():
+		return model.Comparison{}, ctx.Err()
+	default:
+	}
+
+	if filter.From.IsZero() || filter.To.IsZero() || previous.From.IsZero() || previous.To.IsZero() {
+		return model.Comparison{}, fmt.Errorf("comparison needs both ends of both periods")
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	current, err := p.aggregate(filter)
+	if err != nil {
+		return model.Comparison{}, err
+	}
+	prevFilter := filter
+	prevFilter.From, prevFilter.To = previous.From, previous.To
+	prev, err := p.aggregate(prevFilter)
+	if err != nil {
+		return model.Comparison{}, err
+	}
+
+	res := model.NewComparison(
+		model.PeriodReport{Period: model.Period{From: filter.From, To: filter.To}, Report: current.report},
+		model.PeriodReport{Period: previous, Report: prev.report})
+	if !byCategory {
+		return res, nil
+	}
+	columns := []*totals{current, prev}
+	for _, c := range []struct {
+		tp    model.TrType
+		lines []model.PnLLine
+	}{
+		{model.Income, pnlLines(columns, func(t *totals) map[string]float64 { return t.income })},
+		{model.Expense, pnlLines(columns, func(t *totals) map[string]float64 { return t.expenses })},
+	} {
+		for _, l := range c.lines {
+			res.Categories = append(res.Categories, model.CategoryChange{Category: l.Category, Type: c.tp,
+				Current: l.Amounts[0], Previous: l.Amounts[1], Absolute: model.RoundCents(l.Amounts[0] - l.Amounts[1]),
+				Percent: model.PercentDelta(l.Amounts[0], l.Amounts[1])})
+		}
+	}
+	return res, nil
+}
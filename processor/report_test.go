@@ -0,0 +1,123 @@
+package processor
+
+import (
+	"context"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestProc_ProfitAndLoss(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	proc := NewProc()
+	_, err := proc.ProcessTransactions(ctx, []model.Transaction{
+		{Date: date(2020, 6, 10), Type: model.Expense, Amount: 10, Memo: "Fuel"},
+		{Date: date(2020, 6, 12), Type: model.Income, Amount: 40, Memo: "347 Woodrow", Category: "Lawns"},
+		{Date: date(2020, 7, 1), Type: model.Expense, Amount: 18.77, Memo: "Fuel"},
+		{Date: date(2020, 7, 4), Type: model.Income, Amount: 40, Memo: "347 Woodrow", Category: "Lawns"},
+		{Date: date(2020, 7, 6), Type: model.Income, Amount: 35, Memo: "219 Pleasant", Category: "Lawns"},
+		{Date: date(2020, 7, 12), Type: model.Expense, Amount: 27.5, Memo: "Repairs"},
+		{Date: date(2019, 7, 12), Type: model.Income, Amount: 20, Memo: "Snow", Category: "Snow removal"},
+	})
+	require.NoError(t, err)
+
+	july := model.Filter{From: date(2020, 7, 1), To: date(2020, 7, 31)}
+	pnl, err := proc.ProfitAndLoss(ctx, july, "")
+	require.NoError(t, err)
+	require.Len(t, pnl.Periods, 1)
+	assert.Equal(t, []model.PnLLine{{Category: "Lawns", Amounts: []float64{75}}}, pnl.Income)
+	assert.Equal(t, []model.PnLLine{{Category: "Fuel", Amounts: []float64{18.77}},
+		{Category: "Repairs", Amounts: []float64{27.5}}}, pnl.Expenses)
+	assert.InDelta(t, 28.73, pnl.Totals[0].NetRevenue, 0.0001)
+
+	pnl, err = proc.ProfitAndLoss(ctx, july, model.ComparePrevious)
+	require.NoError(t, err)
+	assert.Equal(t, model.Period{From: date(2020, 5, 31), To: date(2020, 6, 30)}, pnl.Periods[1])
+	assert.Equal(t, []model.PnLLine{{Category: "Lawns", Amounts: []float64{75, 40}}}, pnl.Income)
+	assert.Equal(t, []model.PnLLine{{Category: "Fuel", Amounts: []float64{18.77, 10}},
+		{Category: "Repairs", Amounts: []float64{27.5, 0}}}, pnl.Expenses)
+	assert.Equal(t, model.Report{GrossRevenue: 40, Expenses: 10, NetRevenue: 30}, pnl.Totals[1])
+
+	pnl, err = proc.ProfitAndLoss(ctx, july, model.CompareLastYear)
+	require.NoError(t, err)
+	assert.Equal(t, model.Period{From: date(2019, 7, 1), To: date(2019, 7, 31)}, pnl.Periods[1])
+	assert.Equal(t, []model.PnLLine{{Category: "Lawns", Amounts: []float64{75, 0}},
+		{Category: "Snow removal", Amounts: []float64{0, 20}}}, pnl.Income)
+
+	_, err = proc.ProfitAndLoss(ctx, model.Filter{From: date(2020, 7, 1)}, "")
+	require.Error(t, err)
+	_, err = proc.ProfitAndLoss(ctx, july, "next")
+	require.Error(t, err)
+
+	// double-entry categories come from income and expense accounts
+	require.NoError(t, proc.EnableDoubleEntry("Assets:Checking"))
+	_, err = proc.SetChartAccount(ctx, model.ChartAccount{Name: "Expenses:Insurance", Type: model.ExpenseAccount})
+	require.NoError(t, err)
+	_, err = proc.PostEntry(ctx, model.JournalEntry{Date: date(2020, 7, 20), Postings: []model.Posting{
+		{Account: "Expenses:Insurance", Amount: 100}, {Account: "Assets:Checking", Amount: -100}}})
+	require.NoError(t, err)
+	pnl, err = proc.ProfitAndLoss(ctx, july, "")
+	require.NoError(t, err)
+	assert.Equal(t, []model.PnLLine{{Category: "Expenses:Insurance", Amounts: []float64{100}},
+		{Category: "Fuel", Amounts: []float64{18.77}}, {Category: "Repairs", Amounts: []float64{27.5}}}, pnl.Expenses)
+}
+
+func TestProc_Compare(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	proc := NewProc()
+	_, err := proc.ProcessTransactions(ctx, []model.Transaction{
+		{Date: date(2019, 7, 3), Type: model.Income, Amount: 50, Memo: "347 Woodrow", Category: "Lawns"},
+		{Date: date(2019, 7, 12), Type: model.Income, Amount: 20, Memo: "Snow", Category: "Snow removal"},
+		{Date: date(2020, 7, 4), Type: model.Income, Amount: 75, Memo: "347 Woodrow", Category: "Lawns"},
+		{Date: date(2020, 7, 1), Type: model.Expense, Amount: 18.77, Memo: "Fuel"},
+	})
+	require.NoError(t, err)
+
+	july := model.Filter{From: date(2020, 7, 1), To: date(2020, 7, 31)}
+	lastJuly := model.Period{From: date(2019, 7, 1), To: date(2019, 7, 31)}
+	res, err := proc.Compare(ctx, july, lastJuly, false)
+	require.NoError(t, err)
+	assert.Equal(t, model.Period{From: july.From, To: july.To}, res.Current.Period)
+	assert.InDelta(t, 56.23, res.Current.NetRevenue, 0.0001)
+	assert.Equal(t, model.Report{GrossRevenue: 70, NetRevenue: 70}, res.Previous.Report)
+	assert.Equal(t, model.Report{GrossRevenue: 5, Expenses: 18.77, NetRevenue: -13.77}, res.Delta.Absolute)
+	assert.Equal(t, 7.14, *res.Delta.Percent.GrossRevenue)
+	assert.Nil(t, res.Delta.Percent.Expenses)
+	assert.Empty(t, res.Categories)
+
+	res, err = proc.Compare(ctx, july, lastJuly, true)
+	require.NoError(t, err)
+	require.Len(t, res.Categories, 3)
+	assert.Equal(t, model.CategoryChange{Category: "Lawns", Type: model.Income, Current: 75, Previous: 50, Absolute: 25,
+		Percent: res.Categories[0].Percent}, res.Categories[0])
+	assert.Equal(t, 50.0, *res.Categories[0].Percent)
+	assert.Equal(t, -100.0, *res.Categories[1].Percent)
+	assert.Equal(t, "Fuel", res.Categories[2].Category)
+	assert.Nil(t, res.Categories[2].Percent)
+
+	_, err = proc.Compare(ctx, july, model.Period{From: date(2019, 7, 1)}, false)
+	require.Error(t, err)
+}
+
+func TestProc_GenerateReportSameAccountTwice(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	proc := NewProc()
+	_, err := proc.ProcessTransactions(ctx, []model.Transaction{
+		{Account: "card", Date: date(2020, 7, 4), Type: model.Income, Amount: 40, Memo: "347 Woodrow"},
+	})
+	require.NoError(t, err)
+	report, err := proc.GenerateReport(ctx, model.Filter{Accounts: []string{"card", "card"}})
+	require.NoError(t, err)
+	assert.Equal(t, model.Report{GrossRevenue: 40, NetRevenue: 40}, report, "counted once")
+	lines, err := proc.Transactions(ctx, model.Filter{Accounts: []string{"card", "card"}})
+	require.NoError(t, err)
+	assert.Len(t, lines, 1)
+}
@@ -0,0 +1,210 @@
+package processor
+
+import (
+	"fmt"
+	"github.com/mrnbort/summer_break/model"
+	"log"
+	"math"
+	"time"
+)
+
+// rollupTolerance is the largest difference between running totals and a full rescan treated as a match
+const rollupTolerance = 0.005
+
+// rollup keeps running totals of a ledger, overall and by month and day, so reports don't rescan
+// transactions. Transactions dated exactly at the local midnight are counted by their day, others
+// only in the overall totals, and date-ranged reports fall back to rescanning if there are any.
+type rollup struct {
+	all       *totals
+	months    map[int]*monthTotals // by year*12 + month-1
+	irregular int                  // transactions not dated at the local midnight
+	badType   model.TrType         // first unsupported type seen, reports rescan to fail on it
+}
+
+// monthTotals are totals of a month and of its days
+type monthTotals struct {
+	*totals
+	days map[int]*totals // by day of month
+}
+
+func newRollup() *rollup {
+	return &rollup{all: newTotals(), months: map[int]*monthTotals{}}
+}
+
+// add counts the transaction in the running totals
+func (r *rollup) add(tr model.Transaction) {
+	r.count(tr, 1)
+}
+
+// remove takes the transaction out of the running totals
+func (r *rollup) remove(tr model.Transaction) {
+	r.count(tr, -1)
+}
+
+// count adds the transaction to all its buckets, sign is 1 to add and -1 to remove
+func (r *rollup) count(tr model.Transaction, sign int) {
+	if tr.Type != model.Income && tr.Type != model.Expense {
+		if r.badType == "" {
+			r.badType = tr.Type
+		}
+		return
+	}
+	r.all.count(tr, sign)
+	y, m, d := tr.Date.In(time.Local).Date()
+	if !tr.Date.Equal(time.Date(y, m, d, 0, 0, 0, 0, time.Local)) {
+		r.irregular += sign
+		return
+	}
+	mt, ok := r.months[y*12+int(m)-1]
+	if !ok {
+		mt = &monthTotals{totals: newTotals(), days: map[int]*totals{}}
+		r.months[y*12+int(m)-1] = mt
+	}
+	mt.count(tr, sign)
+	dt, ok := mt.days[d]
+	if !ok {
+		dt = newTotals()
+		mt.days[d] = dt
+	}
+	dt.count(tr, sign)
+}
+
+// covers checks if the running totals can answer the filter without rescan
+func (r *rollup) covers(filter model.Filter) bool {
+	if r.badType != "" || filter.Flagged || !filter.Tags.IsEmpty() {
+		return false
+	}
+	return r.irregular == 0 || (filter.From.IsZero() && filter.To.IsZero())
+}
+
+// sum adds totals of transactions in the filter's date range to res, the filter should be covered
+func (r *rollup) sum(filter model.Filter, res *totals) {
+	if filter.From.IsZero() && filter.To.IsZero() {
+		res.merge(r.all)
+		return
+	}
+	inRange := func(t time.Time) bool {
+		return (filter.From.IsZero() || !t.Before(filter.From)) && (filter.To.IsZero() || !t.After(filter.To))
+	}
+	for key, mt := range r.months {
+		first := time.Date(key/12, time.Month(key%12+1), 1, 0, 0, 0, 0, time.Local)
+		if inRange(first) && inRange(first.AddDate(0, 1, -1)) {
+			res.merge(mt.totals)
+			continue
+		}
+		if !filter.To.IsZero() && first.After(filter.To) ||
+			!filter.From.IsZero() && first.AddDate(0, 1, -1).Before(filter.From) {
+			continue
+		}
+		for day, dt := range mt.days {
+			if inRange(time.Date(key/12, time.Month(key%12+1), day, 0, 0, 0, 0, time.Local)) {
+				res.merge(dt)
+			}
+		}
+	}
+}
+
+// scan sums up transactions of the ledger matching the filter
+func (l *ledger) scan(filter model.Filter, res *totals) error {
+	var err error
+	l.transactions.between(filter.From, filter.To, func(tr *model.Transaction) {
+		if err != nil || !filter.Match(*tr) {
+			return
+		}
+		err = res.add(tr.CategoryName(), tr.Type, tr.Amount)
+	})
+	return err
+}
+
+// sum adds up transactions of the ledger matching the filter to res, from the running totals when they
+// cover the filter. In check mode the running totals are compared with a full rescan, mismatch is logged
+// and the rescan result is used.
+func (l *ledger) sum(filter model.Filter, res *totals, check bool) error {
+	if l.rollup == nil || !l.rollup.covers(filter) {
+		return l.scan(filter, res)
+	}
+	if !check {
+		l.rollup.sum(filter, res)
+		return nil
+	}
+	fast, slow := newTotals(), newTotals()
+	l.rollup.sum(filter, fast)
+	if err := l.scan(filter, slow); err != nil {
+		return err
+	}
+	if err := fast.diff(slow); err != nil {
+		log.Printf("[WARN] running totals don't match rescan, %v", err)
+	}
+	res.merge(slow)
+	return nil
+}
+
+// count adds the transaction to the totals, sign is 1 to add and -1 to remove. Categories without
+// transactions left are dropped, so the totals match the ones made by add from scratch.
+func (t *totals) count(tr model.Transaction, sign int) {
+	category := tr.CategoryName()
+	amounts := t.income
+	if tr.Type == model.Expense {
+		amounts = t.expenses
+	}
+	_ = t.add(category, tr.Type, float64(sign)*tr.Amount) // type is checked by the caller
+	if t.lines == nil {
+		t.lines = map[string]int{}
+	}
+	key := string(tr.Type) + ":" + category
+	t.lines[key] += sign
+	if t.lines[key] <= 0 {
+		delete(t.lines, key)
+		delete(amounts, category)
+	}
+}
+
+// merge adds other totals to t
+func (t *totals) merge(other *totals) {
+	t.report.GrossRevenue += other.report.GrossRevenue
+	t.report.Expenses += other.report.Expenses
+	t.report.NetRevenue = t.report.GrossRevenue - t.report.Expenses
+	for category, amount := range other.income {
+		t.income[category] += amount
+	}
+	for category, amount := range other.expenses {
+		t.expenses[category] += amount
+	}
+}
+
+// diff returns error describing the first difference from other totals, nil if they match
+func (t *totals) diff(other *totals) error {
+	for _, c := range []struct {
+		name        string
+		this, other float64
+	}{
+		{"gross revenue", t.report.GrossRevenue, other.report.GrossRevenue},
+		{"expenses", t.report.Expenses, other.report.Expenses},
+	} {
+		if math.Abs(c.this-c.other) > rollupTolerance {
+			return fmt.Errorf("%s %.2f, expected %.2f", c.name, c.this, c.other)
+		}
+	}
+	for _, c := range []struct {
+		name        string
+		this, other map[string]float64
+	}{{"income", t.income, other.income}, {"expenses", t.expenses, other.expenses}} {
+		if len(c.this) != len(c.other) {
+			return fmt.Errorf("%d %s categories, expected %d", len(c.this), c.name, len(c.other))
+		}
+		for category, amount := range c.other {
+			if v, ok := c.this[category]; !ok || math.Abs(v-amount) > rollupTolerance {
+				return fmt.Errorf("%s category %q %.2f, expected %.2f", c.name, category, v, amount)
+			}
+		}
+	}
+	return nil
+}
+
+// SetAggregateCheck turns on or off the check mode, where every report made from running totals
+// is compared with a full rescan of transactions. Mismatches are logged and rescan results are used.
+func (p *Proc) SetAggregateCheck(on bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.checkAggregates = on
+}
@@ -0,0 +1,108 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestProc_aggregateRunningTotals(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	rnd := rand.New(rand.NewSource(1))
+	var transactions []model.Transaction
+	for i := 0; i < 1000; i++ {
+		tr := model.Transaction{Account: []string{"card", "cash"}[rnd.Intn(2)], Date: date(2020, 1, 1).AddDate(0, 0, rnd.Intn(500)),
+			Type: model.Expense, Amount: float64(rnd.Intn(10000)) / 100, Category: []string{"Fuel", "Food", ""}[rnd.Intn(3)]}
+		if rnd.Intn(3) == 0 {
+			tr.Type = model.Income
+		}
+		transactions = append(transactions, tr)
+	}
+	proc := NewProc()
+	_, err := proc.ProcessTransactions(ctx, transactions)
+	require.NoError(t, err)
+	category := "Fuel"
+	_, err = proc.UpdateTransaction(ctx, "1", model.TransactionUpdate{Category: &category})
+	require.NoError(t, err)
+
+	for _, filter := range []model.Filter{
+		{},
+		{Accounts: []string{"card"}},
+		{From: date(2020, 3, 1), To: date(2020, 3, 31)},
+		{From: date(2020, 2, 15), To: date(2020, 7, 3)},
+		{From: date(2020, 12, 31)},
+		{To: date(2020, 1, 1)},
+		{From: date(2020, 5, 10), To: date(2020, 5, 10), Accounts: []string{"cash"}},
+		{From: date(2022, 1, 1)},
+	} {
+		t.Run(fmt.Sprintf("%v", filter), func(t *testing.T) {
+			res, err := proc.aggregate(filter)
+			require.NoError(t, err)
+			expected := newTotals()
+			ledgers, err := proc.selectLedgers(filter.Accounts)
+			require.NoError(t, err)
+			for _, l := range ledgers {
+				require.NoError(t, l.scan(filter, expected))
+			}
+			assert.NoError(t, res.diff(expected))
+			assert.InDelta(t, expected.report.NetRevenue, res.report.NetRevenue, rollupTolerance)
+		})
+	}
+}
+
+func TestRollup_covers(t *testing.T) {
+	r := newRollup()
+	r.add(model.Transaction{Date: date(2020, 7, 1), Type: model.Income, Amount: 40})
+	expr, err := model.ParseTagExpr("lawn")
+	require.NoError(t, err)
+	assert.True(t, r.covers(model.Filter{From: date(2020, 7, 1)}))
+	assert.False(t, r.covers(model.Filter{Tags: expr}), "tags need rescan")
+	assert.False(t, r.covers(model.Filter{Flagged: true}), "flags need rescan")
+
+	r.add(model.Transaction{Date: date(2020, 7, 1).Add(time.Hour), Type: model.Expense, Amount: 10})
+	assert.True(t, r.covers(model.Filter{}))
+	assert.False(t, r.covers(model.Filter{From: date(2020, 7, 1)}), "transaction not at midnight needs rescan")
+	r.remove(model.Transaction{Date: date(2020, 7, 1).Add(time.Hour), Type: model.Expense, Amount: 10})
+	assert.True(t, r.covers(model.Filter{From: date(2020, 7, 1)}))
+	assert.Equal(t, model.Report{GrossRevenue: 40, NetRevenue: 40}, r.all.report)
+	assert.Equal(t, map[string]float64{}, r.all.expenses, "emptied category dropped")
+
+	r.add(model.Transaction{Type: "Refund", Amount: 10})
+	assert.False(t, r.covers(model.Filter{}), "unsupported type needs rescan")
+}
+
+func TestProc_SetAggregateCheck(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	proc := NewProc()
+	_, err := proc.ProcessTransactions(ctx, []model.Transaction{
+		{Date: date(2020, 7, 1), Type: model.Expense, Amount: 18.77, Memo: "Fuel"},
+		{Date: date(2020, 7, 4), Type: model.Income, Amount: 40, Memo: "347 Woodrow"},
+	})
+	require.NoError(t, err)
+	proc.ledgers[model.DefaultAccount].rollup.months[2020*12+6].days[4].report.GrossRevenue = 400 // corrupt
+	buf := bytes.Buffer{}
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&buf)
+
+	report, err := proc.GenerateReport(ctx, model.Filter{From: date(2020, 7, 4), To: date(2020, 7, 4)})
+	require.NoError(t, err)
+	assert.Equal(t, 400.0, report.GrossRevenue, "running totals used")
+	assert.Empty(t, buf.String())
+
+	proc.SetAggregateCheck(true)
+	report, err = proc.GenerateReport(ctx, model.Filter{From: date(2020, 7, 4), To: date(2020, 7, 4)})
+	require.NoError(t, err)
+	assert.Equal(t, 40.0, report.GrossRevenue, "rescan used")
+	assert.Contains(t, buf.String(), "[WARN] running totals don't match rescan, gross revenue 400.00, expected 40.00")
+}
@@ -0,0 +1,96 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"github.com/mrnbort/summer_break/model"
+	"strconv"
+	"strings"
+)
+
+// Schedules returns all recurring schedules in order they were added
+func (p *Proc) Schedules(ctx context.Context) ([]model.Schedule, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	res := make([]model.Schedule, 0, len(p.schedules))
+	for _, s := range p.schedules {
+		res = append(res, withNext(s))
+	}
+	return res, nil
+}
+
+// AddSchedule adds recurring schedule and returns it with assigned id
+func (p *Proc) AddSchedule(ctx context.Context, sch model.Schedule) (model.Schedule, error) {
+	select {
+	case <-ctx.Done():
+		return model.Schedule{}, ctx.Err()
+	default:
+	}
+
+	if err := sch.Validate(); err != nil {
+		return model.Schedule{}, err
+	}
+	sch.Tags = model.NormalizeTags(sch.Tags)
+	sch.Category = strings.TrimSpace(sch.Category)
+	sch.Count = 0
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastScheduleID++
+	sch.ID = "s-" + strconv.FormatInt(p.lastScheduleID, 10)
+	p.schedules = append(p.schedules, sch)
+	return withNext(sch), nil
+}
+
+// DeleteSchedule removes schedule with given id, transactions already made from it are kept
+func (p *Proc) DeleteSchedule(ctx context.Context, id string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, s := range p.schedules {
+		if s.ID == id {
+			p.schedules = append(p.schedules[:i], p.schedules[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("schedule %q: %w", id, model.ErrNotFound)
+}
+
+// AdvanceSchedule records that the first count occurrences of the schedule are turned into transactions.
+// The count never goes back, so repeated calls are harmless.
+func (p *Proc) AdvanceSchedule(ctx context.Context, id string, count int) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := range p.schedules {
+		if p.schedules[i].ID == id {
+			if count > p.schedules[i].Count {
+				p.schedules[i].Count = count
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("schedule %q: %w", id, model.ErrNotFound)
+}
+
+// withNext sets date of the next occurrence of the schedule
+func withNext(s model.Schedule) model.Schedule {
+	s.Next, _ = s.Occurrence(s.Count)
+	return s
+}
@@ -0,0 +1,40 @@
+package processor
+
+import (
+	"context"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestProc_Schedules(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	proc := NewProc()
+	rent, err := proc.AddSchedule(ctx, model.Schedule{Type: model.Expense, Amount: 1200, Memo: "Rent", Tags: []string{"Home"},
+		Freq: model.FreqMonthly, Start: date(2020, 7, 1)})
+	require.NoError(t, err)
+	assert.Equal(t, "s-1", rent.ID)
+	assert.Equal(t, []string{"home"}, rent.Tags)
+	assert.Equal(t, date(2020, 7, 1), rent.Next)
+	_, err = proc.AddSchedule(ctx, model.Schedule{Type: model.Expense, Amount: 1200, Freq: "daily", Start: date(2020, 7, 1)})
+	require.Error(t, err)
+
+	require.NoError(t, proc.AdvanceSchedule(ctx, "s-1", 2))
+	require.NoError(t, proc.AdvanceSchedule(ctx, "s-1", 1), "count never goes back")
+	require.ErrorIs(t, proc.AdvanceSchedule(ctx, "s-9", 1), model.ErrNotFound)
+	schedules, err := proc.Schedules(ctx)
+	require.NoError(t, err)
+	require.Len(t, schedules, 1)
+	assert.Equal(t, 2, schedules[0].Count)
+	assert.Equal(t, date(2020, 9, 1), schedules[0].Next)
+
+	require.NoError(t, proc.DeleteSchedule(ctx, "s-1"))
+	require.ErrorIs(t, proc.DeleteSchedule(ctx, "s-1"), model.ErrNotFound)
+	schedules, err = proc.Schedules(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, schedules)
+}
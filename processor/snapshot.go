@@ -0,0 +1,136 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mrnbort/summer_break/model"
+)
+
+// Snapshot returns the stored state, transactions are ordered by account and date
+func (p *Proc) Snapshot(ctx context.Context) (model.Snapshot, error) {
+	select {
+	case <-ctx.Done():
+		return model.Snapshot{}, ctx.Err()
+	default:
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.snapshot(), nil
+}
+
+// snapshot returns the stored state, caller should hold the lock
+func (p *Proc) snapshot() model.Snapshot {
+	snap := model.Snapshot{
+		Version:        model.SnapshotVersion,
+		Accounts:       []model.Account{},
+		Transactions:   []model.Transaction{},
+		Budgets:        append([]model.Budget{}, p.budgets...),
+		Schedules:      append([]model.Schedule{}, p.schedules...),
+		Tax:            p.tax,
+		LastID:         p.lastID,
+		LastBudgetID:   p.lastBudgetID,
+		LastScheduleID: p.lastScheduleID,
+		APIKeys:        append([]model.APIKey(nil), p.apiKeys...),
+		LastAPIKeyID:   p.lastAPIKeyID,
+		Users:          append([]model.User(nil), p.users...),
+		LastGeneration: p.lastGeneration,
+		Chart:          append([]model.ChartAccount(nil), p.saved.chart...),
+		JournalEntries: append([]model.JournalEntry(nil), p.saved.entries...),
+	}
+	if p.journal != nil {
+		snap.Chart, snap.JournalEntries = p.journal.chart(), p.journal.manual()
+	}
+	for _, name := range p.accountNames() {
+		l := p.ledgers[name]
+		snap.Accounts = append(snap.Accounts, l.account(name))
+		l.transactions.each(func(tr *model.Transaction) {
+			snap.Transactions = append(snap.Transactions, *tr)
+		})
+	}
+	return snap
+}
+
+// Restore replaces the stored state with the snapshot. Transactions are stored as they are, without
+// anomaly checks. Must be called before double-entry mode is enabled.
+func (p *Proc) Restore(ctx context.Context, snap model.Snapshot) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	if snap.Version != model.SnapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.journal != nil {
+		return errors.New("can't restore in double-entry mode")
+	}
+	return p.restore(snap)
+}
+
+// restore replaces the stored state with the snapshot, nothing is changed if the snapshot is invalid.
+// Caller should hold the lock.
+func (p *Proc) restore(snap model.Snapshot) error {
+	if err := validateAccounts(snap.Transactions); err != nil {
+		return err
+	}
+	if err := snap.Tax.Validate(); err != nil {
+		return fmt.Errorf("tax config: %w", err)
+	}
+
+	for _, acc := range snap.Chart {
+		if err := acc.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, entry := range snap.JournalEntries {
+		if err := entry.Validate(); err != nil {
+			return fmt.Errorf("journal entry %s: %w", entry.ID, err)
+		}
+	}
+
+	ledgers, byID := map[string]*ledger{}, map[string]string{}
+	for _, acc := range snap.Accounts {
+		if err := model.ValidateAccount(acc.Name); err != nil {
+			return err
+		}
+		ledgers[acc.Name] = &ledger{openingBalance: acc.OpeningBalance, openingDate: acc.OpeningDate}
+	}
+	for _, tr := range snap.Transactions {
+		if tr.ID == "" {
+			return errors.New("transaction without id")
+		}
+		if _, ok := byID[tr.ID]; ok {
+			return fmt.Errorf("transaction %q: %w", tr.ID, model.ErrConflict)
+		}
+		if tr.Account == "" {
+			tr.Account = model.DefaultAccount
+		}
+		l, ok := ledgers[tr.Account]
+		if !ok {
+			l = &ledger{}
+			ledgers[tr.Account] = l
+		}
+		l.add(tr)
+		byID[tr.ID] = tr.Account
+	}
+
+	p.ledgers, p.byID, p.lastID = ledgers, byID, snap.LastID
+	p.budgets, p.lastBudgetID = append([]model.Budget(nil), snap.Budgets...), snap.LastBudgetID
+	p.schedules, p.lastScheduleID = append([]model.Schedule(nil), snap.Schedules...), snap.LastScheduleID
+	p.tax = snap.Tax
+	p.apiKeys, p.lastAPIKeyID = append([]model.APIKey(nil), snap.APIKeys...), snap.LastAPIKeyID
+	p.users, p.lastGeneration = append([]model.User(nil), snap.Users...), snap.LastGeneration
+	for _, u := range p.users {
+		if u.Generation > p.lastGeneration {
+			p.lastGeneration = u.Generation
+		}
+	}
+	p.saved = savedJournal{chart: append([]model.ChartAccount(nil), snap.Chart...),
+		entries: append([]model.JournalEntry(nil), snap.JournalEntries...)}
+	return nil
+}
@@ -0,0 +1,100 @@
+package processor
+
+import (
+	"context"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestProc_Snapshot(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	proc := NewProc()
+	_, err := proc.SetOpeningBalance(ctx, "card", 100, date(2020, 6, 30))
+	require.NoError(t, err)
+	_, err = proc.ProcessTransactions(ctx, []model.Transaction{
+		{Account: "card", Date: date(2020, 7, 4), Type: model.Income, Amount: 40, Memo: "347 Woodrow", Tags: []string{"woodrow"}},
+		{Date: date(2020, 7, 1), Type: model.Expense, Amount: 18.77, Memo: "Shell", Category: "Fuel"},
+	})
+	require.NoError(t, err)
+	_, err = proc.AddBudget(ctx, model.Budget{Category: "Fuel", Period: model.BudgetMonthly, Amount: 50})
+	require.NoError(t, err)
+	require.NoError(t, proc.SetTaxConfig(ctx, model.TaxConfig{FiscalYearStart: time.April}))
+
+	snap, err := proc.Snapshot(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, model.SnapshotVersion, snap.Version)
+	require.Len(t, snap.Transactions, 2)
+	assert.Equal(t, "card", snap.Transactions[0].Account, "ordered by account")
+	assert.Equal(t, int64(2), snap.LastID)
+	assert.Equal(t, int64(1), snap.LastBudgetID)
+
+	restored := NewProc()
+	require.NoError(t, restored.Restore(ctx, snap))
+	again, err := restored.Snapshot(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, snap, again)
+
+	balance, err := restored.Balance(ctx, []string{"card"}, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, 140.0, balance.Balance)
+	stored, err := restored.ProcessTransactions(ctx, []model.Transaction{{Type: model.Income, Amount: 1}})
+	require.NoError(t, err)
+	assert.Equal(t, "3", stored[0].ID, "ids continue")
+	budget, err := restored.AddBudget(ctx, model.Budget{Category: "Rent", Period: model.BudgetMonthly, Amount: 500})
+	require.NoError(t, err)
+	assert.Equal(t, "b-2", budget.ID)
+
+	snap.Version = 42
+	assert.Error(t, restored.Restore(ctx, snap))
+	snap.Version = model.SnapshotVersion
+	snap.Transactions = append(snap.Transactions, snap.Transactions[0])
+	assert.ErrorIs(t, restored.Restore(ctx, snap), model.ErrConflict)
+}
+
+func TestProc_SnapshotJournal(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	proc := NewProc()
+	_, err := proc.ProcessTransactions(ctx, []model.Transaction{
+		{Account: "card", Date: date(2020, 7, 12), Type: model.Expense, Amount: 27.5, Memo: "Repairs"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, proc.EnableDoubleEntry("Assets:Checking"))
+	_, err = proc.SetChartAccount(ctx, model.ChartAccount{Name: "Liabilities:Card", Type: model.LiabilityAccount, Ledger: "card"})
+	require.NoError(t, err)
+	_, err = proc.PostEntry(ctx, model.JournalEntry{Date: date(2020, 7, 20), Memo: "Card payment", Postings: []model.Posting{
+		{Account: "Liabilities:Card", Amount: 27.5}, {Account: "Assets:Checking", Amount: -27.5}}})
+	require.NoError(t, err)
+
+	snap, err := proc.Snapshot(ctx)
+	require.NoError(t, err)
+	assert.Len(t, snap.Chart, 4)
+	require.Len(t, snap.JournalEntries, 1, "manual entries only")
+	assert.Equal(t, "Card payment", snap.JournalEntries[0].Memo)
+
+	restored := NewProc()
+	require.NoError(t, restored.Restore(ctx, snap))
+	again, err := restored.Snapshot(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, snap, again, "kept until double-entry mode is enabled")
+	require.NoError(t, restored.EnableDoubleEntry("Assets:Checking"))
+	balances, err := restored.TrialBalance(ctx, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, []model.ChartBalance{
+		{Account: "Assets:Checking", Type: model.AssetAccount, Balance: -27.5},
+		{Account: "Expenses", Type: model.ExpenseAccount, Balance: 27.5},
+		{Account: "Income", Type: model.IncomeAccount},
+		{Account: "Liabilities:Card", Type: model.LiabilityAccount},
+	}, balances, "card expense posted against the mapped account and paid off")
+
+	snap.JournalEntries[0].Postings[0].Account = "Liabilities:Other"
+	broken := NewProc()
+	require.NoError(t, broken.Restore(ctx, snap))
+	assert.ErrorIs(t, broken.EnableDoubleEntry("Assets:Checking"), model.ErrNotFound)
+}
@@ -0,0 +1,121 @@
+package processor
+
+import (
+	"context"
+	"github.com/mrnbort/summer_break/model"
+	"sort"
+)
+
+// SetTaxConfig sets fiscal year start and mapping of categories to tax lines
+func (p *Proc) SetTaxConfig(ctx context.Context, cfg model.TaxConfig) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	lines := make(map[string]string, len(cfg.Lines))
+	for category, line := range cfg.Lines {
+		lines[category] = line
+	}
+	cfg.Lines = lines
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.tax = cfg
+	return nil
+}
+
+// TaxConfig returns the current tax configuration
+func (p *Proc) TaxConfig(ctx context.Context) (model.TaxConfig, error) {
+	select {
+	case <-ctx.Done():
+		return model.TaxConfig{}, ctx.Err()
+	default:
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	res := model.TaxConfig{FiscalYearStart: p.tax.FiscalYearStart, Lines: make(map[string]string, len(p.tax.Lines))}
+	for category, line := range p.tax.Lines {
+		res.Lines[category] = line
+	}
+	return res, nil
+}
+
+// TaxSummary makes summary of the tax year from stored transactions matching the filter's accounts and tags.
+// Income of unmapped categories goes to model.GrossReceiptsLine, expenses of unmapped categories are not deductible.
+func (p *Proc) TaxSummary(ctx context.Context, year int, filter model.Filter) (model.TaxSummary, error) {
+	select {
+	case <-ctx.Done():
+		return model.TaxSummary{}, ctx.Err()
+	default:
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	period := p.tax.TaxYear(year)
+	filter.From, filter.To = period.From, period.To
+	ledgers, err := p.selectLedgers(filter.Accounts)
+	if err != nil {
+		return model.TaxSummary{}, err
+	}
+
+	income, deductions, nonDeductible := map[string]*model.TaxLine{}, map[string]*model.TaxLine{}, map[string]*model.TaxLine{}
+	res := model.TaxSummary{Year: year, Period: period}
+	for _, l := range ledgers {
+		l.transactions.between(filter.From, filter.To, func(t *model.Transaction) {
+			tr := *t
+			if !filter.Match(tr) {
+				return
+			}
+			category := tr.CategoryName()
+			line := p.tax.Line(category)
+			switch {
+			case tr.Type == model.Income:
+				if line == "" {
+					line = model.GrossReceiptsLine
+				}
+				addTaxLine(income, line, category, tr)
+				res.GrossIncome += tr.Amount
+			case tr.Type == model.Expense && line != "":
+				addTaxLine(deductions, line, category, tr)
+				res.TotalDeductions += tr.Amount
+			case tr.Type == model.Expense:
+				addTaxLine(nonDeductible, category, category, tr)
+			}
+		})
+	}
+	res.NetProfit = res.GrossIncome - res.TotalDeductions
+	res.Income, res.Deductions, res.NonDeductible = taxLines(income), taxLines(deductions), taxLines(nonDeductible)
+	return res, nil
+}
+
+// addTaxLine adds transaction of the category to the named line
+func addTaxLine(lines map[string]*model.TaxLine, line, category string, tr model.Transaction) {
+	l, ok := lines[line]
+	if !ok {
+		l = &model.TaxLine{Line: line}
+		lines[line] = l
+	}
+	if !model.ContainsString(l.Categories, category) {
+		l.Categories = append(l.Categories, category)
+	}
+	l.Total += tr.Amount
+	l.Transactions = append(l.Transactions, tr)
+}
+
+// taxLines returns lines sorted by name, with sorted categories and transactions ordered by date
+func taxLines(lines map[string]*model.TaxLine) []model.TaxLine {
+	res := make([]model.TaxLine, 0, len(lines))
+	for _, l := range lines {
+		sort.Strings(l.Categories)
+		sort.SliceStable(l.Transactions, func(i, j int) bool { return l.Transactions[i].Date.Before(l.Transactions[j].Date) })
+		res = append(res, *l)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Line < res[j].Line })
+	return res
+}
@@ -0,0 +1,70 @@
+package processor
+
+import (
+	"context"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestProc_TaxSummary(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	proc := NewProc()
+	_, err := proc.ProcessTransactions(ctx, []model.Transaction{
+		{Date: date(2020, 6, 10), Type: model.Expense, Amount: 10, Memo: "Fuel"},
+		{Date: date(2020, 7, 4), Type: model.Income, Amount: 40, Memo: "347 Woodrow", Category: "Lawns"},
+		{Date: date(2020, 7, 1), Type: model.Expense, Amount: 18.77, Memo: "Fuel"},
+		{Date: date(2020, 7, 12), Type: model.Expense, Amount: 27.5, Memo: "Repairs"},
+		{Date: date(2020, 8, 1), Type: model.Expense, Amount: 12, Memo: "Lunch"},
+		{Date: date(2020, 9, 1), Type: model.Income, Amount: 100, Memo: "Interest", Category: "Interest"},
+		{Date: date(2021, 1, 5), Type: model.Income, Amount: 50, Memo: "Snow", Category: "Snow removal"},
+	})
+	require.NoError(t, err)
+
+	err = proc.SetTaxConfig(ctx, model.TaxConfig{Lines: map[string]string{
+		"Fuel":     "Car and truck expenses",
+		"Repairs":  "Repairs and maintenance",
+		"interest": "Other income",
+	}})
+	require.NoError(t, err)
+
+	res, err := proc.TaxSummary(ctx, 2020, model.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, model.Period{From: date(2020, 1, 1), To: date(2020, 12, 31)}, res.Period)
+	require.Len(t, res.Income, 2)
+	assert.Equal(t, model.GrossReceiptsLine, res.Income[0].Line)
+	assert.Equal(t, []string{"Lawns"}, res.Income[0].Categories)
+	assert.Equal(t, "Other income", res.Income[1].Line)
+	assert.Equal(t, 100.0, res.Income[1].Total)
+
+	require.Len(t, res.Deductions, 2)
+	assert.Equal(t, "Car and truck expenses", res.Deductions[0].Line)
+	assert.InDelta(t, 28.77, res.Deductions[0].Total, 0.0001)
+	require.Len(t, res.Deductions[0].Transactions, 2)
+	assert.Equal(t, date(2020, 6, 10), res.Deductions[0].Transactions[0].Date)
+	assert.Equal(t, "Repairs and maintenance", res.Deductions[1].Line)
+
+	require.Len(t, res.NonDeductible, 1)
+	assert.Equal(t, "Lunch", res.NonDeductible[0].Line)
+	assert.Equal(t, 140.0, res.GrossIncome)
+	assert.InDelta(t, 56.27, res.TotalDeductions, 0.0001)
+	assert.InDelta(t, 83.73, res.NetProfit, 0.0001)
+
+	// fiscal year starting in July, 2021 is 2020-07-01..2021-06-30
+	cfg, err := proc.TaxConfig(ctx)
+	require.NoError(t, err)
+	cfg.FiscalYearStart = time.July
+	require.NoError(t, proc.SetTaxConfig(ctx, cfg))
+	res, err = proc.TaxSummary(ctx, 2021, model.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, 190.0, res.GrossIncome)
+	assert.InDelta(t, 46.27, res.TotalDeductions, 0.0001)
+
+	_, err = proc.TaxSummary(ctx, 2021, model.Filter{Accounts: []string{"unknown"}})
+	require.ErrorIs(t, err, model.ErrNotFound)
+	require.Error(t, proc.SetTaxConfig(ctx, model.TaxConfig{FiscalYearStart: 13}))
+}
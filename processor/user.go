@@ -0,0 +1,143 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mrnbort/summer_break/model"
+	"strings"
+)
+
+// Users returns all users in order they were added
+func (p *Proc) Users(ctx context.Context) ([]model.User, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return append([]model.User{}, p.users...), nil
+}
+
+// User returns user with given name
+func (p *Proc) User(ctx context.Context, name string) (model.User, error) {
+	select {
+	case <-ctx.Done():
+		return model.User{}, ctx.Err()
+	default:
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if i := p.userIndex(name); i >= 0 {
+		return p.users[i], nil
+	}
+	return model.User{}, fmt.Errorf("user %q: %w", name, model.ErrNotFound)
+}
+
+// AddUser stores new user with password hash, returns model.ErrConflict if the name is taken
+func (p *Proc) AddUser(ctx context.Context, u model.User) (model.User, error) {
+	select {
+	case <-ctx.Done():
+		return model.User{}, ctx.Err()
+	default:
+	}
+
+	u.Name = strings.TrimSpace(u.Name)
+	if err := u.Validate(); err != nil {
+		return model.User{}, err
+	}
+	if u.PasswordHash == "" {
+		return model.User{}, errors.New("password hash is required")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.userIndex(u.Name) >= 0 {
+		return model.User{}, fmt.Errorf("user %q: %w", u.Name, model.ErrConflict)
+	}
+	p.lastGeneration++
+	u.Generation = p.lastGeneration
+	p.users = append(p.users, u)
+	return u, nil
+}
+
+// UpdateUser replaces role and ledgers of the user, and the password hash if it's set. A new password
+// hash gets a new generation of the user's session tokens. Returns model.ErrConflict if the last owner
+// would lose the role.
+func (p *Proc) UpdateUser(ctx context.Context, u model.User) (model.User, error) {
+	select {
+	case <-ctx.Done():
+		return model.User{}, ctx.Err()
+	default:
+	}
+
+	if err := u.Validate(); err != nil {
+		return model.User{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	i := p.userIndex(u.Name)
+	if i < 0 {
+		return model.User{}, fmt.Errorf("user %q: %w", u.Name, model.ErrNotFound)
+	}
+	if u.Role != model.RoleOwner && p.lastOwner(i) {
+		return model.User{}, fmt.Errorf("user %q is the last owner: %w", u.Name, model.ErrConflict)
+	}
+	u.Created, u.Generation = p.users[i].Created, p.users[i].Generation
+	if u.PasswordHash == "" {
+		u.PasswordHash = p.users[i].PasswordHash
+	} else {
+		p.lastGeneration++
+		u.Generation = p.lastGeneration
+	}
+	p.users[i] = u
+	return u, nil
+}
+
+// DeleteUser removes user with given name, returns model.ErrConflict for the last owner
+func (p *Proc) DeleteUser(ctx context.Context, name string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	i := p.userIndex(name)
+	if i < 0 {
+		return fmt.Errorf("user %q: %w", name, model.ErrNotFound)
+	}
+	if p.lastOwner(i) {
+		return fmt.Errorf("user %q is the last owner: %w", name, model.ErrConflict)
+	}
+	p.users = append(p.users[:i], p.users[i+1:]...)
+	return nil
+}
+
+// userIndex returns index of the user with given name, -1 if there is none. Caller should hold the lock.
+func (p *Proc) userIndex(name string) int {
+	for i, u := range p.users {
+		if u.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// lastOwner tells if the i-th user is the only owner, caller should hold the lock
+func (p *Proc) lastOwner(i int) bool {
+	if p.users[i].Role != model.RoleOwner {
+		return false
+	}
+	for j, u := range p.users {
+		if j != i && u.Role == model.RoleOwner {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package processor
+
+import (
+	"context"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestProc_Users(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	proc := NewProc()
+	owner, err := proc.AddUser(ctx, model.User{Name: " alice ", Role: model.RoleOwner, PasswordHash: "h1"})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", owner.Name)
+	_, err = proc.AddUser(ctx, model.User{Name: "alice", Role: model.RoleViewer, PasswordHash: "h2"})
+	assert.ErrorIs(t, err, model.ErrConflict)
+	_, err = proc.AddUser(ctx, model.User{Name: "bob", Role: "boss", PasswordHash: "h2"})
+	assert.Error(t, err)
+	_, err = proc.AddUser(ctx, model.User{Name: "bob", Role: model.RoleViewer})
+	assert.Error(t, err, "no password")
+	_, err = proc.AddUser(ctx, model.User{Name: "bob", Role: model.RoleViewer, PasswordHash: "h2", Ledgers: []string{"Card"}})
+	assert.Error(t, err, "invalid ledger")
+	_, err = proc.AddUser(ctx, model.User{Name: "bob", Role: model.RoleViewer, PasswordHash: "h2", Ledgers: []string{"card"}})
+	require.NoError(t, err)
+
+	bob, err := proc.UpdateUser(ctx, model.User{Name: "bob", Role: model.RoleBookkeeper})
+	require.NoError(t, err)
+	assert.Equal(t, "h2", bob.PasswordHash, "kept if not set")
+	assert.Empty(t, bob.Ledgers, "access to all ledgers")
+	assert.Equal(t, int64(2), bob.Generation, "same password, alice has the first one")
+	bob, err = proc.UpdateUser(ctx, model.User{Name: "bob", Role: model.RoleBookkeeper, PasswordHash: "h3", Generation: 7})
+	require.NoError(t, err)
+	assert.Equal(t, "h3", bob.PasswordHash)
+	assert.Equal(t, int64(3), bob.Generation, "changed with the password")
+	_, err = proc.UpdateUser(ctx, model.User{Name: "alice", Role: model.RoleViewer})
+	assert.ErrorIs(t, err, model.ErrConflict, "the last owner")
+	assert.ErrorIs(t, proc.DeleteUser(ctx, "alice"), model.ErrConflict)
+	_, err = proc.UpdateUser(ctx, model.User{Name: "carol", Role: model.RoleViewer})
+	assert.ErrorIs(t, err, model.ErrNotFound)
+
+	snap, err := proc.Snapshot(ctx)
+	require.NoError(t, err)
+	restored := NewProc()
+	require.NoError(t, restored.Restore(ctx, snap))
+	users, err := restored.Users(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []model.User{owner, bob}, users)
+	carol, err := restored.AddUser(ctx, model.User{Name: "carol", Role: model.RoleViewer, PasswordHash: "h4"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), carol.Generation, "continues after restore")
+
+	require.NoError(t, proc.DeleteUser(ctx, "bob"))
+	_, err = proc.User(ctx, "bob")
+	assert.ErrorIs(t, err, model.ErrNotFound)
+	assert.ErrorIs(t, proc.DeleteUser(ctx, "bob"), model.ErrNotFound)
+	bob, err = proc.AddUser(ctx, model.User{Name: "bob", Role: model.RoleViewer, PasswordHash: "h3"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), bob.Generation, "made again with a new generation")
+	u, err := proc.User(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, owner, u)
+}
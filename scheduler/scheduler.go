@@ -0,0 +1,94 @@
+// Package scheduler turns occurrences of recurring schedules into transactions.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mrnbort/summer_break/model"
+	"log"
+	"time"
+)
+
+// Store provides schedules and keeps transactions made from them
+type Store interface {
+	Schedules(ctx context.Context) ([]model.Schedule, error)
+	ProcessTransactions(ctx context.Context, transactions []model.Transaction) ([]model.Transaction, error)
+	AdvanceSchedule(ctx context.Context, id string, count int) error
+}
+
+// Scheduler periodically stores transactions for all due occurrences of schedules. Missed occurrences,
+// i.e. after downtime, are caught up on the first run. Every occurrence has its own transaction id,
+// so an occurrence stored but not recorded as done in the schedule is not stored again.
+type Scheduler struct {
+	Store    Store
+	Interval time.Duration    // how often to check schedules, a minute if not set
+	Now      func() time.Time // current time, time.Now if not set
+}
+
+// Run materializes due occurrences right away and then every Interval until ctx is canceled
+func (s *Scheduler) Run(ctx context.Context) error {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.Materialize(ctx); err != nil {
+			log.Printf("[WARN] can't materialize schedules: %v", err)
+		} else if n > 0 {
+			log.Printf("[INFO] added %d scheduled transactions", n)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Materialize stores transactions for all occurrences due by now and returns their number
+func (s *Scheduler) Materialize(ctx context.Context) (int, error) {
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	schedules, err := s.Store.Schedules(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	var errs []error
+	for _, sch := range schedules {
+		n, err := s.materialize(ctx, sch, now())
+		count += n
+		if err != nil {
+			errs = append(errs, fmt.Errorf("schedule %s: %w", sch.ID, err))
+		}
+	}
+	return count, errors.Join(errs...)
+}
+
+// materialize stores due occurrences of the schedule one by one, so a failure doesn't lose the ones before it
+func (s *Scheduler) materialize(ctx context.Context, sch model.Schedule, now time.Time) (int, error) {
+	count := 0
+	for n := sch.Count; ; n++ {
+		tr, ok := sch.Transaction(n)
+		if !ok || tr.Date.After(now) {
+			return count, nil
+		}
+		_, err := s.Store.ProcessTransactions(ctx, []model.Transaction{tr})
+		switch {
+		case err == nil:
+			count++
+		case errors.Is(err, model.ErrConflict): // stored before, but not recorded in the schedule
+		default:
+			return count, err
+		}
+		if err = s.Store.AdvanceSchedule(ctx, sch.ID, n+1); err != nil {
+			return count, err
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/mrnbort/summer_break/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestScheduler_Materialize(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	proc := processor.NewProc()
+	_, err := proc.AddSchedule(ctx, model.Schedule{Type: model.Expense, Amount: 1200, Memo: "Rent", Freq: model.FreqMonthly,
+		Start: date(2020, 5, 1)})
+	require.NoError(t, err)
+	_, err = proc.AddSchedule(ctx, model.Schedule{Account: "card", Type: model.Expense, Amount: 10, Memo: "Music", Freq: model.FreqWeekly,
+		Start: date(2020, 6, 20), End: date(2020, 7, 5)})
+	require.NoError(t, err)
+
+	now := date(2020, 6, 30)
+	s := Scheduler{Store: proc, Now: func() time.Time { return now }}
+
+	// catch up with occurrences since the start
+	n, err := s.Materialize(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 4, n) // rent for may and june, music on june 20 and 27
+	n, err = s.Materialize(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n, "nothing is due")
+
+	now = date(2020, 8, 1)
+	n, err = s.Materialize(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n) // rent for july and august, music on july 4, the schedule is over
+
+	lines, err := proc.Transactions(ctx, model.Filter{Accounts: []string{model.DefaultAccount}})
+	require.NoError(t, err)
+	require.Len(t, lines, 4)
+	assert.Equal(t, "s-1-4", lines[3].ID)
+	assert.Equal(t, date(2020, 8, 1), lines[3].Date)
+
+	schedules, err := proc.Schedules(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 4, schedules[0].Count)
+	assert.Equal(t, date(2020, 9, 1), schedules[0].Next)
+	assert.Equal(t, 3, schedules[1].Count)
+	assert.True(t, schedules[1].Next.IsZero())
+}
+
+func TestScheduler_MaterializeNoDuplicates(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	proc := processor.NewProc()
+	_, err := proc.AddSchedule(ctx, model.Schedule{Type: model.Expense, Amount: 1200, Memo: "Rent", Freq: model.FreqMonthly,
+		Start: date(2020, 5, 1)})
+	require.NoError(t, err)
+
+	// the first occurrence is stored, but the schedule wasn't advanced, i.e. service stopped in between
+	store := &failingStore{Proc: proc, failAdvance: true}
+	s := Scheduler{Store: store, Now: func() time.Time { return date(2020, 6, 15) }}
+	_, err = s.Materialize(ctx)
+	require.Error(t, err)
+
+	store.failAdvance = false
+	n, err := s.Materialize(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n, "only june is added")
+	lines, err := proc.Transactions(ctx, model.Filter{})
+	require.NoError(t, err)
+	assert.Len(t, lines, 2)
+}
+
+func TestScheduler_Run(t *testing.T) {
+	proc := processor.NewProc()
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := proc.AddSchedule(ctx, model.Schedule{Type: model.Expense, Amount: 1200, Memo: "Rent", Freq: model.FreqMonthly,
+		Start: date(2020, 5, 1), End: date(2020, 6, 30)})
+	require.NoError(t, err)
+
+	s := Scheduler{Store: proc, Interval: time.Millisecond}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assert.ErrorIs(t, s.Run(ctx), context.Canceled)
+	}()
+	require.Eventually(t, func() bool {
+		lines, err := proc.Transactions(ctx, model.Filter{})
+		return err == nil && len(lines) == 2
+	}, time.Second, time.Millisecond)
+	cancel()
+	wg.Wait()
+}
+
+type failingStore struct {
+	*processor.Proc
+	failAdvance bool
+}
+
+func (s *failingStore) AdvanceSchedule(ctx context.Context, id string, count int) error {
+	if s.failAdvance {
+		return errors.New("failed")
+	}
+	return s.Proc.AdvanceSchedule(ctx, id, count)
+}
+
+func date(y, m, d int) time.Time {
+	return time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.Local)
+}
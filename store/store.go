@@ -0,0 +1,124 @@
+// Package store keeps snapshots of the stored state in a file between runs.
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mrnbort/summer_break/model"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultInterval is how often the state is saved by File.Run if no interval is given
+const DefaultInterval = time.Minute
+
+// State is the state kept in the file
+type State interface {
+	Snapshot(ctx context.Context) (model.Snapshot, error)
+	Restore(ctx context.Context, snap model.Snapshot) error
+}
+
+// ErrLocked returned by File.Lock if the store is used by another process
+var ErrLocked = errors.New("store is locked")
+
+// File keeps JSON snapshot of the state in Path. The file is replaced by rename on save,
+// so it always has a complete snapshot.
+type File struct {
+	Path string
+}
+
+// Load restores the state from the file, a missing file leaves the state as is
+func (f File) Load(ctx context.Context, state State) error {
+	data, err := os.ReadFile(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	snap := model.Snapshot{}
+	if err = json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("can't parse %s: %w", f.Path, err)
+	}
+	return state.Restore(ctx, snap)
+}
+
+// Save writes snapshot of the state to the file
+func (f File) Save(ctx context.Context, state State) error {
+	snap, err := state.Snapshot(ctx)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.Path), filepath.Base(f.Path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if e := tmp.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), f.Path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("can't save %s: %w", f.Path, err)
+	}
+	return nil
+}
+
+// Lock takes the lock of the file, Path + ".lock" with pid of the process, so the server and commands changing
+// the store don't run at once and overwrite each other's changes. Returns ErrLocked if it's taken, a lock left
+// by a crashed process has to be removed by hand. The returned func releases the lock.
+func (f File) Lock() (func() error, error) {
+	path := f.Path + ".lock"
+	lock, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if errors.Is(err, os.ErrExist) {
+		owner, _ := os.ReadFile(path)
+		return nil, fmt.Errorf("%s is taken by %s, remove it if the process is not running: %w", path,
+			strings.TrimSpace(string(owner)), ErrLocked)
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, err = fmt.Fprintf(lock, "pid %d\n", os.Getpid())
+	if e := lock.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return nil, fmt.Errorf("can't lock %s: %w", f.Path, err)
+	}
+	return func() error { return os.Remove(path) }, nil
+}
+
+// Run saves the state every interval and once more when ctx is canceled
+func (f File) Run(ctx context.Context, state State, interval time.Duration) error {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := f.Save(context.Background(), state); err != nil {
+				return err
+			}
+			return ctx.Err()
+		case <-ticker.C:
+			if err := f.Save(ctx, state); err != nil {
+				log.Printf("[WARN] can't save state: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"github.com/mrnbort/summer_break/model"
+	"github.com/mrnbort/summer_break/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFile(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	f := File{Path: filepath.Join(t.TempDir(), "state.json")}
+	proc := processor.NewProc()
+	require.NoError(t, f.Load(ctx, proc), "missing file is fine")
+
+	_, err := proc.ProcessTransactions(ctx, []model.Transaction{
+		{Date: time.Date(2020, 7, 4, 0, 0, 0, 0, time.Local), Type: model.Income, Amount: 40, Memo: "347 Woodrow"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, f.Save(ctx, proc))
+
+	loaded := processor.NewProc()
+	require.NoError(t, f.Load(ctx, loaded))
+	report, err := loaded.GenerateReport(ctx, model.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, model.Report{GrossRevenue: 40, NetRevenue: 40}, report)
+	files, err := os.ReadDir(filepath.Dir(f.Path))
+	require.NoError(t, err)
+	assert.Len(t, files, 1, "no temp files left")
+
+	require.NoError(t, os.WriteFile(f.Path, []byte("{bad"), 0o600))
+	assert.Error(t, f.Load(ctx, processor.NewProc()))
+}
+
+func TestFile_Lock(t *testing.T) {
+	f := File{Path: filepath.Join(t.TempDir(), "state.json")}
+	unlock, err := f.Lock()
+	require.NoError(t, err)
+	_, err = f.Lock()
+	assert.ErrorIs(t, err, ErrLocked)
+	assert.Contains(t, err.Error(), fmt.Sprintf("pid %d", os.Getpid()))
+	require.NoError(t, unlock())
+	unlock, err = f.Lock()
+	require.NoError(t, err, "released")
+	require.NoError(t, unlock())
+}
+
+func TestFile_Run(t *testing.T) {
+	f := File{Path: filepath.Join(t.TempDir(), "state.json")}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- f.Run(ctx, processor.NewProc(), time.Hour) }()
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.FileExists(t, f.Path, "saved on shutdown")
+}